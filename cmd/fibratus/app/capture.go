@@ -53,6 +53,7 @@ func capture(cmd *cobra.Command, args []string) error {
 	if err := common.Init(captureConfig, true); err != nil {
 		return err
 	}
+	defer captureConfig.Filters.CloseIOCs()
 
 	// set up the signals
 	stopCh := common.Signals()
@@ -86,7 +87,7 @@ func capture(cmd *cobra.Command, args []string) error {
 	}
 
 	kstreamc := kstream.NewConsumer(ktracec, psnap, hsnap, captureConfig)
-	kfilter, err := filter.NewFromCLI(args, captureConfig)
+	kfilter, err := filter.NewFromCLI(args, psnap, captureConfig)
 	if err != nil {
 		return err
 	}
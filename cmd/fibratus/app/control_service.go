@@ -24,6 +24,7 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/aggregator"
 	"github.com/rabbitstack/fibratus/pkg/api"
 	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/exceptions"
 	"github.com/rabbitstack/fibratus/pkg/handle"
 	"github.com/rabbitstack/fibratus/pkg/kstream"
 	"github.com/rabbitstack/fibratus/pkg/ps"
@@ -61,9 +62,10 @@ var (
 	// windows event logger
 	evtlog debug.Log
 
-	ctrl     kstream.KtraceController
-	consumer kstream.Consumer
-	aggr     *aggregator.BufferedAggregator
+	ctrl      kstream.KtraceController
+	consumer  kstream.Consumer
+	aggr      *aggregator.BufferedAggregator
+	allowlist *exceptions.Allowlist
 )
 
 func init() {
@@ -205,6 +207,10 @@ loop:
 	if aggr != nil {
 		_ = aggr.Stop()
 	}
+	if allowlist != nil {
+		allowlist.Close()
+	}
+	svcConfig.Filters.CloseIOCs()
 	_ = handle.CloseTimeout()
 	_ = api.CloseServer()
 
@@ -229,19 +235,35 @@ func (s *fsvc) run() error {
 	hsnap := handle.NewSnapshotter(svcConfig, nil)
 	psnap := ps.NewSnapshotter(hsnap, svcConfig)
 	consumer = kstream.NewConsumer(ctrl, psnap, hsnap, svcConfig)
+	if svcConfig.Exceptions.Enabled {
+		allowlist, err = exceptions.New(psnap, svcConfig)
+		if err != nil {
+			return err
+		}
+		consumer.SetAllowlist(allowlist)
+	}
 	// open the kernel event stream, start processing events and forwarding to outputs
 	err = consumer.OpenKstream()
 	if err != nil {
 		return err
 	}
 
+	listeners, err := common.Listeners(psnap, svcConfig)
+	if err != nil {
+		return err
+	}
+	outputs, err := common.Outputs(psnap, svcConfig)
+	if err != nil {
+		return err
+	}
 	aggr, err = aggregator.NewBuffered(
 		consumer.Events(),
 		consumer.Errors(),
 		svcConfig.Aggregator,
-		svcConfig.Output,
+		outputs,
 		svcConfig.Transformers,
 		svcConfig.Alertsenders,
+		listeners...,
 	)
 	if err != nil {
 		return err
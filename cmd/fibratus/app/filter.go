@@ -0,0 +1,334 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/rabbitstack/fibratus/cmd/fibratus/common"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
+	"github.com/rabbitstack/fibratus/pkg/filter/testcase"
+	"github.com/rabbitstack/fibratus/pkg/kcap"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/ps"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+var filterCmd = &cobra.Command{
+	Use:   "filter",
+	Short: "Debug filter expressions",
+}
+
+var explainCmd = &cobra.Command{
+	Use:   "explain [expression]",
+	Short: "Show how the filter expression is parsed and why it doesn't match events from the kcap file",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  explain,
+}
+
+var toJSONCmd = &cobra.Command{
+	Use:   "to-json [expression]",
+	Short: "Print the filter expression tree in JSON format",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  toJSON,
+}
+
+var fromJSONCmd = &cobra.Command{
+	Use:   "from-json [file]",
+	Short: "Build the filter expression from the JSON expression tree read from the file or standard input",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  fromJSON,
+}
+
+var testCmd = &cobra.Command{
+	Use:   "test [file|dir...]",
+	Short: "Run filter test cases and report the events that didn't match as expected",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  testFilters,
+}
+
+var filterConfig = config.NewWithOpts(config.WithFilter())
+
+func init() {
+	filterConfig.MustViperize(explainCmd)
+
+	filterConfig.MustViperize(toJSONCmd)
+	filterConfig.MustViperize(fromJSONCmd)
+	filterConfig.MustViperize(testCmd)
+
+	filterCmd.AddCommand(explainCmd)
+	filterCmd.AddCommand(toJSONCmd)
+	filterCmd.AddCommand(fromJSONCmd)
+	filterCmd.AddCommand(testCmd)
+
+	RootCmd.AddCommand(filterCmd)
+}
+
+// explain prints the normalized expression, its tree and the fields involved in the filter. If the kcap
+// file is given, the filter is evaluated against each event and the predicates that didn't match are
+// printed along with the values of the fields.
+func explain(cmd *cobra.Command, args []string) error {
+	if err := common.Init(filterConfig, false); err != nil {
+		return err
+	}
+
+	var (
+		reader kcap.Reader
+		psnap  ps.Snapshotter
+		err    error
+	)
+	if filterConfig.KcapFile != "" {
+		reader, err = kcap.NewReader(filterConfig.KcapFile, filterConfig)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		_, psnap, err = reader.RecoverSnapshotters()
+		if err != nil {
+			return err
+		}
+	}
+
+	explainer, err := filter.NewExplainer(strings.Join(args, " "), psnap, filterConfig)
+	if err != nil {
+		return fmt.Errorf("bad filter: \n  %v", err)
+	}
+
+	fmt.Printf("Normalized expression:\n\n  %s\n\n", explainer.Normalized())
+	fmt.Printf("Expression tree:\n\n%s\n", explainer.Tree())
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Field", "Type", "Accessor", "Enabled"})
+	t.SetStyle(table.StyleLight)
+	for _, f := range explainer.Fields() {
+		t.AppendRow(table.Row{f.Field, f.Field.Type(), f.Accessor, f.Enabled})
+	}
+	t.Render()
+
+	if reader == nil {
+		return nil
+	}
+
+	stopCh := common.Signals()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var nevents, nmatches int
+	kevents, errs := reader.Read(ctx)
+	for {
+		select {
+		case kevt, ok := <-kevents:
+			if !ok {
+				fmt.Printf("\n%d event(s) evaluated, %d matched\n", nevents, nmatches)
+				return nil
+			}
+			expl := explainer.Explain(kevt)
+			nevents++
+			if expl.Matches {
+				nmatches++
+			}
+			printExplanation(kevt, expl, explainer.Fields())
+		case err := <-errs:
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		case <-stopCh:
+			return nil
+		}
+	}
+}
+
+// printExplanation prints the outcome of evaluating the filter against the event.
+func printExplanation(kevt *kevent.Kevent, expl *filter.Explanation, fields []filter.FieldInfo) {
+	outcome := "MATCH"
+	if !expl.Matches {
+		outcome = "NO MATCH"
+	}
+	fmt.Printf("\n#%d %s (pid: %d) -> %s\n", kevt.Seq, kevt.Name, kevt.PID, outcome)
+	for _, expr := range expl.Mismatches {
+		fmt.Printf("  false: %s\n", ql.Normalize(expr))
+	}
+	for _, f := range fields {
+		switch v := expl.Values[f.Field].(type) {
+		case []ql.Valuer:
+			fmt.Printf("  %s = <%d element(s)>\n", f.Field, len(v))
+		case nil:
+			fmt.Printf("  %s = <nil>\n", f.Field)
+		default:
+			fmt.Printf("  %s = %v\n", f.Field, v)
+		}
+	}
+}
+
+// toJSON parses the filter expression and prints its tree in JSON format. Macros
+// and lists are expanded, so the tree only consists of fields, literals and operators.
+func toJSON(cmd *cobra.Command, args []string) error {
+	if err := common.Init(filterConfig, false); err != nil {
+		return err
+	}
+	p := ql.NewParserWithConfig(strings.Join(args, " "), &filterConfig.Filters)
+	expr, err := p.ParseExpr()
+	if err == nil {
+		err = p.Validate(expr)
+	}
+	if err != nil {
+		return fmt.Errorf("bad filter: \n  %v", err)
+	}
+	b, err := json.MarshalIndent(expr, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// fromJSON builds the filter expression from the JSON expression tree and prints its
+// canonical form. The tree is read from the standard input if the file is not given.
+func fromJSON(cmd *cobra.Command, args []string) error {
+	var (
+		b   []byte
+		err error
+	)
+	if len(args) > 0 && args[0] != "-" {
+		b, err = ioutil.ReadFile(args[0])
+	} else {
+		b, err = ioutil.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+	_, expr, err := ql.ParseJSON(b)
+	if err != nil {
+		return fmt.Errorf("bad filter: \n  %v", err)
+	}
+	fmt.Println(expr)
+	return nil
+}
+
+// testFilters loads the test cases from the given files or directories and runs each filter against
+// the events of the test case. The command fails if any of the filters doesn't match exactly
+// the expected events.
+func testFilters(cmd *cobra.Command, args []string) error {
+	if err := common.Init(filterConfig, false); err != nil {
+		return err
+	}
+	tests, err := testcase.Load(args)
+	if err != nil {
+		return err
+	}
+
+	var nfailed int
+	for _, tc := range tests {
+		var res testcase.Result
+		if tc.Kcap != "" {
+			res = runKcapTest(tc)
+		} else {
+			kevents, err := tc.Kevents()
+			if err != nil {
+				res = testcase.Result{Name: tc.Name, File: tc.File, Err: err}
+			} else {
+				res = testcase.Run(tc, kevents, nil, filterConfig)
+			}
+		}
+		if !res.Passed() {
+			nfailed++
+		}
+		printTestResult(res)
+	}
+
+	fmt.Printf("\n%d test(s) passed, %d failed\n", len(tests)-nfailed, nfailed)
+	if nfailed > 0 {
+		return fmt.Errorf("%d filter test(s) failed", nfailed)
+	}
+	return nil
+}
+
+// runKcapTest runs the filter of the test case against each event as it is read from the kcap file.
+func runKcapTest(tc testcase.TestCase) testcase.Result {
+	reader, err := kcap.NewReader(tc.KcapFile(), filterConfig)
+	if err != nil {
+		return testcase.Result{Name: tc.Name, File: tc.File, Err: err}
+	}
+	defer reader.Close()
+	_, psnap, err := reader.RecoverSnapshotters()
+	if err != nil {
+		return testcase.Result{Name: tc.Name, File: tc.File, Err: err}
+	}
+
+	runner, err := testcase.NewRunner(tc, psnap, filterConfig)
+	if err != nil {
+		return testcase.Result{Name: tc.Name, File: tc.File, Err: err}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kevts, errs := reader.Read(ctx)
+	for {
+		select {
+		case kevt, ok := <-kevts:
+			if !ok {
+				// errors are pushed before the event channel is closed
+				select {
+				case err := <-errs:
+					return testcase.Result{Name: tc.Name, File: tc.File, Err: err}
+				default:
+				}
+				return runner.Result()
+			}
+			runner.Next(kevt)
+		case err := <-errs:
+			return testcase.Result{Name: tc.Name, File: tc.File, Err: err}
+		}
+	}
+}
+
+// printTestResult prints the outcome of the test case along with the sequence numbers
+// of the events that were expected to match but didn't and vice versa.
+func printTestResult(res testcase.Result) {
+	if res.Passed() {
+		fmt.Printf("PASS  %s\n", res.Name)
+		return
+	}
+	fmt.Printf("FAIL  %s (%s)\n", res.Name, res.File)
+	if res.Err != nil {
+		fmt.Printf("  error: %v\n", res.Err)
+		return
+	}
+	if len(res.Missing) > 0 {
+		fmt.Printf("  - expected but not matched: %s\n", joinSeqs(res.Missing))
+	}
+	if len(res.Unexpected) > 0 {
+		fmt.Printf("  + matched but not expected: %s\n", joinSeqs(res.Unexpected))
+	}
+}
+
+func joinSeqs(seqs []uint64) string {
+	s := make([]string, len(seqs))
+	for i, seq := range seqs {
+		s[i] = fmt.Sprintf("#%d", seq)
+	}
+	return strings.Join(s, ", ")
+}
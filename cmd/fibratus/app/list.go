@@ -25,6 +25,7 @@ import (
 	"github.com/rabbitstack/fibratus/cmd/fibratus/common"
 	"github.com/rabbitstack/fibratus/pkg/config"
 	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
 	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
 	"github.com/spf13/cobra"
 	"io/ioutil"
@@ -35,7 +36,7 @@ import (
 
 var listCmd = &cobra.Command{
 	Use:   "list",
-	Short: "Show info about filaments, filter fields or kernel event types",
+	Short: "Show info about filaments, filter fields, macros or kernel event types",
 }
 
 var listFilamentsCmd = &cobra.Command{
@@ -50,6 +51,12 @@ var listFieldsCmd = &cobra.Command{
 	Run:   listFields,
 }
 
+var listMacrosCmd = &cobra.Command{
+	Use:   "macros",
+	Short: "List filter macros and their expansions",
+	RunE:  listMacros,
+}
+
 var listsKeventsCmd = &cobra.Command{
 	Use:   "kevents",
 	Short: "List supported kernel event types",
@@ -60,9 +67,11 @@ var listConfig = config.NewWithOpts(config.WithList())
 
 func init() {
 	listConfig.MustViperize(listFilamentsCmd)
+	listConfig.MustViperize(listMacrosCmd)
 
 	listCmd.AddCommand(listFilamentsCmd)
 	listCmd.AddCommand(listFieldsCmd)
+	listCmd.AddCommand(listMacrosCmd)
 	listCmd.AddCommand(listsKeventsCmd)
 
 	RootCmd.AddCommand(listCmd)
@@ -126,6 +135,33 @@ func listFilaments(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// listMacros renders a table with macros defined in the configuration file along with the expressions they expand to.
+func listMacros(cmd *cobra.Command, args []string) error {
+	if err := common.Init(listConfig, false); err != nil {
+		return err
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Name", "Expression", "Expansion"})
+	t.SetStyle(table.StyleLight)
+
+	for _, macro := range listConfig.Filters.Macros {
+		var expansion string
+		expr, err := ql.NewParserWithConfig(macro.Name, &listConfig.Filters).ParseExpr()
+		if err != nil {
+			expansion = err.Error()
+		} else {
+			expansion = expr.String()
+		}
+		t.AppendRow(table.Row{macro.Name, macro.Expr, expansion})
+	}
+
+	t.Render()
+
+	return nil
+}
+
 // listKevents renders a table with supported kernel event types showing the category to which their pertain and a short description.
 func listKevents(cmd *cobra.Command, args []string) {
 	t := table.NewWriter()
@@ -24,6 +24,7 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/aggregator"
 	"github.com/rabbitstack/fibratus/pkg/api"
 	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/exceptions"
 	"github.com/rabbitstack/fibratus/pkg/filament"
 	"github.com/rabbitstack/fibratus/pkg/filter"
 	"github.com/rabbitstack/fibratus/pkg/kcap"
@@ -50,15 +51,11 @@ func replay(cmd *cobra.Command, args []string) error {
 	if err := common.Init(replayConfig, false); err != nil {
 		return err
 	}
+	defer replayConfig.Filters.CloseIOCs()
 
 	// set up the signals
 	stopCh := common.Signals()
 
-	kfilter, err := filter.NewFromCLIWithAllAccessors(args)
-	if err != nil {
-		return err
-	}
-
 	// initialize kcap reader and try to recover the snapshotters
 	// from the captured state
 	reader, err := kcap.NewReader(replayConfig.KcapFile, replayConfig)
@@ -70,6 +67,22 @@ func replay(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// the filter is built after the snapshotters are recovered,
+	// so the ancestry of captured processes can be resolved
+	kfilter, err := filter.NewFromCLIWithAllAccessors(args, psnap, replayConfig)
+	if err != nil {
+		return err
+	}
+
+	if replayConfig.Exceptions.Enabled {
+		allowlist, err := exceptions.New(psnap, replayConfig)
+		if err != nil {
+			return err
+		}
+		defer allowlist.Close()
+		reader.SetAllowlist(allowlist)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	// stop kcap reader consumers
 	defer cancel()
@@ -105,17 +118,28 @@ func replay(cmd *cobra.Command, args []string) error {
 			reader.SetFilter(kfilter)
 		}
 
+		// rules and correlation engines are fed
+		// with events dequeued by the aggregator
+		listeners, err := common.Listeners(psnap, replayConfig)
+		if err != nil {
+			return err
+		}
+		outputs, err := common.Outputs(psnap, replayConfig)
+		if err != nil {
+			return err
+		}
+
 		// use the channels where events are read from the kcap as aggregator source
 		kevents, errs := reader.Read(ctx)
 
-		var err error
 		agg, err = aggregator.NewBuffered(
 			kevents,
 			errs,
 			replayConfig.Aggregator,
-			replayConfig.Output,
+			outputs,
 			replayConfig.Transformers,
 			replayConfig.Alertsenders,
+			listeners...,
 		)
 		if err != nil {
 			return err
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/rules"
+	"github.com/rabbitstack/fibratus/pkg/rules/sigma"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+	"os"
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Manage detection rules",
+}
+
+var importSigmaCmd = &cobra.Command{
+	Use:   "import-sigma [file|dir]",
+	Short: "Translate Sigma rules to detection rules",
+	Long: `
+	Translates Sigma rules to detection rules and prints them to the standard output. The
+	condition of each rule is the filter expression equivalent to the Sigma detection. It
+	can be used in the run or replay commands or as the filament filter. Sigma rules that
+	can't be translated are reported to the standard error along with the reasons.
+	`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: importSigma,
+}
+
+func init() {
+	rulesCmd.AddCommand(importSigmaCmd)
+
+	RootCmd.AddCommand(rulesCmd)
+}
+
+func importSigma(cmd *cobra.Command, args []string) error {
+	srules, err := sigma.Load(args)
+	if err != nil {
+		return err
+	}
+
+	rs := make([]rules.Rule, 0, len(srules))
+	for _, srule := range srules {
+		r, err := sigma.Translate(srule)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", srule.File, err)
+			continue
+		}
+		rs = append(rs, r)
+	}
+	if len(rs) == 0 {
+		return fmt.Errorf("none of %d Sigma rule(s) could be translated", len(srules))
+	}
+
+	b, err := yaml.Marshal(rs)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "translated %d of %d Sigma rule(s)\n", len(rs), len(srules))
+	_, err = os.Stdout.Write(b)
+	return err
+}
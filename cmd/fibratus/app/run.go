@@ -24,6 +24,7 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/alertsender"
 	"github.com/rabbitstack/fibratus/pkg/api"
 	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/exceptions"
 	"github.com/rabbitstack/fibratus/pkg/filament"
 	"github.com/rabbitstack/fibratus/pkg/filter"
 	"github.com/rabbitstack/fibratus/pkg/handle"
@@ -72,6 +73,7 @@ func run(cmd *cobra.Command, args []string) error {
 	if err := common.Init(cfg, true); err != nil {
 		return err
 	}
+	defer cfg.Filters.CloseIOCs()
 
 	// set up the signals
 	stopCh := common.Signals()
@@ -91,13 +93,22 @@ func run(cmd *cobra.Command, args []string) error {
 	// build the filter from the CLI argument. If we got a valid expression the filter
 	// is linked to the kernel stream consumer so it can drop any events that don't match
 	// the filter criteria
-	kfilter, err := filter.NewFromCLI(args, cfg)
+	kfilter, err := filter.NewFromCLI(args, psnap, cfg)
 	if err != nil {
 		return err
 	}
 	if kfilter != nil {
 		kstreamc.SetFilter(kfilter)
 	}
+	// exceptions exempt known-good events from outputs and alerts
+	if cfg.Exceptions.Enabled {
+		allowlist, err := exceptions.New(psnap, cfg)
+		if err != nil {
+			return err
+		}
+		defer allowlist.Close()
+		kstreamc.SetAllowlist(allowlist)
+	}
 	log.Infof("bootstrapping with pid %d", os.Getpid())
 
 	// user can either instruct to bootstrap a filament or start a regular run. We'll setup
@@ -137,14 +148,25 @@ func run(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return multierror.Wrap(err, ktracec.CloseKtrace())
 		}
+		// rules and correlation engines are fed
+		// with events dequeued by the aggregator
+		listeners, err := common.Listeners(psnap, cfg)
+		if err != nil {
+			return err
+		}
+		outputs, err := common.Outputs(psnap, cfg)
+		if err != nil {
+			return err
+		}
 		// setup the aggregator that forwards events to outputs
 		agg, err := aggregator.NewBuffered(
 			kstreamc.Events(),
 			kstreamc.Errors(),
 			cfg.Aggregator,
-			cfg.Output,
+			outputs,
 			cfg.Transformers,
 			cfg.Alertsenders,
+			listeners...,
 		)
 		if err != nil {
 			return err
@@ -47,13 +47,22 @@ type Stats struct {
 	AggregatorBatchEvents               int            `json:"aggregator.batch.events"`
 	AggregatorFlushesCount              int            `json:"aggregator.flushes.count"`
 	AggregatorKeventErrors              int            `json:"aggregator.kevent.errors"`
+	AggregatorListenerErrors            map[string]int `json:"aggregator.listener.errors"`
+	AggregatorOutputBatchesDropped      map[string]int `json:"aggregator.output.batches.dropped"`
+	AggregatorSpoolBatchesEvicted       map[string]int `json:"aggregator.spool.batches.evicted"`
+	AggregatorSpoolDepth                map[string]int `json:"aggregator.spool.depth"`
+	AggregatorSpoolErrors               int            `json:"aggregator.spool.errors"`
 	AggregatorTransformerErrors         map[string]int `json:"aggregator.transformer.errors"`
 	AggregatorWorkerClientPublishErrors int            `json:"aggregator.worker.client.publish.errors"`
+	CorrelationAlertErrors              int            `json:"correlation.alert.errors"`
+	CorrelationSequenceMatches          map[string]int `json:"correlation.sequence.matches"`
 	FilamentKdictErrors                 int            `json:"filament.kdict.errors"`
 	FilamentKeventBatchFlushes          int            `json:"filament.kevent.batch.flushes"`
 	FilamentKeventErrors                map[string]int `json:"filament.kevent.errors"`
 	FilamentKeventProcessErrors         int            `json:"filament.kevent.process.errors"`
 	FilterAccessorErrors                map[string]int `json:"filter.accessor.errors"`
+	FilterSequencePartialsEvicted       int            `json:"filter.sequence.partials.evicted"`
+	FilterSequencePartialsExpired       int            `json:"filter.sequence.partials.expired"`
 	FsFileObjectHandleHits              int            `json:"fs.file.object.handle.hits"`
 	FsFileObjectMisses                  int            `json:"fs.file.object.misses"`
 	FsFileReleases                      int            `json:"fs.file.releases"`
@@ -94,7 +103,15 @@ type Stats struct {
 	OutputAmqpConnectionFailures        int            `json:"output.amqp.connection.failures"`
 	OutputAmqpPublishErrors             int            `json:"output.amqp.publish.errors"`
 	OutputConsoleErrors                 int            `json:"output.console.errors"`
+	OutputFileBytesWritten              int            `json:"output.file.bytes.written"`
+	OutputFileErrors                    int            `json:"output.file.errors"`
+	OutputFileRotations                 int            `json:"output.file.rotations"`
+	OutputHTTPErrors                    int            `json:"output.http.errors"`
+	OutputHTTPRequests                  int            `json:"output.http.requests"`
+	OutputHTTPRetries                   int            `json:"output.http.retries"`
 	OutputNullBlackholeEvents           int            `json:"output.null.blackhole.events"`
+	OutputSyslogErrors                  int            `json:"output.syslog.errors"`
+	OutputSyslogMessages                int            `json:"output.syslog.messages"`
 	PeFailedResourceEntryReads          int            `json:"pe.failed.resource.entry.reads"`
 	PeMaxResourceEntriesExceeded        int            `json:"pe.max.resource.entries.exceeded"`
 	ProcessCount                        int            `json:"process.count"`
@@ -107,6 +124,8 @@ type Stats struct {
 	RegistryKcbMisses                   int            `json:"registry.kcb.misses"`
 	RegistryKeyHandleHits               int            `json:"registry.key.handle.hits"`
 	RegistryUnknownKeysCount            int            `json:"registry.unknown.keys.count"`
+	RulesAlertErrors                    int            `json:"rules.alert.errors"`
+	RulesMatches                        map[string]int `json:"rules.matches"`
 	SidsCount                           int            `json:"sids.count"`
 	YaraImageScans                      int            `json:"yara.image.scans"`
 	YaraProcScans                       int            `json:"yara.proc.scans"`
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/aggregator"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/correlation"
+	"github.com/rabbitstack/fibratus/pkg/ps"
+	"github.com/rabbitstack/fibratus/pkg/rules"
+)
+
+// Listeners builds the aggregator listeners that are enabled in the config.
+func Listeners(psnap ps.Snapshotter, c *config.Config) ([]aggregator.Listener, error) {
+	var listeners []aggregator.Listener
+	if c.Rules.Enabled {
+		engine, err := rules.NewEngine(psnap, c)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, engine)
+	}
+	if c.Correlation.Enabled {
+		engine, err := correlation.NewEngine(psnap, c)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, engine)
+	}
+	return listeners, nil
+}
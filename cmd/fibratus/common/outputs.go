@@ -0,0 +1,46 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/aggregator"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter"
+	"github.com/rabbitstack/fibratus/pkg/ps"
+)
+
+// Outputs builds the aggregator outputs from the config. The filter expression
+// of each output is compiled into the filter that decides which events are
+// published to the output.
+func Outputs(psnap ps.Snapshotter, c *config.Config) ([]aggregator.Output, error) {
+	outputs := make([]aggregator.Output, len(c.Outputs))
+	for i, outputConfig := range c.Outputs {
+		outputs[i] = aggregator.Output{Config: outputConfig}
+		if outputConfig.Filter == "" {
+			continue
+		}
+		f := filter.New(outputConfig.Filter, psnap, c)
+		if err := f.Compile(); err != nil {
+			return nil, fmt.Errorf("invalid %s output filter: %v", outputConfig.Type, err)
+		}
+		outputs[i].Filter = f
+	}
+	return outputs, nil
+}
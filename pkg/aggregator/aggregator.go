@@ -24,14 +24,17 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/aggregator/transformers"
 	"github.com/rabbitstack/fibratus/pkg/alertsender"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
-	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/rabbitstack/fibratus/pkg/util/multierror"
 	log "github.com/sirupsen/logrus"
 	"time"
 	// initialize outputs
 	_ "github.com/rabbitstack/fibratus/pkg/outputs/amqp"
 	_ "github.com/rabbitstack/fibratus/pkg/outputs/console"
 	_ "github.com/rabbitstack/fibratus/pkg/outputs/elasticsearch"
+	_ "github.com/rabbitstack/fibratus/pkg/outputs/file"
+	_ "github.com/rabbitstack/fibratus/pkg/outputs/http"
 	_ "github.com/rabbitstack/fibratus/pkg/outputs/null"
+	_ "github.com/rabbitstack/fibratus/pkg/outputs/syslog"
 	// initialize alert senders
 	_ "github.com/rabbitstack/fibratus/pkg/alertsender/mail"
 	_ "github.com/rabbitstack/fibratus/pkg/alertsender/slack"
@@ -54,32 +57,46 @@ var (
 	transformerErrors = expvar.NewMap("aggregator.transformer.errors")
 	/// keventErrors is the number of kernel event errors
 	keventErrors = expvar.NewInt("aggregator.kevent.errors")
+	// listenerErrors is the count of errors returned by event listeners
+	listenerErrors = expvar.NewMap("aggregator.listener.errors")
 )
 
+// Listener is notified of every event that is dequeued by the aggregator. Listeners receive the
+// event before it is handed to transformers. Events exempted by exceptions are received by listeners,
+// but they are not published to outputs.
+type Listener interface {
+	// ProcessEvent receives the event dequeued by the aggregator.
+	ProcessEvent(kevt *kevent.Kevent) error
+}
+
 // BufferedAggregator collects events from the inbound channel and produces batches on regular intervals. The batches
-// are pushed to the work queue from which load-balanced configured workers consume the batches and publish to the outputs.
+// are fanned out to the work queue of each output from which load-balanced configured workers consume the batches and
+// publish to the outputs.
 type BufferedAggregator struct {
 	kevtsc  chan *kevent.Kevent
 	errsc   chan error
 	stop    chan struct{}
+	stopped chan struct{}
 	flusher *time.Ticker
 	// queue of inbound kernel events
 	kevts []*kevent.Kevent
-	// work queue that forwarder passes to outputs
-	wq         queue
-	submitter  *submitter
+	// submitters dispatch batches to each of the outputs
+	submitters []*submitter
 	transforms []transformers.Transformer
+	listeners  []Listener
 	c          Config
 }
 
-// NewBuffered creates a new instance of the event aggregator.
+// NewBuffered creates a new instance of the event aggregator that forwards batches to all
+// outputs. Optional listeners are notified of every event that the aggregator dequeues.
 func NewBuffered(
 	kevents chan *kevent.Kevent,
 	errs chan error,
 	config Config,
-	outputConfig outputs.Config,
+	outputs []Output,
 	transformerConfigs []transformers.Config,
 	alertsenderConfigs []alertsender.Config,
+	listeners ...Listener,
 ) (*BufferedAggregator, error) {
 
 	flushInterval := config.FlushPeriod
@@ -87,20 +104,29 @@ func NewBuffered(
 		flushInterval = time.Millisecond * 250
 	}
 	agg := &BufferedAggregator{
-		kevtsc:  kevents,
-		kevts:   make([]*kevent.Kevent, 0),
-		errsc:   errs,
-		stop:    make(chan struct{}, 1),
-		flusher: time.NewTicker(flushInterval),
-		wq:      make(chan *kevent.Batch),
-		c:       config,
+		kevtsc:    kevents,
+		kevts:     make([]*kevent.Kevent, 0),
+		errsc:     errs,
+		stop:      make(chan struct{}, 1),
+		stopped:   make(chan struct{}),
+		flusher:   time.NewTicker(flushInterval),
+		listeners: listeners,
+		c:         config,
 	}
 
-	var err error
-	agg.submitter, err = newSubmitter(agg.wq, outputConfig)
-	if err != nil {
-		return nil, err
+	queueSize := config.QueueSize
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	for _, output := range outputs {
+		s, err := newSubmitter(output, queueSize, len(outputs) > 1, config.Spool)
+		if err != nil {
+			return nil, err
+		}
+		agg.submitters = append(agg.submitters, s)
 	}
+
+	var err error
 	agg.transforms, err = transformers.LoadAll(transformerConfigs)
 	if err != nil {
 		return nil, err
@@ -116,41 +142,67 @@ func NewBuffered(
 	return agg, nil
 }
 
-// Stop flushes pending event batches and instructs the aggregator to stop processing events.
+// Stop flushes pending event batches and instructs the aggregator to stop processing events. If outputs
+// don't consume enqueued batches within the flush timeout, the remaining batches are spooled if spooling
+// is enabled, or dropped otherwise. The output clients and spools are closed in either case.
 func (agg *BufferedAggregator) Stop() error {
 	agg.stop <- struct{}{}
 
-	// flush enqueued events
-	b := kevent.NewBatch(agg.kevts...)
-	if b.Len() > 0 {
-		done := make(chan struct{}, 1)
-		go func() {
-			agg.wq <- b
-			done <- struct{}{}
-		}()
+	done := make(chan struct{})
+	go func() {
+		// wait for the aggregator loop to stop pushing batches
+		<-agg.stopped
+		// flush enqueued events
+		b := kevent.NewBatch(agg.kevts...)
+		if b.Len() > 0 {
+			agg.submit(b)
+		}
+		for _, s := range agg.submitters {
+			s.close()
+		}
+		for _, s := range agg.submitters {
+			s.wait()
+		}
+		close(done)
+	}()
 
-		select {
-		case <-done:
-			close(agg.wq)
-		case <-time.After(agg.c.FlushTimeout):
-			return errors.New("fail to flush events after stop timed out")
+	errs := make([]error, 0)
+	select {
+	case <-done:
+	case <-time.After(agg.c.FlushTimeout):
+		errs = append(errs, errors.New("fail to flush events after stop timed out"))
+		for _, s := range agg.submitters {
+			s.abort()
 		}
+		<-done
 	}
 
-	// sleep a bit before closing the clients
-	time.Sleep(time.Millisecond * 150)
-
-	err := agg.submitter.shutdown()
-	if err != nil {
-		return err
+	for _, s := range agg.submitters {
+		if err := s.shutdown(); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	return nil
+	return multierror.Wrap(errs...)
+}
+
+// submit fans out the batch to all outputs. The batch is released
+// when none of the outputs holds a reference to its events.
+func (agg *BufferedAggregator) submit(b *kevent.Batch) {
+	// keep the events alive until the batch is fanned out to all outputs
+	hold := b.Share(b.Events...)
+	defer hold.Release()
+	for _, s := range agg.submitters {
+		if batch := s.batch(b); batch != nil {
+			s.submit(batch)
+		}
+	}
 }
 
 // run starts the aggregator loop. The aggregator receives kernel event stream from the upstream channel, buffers
 // them to intermediate queue and dispatches batches to downstream worker queue.
 func (agg *BufferedAggregator) run() {
+	defer close(agg.stopped)
 	for {
 		select {
 		case <-agg.stop:
@@ -163,14 +215,31 @@ func (agg *BufferedAggregator) run() {
 			b := kevent.NewBatch(agg.kevts...)
 			l := b.Len()
 			batchEvents.Add(l)
-			// push the batch to the work queue
+			// push the batch to the work queues
 			if l > 0 {
-				agg.wq <- b
+				agg.submit(b)
 			}
 			flushesCount.Add(1)
 			// clear the queue
 			agg.kevts = nil
-		case kevt := <-agg.kevtsc:
+		case kevt, ok := <-agg.kevtsc:
+			if !ok {
+				// the source is drained, but the queued
+				// events are still flushed on the next tick
+				agg.kevtsc = nil
+				continue
+			}
+			for _, listener := range agg.listeners {
+				if err := listener.ProcessEvent(kevt); err != nil {
+					log.Warnf("listener error occurred: %v", err)
+					listenerErrors.Add(err.Error(), 1)
+				}
+			}
+			// events exempted by exceptions are
+			// only received by the listeners
+			if kevt.IsExempted() {
+				continue
+			}
 			for _, transformer := range agg.transforms {
 				if transformer == nil {
 					continue
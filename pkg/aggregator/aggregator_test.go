@@ -19,6 +19,10 @@
 package aggregator
 
 import (
+	"errors"
+	"github.com/rabbitstack/fibratus/pkg/aggregator/spool"
+	"github.com/rabbitstack/fibratus/pkg/aggregator/transformers"
+	"github.com/rabbitstack/fibratus/pkg/aggregator/transformers/remove"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
 	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
@@ -26,11 +30,55 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/outputs/console"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io/ioutil"
 	"net"
+	"os"
+	"sync"
 	"testing"
 	"time"
 )
 
+// memOutput is the output type that publishes batches to the memory client
+const memOutput outputs.Type = 200
+
+func init() {
+	outputs.Register(memOutput, func(config outputs.Config) (outputs.OutputGroup, error) {
+		return outputs.Success(config.Output.(*memClient)), nil
+	})
+}
+
+// memClient records the events published to the output. The client fails to connect if it is down.
+type memClient struct {
+	sync.Mutex
+	names  []string
+	params []int
+	down   bool
+}
+
+func (c *memClient) Connect() error {
+	if c.down {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (c *memClient) Close() error { return nil }
+
+func (c *memClient) Publish(b *kevent.Batch) error {
+	c.Lock()
+	defer c.Unlock()
+	for _, kevt := range b.Events {
+		c.names = append(c.names, kevt.Name)
+		c.params = append(c.params, len(kevt.Kparams))
+	}
+	b.Release()
+	return nil
+}
+
+type filterFunc func(kevt *kevent.Kevent) bool
+
+func (f filterFunc) Run(kevt *kevent.Kevent) bool { return f(kevt) }
+
 func TestNewBufferedAggregator(t *testing.T) {
 	keventsc := make(chan *kevent.Kevent, 20)
 	errsc := make(chan error, 1)
@@ -38,7 +86,7 @@ func TestNewBufferedAggregator(t *testing.T) {
 		keventsc,
 		errsc,
 		Config{FlushPeriod: time.Millisecond * 200},
-		outputs.Config{Type: outputs.Console, Output: console.Config{Format: "pretty"}},
+		[]Output{{Config: outputs.Config{Type: outputs.Console, Output: console.Config{Format: "pretty"}}}},
 		nil,
 		nil,
 	)
@@ -81,3 +129,162 @@ func TestNewBufferedAggregator(t *testing.T) {
 	assert.Equal(t, int64(6), batchEvents.Value())
 	assert.Equal(t, int64(2), flushesCount.Value())
 }
+
+func TestFanoutOutputs(t *testing.T) {
+	keventsc := make(chan *kevent.Kevent, 20)
+	errsc := make(chan error, 1)
+
+	all := &memClient{}
+	network := &memClient{}
+	stripped := &memClient{}
+
+	agg, err := NewBuffered(
+		keventsc,
+		errsc,
+		Config{FlushPeriod: time.Millisecond * 200, FlushTimeout: time.Second, QueueSize: 10},
+		[]Output{
+			{Config: outputs.Config{Type: memOutput, Output: all}},
+			{
+				Config: outputs.Config{Type: memOutput, Output: network},
+				Filter: filterFunc(func(kevt *kevent.Kevent) bool { return kevt.Type == ktypes.SendTCPv4 }),
+			},
+			{
+				Config: outputs.Config{
+					Type:   memOutput,
+					Output: stripped,
+					Transformers: []transformers.Config{
+						{Type: transformers.Remove, Transformer: remove.Config{Kparams: []string{kparams.NetDport}}},
+					},
+				},
+			},
+		},
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	keventsc <- &kevent.Kevent{
+		Type: ktypes.SendTCPv4,
+		Name: "Send",
+		Kparams: kevent.Kparams{
+			kparams.NetDport: {Name: kparams.NetDport, Type: kparams.Uint16, Value: uint16(443)},
+			kparams.NetSport: {Name: kparams.NetSport, Type: kparams.Uint16, Value: uint16(43123)},
+		},
+	}
+	keventsc <- &kevent.Kevent{
+		Type: ktypes.CreateFile,
+		Name: "CreateFile",
+		Kparams: kevent.Kparams{
+			kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "C:\\Windows\\notepad.exe"},
+		},
+	}
+	<-time.After(time.Millisecond * 300)
+	require.NoError(t, agg.Stop())
+
+	assert.Equal(t, []string{"Send", "CreateFile"}, all.names)
+	assert.Equal(t, []int{2, 1}, all.params)
+	assert.Equal(t, []string{"Send"}, network.names)
+	assert.Equal(t, []string{"Send", "CreateFile"}, stripped.names)
+	assert.Equal(t, []int{1, 1}, stripped.params)
+}
+
+func TestStopSpoolsBatches(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	spoolConfig := spool.Config{Enabled: true, Path: dir, MaxSize: 10, MaxAge: time.Hour}
+	keventsc := make(chan *kevent.Kevent, 20)
+	errsc := make(chan error, 1)
+	down := &memClient{down: true}
+
+	agg, err := NewBuffered(
+		keventsc,
+		errsc,
+		Config{FlushPeriod: time.Millisecond * 200, FlushTimeout: time.Second, QueueSize: 10, Spool: spoolConfig},
+		[]Output{{Config: outputs.Config{Type: memOutput, Output: down}}},
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	keventsc <- &kevent.Kevent{
+		Type: ktypes.CreateFile,
+		Name: "CreateFile",
+		Kparams: kevent.Kparams{
+			kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "C:\\Windows\\notepad.exe"},
+		},
+	}
+	<-time.After(time.Millisecond * 50)
+	require.NoError(t, agg.Stop())
+
+	// the batch is spooled while the output is down, and the spool is closed on stop
+	sp, err := spool.Open(memOutput.String(), spoolConfig)
+	require.NoError(t, err)
+	defer sp.Close()
+	assert.Equal(t, 1, sp.Len())
+	assert.Empty(t, down.names)
+}
+
+func TestStopTimeout(t *testing.T) {
+	keventsc := make(chan *kevent.Kevent, 20)
+	errsc := make(chan error, 1)
+
+	agg, err := NewBuffered(
+		keventsc,
+		errsc,
+		Config{FlushPeriod: time.Millisecond * 200, FlushTimeout: time.Millisecond * 500, QueueSize: 10},
+		[]Output{{Config: outputs.Config{Type: memOutput, Output: &memClient{down: true}}}},
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	keventsc <- &kevent.Kevent{Type: ktypes.CreateFile, Name: "CreateFile", Kparams: kevent.Kparams{}}
+	<-time.After(time.Millisecond * 50)
+
+	// the worker stops reconnecting the client when the flush times out
+	start := time.Now()
+	require.Error(t, agg.Stop())
+	assert.True(t, time.Since(start) < time.Second*2)
+}
+
+func TestSubmitFullQueue(t *testing.T) {
+	s := &submitter{typ: memOutput, wq: make(queue, 1), quit: make(chan struct{})}
+
+	// the only output waits until there is room in the queue
+	s.submit(newSeqBatch(1))
+	submitted := make(chan struct{})
+	go func() {
+		s.submit(newSeqBatch(2))
+		close(submitted)
+	}()
+	select {
+	case <-submitted:
+		t.Fatal("batch submitted to the full queue")
+	case <-time.After(time.Millisecond * 100):
+	}
+	assert.Equal(t, uint64(1), (<-s.wq).Events[0].Seq)
+	<-submitted
+	assert.Equal(t, uint64(2), (<-s.wq).Events[0].Seq)
+
+	// batches are dropped if they are fanned out to more outputs
+	s.fanout = true
+	s.submit(newSeqBatch(3))
+	s.submit(newSeqBatch(4))
+	assert.Len(t, s.wq, 1)
+	assert.Equal(t, uint64(3), (<-s.wq).Events[0].Seq)
+
+	// batches are spooled if spooling is enabled
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	s.spool, err = spool.Open("mem", spool.Config{Path: dir, MaxSize: 10, MaxAge: time.Hour})
+	require.NoError(t, err)
+	defer s.spool.Close()
+
+	s.submit(newSeqBatch(5))
+	s.submit(newSeqBatch(6))
+	assert.Len(t, s.wq, 1)
+	assert.Equal(t, 1, s.spool.Len())
+}
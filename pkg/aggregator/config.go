@@ -19,6 +19,7 @@
 package aggregator
 
 import (
+	"github.com/rabbitstack/fibratus/pkg/aggregator/spool"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"time"
@@ -27,6 +28,7 @@ import (
 const (
 	flushPeriod  = "aggregator.flush-period"
 	flushTimeout = "aggregator.flush-timeout"
+	queueSize    = "aggregator.queue-size"
 )
 
 // Config contains aggregator-specific configuration tweaks.
@@ -35,16 +37,24 @@ type Config struct {
 	FlushPeriod time.Duration `json:"aggregator.flush-period" yaml:"aggregator.flush-period"`
 	// FlushTimeout represents the max time to wait before announcing failed flushing of enqueued events
 	FlushTimeout time.Duration `json:"aggregator.flush-timeout" yaml:"aggregator.flush-timeout"`
+	// QueueSize is the number of batches each output can hold before new batches are spooled or dropped
+	QueueSize int `json:"aggregator.queue-size" yaml:"aggregator.queue-size"`
+	// Spool contains the options for spooling the batches that couldn't be published
+	Spool spool.Config `json:"aggregator.spool" yaml:"aggregator.spool"`
 }
 
 // AddFlags registers persistent aggregator flags.
 func AddFlags(flags *pflag.FlagSet) {
 	flags.Duration(flushPeriod, time.Millisecond*200, "Determines the period for flushing batches to outputs")
 	flags.Duration(flushTimeout, time.Second*4, "Represents the max time to wait before announcing failed flushing of enqueued events on aggregator shutdown")
+	flags.Int(queueSize, 100, "Specifies the number of batches each output can hold before new batches are spooled, or dropped if there is more than one output")
+	spool.AddFlags(flags)
 }
 
 // InitFromViper initializes aggregator flags from viper.
 func (c *Config) InitFromViper(v *viper.Viper) {
 	c.FlushPeriod = v.GetDuration(flushPeriod)
 	c.FlushTimeout = v.GetDuration(flushTimeout)
+	c.QueueSize = v.GetInt(queueSize)
+	c.Spool.InitFromViper(v)
 }
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spool
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	enabled = "aggregator.spool.enabled"
+	path    = "aggregator.spool.path"
+	maxSize = "aggregator.spool.max-size"
+	maxAge  = "aggregator.spool.max-age"
+)
+
+// Config contains the tweaks that influence the behaviour of the spool.
+type Config struct {
+	// Enabled indicates if the batches that couldn't be published are spooled to disk.
+	Enabled bool `json:"aggregator.spool.enabled" yaml:"aggregator.spool.enabled"`
+	// Path is the directory where spool files are stored. Each output is spooled in its own subdirectory.
+	Path string `json:"aggregator.spool.path" yaml:"aggregator.spool.path"`
+	// MaxSize is the maximum size of each output spool in megabytes.
+	MaxSize int `json:"aggregator.spool.max-size" yaml:"aggregator.spool.max-size"`
+	// MaxAge is the maximum time batches are retained in the spool.
+	MaxAge time.Duration `json:"aggregator.spool.max-age" yaml:"aggregator.spool.max-age"`
+}
+
+// AddFlags registers persistent spool flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.Bool(enabled, false, "Indicates if the batches that couldn't be published are spooled to disk")
+	flags.String(path, filepath.Join(os.Getenv("PROGRAMFILES"), "fibratus", "spool"), "Represents the directory where spool files are stored")
+	flags.Int(maxSize, 1024, "Specifies the maximum size of each output spool in megabytes")
+	flags.Duration(maxAge, time.Hour*24, "Specifies the maximum time batches are retained in the spool")
+}
+
+// InitFromViper initializes spool flags from viper.
+func (c *Config) InitFromViper(v *viper.Viper) {
+	c.Enabled = v.GetBool(enabled)
+	c.Path = v.GetString(path)
+	c.MaxSize = v.GetInt(maxSize)
+	c.MaxAge = v.GetDuration(maxAge)
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spool
+
+import (
+	"errors"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	pstypes "github.com/rabbitstack/fibratus/pkg/ps/types"
+	"github.com/rabbitstack/fibratus/pkg/util/bytes"
+	"hash/crc32"
+)
+
+// headerSize is the size of the record header that stores the length and the checksum of the batch
+const headerSize = 8
+
+var errCorruptedRecord = errors.New("corrupted record")
+
+// encode serializes the batch into the record that is appended to the spool file. Besides
+// the raw event, the process state is stored for every event, since the raw event only
+// carries the process state for process creation events.
+func encode(b *kevent.Batch) []byte {
+	buf := make([]byte, headerSize)
+	buf = append(buf, bytes.WriteUint32(uint32(len(b.Events)))...)
+	for _, kevt := range b.Events {
+		raw := kevt.MarshalRaw()
+		buf = append(buf, bytes.WriteUint32(uint32(len(raw)))...)
+		buf = append(buf, raw...)
+		var ps []byte
+		if kevt.PS != nil {
+			ps = kevt.PS.Marshal()
+		}
+		buf = append(buf, bytes.WriteUint32(uint32(len(ps)))...)
+		buf = append(buf, ps...)
+	}
+	payload := buf[headerSize:]
+	copy(buf[0:], bytes.WriteUint32(uint32(len(payload))))
+	copy(buf[4:], bytes.WriteUint32(crc32.ChecksumIEEE(payload)))
+	return buf
+}
+
+// decode recovers the batch from the record payload.
+func decode(payload []byte) (*kevent.Batch, error) {
+	if len(payload) < 4 {
+		return nil, errCorruptedRecord
+	}
+	n := bytes.ReadUint32(payload)
+	off := 4
+	evts := make([]*kevent.Kevent, 0, n)
+	for i := 0; i < int(n); i++ {
+		var raw, ps []byte
+		var err error
+		raw, off, err = field(payload, off)
+		if err != nil {
+			return nil, err
+		}
+		ps, off, err = field(payload, off)
+		if err != nil {
+			return nil, err
+		}
+		kevt, err := kevent.NewFromKcap(raw)
+		if err != nil {
+			return nil, err
+		}
+		if len(ps) > 0 {
+			kevt.PS, err = pstypes.NewFromKcap(ps)
+			if err != nil {
+				return nil, err
+			}
+		}
+		evts = append(evts, kevt)
+	}
+	return kevent.NewBatch(evts...), nil
+}
+
+// field reads the length-prefixed field at the given offset and returns the offset of the next field.
+func field(b []byte, off int) ([]byte, int, error) {
+	if len(b) < off+4 {
+		return nil, 0, errCorruptedRecord
+	}
+	l := int(bytes.ReadUint32(b[off:]))
+	off += 4
+	if len(b) < off+l {
+		return nil, 0, errCorruptedRecord
+	}
+	return b[off : off+l], off + l, nil
+}
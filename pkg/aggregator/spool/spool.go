@@ -0,0 +1,412 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spool
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/rabbitstack/fibratus/pkg/util/bytes"
+	log "github.com/sirupsen/logrus"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// spoolDepth represents the number of batches waiting to be replayed in each output spool
+	spoolDepth = expvar.NewMap("aggregator.spool.depth")
+	// spoolEvicted counts the batches evicted from each output spool because of size or age constraints
+	spoolEvicted = expvar.NewMap("aggregator.spool.batches.evicted")
+	// spoolErrors counts the spool read and write errors
+	spoolErrors = expvar.NewInt("aggregator.spool.errors")
+)
+
+const (
+	// segmentSize is the size of the spool file after which batches are appended to a new file
+	segmentSize = 1024 * 1024 * 4
+	ext         = ".spool"
+)
+
+// segment is the spool file that stores a sequence of batches.
+type segment struct {
+	path string
+	seq  uint64
+	size int64
+	// offset is the position of the next batch to replay
+	offset int64
+	// published is the number of leading events of the batch at offset that
+	// were published before the output failed to publish the rest of the batch
+	published int
+	// batches is the number of batches waiting to be replayed
+	batches int
+	modTime time.Time
+}
+
+// Spool persists the batches that couldn't be published to the output. Batches are appended
+// to a sequence of spool files, so they are replayed in the order they were spooled, even
+// after the restart. When the spool exceeds the max size, the oldest batches are evicted.
+// The replay progress is only tracked in memory, so batches are delivered at least once: after
+// the restart, the spool file that was partially replayed is replayed again from the start.
+type Spool struct {
+	name    string
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+
+	mu       sync.Mutex
+	segments []*segment
+	// w is the file of the last segment where batches are appended
+	w       *os.File
+	size    int64
+	batches int
+	depth   *expvar.Int
+	// replaying is set while one of the callers is replaying batches
+	replaying int32
+}
+
+// Open opens the spool of the named output. Batches that were spooled
+// before the restart are recovered from the spool directory.
+func Open(name string, config Config) (*Spool, error) {
+	dir := filepath.Join(config.Path, name)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("couldn't create spool directory: %v", err)
+	}
+	s := &Spool{
+		name:     name,
+		dir:      dir,
+		maxSize:  int64(config.MaxSize) * 1024 * 1024,
+		maxAge:   config.MaxAge,
+		segments: make([]*segment, 0),
+		depth:    new(expvar.Int),
+	}
+	spoolDepth.Set(name, s.depth)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		seg, err := load(file)
+		if err != nil {
+			spoolErrors.Add(1)
+			log.Warnf("discarding unreadable spool file %s: %v", file, err)
+			_ = os.Remove(file)
+			continue
+		}
+		if seg.batches == 0 {
+			_ = os.Remove(file)
+			continue
+		}
+		s.segments = append(s.segments, seg)
+		s.size += seg.size
+		s.batches += seg.batches
+	}
+	s.evict(0)
+	s.depth.Set(int64(s.batches))
+	if s.batches > 0 {
+		log.Infof("recovered %d spooled batches for %s output", s.batches, name)
+	}
+
+	return s, nil
+}
+
+// load scans the records of the spool file. The incomplete or corrupted
+// trailing records, e.g. left by a crash, are truncated.
+func load(path string) (*segment, error) {
+	seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), ext), 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spool file name: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	seg := &segment{path: path, seq: seq, modTime: stat.ModTime()}
+	header := make([]byte, headerSize)
+	for {
+		if n, _ := f.ReadAt(header, seg.size); n < headerSize {
+			break
+		}
+		l := int64(bytes.ReadUint32(header))
+		if seg.size+headerSize+l > stat.Size() {
+			break
+		}
+		payload := make([]byte, l)
+		if n, _ := f.ReadAt(payload, seg.size+headerSize); n < len(payload) {
+			break
+		}
+		if crc32.ChecksumIEEE(payload) != bytes.ReadUint32(header[4:]) {
+			break
+		}
+		seg.size += int64(headerSize + len(payload))
+		seg.batches++
+	}
+	if seg.size < stat.Size() {
+		log.Warnf("truncating %d bytes of incomplete records in spool file %s", stat.Size()-seg.size, path)
+		if err := f.Truncate(seg.size); err != nil {
+			return nil, err
+		}
+	}
+	return seg, nil
+}
+
+// Len returns the number of batches waiting to be replayed.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batches
+}
+
+// Put appends the batch to the spool. If there is no room for the
+// batch in the spool, the oldest batches are evicted.
+func (s *Spool) Put(b *kevent.Batch) error {
+	rec := encode(b)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxSize > 0 && int64(len(rec)) > s.maxSize {
+		spoolEvicted.Add(s.name, 1)
+		return fmt.Errorf("batch of %d bytes exceeds the max spool size", len(rec))
+	}
+	s.evict(int64(len(rec)))
+
+	if s.w == nil || s.segments[len(s.segments)-1].size >= segmentSize {
+		if err := s.rotate(); err != nil {
+			spoolErrors.Add(1)
+			return err
+		}
+	}
+	seg := s.segments[len(s.segments)-1]
+	n, err := s.w.Write(rec)
+	if err != nil {
+		spoolErrors.Add(1)
+		// drop the partially written record and rewind the writer, so the next
+		// record isn't written past the end of the file. If the writer can't be
+		// rewound, subsequent batches are appended to a new spool file
+		if s.w.Truncate(seg.size) != nil {
+			s.closeWriter()
+		} else if _, serr := s.w.Seek(seg.size, io.SeekStart); serr != nil {
+			s.closeWriter()
+		}
+		return err
+	}
+	seg.size += int64(n)
+	seg.batches++
+	seg.modTime = time.Now()
+	s.size += int64(n)
+	s.batches++
+	s.depth.Set(int64(s.batches))
+
+	return nil
+}
+
+// closeWriter closes the file of the last segment. Subsequent batches are appended to a new spool file.
+func (s *Spool) closeWriter() {
+	_ = s.w.Close()
+	s.w = nil
+}
+
+// rotate creates a new spool file where subsequent batches are appended.
+func (s *Spool) rotate() error {
+	if s.w != nil {
+		if err := s.w.Close(); err != nil {
+			return err
+		}
+		s.w = nil
+	}
+	var seq uint64
+	if len(s.segments) > 0 {
+		seq = s.segments[len(s.segments)-1].seq + 1
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf("%016x%s", seq, ext))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	s.w = f
+	s.segments = append(s.segments, &segment{path: path, seq: seq, modTime: time.Now()})
+	return nil
+}
+
+// evict removes the segments older than the max age, and the oldest
+// segments until there is enough room to spool n bytes.
+func (s *Spool) evict(n int64) {
+	for len(s.segments) > 0 {
+		seg := s.segments[0]
+		expired := s.maxAge > 0 && time.Since(seg.modTime) > s.maxAge
+		full := s.maxSize > 0 && s.size+n > s.maxSize
+		if !expired && !full {
+			break
+		}
+		if seg.batches > 0 {
+			spoolEvicted.Add(s.name, int64(seg.batches))
+			log.Warnf("evicting %d spooled batches from %s output spool", seg.batches, s.name)
+		}
+		s.remove()
+	}
+}
+
+// remove deletes the oldest segment.
+func (s *Spool) remove() {
+	seg := s.segments[0]
+	if len(s.segments) == 1 && s.w != nil {
+		s.closeWriter()
+	}
+	if err := os.Remove(seg.path); err != nil {
+		spoolErrors.Add(1)
+		log.Warnf("couldn't remove spool file %s: %v", seg.path, err)
+	}
+	s.segments = s.segments[1:]
+	s.size -= seg.size
+	s.batches -= seg.batches
+	s.depth.Set(int64(s.batches))
+}
+
+// Replay publishes at most n spooled batches in the order they were spooled. Replay stops on
+// the first batch that fails to get published and returns the error. The failed batch remains
+// in the spool, but if the output published a part of the batch, only the unpublished events
+// are replayed next time. If another caller is already replaying batches, Replay returns immediately.
+func (s *Spool) Replay(publish func(*kevent.Batch) error, n int) error {
+	if !atomic.CompareAndSwapInt32(&s.replaying, 0, 1) {
+		return nil
+	}
+	defer atomic.StoreInt32(&s.replaying, 0)
+
+	for i := 0; i < n; i++ {
+		seg, b, next, err := s.next()
+		if err != nil {
+			spoolErrors.Add(1)
+			log.Warnf("discarding unreadable spool file %s: %v", seg.path, err)
+			s.commit(seg, -1)
+			continue
+		}
+		if b == nil {
+			return nil
+		}
+		if err := publish(b); err != nil {
+			var perr *outputs.PartialError
+			if errors.As(err, &perr) {
+				s.advance(seg, perr.Published)
+			}
+			return err
+		}
+		s.commit(seg, next)
+	}
+	return nil
+}
+
+// next reads the oldest spooled batch. It returns the segment
+// the batch was read from and the offset of the next batch.
+func (s *Spool) next() (*segment, *kevent.Batch, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evict(0)
+	if len(s.segments) == 0 || s.segments[0].batches == 0 {
+		return nil, nil, 0, nil
+	}
+	seg := s.segments[0]
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return seg, nil, 0, err
+	}
+	defer f.Close()
+	header := make([]byte, headerSize)
+	if _, err := f.ReadAt(header, seg.offset); err != nil {
+		return seg, nil, 0, err
+	}
+	payload := make([]byte, bytes.ReadUint32(header))
+	if n, err := f.ReadAt(payload, seg.offset+headerSize); n < len(payload) {
+		return seg, nil, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != bytes.ReadUint32(header[4:]) {
+		return seg, nil, 0, errCorruptedRecord
+	}
+	b, err := decode(payload)
+	if err != nil {
+		return seg, nil, 0, err
+	}
+	if seg.published > 0 && seg.published < len(b.Events) {
+		b.Events = b.Events[seg.published:]
+	}
+	return seg, b, seg.offset + int64(headerSize+len(payload)), nil
+}
+
+// advance skips the first n events of the batch at the segment offset when the
+// batch is replayed again. The segment that was evicted in the meantime is ignored.
+func (s *Spool) advance(seg *segment, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.segments) == 0 || s.segments[0] != seg {
+		return
+	}
+	seg.published += n
+}
+
+// commit removes the replayed batch from the segment. The segment is deleted
+// when all of its batches are replayed. A negative offset discards the whole
+// segment. The segment that was evicted in the meantime is ignored.
+func (s *Spool) commit(seg *segment, offset int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.segments) == 0 || s.segments[0] != seg {
+		return
+	}
+	if offset < 0 {
+		if seg.batches > 0 {
+			spoolEvicted.Add(s.name, int64(seg.batches))
+		}
+		s.remove()
+		return
+	}
+	seg.offset = offset
+	seg.published = 0
+	seg.batches--
+	s.batches--
+	s.depth.Set(int64(s.batches))
+	if seg.batches == 0 {
+		s.remove()
+	}
+}
+
+// Close closes the spool. Batches that weren't replayed remain on disk.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return nil
+	}
+	err := s.w.Close()
+	s.w = nil
+	return err
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spool
+
+import (
+	"errors"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	pstypes "github.com/rabbitstack/fibratus/pkg/ps/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newBatch(seqs ...uint64) *kevent.Batch {
+	evts := make([]*kevent.Kevent, len(seqs))
+	for i, seq := range seqs {
+		evts[i] = &kevent.Kevent{
+			Type:      ktypes.CreateFile,
+			Tid:       2484,
+			PID:       859,
+			Seq:       seq,
+			Name:      "CreateFile",
+			Timestamp: time.Now(),
+			Category:  ktypes.File,
+			Kparams: kevent.Kparams{
+				kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "C:\\Windows\\system32\\user32.dll"},
+			},
+			Metadata: make(map[string]string),
+			PS:       &pstypes.PS{PID: 859, Name: "svchost.exe"},
+		}
+	}
+	return kevent.NewBatch(evts...)
+}
+
+// recorder publishes replayed batches by recording the sequence numbers of their events.
+type recorder struct {
+	seqs []uint64
+	fail bool
+}
+
+func (r *recorder) publish(b *kevent.Batch) error {
+	if r.fail {
+		return errors.New("connection refused")
+	}
+	for _, kevt := range b.Events {
+		r.seqs = append(r.seqs, kevt.Seq)
+	}
+	return nil
+}
+
+func openSpool(t *testing.T, dir string) *Spool {
+	s, err := Open("elasticsearch", Config{Path: dir, MaxSize: 10, MaxAge: time.Hour})
+	require.NoError(t, err)
+	return s
+}
+
+func TestPutReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := openSpool(t, dir)
+	defer s.Close()
+
+	require.NoError(t, s.Put(newBatch(1, 2)))
+	require.NoError(t, s.Put(newBatch(3)))
+	require.NoError(t, s.Put(newBatch(4, 5)))
+	assert.Equal(t, 3, s.Len())
+
+	r := &recorder{}
+	require.NoError(t, s.Replay(func(b *kevent.Batch) error {
+		require.NotNil(t, b.Events[0].PS)
+		assert.Equal(t, "svchost.exe", b.Events[0].PS.Name)
+		filename, err := b.Events[0].Kparams.GetString(kparams.FileName)
+		require.NoError(t, err)
+		assert.Equal(t, "C:\\Windows\\system32\\user32.dll", filename)
+		return r.publish(b)
+	}, 2))
+	assert.Equal(t, []uint64{1, 2, 3}, r.seqs)
+	assert.Equal(t, 1, s.Len())
+
+	require.NoError(t, s.Replay(r.publish, 10))
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, r.seqs)
+	assert.Equal(t, 0, s.Len())
+
+	files, err := filepath.Glob(filepath.Join(dir, "elasticsearch", "*"+ext))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestReplayFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := openSpool(t, dir)
+	defer s.Close()
+
+	require.NoError(t, s.Put(newBatch(1)))
+	require.NoError(t, s.Put(newBatch(2)))
+
+	r := &recorder{fail: true}
+	require.Error(t, s.Replay(r.publish, 10))
+	assert.Equal(t, 2, s.Len())
+
+	r.fail = false
+	require.NoError(t, s.Replay(r.publish, 10))
+	assert.Equal(t, []uint64{1, 2}, r.seqs)
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestReplayPartialFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := openSpool(t, dir)
+	defer s.Close()
+
+	require.NoError(t, s.Put(newBatch(1, 2, 3)))
+	require.NoError(t, s.Put(newBatch(4)))
+
+	// the output publishes the first event and fails on the rest of the batch
+	r := &recorder{}
+	err = s.Replay(func(b *kevent.Batch) error {
+		require.NoError(t, r.publish(kevent.NewBatch(b.Events[0])))
+		return &outputs.PartialError{Err: errors.New("connection refused"), Published: 1}
+	}, 10)
+	require.Error(t, err)
+	assert.Equal(t, 2, s.Len())
+
+	require.NoError(t, s.Replay(r.publish, 10))
+	assert.Equal(t, []uint64{1, 2, 3, 4}, r.seqs)
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestRecover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := openSpool(t, dir)
+	require.NoError(t, s.Put(newBatch(1)))
+	require.NoError(t, s.Put(newBatch(2, 3)))
+	require.NoError(t, s.Close())
+
+	// simulate the record that was partially written before the crash
+	files, err := filepath.Glob(filepath.Join(dir, "elasticsearch", "*"+ext))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	f, err := os.OpenFile(files[0], os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.Write(encode(newBatch(4))[:20])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s = openSpool(t, dir)
+	defer s.Close()
+	assert.Equal(t, 2, s.Len())
+
+	require.NoError(t, s.Put(newBatch(5)))
+
+	r := &recorder{}
+	require.NoError(t, s.Replay(r.publish, 10))
+	assert.Equal(t, []uint64{1, 2, 3, 5}, r.seqs)
+}
+
+func TestEvict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := openSpool(t, dir)
+	defer s.Close()
+
+	size := int64(len(encode(newBatch(1))))
+	s.maxSize = size*2 + size/2
+
+	require.NoError(t, s.Put(newBatch(1)))
+	require.NoError(t, s.Put(newBatch(2)))
+	// there is no room for the batch, so the oldest batches are evicted
+	require.NoError(t, s.Put(newBatch(3)))
+	assert.Equal(t, 1, s.Len())
+	require.Error(t, s.Put(newBatch(4, 5, 6)))
+
+	r := &recorder{}
+	require.NoError(t, s.Replay(r.publish, 10))
+	assert.Equal(t, []uint64{3}, r.seqs)
+
+	s.maxAge = time.Millisecond
+	require.NoError(t, s.Put(newBatch(7)))
+	time.Sleep(time.Millisecond * 10)
+	require.NoError(t, s.Replay(r.publish, 10))
+	assert.Equal(t, []uint64{3}, r.seqs)
+	assert.Equal(t, 0, s.Len())
+}
@@ -19,39 +19,187 @@
 package aggregator
 
 import (
+	"expvar"
+	"github.com/rabbitstack/fibratus/pkg/aggregator/spool"
+	"github.com/rabbitstack/fibratus/pkg/aggregator/transformers"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/rabbitstack/fibratus/pkg/util/multierror"
+	log "github.com/sirupsen/logrus"
 )
 
+// batchesDropped counts the batches dropped by each output because of the full queue
+var batchesDropped = expvar.NewMap("aggregator.output.batches.dropped")
+
 // queue defines the type alias for the batch worker queue
 type queue chan *kevent.Batch
 
-// submitter initializes a group of load balanced output producers.
+// Filter decides whether the event is published to the output.
+type Filter interface {
+	// Run evaluates the filter against the event.
+	Run(kevt *kevent.Kevent) bool
+}
+
+// Output bundles the output configuration with the optional filter that events have to match
+// in order to get published to the output.
+type Output struct {
+	Config outputs.Config
+	Filter Filter
+}
+
+// submitter initializes a group of load balanced output producers. Every output has its own submitter
+// and work queue, so a slow output doesn't stall the rest of outputs.
 type submitter struct {
-	wq      queue
-	workers []*worker
+	typ        outputs.Type
+	wq         queue
+	workers    []*worker
+	filter     Filter
+	transforms []transformers.Transformer
+	spool      *spool.Spool
+	// fanout indicates if batches are fanned out to more than one output
+	fanout bool
+	// quit instructs workers to stop waiting for the output
+	quit chan struct{}
 }
 
-func newSubmitter(wq queue, outputConfig outputs.Config) (*submitter, error) {
-	output, err := outputs.Load(outputConfig.Type, outputConfig)
+func newSubmitter(output Output, queueSize int, fanout bool, spoolConfig spool.Config) (*submitter, error) {
+	outputConfig := output.Config
+	group, err := outputs.Load(outputConfig.Type, outputConfig)
 	if err != nil {
 		return nil, err
 	}
-	clients := output.Clients
+	transforms, err := transformers.LoadAll(outputConfig.Transformers)
+	if err != nil {
+		return nil, err
+	}
+
+	// workers share the output spool
+	var sp *spool.Spool
+	if spoolConfig.Enabled {
+		sp, err = spool.Open(outputConfig.Type.String(), spoolConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	wq := make(queue, queueSize)
+	quit := make(chan struct{})
+	clients := group.Clients
 	workers := make([]*worker, len(clients))
 
 	for i, client := range clients {
-		workers[i] = initWorker(wq, client)
+		workers[i] = initWorker(wq, client, sp, quit)
+	}
+
+	return &submitter{
+		typ:        outputConfig.Type,
+		wq:         wq,
+		workers:    workers,
+		filter:     output.Filter,
+		transforms: transforms,
+		spool:      sp,
+		fanout:     fanout,
+		quit:       quit,
+	}, nil
+}
+
+// batch produces the batch of events destined to the output. The events are shared
+// with the original batch, unless the output has its own transformers. In that case,
+// transformers are applied to copies of the events.
+func (s *submitter) batch(b *kevent.Batch) *kevent.Batch {
+	evts := b.Events
+	if s.filter != nil {
+		evts = make([]*kevent.Kevent, 0, len(b.Events))
+		for _, kevt := range b.Events {
+			if s.filter.Run(kevt) {
+				evts = append(evts, kevt)
+			}
+		}
+	}
+	if len(evts) == 0 {
+		return nil
+	}
+	if len(s.transforms) == 0 {
+		return b.Share(evts...)
 	}
 
-	return &submitter{wq: wq, workers: workers}, nil
+	clones := make([]*kevent.Kevent, len(evts))
+	for i, kevt := range evts {
+		clone := kevt.Clone()
+		for _, transformer := range s.transforms {
+			if err := transformer.Transform(clone); err != nil {
+				log.Warnf("%s output transformer error occurred: %v", s.typ, err)
+				transformerErrors.Add(err.Error(), 1)
+			}
+		}
+		clones[i] = clone
+	}
+	return kevent.NewBatch(clones...)
+}
+
+// submit pushes the batch to the work queue. If the queue is full, the batch is spooled when spooling is
+// enabled. Otherwise, the batch is dropped if batches are fanned out to more than one output, so a slow
+// output doesn't stall the rest of outputs. The only output blocks until there is room in the queue.
+func (s *submitter) submit(b *kevent.Batch) {
+	select {
+	case s.wq <- b:
+		return
+	default:
+	}
+	switch {
+	case s.spool != nil:
+		if err := s.spool.Put(b); err != nil {
+			log.Warnf("couldn't spool batch: %v", err)
+			batchesDropped.Add(s.typ.String(), 1)
+		}
+		b.Release()
+	case s.fanout:
+		batchesDropped.Add(s.typ.String(), 1)
+		b.Release()
+	default:
+		select {
+		case s.wq <- b:
+		case <-s.quit:
+			batchesDropped.Add(s.typ.String(), 1)
+			b.Release()
+		}
+	}
+}
+
+// close closes the work queue. Workers exit after they consume all enqueued batches.
+func (s *submitter) close() {
+	close(s.wq)
 }
 
+// wait waits until workers consume all enqueued batches.
+func (s *submitter) wait() {
+	for _, w := range s.workers {
+		<-w.done
+	}
+}
+
+// abort instructs workers to stop waiting for the output. Workers spool the
+// batches left in the work queue if spooling is enabled, or drop them otherwise.
+func (s *submitter) abort() {
+	if s.spool == nil && len(s.wq) > 0 {
+		log.Warnf("dropping %d batches left in the %s output queue", len(s.wq), s.typ)
+		batchesDropped.Add(s.typ.String(), int64(len(s.wq)))
+	}
+	close(s.quit)
+}
+
+// shutdown closes the output clients and the spool.
 func (s *submitter) shutdown() error {
+	errs := make([]error, 0)
 	for _, w := range s.workers {
 		if err := w.close(); err != nil {
-			return err
+			errs = append(errs, err)
+		}
+	}
+	if s.spool != nil {
+		if err := s.spool.Close(); err != nil {
+			errs = append(errs, err)
 		}
 	}
-	return nil
+	return multierror.Wrap(errs...)
 }
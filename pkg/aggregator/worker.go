@@ -19,7 +19,10 @@
 package aggregator
 
 import (
+	"errors"
 	"expvar"
+	"github.com/rabbitstack/fibratus/pkg/aggregator/spool"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/outputs"
 	log "github.com/sirupsen/logrus"
 	"time"
@@ -28,41 +31,158 @@ import (
 // maxBackoff determines the maximum exponential backoff wait time before reconnecting the client
 const maxBackoff = time.Minute
 
+const (
+	// replayInterval determines how often the worker attempts to replay spooled batches
+	replayInterval = time.Second * 5
+	// replayBatches is the maximum number of spooled batches replayed at once, so the
+	// worker keeps consuming the work queue while the spool is being drained
+	replayBatches = 10
+)
+
 var clientPublishErrors = expvar.NewInt("aggregator.worker.client.publish.errors")
 
 type worker struct {
 	qu      queue
 	client  outputs.Client
 	backoff time.Duration
+	// spool persists the batches the client fails to publish. It is nil if spooling is disabled
+	spool *spool.Spool
+	// connected indicates whether the client connection is established
+	connected bool
+	// quit is closed when the worker should stop waiting for the output. The batches
+	// left in the work queue are spooled if spooling is enabled, or dropped otherwise
+	quit <-chan struct{}
+	// done is closed when the work queue is closed and drained
+	done chan struct{}
 }
 
-func initWorker(q queue, client outputs.Client) *worker {
-	w := &worker{qu: q, client: client, backoff: time.Second * 2}
+func initWorker(q queue, client outputs.Client, spool *spool.Spool, quit <-chan struct{}) *worker {
+	w := &worker{qu: q, client: client, backoff: time.Second * 2, spool: spool, quit: quit, done: make(chan struct{})}
 	go w.run()
 	return w
 }
 
 func (w *worker) run() {
+	defer close(w.done)
+	w.connect()
+	var replay <-chan time.Time
+	if w.spool != nil {
+		ticker := time.NewTicker(replayInterval)
+		defer ticker.Stop()
+		replay = ticker.C
+	}
 	for {
-		err := w.client.Connect()
-		if err != nil {
-			// schedule an exponential backoff reconnect strategy for the client
-			w.backoff *= 2
-			log.Warnf("fail to connect the client: %v. Reconnecting in %v...", err, w.backoff)
-			if w.backoff > maxBackoff {
-				w.backoff = maxBackoff
+		select {
+		case batch, ok := <-w.qu:
+			if !ok {
+				return
+			}
+			w.publish(batch)
+		case <-replay:
+			if !w.connected {
+				w.connect()
 			}
-			<-time.After(w.backoff)
-			continue
+			w.replay()
 		}
-		break
 	}
-	for batch := range w.qu {
+}
+
+// publish sends the batch to the output. If spooling is enabled, the events the client fails
+// to publish are spooled. The batch is also spooled if the client is disconnected or older
+// batches are waiting to be replayed, so the output receives batches in the order they were
+// produced. Without the spool, the worker blocks until the client connection is established.
+func (w *worker) publish(batch *kevent.Batch) {
+	if w.spool == nil {
+		if w.stopping() || (!w.connected && !w.reconnect()) {
+			batch.Release()
+			return
+		}
 		if err := w.client.Publish(batch); err != nil {
-			clientPublishErrors.Add(1)
-			log.Warnf("couldn't publish batch to client: %v", err)
+			w.fail(err)
+		}
+		return
+	}
+	if !w.connected || w.spool.Len() > 0 || w.stopping() {
+		w.store(batch)
+		batch.Release()
+		w.replay()
+		return
+	}
+	// keep the events alive after the client releases the batch, so they can be spooled
+	hold := batch.Share(batch.Events...)
+	defer hold.Release()
+	if err := w.client.Publish(batch); err != nil {
+		unpublished := hold.Share(outputs.Unpublished(hold, err)...)
+		w.store(unpublished)
+		unpublished.Release()
+		w.fail(err)
+	}
+}
+
+// replay publishes the spooled batches if the client is connected.
+func (w *worker) replay() {
+	if !w.connected || w.stopping() {
+		return
+	}
+	if err := w.spool.Replay(w.client.Publish, replayBatches); err != nil {
+		w.fail(err)
+	}
+}
+
+func (w *worker) store(batch *kevent.Batch) {
+	if err := w.spool.Put(batch); err != nil {
+		log.Warnf("couldn't spool batch: %v", err)
+	}
+}
+
+// fail handles the client publish error. The client is
+// marked as disconnected if it lost the connection.
+func (w *worker) fail(err error) {
+	clientPublishErrors.Add(1)
+	log.Warnf("couldn't publish batch to client: %v", err)
+	if errors.Is(err, outputs.ErrConnectionLost) {
+		w.connected = false
+	}
+}
+
+// connect makes a single attempt to establish the client connection.
+func (w *worker) connect() bool {
+	if err := w.client.Connect(); err != nil {
+		log.Warnf("fail to connect the client: %v", err)
+		return false
+	}
+	w.connected = true
+	return true
+}
+
+// reconnect connects the client with exponential backoff until the connection
+// is established. It returns false if the worker is instructed to quit meanwhile.
+func (w *worker) reconnect() bool {
+	backoff := w.backoff
+	for !w.connect() {
+		// schedule an exponential backoff reconnect strategy for the client
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		log.Warnf("reconnecting the client in %v...", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-w.quit:
+			return false
 		}
 	}
+	return true
+}
+
+// stopping determines whether the worker is instructed to quit.
+func (w *worker) stopping() bool {
+	select {
+	case <-w.quit:
+		return true
+	default:
+		return false
+	}
 }
 
 func (w *worker) close() error {
@@ -19,10 +19,20 @@
 package aggregator
 
 import (
+	"errors"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/aggregator/spool"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"sync"
 	"testing"
 	"time"
 )
@@ -74,7 +84,7 @@ func TestRunWorker(t *testing.T) {
 
 	client := &httpClient{url: srv.URL, wait: make(chan struct{}, 1), expectedPublished: 2}
 
-	w := initWorker(q, client)
+	w := initWorker(q, client, nil, nil)
 	defer w.close()
 
 	<-client.wait
@@ -110,10 +120,227 @@ func TestConnectClientBackoff(t *testing.T) {
 		fail = false
 	})
 
-	w := initWorker(q, client)
+	w := initWorker(q, client, nil, nil)
 	defer w.close()
 
 	<-client.wait
 
 	assert.Equal(t, 2, client.published)
 }
+
+type flakyClient struct {
+	connects  int
+	published int
+}
+
+func (c *flakyClient) Connect() error { c.connects++; return nil }
+func (c *flakyClient) Close() error   { return nil }
+
+func (c *flakyClient) Publish(b *kevent.Batch) error {
+	if c.connects == 1 {
+		return fmt.Errorf("%w: broken pipe", outputs.ErrConnectionLost)
+	}
+	c.published++
+	return nil
+}
+
+func TestReconnectClient(t *testing.T) {
+	q := make(chan *kevent.Batch, 2)
+	q <- &kevent.Batch{}
+	q <- &kevent.Batch{}
+	close(q)
+
+	client := &flakyClient{}
+	w := initWorker(q, client, nil, nil)
+	<-w.done
+
+	assert.Equal(t, 2, client.connects)
+	assert.Equal(t, 1, client.published)
+}
+
+// unavailableClient fails to publish batches until it becomes available.
+type unavailableClient struct {
+	sync.Mutex
+	available bool
+	seqs      []uint64
+}
+
+func (c *unavailableClient) Connect() error { return nil }
+func (c *unavailableClient) Close() error   { return nil }
+
+func (c *unavailableClient) Publish(b *kevent.Batch) error {
+	defer b.Release()
+	c.Lock()
+	defer c.Unlock()
+	if !c.available {
+		return errors.New("service unavailable")
+	}
+	for _, kevt := range b.Events {
+		c.seqs = append(c.seqs, kevt.Seq)
+	}
+	return nil
+}
+
+func (c *unavailableClient) setAvailable() {
+	c.Lock()
+	defer c.Unlock()
+	c.available = true
+}
+
+// partialClient publishes only the first event of each batch until it becomes available.
+type partialClient struct {
+	unavailableClient
+}
+
+func (c *partialClient) Publish(b *kevent.Batch) error {
+	defer b.Release()
+	c.Lock()
+	defer c.Unlock()
+	n := len(b.Events)
+	if !c.available && n > 1 {
+		n = 1
+	}
+	for _, kevt := range b.Events[:n] {
+		c.seqs = append(c.seqs, kevt.Seq)
+	}
+	if n < len(b.Events) {
+		return &outputs.PartialError{Err: errors.New("service unavailable"), Published: n}
+	}
+	return nil
+}
+
+func newSeqBatch(seqs ...uint64) *kevent.Batch {
+	evts := make([]*kevent.Kevent, len(seqs))
+	for i, seq := range seqs {
+		evts[i] = &kevent.Kevent{
+			Type:      ktypes.CreateFile,
+			Seq:       seq,
+			Name:      "CreateFile",
+			Timestamp: time.Now(),
+			Category:  ktypes.File,
+			Kparams: kevent.Kparams{
+				kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "C:\\Windows\\notepad.exe"},
+			},
+			Metadata: make(map[string]string),
+		}
+	}
+	return kevent.NewBatch(evts...)
+}
+
+func TestSpoolWorker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sp, err := spool.Open("mem", spool.Config{Path: dir, MaxSize: 10, MaxAge: time.Hour})
+	require.NoError(t, err)
+	defer sp.Close()
+
+	q := make(queue, 3)
+	client := &unavailableClient{}
+	w := initWorker(q, client, sp, nil)
+
+	q <- newSeqBatch(1)
+	q <- newSeqBatch(2)
+	require.Eventually(t, func() bool { return sp.Len() == 2 }, time.Second*5, time.Millisecond*10)
+
+	// spooled batches are replayed before the new batch
+	client.setAvailable()
+	q <- newSeqBatch(3)
+	close(q)
+	<-w.done
+
+	assert.Equal(t, []uint64{1, 2, 3}, client.seqs)
+	assert.Equal(t, 0, sp.Len())
+}
+
+func TestSpoolWorkerPartialFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sp, err := spool.Open("mem", spool.Config{Path: dir, MaxSize: 10, MaxAge: time.Hour})
+	require.NoError(t, err)
+	defer sp.Close()
+
+	q := make(queue, 3)
+	client := &partialClient{}
+	w := initWorker(q, client, sp, nil)
+
+	// only the unpublished events of both batches are spooled and replayed
+	q <- newSeqBatch(1, 2, 3)
+	q <- newSeqBatch(4, 5)
+	require.Eventually(t, func() bool { return sp.Len() == 2 }, time.Second*5, time.Millisecond*10)
+
+	client.setAvailable()
+	q <- newSeqBatch(6)
+	close(q)
+	<-w.done
+
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6}, client.seqs)
+	assert.Equal(t, 0, sp.Len())
+}
+
+// downClient fails to connect until it becomes available.
+type downClient struct {
+	unavailableClient
+	connects int
+}
+
+func (c *downClient) Connect() error {
+	c.Lock()
+	defer c.Unlock()
+	c.connects++
+	if !c.available {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestSpoolWorkerDisconnected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sp, err := spool.Open("mem", spool.Config{Path: dir, MaxSize: 10, MaxAge: time.Hour})
+	require.NoError(t, err)
+	defer sp.Close()
+
+	q := make(queue, 3)
+	client := &downClient{}
+	w := initWorker(q, client, sp, nil)
+
+	// batches are spooled while the client is disconnected
+	q <- newSeqBatch(1)
+	q <- newSeqBatch(2)
+	require.Eventually(t, func() bool { return sp.Len() == 2 }, time.Second, time.Millisecond*10)
+
+	// the client is reconnected on the replay tick
+	client.setAvailable()
+	require.Eventually(t, func() bool { return sp.Len() == 0 }, replayInterval*2, time.Millisecond*10)
+	q <- newSeqBatch(3)
+	close(q)
+	<-w.done
+
+	assert.Equal(t, []uint64{1, 2, 3}, client.seqs)
+	assert.Equal(t, 2, client.connects)
+}
+
+func TestQuitWorker(t *testing.T) {
+	q := make(queue, 2)
+	quit := make(chan struct{})
+	client := &downClient{}
+	w := initWorker(q, client, nil, quit)
+
+	q <- newSeqBatch(1)
+	q <- newSeqBatch(2)
+	close(q)
+	close(quit)
+
+	select {
+	case <-w.done:
+	case <-time.After(time.Second):
+		t.Fatal("worker is still reconnecting the client")
+	}
+	assert.Empty(t, client.seqs)
+}
@@ -70,10 +70,17 @@ type Alert struct {
 	Tags []string
 	// Severity determines the severity of this alert.
 	Severity Severity
+	// Tactics contains the identifiers of MITRE ATT&CK tactics the alert is associated with.
+	Tactics []string
+	// Techniques contains the identifiers of MITRE ATT&CK techniques the alert is associated with.
+	Techniques []string
 }
 
 // String returns the alert string representation.
 func (a Alert) String() string {
+	if len(a.Tactics) > 0 || len(a.Techniques) > 0 {
+		return fmt.Sprintf("Title: %s, Text: %s, Severity: %s, Tags: %v, Tactics: %v, Techniques: %v", a.Title, a.Text, a.Severity, a.Tags, a.Tactics, a.Techniques)
+	}
 	return fmt.Sprintf("Title: %s, Text: %s, Severity: %s, Tags: %v", a.Title, a.Text, a.Severity, a.Tags)
 }
 
@@ -81,3 +88,9 @@ func (a Alert) String() string {
 func NewAlert(title, text string, tags []string, severity Severity) Alert {
 	return Alert{Title: title, Text: text, Tags: tags, Severity: severity}
 }
+
+// WithAttack returns the alert associated with MITRE ATT&CK tactics and techniques.
+func (a Alert) WithAttack(tactics, techniques []string) Alert {
+	a.Tactics, a.Techniques = tactics, techniques
+	return a
+}
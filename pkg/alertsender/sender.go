@@ -18,7 +18,11 @@
 
 package alertsender
 
-import "fmt"
+import (
+	"expvar"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+)
 
 // ErrInvalidConfig signals an invalid sender config
 var ErrInvalidConfig = func(name Type) error { return fmt.Errorf("invalid config for %q sender", name) }
@@ -63,6 +67,18 @@ type Sender interface {
 	Send(Alert) error
 }
 
+// SendAsync emits the alert in a separate goroutine. Alert senders may block on network I/O,
+// so this prevents stalling the event flow. Failures are logged and counted in the provided
+// errors metric.
+func SendAsync(s Sender, alert Alert, errs *expvar.Int) {
+	go func() {
+		if err := s.Send(alert); err != nil {
+			errs.Add(1)
+			log.Warnf("fail to send %q alert: %v", alert.Title, err)
+		}
+	}()
+}
+
 // ToType converts the string representation of the alert sender to its corresponding type.
 func ToType(s string) Type {
 	switch s {
@@ -26,9 +26,16 @@ import (
 	removet "github.com/rabbitstack/fibratus/pkg/aggregator/transformers/remove"
 	replacet "github.com/rabbitstack/fibratus/pkg/aggregator/transformers/replace"
 	tagst "github.com/rabbitstack/fibratus/pkg/aggregator/transformers/tags"
+	correlation "github.com/rabbitstack/fibratus/pkg/correlation/config"
+	exceptions "github.com/rabbitstack/fibratus/pkg/exceptions/config"
+	filters "github.com/rabbitstack/fibratus/pkg/filter/config"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/outputs/amqp"
 	"github.com/rabbitstack/fibratus/pkg/outputs/elasticsearch"
+	"github.com/rabbitstack/fibratus/pkg/outputs/file"
+	"github.com/rabbitstack/fibratus/pkg/outputs/http"
+	"github.com/rabbitstack/fibratus/pkg/outputs/syslog"
 	"github.com/rabbitstack/fibratus/pkg/util/log"
 	"github.com/rabbitstack/fibratus/pkg/util/multierror"
 	yara "github.com/rabbitstack/fibratus/pkg/yara/config"
@@ -45,6 +52,7 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/outputs"
 	"github.com/rabbitstack/fibratus/pkg/outputs/console"
 	"github.com/rabbitstack/fibratus/pkg/pe"
+	rules "github.com/rabbitstack/fibratus/pkg/rules/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -74,8 +82,8 @@ type Config struct {
 	Filament FilamentConfig `json:"filament" yaml:"filament"`
 	// PE contains the settings that influences the behaviour of the PE (Portable Executable) reader.
 	PE pe.Config `json:"pe" yaml:"pe"`
-	// Output stores the currently active output config
-	Output outputs.Config
+	// Outputs stores the configs of all active outputs
+	Outputs []outputs.Config
 	// InitHandleSnapshot indicates whether initial handle snapshot is built
 	InitHandleSnapshot bool `json:"init-handle-snapshot" yaml:"init-handle-snapshot"`
 	DebugPrivilege     bool `json:"debug-privilege" yaml:"debug-privilege"`
@@ -87,6 +95,14 @@ type Config struct {
 	Yara yara.Config `json:"yara" yaml:"yara"`
 	// Aggregator stores event aggregator configuration
 	Aggregator aggregator.Config `json:"aggregator" yaml:"aggregator"`
+	// Correlation contains the sequence definitions and settings of the correlation engine
+	Correlation correlation.Config `json:"correlation" yaml:"correlation"`
+	// Rules contains the settings of the detection rules engine
+	Rules rules.Config `json:"rules" yaml:"rules"`
+	// Exceptions contains the settings of the exceptions that exempt events from outputs and alerts
+	Exceptions exceptions.Config `json:"exceptions" yaml:"exceptions"`
+	// Filters contains the settings that influence the evaluation of filters
+	Filters filters.Config `json:"filters" yaml:"filters"`
 	// Log contains log-specific configuration options
 	Log log.Config `json:"logging" yaml:"logging"`
 
@@ -107,6 +123,7 @@ type Options struct {
 	run     bool
 	list    bool
 	stats   bool
+	filter  bool
 }
 
 // Option is the type alias for the config option.
@@ -147,6 +164,13 @@ func WithStats() Option {
 	}
 }
 
+// WithFilter determines the filter command is executed.
+func WithFilter() Option {
+	return func(o *Options) {
+		o.filter = true
+	}
+}
+
 // NewWithOpts builds a new configuration store from a variety of sources such as configuration files,
 // environment variables or command line flags.
 func NewWithOpts(options ...Option) *Config {
@@ -163,15 +187,19 @@ func NewWithOpts(options ...Option) *Config {
 	flagSet := new(pflag.FlagSet)
 
 	c := &Config{
-		Kstream:    KstreamConfig{},
-		Filament:   FilamentConfig{},
-		API:        APIConfig{},
-		PE:         pe.Config{},
-		Log:        log.Config{},
-		Aggregator: aggregator.Config{},
-		viper:      v,
-		flags:      flagSet,
-		opts:       opts,
+		Kstream:     KstreamConfig{},
+		Filament:    FilamentConfig{},
+		API:         APIConfig{},
+		PE:          pe.Config{},
+		Log:         log.Config{},
+		Aggregator:  aggregator.Config{},
+		Correlation: correlation.Config{},
+		Rules:       rules.Config{},
+		Exceptions:  exceptions.Config{},
+		Filters:     filters.Config{},
+		viper:       v,
+		flags:       flagSet,
+		opts:        opts,
 	}
 
 	if opts.run || opts.replay {
@@ -179,6 +207,9 @@ func NewWithOpts(options ...Option) *Config {
 		console.AddFlags(flagSet)
 		amqp.AddFlags(flagSet)
 		elasticsearch.AddFlags(flagSet)
+		file.AddFlags(flagSet)
+		syslog.AddFlags(flagSet)
+		http.AddFlags(flagSet)
 		removet.AddFlags(flagSet)
 		replacet.AddFlags(flagSet)
 		renamet.AddFlags(flagSet)
@@ -187,12 +218,19 @@ func NewWithOpts(options ...Option) *Config {
 		mailsender.AddFlags(flagSet)
 		slacksender.AddFlags(flagSet)
 		yara.AddFlags(flagSet)
+		correlation.AddFlags(flagSet)
+		rules.AddFlags(flagSet)
+		exceptions.AddFlags(flagSet)
 	}
 
 	if opts.run || opts.capture {
 		pe.AddFlags(flagSet)
 	}
 
+	if opts.run || opts.replay || opts.capture || opts.filter {
+		filters.AddFlags(flagSet)
+	}
+
 	c.addFlags()
 
 	return c
@@ -225,6 +263,14 @@ func (c *Config) Init() error {
 	c.Aggregator.InitFromViper(c.viper)
 	c.Log.InitFromViper(c.viper)
 	c.Yara.InitFromViper(c.viper)
+	if err := c.Correlation.InitFromViper(c.viper); err != nil {
+		return err
+	}
+	c.Rules.InitFromViper(c.viper)
+	c.Exceptions.InitFromViper(c.viper)
+	if err := c.Filters.InitFromViper(c.viper); err != nil {
+		return err
+	}
 
 	c.InitHandleSnapshot = c.viper.GetBool(initHandleSnapshot)
 	c.DebugPrivilege = c.viper.GetBool(debugPrivilege)
@@ -236,6 +282,16 @@ func (c *Config) Init() error {
 	kevent.SerializePE = c.viper.GetBool(serializePE)
 	kevent.SerializeEnvs = c.viper.GetBool(serializeEnvs)
 
+	if c.Filters.FuzzyDistance > 0 {
+		ql.FuzzyDistance = c.Filters.FuzzyDistance
+	}
+	// IOC lists are loaded once and shared by all filters
+	if c.opts.run || c.opts.replay || c.opts.capture || c.opts.filter {
+		if err := c.Filters.OpenIOCs(); err != nil {
+			return err
+		}
+	}
+
 	if c.opts.run || c.opts.replay {
 		if err := c.tryLoadOutput(); err != nil {
 			return err
@@ -305,6 +361,9 @@ func (c *Config) addFlags() {
 		c.flags.StringP(kcapFile, "k", "", "The path of the input kcap file")
 
 	}
+	if c.opts.filter {
+		c.flags.StringP(kcapFile, "k", "", "The path of the kcap file with events the filter is evaluated against")
+	}
 	if c.opts.run || c.opts.replay || c.opts.list {
 		c.flags.String(filamentPath, filepath.Join(os.Getenv("PROGRAMFILES"), "fibratus", "filaments"), "Denotes the directory where filaments are located")
 	}
@@ -46,6 +46,9 @@ func TestNewFromYamlFile(t *testing.T) {
 
 	assert.Equal(t, time.Millisecond*230, c.Aggregator.FlushPeriod)
 	assert.Equal(t, time.Second*8, c.Aggregator.FlushTimeout)
+	assert.True(t, c.Aggregator.Spool.Enabled)
+	assert.Equal(t, 256, c.Aggregator.Spool.MaxSize)
+	assert.Equal(t, time.Hour*6, c.Aggregator.Spool.MaxAge)
 
 	assert.Len(t, c.Alertsenders, 2)
 
@@ -25,17 +25,28 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/outputs/amqp"
 	"github.com/rabbitstack/fibratus/pkg/outputs/console"
 	"github.com/rabbitstack/fibratus/pkg/outputs/elasticsearch"
+	"github.com/rabbitstack/fibratus/pkg/outputs/file"
+	"github.com/rabbitstack/fibratus/pkg/outputs/http"
 	"github.com/rabbitstack/fibratus/pkg/outputs/null"
+	"github.com/rabbitstack/fibratus/pkg/outputs/syslog"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sys/windows/svc"
 	"reflect"
-	"strconv"
+	"sort"
 )
 
 var errNoOutputSection = errors.New("no output section in config")
 
 var errOutputConfig = func(output string, err error) error { return fmt.Errorf("%s output invalid config: %v", output, err) }
 
+// outputOptions contains the options that are common to all outputs.
+type outputOptions struct {
+	// Filter is the expression that events have to match to get published to the output.
+	Filter string `mapstructure:"filter"`
+	// Transformers contains the raw config of transformers applied to events published to the output.
+	Transformers map[string]interface{} `mapstructure:"transformers"`
+}
+
 func (c *Config) tryLoadOutput() error {
 	output := c.viper.AllSettings()["output"]
 	if output == nil {
@@ -46,31 +57,10 @@ func (c *Config) tryLoadOutput() error {
 		return fmt.Errorf("expected map[string]interface{} type for output but found %s", reflect.TypeOf(output))
 	}
 
-	humNum := func(n int) string {
-		switch n {
-		case 2:
-			return "two"
-		case 3:
-			return "three"
-		case 4:
-			return "four"
-		case 5:
-			return "five"
-		case 6:
-			return "six"
-		case 7:
-			return "seven"
-		default:
-			return strconv.Itoa(n)
-		}
-	}
-	// don't permit if there are various outputs enabled at a time
-	activeOutputs := findActiveOutputs(mapping)
-	if len(activeOutputs) > 1 {
-		return fmt.Errorf("expected one but found %s active outputs: %s", humNum(len(activeOutputs)), activeOutputs)
-	}
+	c.Outputs = make([]outputs.Config, 0)
 
 	for typ, config := range mapping {
+		var outputConfig outputs.Config
 		switch typ {
 		case "console":
 			var consoleConfig console.Config
@@ -80,7 +70,7 @@ func (c *Config) tryLoadOutput() error {
 			if !consoleConfig.Enabled {
 				continue
 			}
-			c.Output.Type, c.Output.Output = outputs.Console, consoleConfig
+			outputConfig.Type, outputConfig.Output = outputs.Console, consoleConfig
 
 		case "amqp":
 			var amqpConfig amqp.Config
@@ -90,7 +80,7 @@ func (c *Config) tryLoadOutput() error {
 			if !amqpConfig.Enabled {
 				continue
 			}
-			c.Output.Type, c.Output.Output = outputs.AMQP, amqpConfig
+			outputConfig.Type, outputConfig.Output = outputs.AMQP, amqpConfig
 
 		case "elasticsearch":
 			var esConfig elasticsearch.Config
@@ -100,38 +90,81 @@ func (c *Config) tryLoadOutput() error {
 			if !esConfig.Enabled {
 				continue
 			}
-			c.Output.Type, c.Output.Output = outputs.Elasticsearch, esConfig
+			outputConfig.Type, outputConfig.Output = outputs.Elasticsearch, esConfig
+
+		case "file":
+			var fileConfig file.Config
+			if err := decode(config, &fileConfig); err != nil {
+				return errOutputConfig(typ, err)
+			}
+			if !fileConfig.Enabled {
+				continue
+			}
+			outputConfig.Type, outputConfig.Output = outputs.File, fileConfig
+
+		case "syslog":
+			var syslogConfig syslog.Config
+			if err := decode(config, &syslogConfig); err != nil {
+				return errOutputConfig(typ, err)
+			}
+			if !syslogConfig.Enabled {
+				continue
+			}
+			outputConfig.Type, outputConfig.Output = outputs.Syslog, syslogConfig
+
+		case "http":
+			var httpConfig http.Config
+			if err := decode(config, &httpConfig); err != nil {
+				return errOutputConfig(typ, err)
+			}
+			if !httpConfig.Enabled {
+				continue
+			}
+			outputConfig.Type, outputConfig.Output = outputs.HTTP, httpConfig
+
+		default:
+			continue
+		}
+
+		var opts outputOptions
+		if err := decode(config, &opts); err != nil {
+			return errOutputConfig(typ, err)
+		}
+		outputConfig.Filter = opts.Filter
+		if opts.Transformers != nil {
+			transforms, err := decodeTransformers(opts.Transformers)
+			if err != nil {
+				return errOutputConfig(typ, err)
+			}
+			outputConfig.Transformers = transforms
 		}
+
+		c.Outputs = append(c.Outputs, outputConfig)
 	}
 
+	// keep the order of outputs stable
+	sort.Slice(c.Outputs, func(i, j int) bool { return c.Outputs[i].Type < c.Outputs[j].Type })
+
 	// if it is not an interactive session but the console output is enabled
-	// we default to null output and warn about that
+	// we drop the console output and warn about that
 	in, err := svc.IsAnInteractiveSession()
-	if err == nil && !in && c.Output.Output != nil {
-		if c.Output.Type == outputs.Console {
+	if err == nil && !in {
+		for i, o := range c.Outputs {
+			if o.Type != outputs.Console {
+				continue
+			}
 			log.Warn("running in non-interactive session with console output. " +
-				"Please configure a different output type. Defaulting to null output")
-			c.Output.Type, c.Output.Output = outputs.Null, &null.Config{}
-			return nil
+				"Please configure a different output type. Disabling console output")
+			c.Outputs = append(c.Outputs[:i], c.Outputs[i+1:]...)
+			break
 		}
 	}
 
 	// default to null output
-	if c.Output.Output == nil {
+	if len(c.Outputs) == 0 {
 		log.Warn("all outputs disabled. Defaulting to null output")
-		c.Output.Type, c.Output.Output = outputs.Null, &null.Config{}
+		c.Outputs = append(c.Outputs, outputs.Config{Type: outputs.Null, Output: &null.Config{}})
 	}
 
 	return nil
 }
-
-func findActiveOutputs(outputs map[string]interface{}) []string {
-	outputTypes := make([]string, 0)
-	for typ, rawConfig := range outputs {
-		enabled, ok := rawConfig.(map[string]interface{})["enabled"].(bool)
-		if ok && enabled {
-			outputTypes = append(outputTypes, typ)
-		}
-	}
-	return outputTypes
-}
@@ -19,7 +19,10 @@
 package config
 
 import (
+	"github.com/rabbitstack/fibratus/pkg/aggregator/transformers"
+	"github.com/rabbitstack/fibratus/pkg/aggregator/transformers/remove"
 	"github.com/rabbitstack/fibratus/pkg/outputs/amqp"
+	"github.com/rabbitstack/fibratus/pkg/outputs/elasticsearch"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -36,14 +39,24 @@ func TestOutput(t *testing.T) {
 
 	require.NoError(t, c.Init())
 
-	require.NotNil(t, c.Output)
-	require.IsType(t, amqp.Config{}, c.Output.Output)
+	require.Len(t, c.Outputs, 2)
+	require.IsType(t, amqp.Config{}, c.Outputs[0].Output)
 
-	amqpConfig := c.Output.Output.(amqp.Config)
+	amqpConfig := c.Outputs[0].Output.(amqp.Config)
 	assert.Equal(t, "amqp://localhost:5672", amqpConfig.URL)
 	assert.Equal(t, time.Second*5, amqpConfig.Timeout)
 	assert.Equal(t, "fibratus", amqpConfig.Exchange)
 	assert.Equal(t, "topic", amqpConfig.ExchangeType)
 	assert.Equal(t, "fibratus", amqpConfig.RoutingKey)
 	assert.Equal(t, "/", amqpConfig.Vhost)
+	assert.Empty(t, c.Outputs[0].Filter)
+	assert.Empty(t, c.Outputs[0].Transformers)
+
+	require.IsType(t, elasticsearch.Config{}, c.Outputs[1].Output)
+	esConfig := c.Outputs[1].Output.(elasticsearch.Config)
+	assert.Equal(t, []string{"http://localhost:9200"}, esConfig.Servers)
+	assert.Equal(t, "kevt.category = 'net'", c.Outputs[1].Filter)
+	require.Len(t, c.Outputs[1].Transformers, 1)
+	assert.Equal(t, transformers.Remove, c.Outputs[1].Transformers[0].Type)
+	assert.Equal(t, []string{"sport"}, c.Outputs[1].Transformers[0].Transformer.(remove.Config).Kparams)
 }
@@ -34,7 +34,18 @@ var schema = `
 			"type": "object",
 			"properties": {
 				"flush-period":		{"type": "string", "minLength": 2, "pattern": "[0-9]+ms|s"},
-				"flush-timeout":	{"type": "string", "minLength": 2, "pattern": "[0-9]+s"}
+				"flush-timeout":	{"type": "string", "minLength": 2, "pattern": "[0-9]+s"},
+				"queue-size":		{"type": "integer", "minimum": 1},
+				"spool": {
+					"type": "object",
+					"properties": {
+						"enabled":		{"type": "boolean"},
+						"path":			{"type": "string", "minLength": 1},
+						"max-size":		{"type": "integer", "minimum": 1},
+						"max-age":		{"type": "string", "minLength": 2, "pattern": "[0-9]+(s|m|h)"}
+					},
+					"additionalProperties": false
+				}
 			},
 			"additionalProperties": false
 		},
@@ -95,6 +106,36 @@ var schema = `
 			"additionalProperties": false
 		},
 		"config-file": 		{"type": "string"},
+		"correlation": {
+			"type": "object",
+			"properties": {
+				"enabled":			{"type": "boolean"},
+				"alert-via":		{"type": "string", "enum": ["slack", "mail"]},
+				"alert-template":   {
+						"type": 		"object",
+						"properties": {
+							"text":	 	{"type": "string"},
+							"title": 	{"type": "string"}
+						},
+						"additionalProperties": false
+				},
+				"max-partials":		{"type": "integer", "minimum": 1},
+				"sequences":		{"type": "array", "items": {
+											"type": "object",
+											"properties": {
+												"name": 		{"type": "string", "minLength": 1},
+												"expr": 		{"type": "string", "minLength": 1},
+												"severity": 	{"type": "string", "enum": ["normal", "medium", "critical"]},
+												"tags": 		{"type": "array", "items": {"type": "string"}},
+												"tactics": 		{"type": "array", "items": {"type": "string"}},
+												"techniques": 	{"type": "array", "items": {"type": "string"}}
+											},
+											"required": ["name", "expr"],
+											"additionalProperties": false
+										}}
+			},
+			"additionalProperties": false
+		},
 		"debug-privilege":  {"type": "boolean"},
 		"handle": {
 			"type": "object",
@@ -119,6 +160,45 @@ var schema = `
 			},
 			"additionalProperties": false
 		},
+		"filters": {
+			"type": "object",
+			"properties": {
+				"ancestors-depth":	{"type": "integer", "minimum": 1},
+				"fuzzy-distance":	{"type": "integer", "minimum": 1},
+				"macros":			{"type": "array", "items": {
+											"type": "object",
+											"properties": {
+												"name": 		{"type": "string", "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"},
+												"expr": 		{"type": "string", "minLength": 1}
+											},
+											"required": ["name", "expr"],
+											"additionalProperties": false
+										}},
+				"lists":			{"type": "array", "items": {
+											"type": "object",
+											"properties": {
+												"name": 		{"type": "string", "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"},
+												"items": 		{"type": "array", "items": {"type": "string"}, "minItems": 1}
+											},
+											"required": ["name", "items"],
+											"additionalProperties": false
+										}},
+				"iocs":				{"type": "array", "items": {
+											"type": "object",
+											"properties": {
+												"name": 		{"type": "string", "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"},
+												"type": 		{"type": "string", "enum": ["hash", "ip", "domain", "path"]},
+												"path": 		{"type": "string", "minLength": 1},
+												"format": 		{"type": "string", "enum": ["text", "csv", "stix"]},
+												"column": 		{"type": ["string", "integer"]}
+											},
+											"required": ["name", "type", "path"],
+											"additionalProperties": false
+										}},
+				"ioc-reload-interval":	{"type": "string", "minLength": 2, "pattern": "^[0-9]+(ms|s|m|h)$"}
+			},
+			"additionalProperties": false
+		},
 		"kevent": {
 			"type": "object",
 			"properties": {
@@ -177,7 +257,9 @@ var schema = `
 								"enabled":		{"type": "boolean"},
 								"format": 		{"type": "string", "enum": ["json", "pretty"]},
 								"template": 	{"type": "string"},
-								"kv-delimiter": {"type": "string"}
+								"kv-delimiter": {"type": "string"},
+								"filter":		{"type": "string"},
+								"transformers": {"$ref": "#/properties/transformers"}
 							},
 							"additionalProperties": false
 						},
@@ -203,7 +285,72 @@ var schema = `
 								"tls-key": 					{"type": "string"},
 								"tls-cert": 				{"type": "string"},
 								"tls-ca": 					{"type": "string"},
-								"tls-insecure-skip-verify": {"type": "boolean"}
+								"tls-insecure-skip-verify": {"type": "boolean"},
+								"filter":					{"type": "string"},
+								"transformers":				{"$ref": "#/properties/transformers"}
+							},
+							"additionalProperties": false
+						},
+						"file": {
+							"type": "object",
+							"properties": {
+								"enabled":			{"type": "boolean"},
+								"path":				{"type": "string", "minLength": 1},
+								"max-size":			{"type": "integer", "minimum": 1},
+								"max-age":			{"type": "string", "minLength": 2, "pattern": "[0-9]+(ms|s|m|h)"},
+								"max-backups":		{"type": "integer", "minimum": 0},
+								"compression":		{"type": "string", "enum": ["", "gzip", "zstd"]},
+								"fsync-interval":	{"type": "string", "minLength": 2, "pattern": "[0-9]+(ms|s|m|h)"},
+								"filter":			{"type": "string"},
+								"transformers":		{"$ref": "#/properties/transformers"}
+							},
+							"additionalProperties": false
+						},
+						"syslog": {
+							"type": "object",
+							"properties": {
+								"enabled":					{"type": "boolean"},
+								"network":					{"type": "string", "enum": ["udp", "tcp", "tls"]},
+								"address":					{"type": "string", "minLength": 1},
+								"timeout":					{"type": "string"},
+								"format":					{"type": "string", "enum": ["rfc5424", "rfc3164"]},
+								"facility":					{"type": "string", "enum": ["kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news", "uucp", "cron", "authpriv", "ftp", "local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7"]},
+								"severity":					{"type": "string", "enum": ["emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"]},
+								"app-name":					{"type": "string", "minLength": 1, "maxLength": 48},
+								"hostname":					{"type": "string"},
+								"template":					{"type": "string"},
+								"tls-key": 					{"type": "string"},
+								"tls-cert": 				{"type": "string"},
+								"tls-ca": 					{"type": "string"},
+								"tls-insecure-skip-verify": {"type": "boolean"},
+								"filter":					{"type": "string"},
+								"transformers":				{"$ref": "#/properties/transformers"}
+							},
+							"additionalProperties": false
+						},
+						"http": {
+							"type": "object",
+							"properties": {
+								"enabled":					{"type": "boolean"},
+								"endpoint":					{"type": "string", "format": "uri", "minLength": 1, "pattern": "^https?://"},
+								"method":					{"type": "string", "enum": ["POST", "PUT"]},
+								"timeout":					{"type": "string"},
+								"format":					{"type": "string", "enum": ["json", "ndjson"]},
+								"username":					{"type": "string"},
+								"password":					{"type": "string"},
+								"bearer-token":				{"type": "string"},
+								"gzip-compression":			{"type": "boolean"},
+								"max-retries":				{"type": "integer", "minimum": 0},
+								"retry-backoff":			{"type": "string"},
+								"max-retry-after":			{"type": "string"},
+								"max-payload-size":			{"type": "integer", "minimum": 0},
+								"headers":					{"type": "object", "additionalProperties": true},
+								"tls-key": 					{"type": "string"},
+								"tls-cert": 				{"type": "string"},
+								"tls-ca": 					{"type": "string"},
+								"tls-insecure-skip-verify": {"type": "boolean"},
+								"filter":					{"type": "string"},
+								"transformers":				{"$ref": "#/properties/transformers"}
 							},
 							"additionalProperties": false
 						},
@@ -226,7 +373,9 @@ var schema = `
 								"tls-cert": 				{"type": "string"},
 								"tls-ca": 					{"type": "string"},
 								"tls-insecure-skip-verify": {"type": "boolean"},
-								"headers":					{"type": "object", "additionalProperties": true}
+								"headers":					{"type": "object", "additionalProperties": true},
+								"filter":					{"type": "string"},
+								"transformers":				{"$ref": "#/properties/transformers"}
 							},
 							"additionalProperties": false
 						}
@@ -246,6 +395,24 @@ var schema = `
 			},
 			"additionalProperties": false
 		},
+		"rules": {
+			"type": "object",
+			"properties": {
+				"enabled":			{"type": "boolean"},
+				"paths":			{"type": "array", "items": {"type": "string", "minLength": 1}},
+				"alert-via":		{"type": "string", "enum": ["slack", "mail"]}
+			},
+			"additionalProperties": false
+		},
+		"exceptions": {
+			"type": "object",
+			"properties": {
+				"enabled":			{"type": "boolean"},
+				"paths":			{"type": "array", "items": {"type": "string", "minLength": 1}},
+				"reload-interval":	{"type": "string", "minLength": 2, "pattern": "^[0-9]+(ms|s|m|h)$"}
+			},
+			"additionalProperties": false
+		},
 		"transformers": {
 			"type": "object",
 			"anyOf": [{
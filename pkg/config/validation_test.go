@@ -31,13 +31,26 @@ func TestValidate(t *testing.T) {
 	}{
 		{text: `aggregator:
                  flush-period: 20ms
-                 flush-timeout: 1s`, valid: true},
+                 flush-timeout: 1s
+                 queue-size: 50`, valid: true},
 		{text: `aggregator:
                  flush-period: 20
                  flush-timeout: 1s`, valid: false, errs: 1},
 		{text: `aggregator:
                  flush-perio: 20ms
                  flush-timeout: 1`, valid: false, errs: 2},
+		{text: `aggregator:
+                 spool:
+                  enabled: true
+                  path: C:\\fibratus\\spool
+                  max-size: 512
+                  max-age: 12h`, valid: true},
+		{text: `aggregator:
+                 spool:
+                  enabled: true
+                  max-size: 0
+                  max-age: 12
+                  max-depth: 10`, valid: false, errs: 3},
 
 		{text: `alertsenders:
                  mail: 
@@ -74,6 +87,152 @@ func TestValidate(t *testing.T) {
 		{text: `api:
                  transport: "" 
                  timeout: 1s`, valid: false, errs: 1},
+		{text: `correlation:
+                 enabled: true
+                 alert-via: slack
+                 max-partials: 500
+                 sequences:
+                  - name: Network connection from dropped file
+                    expr: sequence by ps.pid maxspan=30s |kevt.name = 'CreateFile'| |kevt.name = 'Connect'|
+                    severity: critical
+                    tags:
+                     - dropper
+                    tactics:
+                     - TA0011
+                    techniques:
+                     - T1105`, valid: true},
+		{text: `correlation:
+                 enabled: true
+                 max-partials: 0
+                 sequences:
+                  - name: Network connection from dropped file
+                    severity: high`, valid: false, errs: 3},
+		{text: `rules:
+                 enabled: true
+                 alert-via: slack
+                 paths:
+                  - C:\\Program Files\\fibratus\\rules`, valid: true},
+		{text: `rules:
+                 enabled: true
+                 alert-via: pagerduty
+                 paths: C:\\rules`, valid: false, errs: 2},
+		{text: `exceptions:
+                 enabled: true
+                 reload-interval: 1m
+                 paths:
+                  - C:\\Program Files\\fibratus\\exceptions`, valid: true},
+		{text: `exceptions:
+                 enabled: true
+                 reload-interval: 1 minute`, valid: false, errs: 1},
+		{text: `filters:
+                 ancestors-depth: 5`, valid: true},
+		{text: `filters:
+                 ancestors-depth: 0`, valid: false, errs: 1},
+		{text: `filters:
+                 fuzzy-distance: 3`, valid: true},
+		{text: `filters:
+                 fuzzy-distance: 0`, valid: false, errs: 1},
+		{text: `filters:
+                 macros:
+                  - name: spawn_shell
+                    expr: kevt.name = 'CreateProcess' and ps.name in $shells
+                 lists:
+                  - name: shells
+                    items: [cmd.exe, powershell.exe]`, valid: true},
+		{text: `filters:
+                 macros:
+                  - name: spawn shell
+                    expr: kevt.name = 'CreateProcess'
+                 lists:
+                  - name: shells`, valid: false, errs: 2},
+		{text: `filters:
+                 ioc-reload-interval: 5m
+                 iocs:
+                  - name: c2_ips
+                    type: ip
+                    path: C:\\iocs\\c2.txt
+                  - name: samples
+                    type: hash
+                    path: C:\\iocs\\samples.csv
+                    format: csv
+                    column: 2`, valid: true},
+		{text: `filters:
+                 ioc-reload-interval: 5 minutes
+                 iocs:
+                  - name: c2_ips
+                    type: url
+                  - name: samples
+                    type: hash
+                    path: C:\\iocs\\samples.xml
+                    format: xml`, valid: false, errs: 4},
+
+		{text: `output:
+                 console:
+                  enabled: true
+                  filter: kevt.category = 'net'
+                 amqp:
+                  enabled: true
+                  url: amqp://localhost:5672
+                  transformers:
+                   remove:
+                    enabled: true
+                    kparams:
+                     - sport`, valid: true},
+		{text: `output:
+                 console:
+                  enabled: true
+                  filter: 1
+                  transformers:
+                   remove:
+                    enabled: true
+                    kparam:
+                     - sport`, valid: false, errs: 4},
+		{text: `output:
+                 file:
+                  enabled: true
+                  path: C:\\fibratus\\events-%h-%Y-%m-%d.json
+                  max-size: 50
+                  max-age: 12h
+                  max-backups: 5
+                  compression: zstd
+                  fsync-interval: 2s`, valid: true},
+		{text: `output:
+                 file:
+                  enabled: true
+                  max-size: 0
+                  compression: lz4
+                  fsync: 1s`, valid: false, errs: 4},
+		{text: `output:
+                 syslog:
+                  enabled: true
+                  network: tls
+                  address: siem.local:6514
+                  format: rfc5424
+                  facility: local4
+                  severity: notice
+                  tls-ca: C:\\certs\\ca.pem`, valid: true},
+		{text: `output:
+                 syslog:
+                  enabled: true
+                  network: unix
+                  format: rfc822
+                  facility: local9`, valid: false, errs: 4},
+		{text: `output:
+                 http:
+                  enabled: true
+                  endpoint: https://collector.local:8443/events
+                  format: ndjson
+                  bearer-token: Zmlicm
+                  gzip-compression: true
+                  max-payload-size: 512
+                  headers:
+                    X-Source: fibratus`, valid: true},
+		{text: `output:
+                 http:
+                  enabled: true
+                  endpoint: collector.local
+                  method: GET
+                  max-retries: -1`, valid: false, errs: 5},
 	}
 
 	for i, tt := range tests {
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	enabled            = "correlation.enabled"
+	alertVia           = "correlation.alert-via"
+	alertTextTemplate  = "correlation.alert-template.text"
+	alertTitleTemplate = "correlation.alert-template.title"
+	maxPartials        = "correlation.max-partials"
+)
+
+// Sequence contains the definition of the sequence that is correlated across events.
+type Sequence struct {
+	// Name is the human-friendly name of the sequence.
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
+	// Expr is the sequence expression (e.g. sequence by ps.pid maxspan=30s |filter1| |filter2|).
+	Expr string `json:"expr" yaml:"expr" mapstructure:"expr"`
+	// Severity determines the severity of the alert emitted when the sequence is completed.
+	Severity string `json:"severity" yaml:"severity" mapstructure:"severity"`
+	// Tags contains a list of tags attached to the alert.
+	Tags []string `json:"tags" yaml:"tags" mapstructure:"tags"`
+	// Tactics contains the identifiers of MITRE ATT&CK tactics the sequence detects, e.g. TA0002.
+	Tactics []string `json:"tactics" yaml:"tactics" mapstructure:"tactics"`
+	// Techniques contains the identifiers of MITRE ATT&CK techniques the sequence detects, e.g. T1059.001.
+	Techniques []string `json:"techniques" yaml:"techniques" mapstructure:"techniques"`
+}
+
+// Config stores the correlation engine configuration.
+type Config struct {
+	// Enabled indicates if the correlation engine is enabled.
+	Enabled bool `json:"correlation.enabled" yaml:"correlation.enabled"`
+	// AlertVia defines which alert sender is used to emit the alert when the sequence is completed.
+	AlertVia string `json:"correlation.alert-via" yaml:"correlation.alert-via"`
+	// AlertTextTemplate defines the template that is used to render the text of the alert.
+	AlertTextTemplate string `json:"correlation.alert-text-template" yaml:"correlation.alert-text-template"`
+	// AlertTitleTemplate represents the template for the alert title.
+	AlertTitleTemplate string `json:"correlation.alert-title-template" yaml:"correlation.alert-title-template"`
+	// MaxPartials is the max number of partial matches each sequence can track.
+	MaxPartials int `json:"correlation.max-partials" yaml:"correlation.max-partials"`
+	// Sequences contains the sequence definitions.
+	Sequences []Sequence `json:"correlation.sequences" yaml:"correlation.sequences" mapstructure:"sequences"`
+}
+
+// InitFromViper initializes the correlation config from Viper. It returns
+// an error if sequence definitions can't be decoded.
+func (c *Config) InitFromViper(v *viper.Viper) error {
+	c.Enabled = v.GetBool(enabled)
+	c.AlertVia = v.GetString(alertVia)
+	c.AlertTextTemplate = v.GetString(alertTextTemplate)
+	c.AlertTitleTemplate = v.GetString(alertTitleTemplate)
+	c.MaxPartials = v.GetInt(maxPartials)
+
+	correlation, ok := v.AllSettings()["correlation"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	var sequences []Sequence
+	if err := decode(correlation["sequences"], &sequences); err != nil {
+		return fmt.Errorf("couldn't decode correlation.sequences: %v", err)
+	}
+	c.Sequences = sequences
+	return nil
+}
+
+// AddFlags registers persistent flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.Bool(enabled, false, "Specifies if the correlation engine is enabled")
+	flags.String(alertVia, "mail", "Defines which alert sender is used to emit the alert when the sequence is completed")
+	flags.String(alertTextTemplate, "", "Defines the template that is used to render the text of the alert")
+	flags.String(alertTitleTemplate, "", "Defines the template that is used to render the title of the alert")
+	flags.Int(maxPartials, 1000, "Specifies the max number of partial matches each sequence can track. When the limit is reached, the oldest partial match is evicted")
+}
+
+func decode(input, output interface{}) error {
+	var decoderConfig = &mapstructure.DecoderConfig{
+		Metadata:         nil,
+		Result:           output,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	}
+	decoder, err := mapstructure.NewDecoder(decoderConfig)
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(input)
+}
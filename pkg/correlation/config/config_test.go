@@ -0,0 +1,50 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestInitFromViper(t *testing.T) {
+	v := viper.New()
+	v.Set("correlation", map[string]interface{}{
+		"sequences": []interface{}{map[string]interface{}{
+			"name":       "LSASS memory dump",
+			"expr":       "sequence by ps.pid |kevt.name = 'OpenProcess'| |kevt.name = 'CreateFile'|",
+			"severity":   "critical",
+			"techniques": []interface{}{"T1003.001"},
+		}},
+	})
+	var c Config
+	require.NoError(t, c.InitFromViper(v))
+	require.Len(t, c.Sequences, 1)
+	assert.Equal(t, "LSASS memory dump", c.Sequences[0].Name)
+	assert.Equal(t, []string{"T1003.001"}, c.Sequences[0].Techniques)
+
+	v.Set("correlation", map[string]interface{}{
+		"sequences": []interface{}{map[string]interface{}{"name": "LSASS memory dump", "tags": map[string]interface{}{"os": "windows"}}},
+	})
+	err := c.InitFromViper(v)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "couldn't decode correlation.sequences")
+}
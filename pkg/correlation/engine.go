@@ -0,0 +1,193 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package correlation
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/alertsender"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/mitre"
+	"github.com/rabbitstack/fibratus/pkg/ps"
+	"github.com/rabbitstack/fibratus/pkg/util/multierror"
+	log "github.com/sirupsen/logrus"
+	"text/template"
+)
+
+var (
+	// sequenceMatches counts the number of completed sequences per sequence name
+	sequenceMatches = expvar.NewMap("correlation.sequence.matches")
+	// alertErrors counts the number of alerts that couldn't be sent
+	alertErrors = expvar.NewInt("correlation.alert.errors")
+)
+
+const alertTitleTmpl = `Sequence {{ .Name }} detected`
+
+const alertTextTmpl = `
+	Sequence {{ .Name }} detected at {{ .Timestamp }}.
+
+	Matched events
+	{{ range $i, $kevt := .Events }}
+		#{{ $i }} {{ $kevt.Name }} at {{ $kevt.Timestamp.Format "02 Jan 2006 15:04:05.000 MST" }}
+		{{- with $kevt.PS }} by {{ .Name }} ({{ .PID }}){{ else }} by process {{ $kevt.PID }}{{ end }}
+		Params: {{ $kevt.Kparams }}
+	{{ end }}
+`
+
+const tsLayout = "02 Jan 2006 15:04:05 MST"
+
+// AlertContext contains the sequence name along with the events that completed the sequence.
+type AlertContext struct {
+	Name      string
+	Events    []*kevent.Kevent
+	Timestamp string
+}
+
+// sequence bundles the compiled sequence with its alert settings.
+type sequence struct {
+	*filter.Sequence
+	name       string
+	severity   alertsender.Severity
+	tags       []string
+	tactics    []string
+	techniques []string
+}
+
+// Engine correlates the event stream with the configured sequences and emits
+// alerts when any of the sequences is completed.
+type Engine struct {
+	sequences []*sequence
+	config    *config.Config
+}
+
+// NewEngine compiles the sequences defined in the config and builds the correlation engine.
+func NewEngine(psnap ps.Snapshotter, config *config.Config) (*Engine, error) {
+	e := &Engine{
+		sequences: make([]*sequence, 0, len(config.Correlation.Sequences)),
+		config:    config,
+	}
+	for _, s := range config.Correlation.Sequences {
+		seq := filter.NewSequence(s.Expr, config.Correlation.MaxPartials, psnap, config)
+		if err := seq.Compile(); err != nil {
+			return nil, fmt.Errorf("invalid %q sequence: %v", s.Name, err)
+		}
+		if err := mitre.Validate(s.Tactics, s.Techniques); err != nil {
+			return nil, fmt.Errorf("invalid %q sequence: %v", s.Name, err)
+		}
+		e.sequences = append(e.sequences, &sequence{
+			Sequence:   seq,
+			name:       s.Name,
+			severity:   alertsender.ParseSeverityFromString(s.Severity),
+			tags:       s.Tags,
+			tactics:    s.Tactics,
+			techniques: s.Techniques,
+		})
+	}
+	log.Infof("loaded %d correlation sequence(s)", len(e.sequences))
+	return e, nil
+}
+
+// ProcessEvent feeds the event into all sequences. An alert is emitted for
+// each sequence that is completed by the event.
+func (e *Engine) ProcessEvent(kevt *kevent.Kevent) error {
+	var errs []error
+	for _, seq := range e.sequences {
+		kevts := seq.Next(kevt)
+		if kevts == nil {
+			continue
+		}
+		sequenceMatches.Add(seq.name, 1)
+		// matched events are clones, so the live event that
+		// completed the sequence is tagged on its own to carry
+		// the ATT&CK identifiers to outputs
+		kevt.AddAttack(seq.tactics, seq.techniques)
+		for _, e := range kevts {
+			e.AddAttack(seq.tactics, seq.techniques)
+		}
+		// sequences completed by any of the exempted events don't trigger alerts
+		if isExempted(kevts) {
+			continue
+		}
+		ctx := AlertContext{
+			Name:      seq.name,
+			Events:    kevts,
+			Timestamp: kevt.Timestamp.Format(tsLayout),
+		}
+		if err := e.send(seq, ctx); err != nil {
+			errs = append(errs, fmt.Errorf("couldn't send %q sequence alert: %v", seq.name, err))
+		}
+	}
+	return multierror.Wrap(errs...)
+}
+
+func (e *Engine) send(seq *sequence, ctx AlertContext) error {
+	c := e.config.Correlation
+	if c.AlertTitleTemplate == "" {
+		c.AlertTitleTemplate = alertTitleTmpl
+	}
+	if c.AlertTextTemplate == "" {
+		c.AlertTextTemplate = alertTextTmpl
+	}
+	title, err := executeTmpl(c.AlertTitleTemplate, ctx)
+	if err != nil {
+		return err
+	}
+	text, err := executeTmpl(c.AlertTextTemplate, ctx)
+	if err != nil {
+		return err
+	}
+
+	sender := alertsender.Find(alertsender.ToType(c.AlertVia))
+	if sender == nil {
+		return fmt.Errorf("%q alert sender is not initialized", c.AlertVia)
+	}
+	alert := alertsender.NewAlert(title, text, seq.tags, seq.severity).WithAttack(seq.tactics, seq.techniques)
+
+	log.Infof("emitting sequence alert via %q sender: %s", c.AlertVia, alert)
+	alertsender.SendAsync(sender, alert, alertErrors)
+
+	return nil
+}
+
+func isExempted(kevts []*kevent.Kevent) bool {
+	for _, kevt := range kevts {
+		if kevt.IsExempted() {
+			return true
+		}
+	}
+	return false
+}
+
+func executeTmpl(body string, ctx AlertContext) (string, error) {
+	var writer bytes.Buffer
+
+	tmpl, err := template.New("correlation").Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("template syntax error: %v", err)
+	}
+	err = tmpl.Execute(&writer, ctx)
+	if err != nil {
+		return "", fmt.Errorf("couldn't execute template: %v", err)
+	}
+
+	return writer.String(), nil
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package correlation
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/alertsender"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	corrconfig "github.com/rabbitstack/fibratus/pkg/correlation/config"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/mitre"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var alerts = make(chan alertsender.Alert, 1)
+
+type mockSender struct{}
+
+func (s *mockSender) Send(a alertsender.Alert) error {
+	alerts <- a
+	return nil
+}
+
+func makeSender(config alertsender.Config) (alertsender.Sender, error) {
+	return &mockSender{}, nil
+}
+
+func init() {
+	alertsender.Register(alertsender.Noop, makeSender)
+}
+
+func TestEngine(t *testing.T) {
+	require.NoError(t, alertsender.LoadAll([]alertsender.Config{{Type: alertsender.Noop}}))
+
+	cfg := &config.Config{
+		Kstream: config.KstreamConfig{EnableFileIOKevents: true},
+		Correlation: corrconfig.Config{
+			Enabled:  true,
+			AlertVia: "noop",
+			Sequences: []corrconfig.Sequence{
+				{
+					Name:       "Dropped executable",
+					Expr:       `sequence by kevt.pid maxspan=1m |kevt.name = 'CreateFile' and file.name endswith '.exe'| |kevt.name = 'CreateProcess'|`,
+					Severity:   "critical",
+					Tags:       []string{"dropper"},
+					Tactics:    []string{"TA0002"},
+					Techniques: []string{"T1204.002"},
+				},
+			},
+		},
+	}
+	e, err := NewEngine(nil, cfg)
+	require.NoError(t, err)
+
+	now := time.Now()
+	kevt1 := &kevent.Kevent{
+		Type:      ktypes.CreateFile,
+		Name:      "CreateFile",
+		PID:       1234,
+		Timestamp: now,
+		Kparams: kevent.Kparams{
+			kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "C:\\Temp\\dropper.exe"},
+		},
+		Metadata: make(map[string]string),
+	}
+	kevt2 := &kevent.Kevent{
+		Type:      ktypes.CreateProcess,
+		Name:      "CreateProcess",
+		PID:       1234,
+		Timestamp: now.Add(time.Second * 5),
+		Kparams:   kevent.Kparams{},
+		Metadata:  make(map[string]string),
+	}
+	require.NoError(t, e.ProcessEvent(kevt1))
+	require.NoError(t, e.ProcessEvent(kevt2))
+
+	// the event that completed the sequence is tagged, while
+	// the events that started the sequence were only cloned
+	assert.Equal(t, "TA0002", kevt2.Metadata[mitre.TacticsMeta])
+	assert.Equal(t, "T1204.002", kevt2.Metadata[mitre.TechniquesMeta])
+	assert.Empty(t, kevt1.Metadata[mitre.TacticsMeta])
+
+	select {
+	case alert := <-alerts:
+		assert.Equal(t, "Sequence Dropped executable detected", alert.Title)
+		assert.Contains(t, alert.Text, "CreateFile")
+		assert.Contains(t, alert.Text, "CreateProcess")
+		assert.Equal(t, alertsender.Critical, alert.Severity)
+		assert.Equal(t, []string{"dropper"}, alert.Tags)
+		assert.Equal(t, []string{"TA0002"}, alert.Tactics)
+		assert.Equal(t, []string{"T1204.002"}, alert.Techniques)
+	case <-time.After(time.Second * 5):
+		t.Fatal("sequence alert wasn't sent")
+	}
+}
+
+func TestEngineInvalidSequence(t *testing.T) {
+	cfg := &config.Config{
+		Correlation: corrconfig.Config{
+			Sequences: []corrconfig.Sequence{
+				{Name: "Broken", Expr: `sequence by kevt.pid |kevt.name = 'CreateFile'|`},
+			},
+		},
+	}
+	_, err := NewEngine(nil, cfg)
+	require.EqualError(t, err, `invalid "Broken" sequence: sequence requires at least two filters`)
+
+	cfg.Correlation.Sequences = []corrconfig.Sequence{
+		{Name: "Unknown technique", Expr: `sequence by kevt.pid |kevt.name = 'CreateFile'| |kevt.name = 'CreateProcess'|`, Techniques: []string{"T9999"}},
+	}
+	_, err = NewEngine(nil, cfg)
+	require.EqualError(t, err, `invalid "Unknown technique" sequence: unknown technique T9999`)
+}
+
+func TestEngineSendErrors(t *testing.T) {
+	cfg := &config.Config{
+		Correlation: corrconfig.Config{
+			Enabled:  true,
+			AlertVia: "slack",
+			Sequences: []corrconfig.Sequence{
+				{Name: "First", Expr: `sequence by kevt.pid |kevt.name = 'CreateFile'| |kevt.name = 'CreateProcess'|`},
+				{Name: "Second", Expr: `sequence by kevt.pid |kevt.name = 'CreateFile'| |kevt.name = 'CreateProcess'|`},
+			},
+		},
+	}
+	e, err := NewEngine(nil, cfg)
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, e.ProcessEvent(&kevent.Kevent{Type: ktypes.CreateFile, Name: "CreateFile", PID: 1234, Timestamp: now, Kparams: kevent.Kparams{}}))
+
+	// both sequences are completed, so the failure to send the first alert doesn't prevent the second
+	err = e.ProcessEvent(&kevent.Kevent{Type: ktypes.CreateProcess, Name: "CreateProcess", PID: 1234, Timestamp: now.Add(time.Second), Kparams: kevent.Kparams{}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `couldn't send "First" sequence alert`)
+	assert.Contains(t, err.Error(), `couldn't send "Second" sequence alert`)
+}
@@ -0,0 +1,220 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exceptions
+
+import (
+	"expvar"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/ps"
+	"github.com/rabbitstack/fibratus/pkg/util/yamlfile"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	// exceptionMatches counts the number of events exempted by each exception
+	exceptionMatches = expvar.NewMap("exceptions.matches")
+	// exceptionsLoaded represents the number of exceptions in effect
+	exceptionsLoaded = expvar.NewInt("exceptions.loaded")
+	// reloads counts the number of times the exceptions were reloaded
+	reloads = expvar.NewInt("exceptions.reloads")
+	// reloadErrors counts the number of reloads that failed
+	reloadErrors = expvar.NewInt("exceptions.reload.errors")
+)
+
+// compiledException bundles the exception with its expiration time.
+type compiledException struct {
+	Exception
+	expires time.Time
+}
+
+func (e *compiledException) isExpired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
+
+// Allowlist decides whether events are exempted by any of the exceptions. Exception
+// files are periodically checked for changes and reloaded, so the exceptions can be
+// modified without restarting the process. The allowlist is safe for concurrent use.
+type Allowlist struct {
+	mu         sync.RWMutex
+	exceptions []*compiledException
+	// multi evaluates the filters of all exceptions in one pass
+	multi *filter.Multi
+	// mtimes contains the modification times of the loaded exception files
+	mtimes map[string]time.Time
+
+	psnap  ps.Snapshotter
+	config *config.Config
+	stop   chan struct{}
+}
+
+// New loads the exceptions from the configured paths and compiles their filters. If the
+// reload interval is configured, exception files are reloaded when they change.
+func New(psnap ps.Snapshotter, config *config.Config) (*Allowlist, error) {
+	a := &Allowlist{
+		psnap:  psnap,
+		config: config,
+		stop:   make(chan struct{}),
+	}
+	if err := a.Reload(); err != nil {
+		return nil, err
+	}
+	if interval := config.Exceptions.ReloadInterval; interval > 0 {
+		go a.watch(interval)
+	}
+	return a, nil
+}
+
+// Allowed determines whether the event matches any of the exceptions that haven't expired.
+func (a *Allowlist) Allowed(kevt *kevent.Kevent) bool {
+	_, ok := a.match(kevt)
+	return ok
+}
+
+// Exempt evaluates the exceptions against the event. If the event matches any of them, the
+// name of the matching exception is stored in the event metadata, so outputs and alert senders
+// skip the event without evaluating the exceptions again.
+func (a *Allowlist) Exempt(kevt *kevent.Kevent) bool {
+	name, ok := a.match(kevt)
+	if ok {
+		kevt.AddMeta(kevent.ExceptionMeta, name)
+	}
+	return ok
+}
+
+// match returns the name of the first exception that matches the event.
+func (a *Allowlist) match(kevt *kevent.Kevent) (string, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	now := time.Now()
+	for _, i := range a.multi.Run(kevt) {
+		e := a.exceptions[i]
+		if e.isExpired(now) {
+			continue
+		}
+		exceptionMatches.Add(e.Name, 1)
+		return e.Name, true
+	}
+	return "", false
+}
+
+// Reload loads and compiles the exceptions. If any of the exceptions is invalid,
+// the previously loaded exceptions remain in effect.
+func (a *Allowlist) Reload() error {
+	paths := a.config.Exceptions.Paths
+	mtimes, err := stat(paths)
+	if err != nil {
+		return fmt.Errorf("couldn't load exceptions: %v", err)
+	}
+	excs, err := Load(paths)
+	if err != nil {
+		return fmt.Errorf("couldn't load exceptions: %v", err)
+	}
+	now := time.Now()
+	compiled := make([]*compiledException, 0, len(excs))
+	filters := make([]filter.Filter, 0, len(excs))
+	for _, e := range excs {
+		expires, _ := e.Expiration()
+		expr, err := e.Expr()
+		if err != nil {
+			return fmt.Errorf("invalid %q exception: %v", e.Name, err)
+		}
+		f := filter.New(expr, a.psnap, a.config)
+		if err := f.Compile(); err != nil {
+			return fmt.Errorf("invalid %q exception: \n  %v", e.Name, err)
+		}
+		c := &compiledException{Exception: e, expires: expires}
+		if c.isExpired(now) {
+			log.Warnf("%q exception expired on %s", e.Name, e.Expires)
+			continue
+		}
+		compiled = append(compiled, c)
+		filters = append(filters, f)
+	}
+	multi, err := filter.NewMulti(filters, a.psnap, a.config)
+	if err != nil {
+		return fmt.Errorf("couldn't load exceptions: %v", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.exceptions = compiled
+	a.multi = multi
+	a.mtimes = mtimes
+	exceptionsLoaded.Set(int64(len(compiled)))
+	reloads.Add(1)
+	log.Infof("loaded %d exception(s)", len(compiled))
+
+	return nil
+}
+
+// Close stops reloading the exceptions.
+func (a *Allowlist) Close() {
+	close(a.stop)
+}
+
+// watch reloads the exceptions when the exception files are
+// modified, or some of them are created or removed.
+func (a *Allowlist) watch(interval time.Duration) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-tick.C:
+			mtimes, err := stat(a.config.Exceptions.Paths)
+			if err != nil {
+				reloadErrors.Add(1)
+				log.Warnf("couldn't stat exception files: %v", err)
+				continue
+			}
+			a.mu.RLock()
+			changed := !reflect.DeepEqual(mtimes, a.mtimes)
+			a.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := a.Reload(); err != nil {
+				reloadErrors.Add(1)
+				log.Warnf("couldn't reload exceptions: %v", err)
+				// don't retry until the files change again
+				a.mu.Lock()
+				a.mtimes = mtimes
+				a.mu.Unlock()
+			}
+		}
+	}
+}
+
+// stat returns the modification times of all exception files in the paths.
+func stat(paths []string) (map[string]time.Time, error) {
+	mtimes := make(map[string]time.Time)
+	err := yamlfile.Walk(paths, func(file string, info os.FileInfo) error {
+		mtimes[file] = info.ModTime()
+		return nil
+	})
+	return mtimes, err
+}
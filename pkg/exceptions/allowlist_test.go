@@ -0,0 +1,202 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exceptions
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/config"
+	excconfig "github.com/rabbitstack/fibratus/pkg/exceptions/config"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	pstypes "github.com/rabbitstack/fibratus/pkg/ps/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAllowlist(t *testing.T) {
+	a, err := New(nil, &config.Config{
+		Kstream:    config.KstreamConfig{EnableNetKevents: true},
+		Exceptions: excconfig.Config{Enabled: true, Paths: []string{"_fixtures/exceptions"}},
+	})
+	require.NoError(t, err)
+	defer a.Close()
+	// expired exceptions are not loaded
+	require.Len(t, a.exceptions, 2)
+
+	var tests = []struct {
+		kevt    *kevent.Kevent
+		allowed bool
+	}{
+		{
+			&kevent.Kevent{
+				Type: ktypes.CreateProcess,
+				Name: "CreateProcess",
+				Kparams: kevent.Kparams{
+					kparams.ProcessName: {Name: kparams.ProcessName, Type: kparams.AnsiString, Value: "CcmExec.exe"},
+				},
+				PS: &pstypes.PS{Name: "CcmExec.exe", Exe: "C:\\Windows\\CCM\\CcmExec.exe"},
+			},
+			true,
+		},
+		{
+			&kevent.Kevent{
+				Type: ktypes.CreateProcess,
+				Name: "CreateProcess",
+				Kparams: kevent.Kparams{
+					kparams.ProcessName: {Name: kparams.ProcessName, Type: kparams.AnsiString, Value: "CcmExec.exe"},
+				},
+				PS: &pstypes.PS{Name: "CcmExec.exe", Exe: "C:\\Temp\\CcmExec.exe"},
+			},
+			false,
+		},
+		{
+			&kevent.Kevent{
+				Type: ktypes.CreateProcess,
+				Name: "CreateProcess",
+				Kparams: kevent.Kparams{
+					kparams.ProcessName: {Name: kparams.ProcessName, Type: kparams.AnsiString, Value: "robocopy.exe"},
+					kparams.Comm:        {Name: kparams.Comm, Type: kparams.UnicodeString, Value: "robocopy C:\\Data \\\\backup01\\data"},
+				},
+			},
+			false,
+		},
+		{
+			&kevent.Kevent{
+				Type:     ktypes.Connect,
+				Name:     "Connect",
+				Category: ktypes.Net,
+				Kparams: kevent.Kparams{
+					kparams.NetDIP:   {Name: kparams.NetDIP, Type: kparams.IPv4, Value: net.ParseIP("10.0.2.16")},
+					kparams.NetDport: {Name: kparams.NetDport, Type: kparams.Uint16, Value: uint16(443)},
+				},
+			},
+			true,
+		},
+		{
+			&kevent.Kevent{
+				Type:     ktypes.Connect,
+				Name:     "Connect",
+				Category: ktypes.Net,
+				Kparams: kevent.Kparams{
+					kparams.NetDIP:   {Name: kparams.NetDIP, Type: kparams.IPv4, Value: net.ParseIP("10.0.2.16")},
+					kparams.NetDport: {Name: kparams.NetDport, Type: kparams.Uint16, Value: uint16(80)},
+				},
+			},
+			false,
+		},
+	}
+
+	for i, tt := range tests {
+		assert.Equal(t, tt.allowed, a.Allowed(tt.kevt), "%d. %s", i, tt.kevt.Name)
+	}
+	assert.Equal(t, "1", exceptionMatches.Get("SCCM agent shells").String())
+	assert.Equal(t, "1", exceptionMatches.Get("Monitoring probes").String())
+	assert.Nil(t, exceptionMatches.Get("Backup job"))
+}
+
+func TestAllowlistExempt(t *testing.T) {
+	a, err := New(nil, &config.Config{
+		Kstream:    config.KstreamConfig{EnableNetKevents: true},
+		Exceptions: excconfig.Config{Enabled: true, Paths: []string{"_fixtures/exceptions/network.yaml"}},
+	})
+	require.NoError(t, err)
+	defer a.Close()
+
+	kevt := &kevent.Kevent{
+		Type:     ktypes.Connect,
+		Name:     "Connect",
+		Category: ktypes.Net,
+		Kparams: kevent.Kparams{
+			kparams.NetDIP:   {Name: kparams.NetDIP, Type: kparams.IPv4, Value: net.ParseIP("10.0.2.15")},
+			kparams.NetDport: {Name: kparams.NetDport, Type: kparams.Uint16, Value: uint16(443)},
+		},
+		Metadata: make(map[string]string),
+	}
+	require.True(t, a.Exempt(kevt))
+	assert.True(t, kevt.IsExempted())
+	assert.Equal(t, "Monitoring probes", kevt.Metadata[kevent.ExceptionMeta])
+
+	kevt.Kparams.Set(kparams.NetDport, uint16(80), kparams.Uint16)
+	delete(kevt.Metadata, kevent.ExceptionMeta)
+	require.False(t, a.Exempt(kevt))
+	assert.False(t, kevt.IsExempted())
+}
+
+func TestAllowlistExpiry(t *testing.T) {
+	a, err := New(nil, &config.Config{
+		Kstream:    config.KstreamConfig{EnableNetKevents: true},
+		Exceptions: excconfig.Config{Enabled: true, Paths: []string{"_fixtures/exceptions/network.yaml"}},
+	})
+	require.NoError(t, err)
+	defer a.Close()
+	require.Len(t, a.exceptions, 1)
+
+	kevt := &kevent.Kevent{
+		Type:     ktypes.Connect,
+		Name:     "Connect",
+		Category: ktypes.Net,
+		Kparams: kevent.Kparams{
+			kparams.NetDIP:   {Name: kparams.NetDIP, Type: kparams.IPv4, Value: net.ParseIP("10.0.2.15")},
+			kparams.NetDport: {Name: kparams.NetDport, Type: kparams.Uint16, Value: uint16(443)},
+		},
+	}
+	require.True(t, a.Allowed(kevt))
+	// the exception expires while in effect
+	a.exceptions[0].expires = time.Now().Add(-time.Second)
+	require.False(t, a.Allowed(kevt))
+}
+
+func TestAllowlistReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exceptions")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "exceptions.yml")
+	require.NoError(t, ioutil.WriteFile(file, []byte("- name: Exc\n  fields:\n    ps.name: cmd.exe\n  comment: admin"), 0644))
+
+	a, err := New(nil, &config.Config{
+		Exceptions: excconfig.Config{Paths: []string{dir}, ReloadInterval: time.Millisecond * 50},
+	})
+	require.NoError(t, err)
+	defer a.Close()
+
+	kevt := &kevent.Kevent{
+		Type: ktypes.CreateProcess,
+		Name: "CreateProcess",
+		Kparams: kevent.Kparams{
+			kparams.ProcessName: {Name: kparams.ProcessName, Type: kparams.AnsiString, Value: "powershell.exe"},
+		},
+	}
+	require.False(t, a.Allowed(kevt))
+
+	require.NoError(t, ioutil.WriteFile(file, []byte("- name: Exc\n  fields:\n    ps.name: powershell.exe\n  comment: admin"), 0644))
+	require.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Minute)))
+	assert.Eventually(t, func() bool { return a.Allowed(kevt) }, time.Second*2, time.Millisecond*50)
+
+	// invalid exceptions leave the previous exceptions in effect
+	require.NoError(t, ioutil.WriteFile(file, []byte("- name: Exc\n  condition: ps.name = \n  comment: admin"), 0644))
+	require.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Minute*2)))
+	assert.Error(t, a.Reload())
+	assert.True(t, a.Allowed(kevt))
+}
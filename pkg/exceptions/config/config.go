@@ -0,0 +1,57 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	enabled        = "exceptions.enabled"
+	paths          = "exceptions.paths"
+	reloadInterval = "exceptions.reload-interval"
+)
+
+// Config stores the settings of the exceptions that exempt events from outputs and alerts.
+type Config struct {
+	// Enabled indicates if the exceptions are consulted.
+	Enabled bool `json:"exceptions.enabled" yaml:"exceptions.enabled"`
+	// Paths contains the directories or files from which the exceptions are loaded.
+	Paths []string `json:"exceptions.paths" yaml:"exceptions.paths"`
+	// ReloadInterval specifies how often the exception files are checked for changes.
+	ReloadInterval time.Duration `json:"exceptions.reload-interval" yaml:"exceptions.reload-interval"`
+}
+
+// InitFromViper initializes exceptions config from Viper.
+func (c *Config) InitFromViper(v *viper.Viper) {
+	c.Enabled = v.GetBool(enabled)
+	c.Paths = v.GetStringSlice(paths)
+	c.ReloadInterval = v.GetDuration(reloadInterval)
+}
+
+// AddFlags registers persistent flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.Bool(enabled, false, "Specifies if the exceptions are consulted before events are handed to outputs")
+	flags.StringSlice(paths, []string{filepath.Join(os.Getenv("PROGRAMFILES"), "fibratus", "exceptions")}, "Contains the comma-separated list of directories or files from which the exceptions are loaded")
+	flags.Duration(reloadInterval, time.Second*30, "Specifies how often the exception files are checked for changes. Zero disables the reloading")
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exceptions
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/util/yamlfile"
+	"gopkg.in/yaml.v2"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exception exempts events that match the exception from being handed to outputs. The
+// exempted events are still received by rules, sequences and filaments, but they don't
+// trigger alerts or YARA scans.
+type Exception struct {
+	// Name is the unique name of the exception.
+	Name string `json:"name" yaml:"name"`
+	// Fields contains the field/value pairs that all have to match the event.
+	Fields map[string]string `json:"fields" yaml:"fields"`
+	// Condition is the filter expression the event has to match in addition to the fields.
+	Condition string `json:"condition" yaml:"condition"`
+	// Expires is the date or the timestamp after which the exception no longer applies.
+	Expires string `json:"expires" yaml:"expires"`
+	// Comment is the justification for the exception.
+	Comment string `json:"comment" yaml:"comment"`
+}
+
+// expiresLayouts are the accepted layouts of the exception expiration.
+var expiresLayouts = []string{"2006-01-02", time.RFC3339}
+
+// Expiration returns the time when the exception expires. Exceptions without the
+// expiration return the zero time.
+func (e Exception) Expiration() (time.Time, error) {
+	if e.Expires == "" {
+		return time.Time{}, nil
+	}
+	var err error
+	for _, layout := range expiresLayouts {
+		var t time.Time
+		t, err = time.ParseInLocation(layout, e.Expires, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid expiration %q: %v", e.Expires, err)
+}
+
+// Expr builds the filter expression by joining the equality predicates
+// of all field/value pairs and the condition of the exception. It returns
+// an error if any of the values is not valid for the type of its field.
+func (e Exception) Expr() (string, error) {
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	preds := make([]string, 0, len(names)+1)
+	for _, name := range names {
+		pred, err := predicate(fields.Field(name), e.Fields[name])
+		if err != nil {
+			return "", err
+		}
+		preds = append(preds, pred)
+	}
+	if e.Condition != "" {
+		if len(preds) == 0 {
+			return e.Condition, nil
+		}
+		preds = append(preds, "("+e.Condition+")")
+	}
+	return strings.Join(preds, " and "), nil
+}
+
+// predicate renders the predicate that compares the field with the value. The value
+// is parsed according to the type the field evaluates to and rendered as the literal
+// of that type, so it can't alter the rest of the expression.
+func predicate(field fields.Field, value string) (string, error) {
+	var lit string
+	switch field.Type() {
+	case kparams.Int8, kparams.Int16, kparams.Int32, kparams.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q of %s: expected integer", value, field)
+		}
+		lit = strconv.FormatInt(n, 10)
+	case kparams.Uint8, kparams.Uint16, kparams.Uint32, kparams.Uint64, kparams.PID, kparams.TID, kparams.Port:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q of %s: expected unsigned integer", value, field)
+		}
+		lit = strconv.FormatUint(n, 10)
+	case kparams.Float, kparams.Double:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+			return "", fmt.Errorf("invalid value %q of %s: expected number", value, field)
+		}
+		lit = strconv.FormatFloat(n, 'f', -1, 64)
+	case kparams.IP, kparams.IPv4, kparams.IPv6:
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return "", fmt.Errorf("invalid value %q of %s: expected IP address", value, field)
+		}
+		lit = ip.String()
+	case kparams.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q of %s: expected boolean", value, field)
+		}
+		lit = strconv.FormatBool(b)
+	case kparams.Slice:
+		return fmt.Sprintf("%s in (%s)", field, ql.Quote(value)), nil
+	default:
+		lit = ql.Quote(value)
+	}
+	return fmt.Sprintf("%s = %s", field, lit), nil
+}
+
+// Load reads the exceptions from the YAML files found in the given paths.
+func Load(paths []string) ([]Exception, error) {
+	excs := make([]Exception, 0)
+	err := yamlfile.Load(paths, "exception", func(file string, b []byte) error {
+		es, err := decode(b)
+		if err != nil {
+			return err
+		}
+		excs = append(excs, es...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return excs, nil
+}
+
+// decode unmarshals the list of exceptions and validates them.
+func decode(b []byte) ([]Exception, error) {
+	var excs []Exception
+	if err := yaml.UnmarshalStrict(b, &excs); err != nil {
+		return nil, err
+	}
+	for _, e := range excs {
+		if len(e.Fields) == 0 && e.Condition == "" {
+			return nil, fmt.Errorf("exception %q has neither fields nor condition", e.Name)
+		}
+		if e.Comment == "" {
+			return nil, fmt.Errorf("exception %q has no comment justifying it", e.Name)
+		}
+		for name := range e.Fields {
+			if fields.Lookup(name) == "" {
+				return nil, fmt.Errorf("exception %q has unknown field %s", e.Name, name)
+			}
+		}
+		if _, err := e.Expr(); err != nil {
+			return nil, fmt.Errorf("exception %q has %v", e.Name, err)
+		}
+		if _, err := e.Expiration(); err != nil {
+			return nil, fmt.Errorf("exception %q has %v", e.Name, err)
+		}
+	}
+	return excs, nil
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exceptions
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	excs, err := Load([]string{"_fixtures/exceptions"})
+	require.NoError(t, err)
+	require.Len(t, excs, 3)
+
+	names := make(map[string]Exception)
+	for _, e := range excs {
+		names[e.Name] = e
+	}
+	require.Contains(t, names, "SCCM agent shells")
+	require.Contains(t, names, "Backup job")
+	require.Contains(t, names, "Monitoring probes")
+
+	e := names["SCCM agent shells"]
+	assert.Equal(t, "SCCM agent spawns shells when deploying patches", e.Comment)
+	expr, err := e.Expr()
+	require.NoError(t, err)
+	assert.Equal(t, `ps.exe = 'C:\\Windows\\CCM\\CcmExec.exe' and ps.name = 'CcmExec.exe'`, expr)
+	expires, err := e.Expiration()
+	require.NoError(t, err)
+	assert.True(t, expires.IsZero())
+
+	e = names["Monitoring probes"]
+	expr, err = e.Expr()
+	require.NoError(t, err)
+	assert.Equal(t, `net.dport = 443 and (net.dip in ('10.0.2.15', '10.0.2.16'))`, expr)
+	expires, err = e.Expiration()
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC), expires.UTC())
+
+	e = names["Backup job"]
+	expr, err = e.Expr()
+	require.NoError(t, err)
+	assert.Equal(t, `ps.name = 'robocopy.exe' and ps.comm contains '\\\\backup01\\'`, expr)
+	expires, err = e.Expiration()
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2019, 1, 1, 0, 0, 0, 0, time.Local), expires)
+}
+
+func TestExpr(t *testing.T) {
+	var tests = []struct {
+		fields map[string]string
+		expr   string
+	}{
+		{map[string]string{"ps.pid": "0004"}, `ps.pid = 4`},
+		{map[string]string{"net.dip": "10.0.2.15", "net.dport": "443"}, `net.dip = 10.0.2.15 and net.dport = 443`},
+		{map[string]string{"ps.name": "cmd.exe' or ps.name != '"}, `ps.name = 'cmd.exe\' or ps.name != \''`},
+		{map[string]string{"ps.comm": "cmd.exe\n/c whoami"}, `ps.comm = 'cmd.exe\n/c whoami'`},
+	}
+
+	for _, tt := range tests {
+		expr, err := Exception{Fields: tt.fields}.Expr()
+		require.NoError(t, err)
+		assert.Equal(t, tt.expr, expr)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	var tests = []struct {
+		excs string
+		err  string
+	}{
+		{"- name: Exc\n  condition: ps.name = 'cmd.exe'\n  comment: admin\n- name: Exc\n  condition: ps.name = 'svchost.exe'\n  comment: admin", "exception \"Exc\" is already defined in"},
+		{"- condition: ps.name = 'cmd.exe'\n  comment: admin", "exception #1 has no name"},
+		{"- name: Exc\n  comment: admin", "exception \"Exc\" has neither fields nor condition"},
+		{"- name: Exc\n  condition: ps.name = 'cmd.exe'", "exception \"Exc\" has no comment justifying it"},
+		{"- name: Exc\n  fields:\n    ps.nmae: cmd.exe\n  comment: admin", "exception \"Exc\" has unknown field ps.nmae"},
+		{"- name: Exc\n  condition: ps.name = 'cmd.exe'\n  comment: admin\n  expires: tomorrow", "exception \"Exc\" has invalid expiration \"tomorrow\""},
+		{"- name: Exc\n  filter: ps.name = 'cmd.exe'", "field filter not found in type exceptions.Exception"},
+		{"- name: Exc\n  fields:\n    ps.pid: 1 or ps.name != ''\n  comment: admin", "exception \"Exc\" has invalid value \"1 or ps.name != ''\" of ps.pid"},
+		{"- name: Exc\n  fields:\n    net.dip: 10.0.2.15 or net.dport = 80\n  comment: admin", "exception \"Exc\" has invalid value \"10.0.2.15 or net.dport = 80\" of net.dip"},
+	}
+
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "exceptions")
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "exceptions.yml"), []byte(tt.excs), 0644))
+		_, err = Load([]string{dir})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), tt.err)
+		_ = os.RemoveAll(dir)
+	}
+
+	_, err := Load([]string{"_fixtures/missing"})
+	require.Error(t, err)
+}
@@ -81,13 +81,13 @@ PyObject* PyArg_ParseString(PyObject *args, int n) {
     return ob;
 }
 
-void PyArg_ParseKeywords(PyObject *args, PyObject *kwargs, char *kwlist[], PyObject **ob1,  PyObject **ob2,  PyObject **ob3,  PyObject **ob4) {
+void PyArg_ParseKeywords(PyObject *args, PyObject *kwargs, char *kwlist[], PyObject **ob1,  PyObject **ob2,  PyObject **ob3,  PyObject **ob4,  PyObject **ob5,  PyObject **ob6) {
     int res;
 
     res = PyArg_ParseTupleAndKeywords(args,
                                       kwargs,
-                                      "OO|$OO", kwlist,
-                                      ob1, ob2, ob3, ob4);
+                                      "OO|$OOOO", kwlist,
+                                      ob1, ob2, ob3, ob4, ob5, ob6);
     if (!res) {
         PyErr_SetString(PyExc_ValueError, "parse keywords failed");
     }
@@ -33,7 +33,7 @@ int PyArg_ParseInt(PyObject *args, int n);
 PyObject* PyArg_ParseString(PyObject *args, int n);
 PyObject* PyArg_ParseList(PyObject *args, int n);
 
-void PyArg_ParseKeywords(PyObject *args, PyObject *kwargs, char *kwlist[], PyObject **ob1,  PyObject **ob2,  PyObject **ob3,  PyObject **ob4);
+void PyArg_ParseKeywords(PyObject *args, PyObject *kwargs, char *kwlist[], PyObject **ob1,  PyObject **ob2,  PyObject **ob3,  PyObject **ob4,  PyObject **ob5,  PyObject **ob6);
 
 PyObject* PyTime_FromDateTime(int year, int month, int day, int hour, int minute, int second, int usecond);
 PyObject* PyChar_FromChar(char v);
@@ -115,12 +115,14 @@ func (args PyArgs) GetSlice(n uint8) ([]interface{}, error) {
 }
 
 // PyArgsParseKeywords parses tuple and keywords arguments.
-func PyArgsParseKeywords(args PyArgs, kwargs PyKwargs, kwlist []string) (string, string, string, []string) {
+func PyArgsParseKeywords(args PyArgs, kwargs PyKwargs, kwlist []string) (string, string, string, []string, []string, []string) {
 	var (
 		ob1 *C.PyObject
 		ob2 *C.PyObject
 		ob3 *C.PyObject
 		ob4 *C.PyObject
+		ob5 *C.PyObject
+		ob6 *C.PyObject
 	)
 
 	klist := make([]*C.char, len(kwlist)+1)
@@ -138,9 +140,12 @@ func PyArgsParseKeywords(args PyArgs, kwargs PyKwargs, kwlist []string) (string,
 		(**C.PyObject)(unsafe.Pointer(&ob2)),
 		(**C.PyObject)(unsafe.Pointer(&ob3)),
 		(**C.PyObject)(unsafe.Pointer(&ob4)),
+		(**C.PyObject)(unsafe.Pointer(&ob5)),
+		(**C.PyObject)(unsafe.Pointer(&ob6)),
 	)
 
-	return fromRawOb(ob1).String(), fromRawOb(ob2).String(), fromRawOb(ob3).String(), fromRawOb(ob4).StringSlice()
+	return fromRawOb(ob1).String(), fromRawOb(ob2).String(), fromRawOb(ob3).String(), fromRawOb(ob4).StringSlice(),
+		fromRawOb(ob5).StringSlice(), fromRawOb(ob6).StringSlice()
 }
 
 // PyObject is the main abstraction for manipulating the native CPython objects.
@@ -30,6 +30,7 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/filter"
 	"github.com/rabbitstack/fibratus/pkg/handle"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/mitre"
 	"github.com/rabbitstack/fibratus/pkg/ps"
 	"github.com/rabbitstack/fibratus/pkg/util/multierror"
 	"github.com/rabbitstack/fibratus/pkg/util/term"
@@ -124,6 +125,8 @@ type filament struct {
 
 	onNextKevent *cpython.PyObject
 	onStop       *cpython.PyObject
+	// kevt is the event that is being handed over to the on_next_kevent function
+	kevt *kevent.Kevent
 
 	table tab
 }
@@ -328,7 +331,7 @@ func New(
 
 	// compile filter from the expression
 	if f.fexpr != "" {
-		f.filter = filter.New(f.fexpr, config)
+		f.filter = filter.New(f.fexpr, psnap, config)
 		if err := f.filter.Compile(); err != nil {
 			return nil, err
 		}
@@ -363,7 +366,13 @@ func (f *filament) Run(kevents chan *kevent.Kevent, errs chan error) error {
 		}
 
 		select {
-		case kevt := <-kevents:
+		case kevt, ok := <-kevents:
+			if !ok {
+				// the source is drained, but the batched
+				// events are still pushed on the next tick
+				kevents = nil
+				continue
+			}
 			batch.append(kevt)
 		case err := <-errs:
 			keventErrors.Add(err.Error(), 1)
@@ -391,13 +400,16 @@ func (f *filament) pushKevents(b kbatch) error {
 	defer f.gil.Unlock()
 	for _, kevt := range b {
 		kdict, err := newKDict(kevt)
-		kevt.Release()
 		if err != nil {
+			kevt.Release()
 			kdict.DecRef()
 			kdictErrors.Add(1)
 			continue
 		}
+		f.kevt = kevt
 		r := f.onNextKevent.Call(kdict.Object())
+		f.kevt = nil
+		kevt.Release()
 		if r != nil {
 			r.DecRef()
 		}
@@ -486,7 +498,7 @@ func (f *filament) titleFn(_ cpython.PyArgs, args cpython.PyArgs) cpython.PyRawO
 	return cpython.NewPyNone()
 }
 
-var keywords = []string{"", "", "severity", "tags"}
+var keywords = []string{"", "", "severity", "tags", "tactics", "techniques"}
 
 func (f *filament) emitAlertFn(_, args cpython.PyArgs, kwargs cpython.PyKwargs) cpython.PyRawObject {
 	f.gil.Lock()
@@ -497,7 +509,16 @@ func (f *filament) emitAlertFn(_, args cpython.PyArgs, kwargs cpython.PyKwargs)
 		return cpython.NewPyNone()
 	}
 
-	title, text, sever, tags := cpython.PyArgsParseKeywords(args, kwargs, keywords)
+	title, text, sever, tags, tactics, techniques := cpython.PyArgsParseKeywords(args, kwargs, keywords)
+	if err := mitre.Validate(tactics, techniques); err != nil {
+		f.fnerrs <- err
+		return cpython.NewPyNone()
+	}
+	// alerts emitted while the event is processed
+	// attach attack identifiers to the event
+	if f.kevt != nil {
+		f.kevt.AddAttack(tactics, techniques)
+	}
 
 	for _, s := range senders {
 		alert := alertsender.NewAlert(
@@ -505,7 +526,7 @@ func (f *filament) emitAlertFn(_, args cpython.PyArgs, kwargs cpython.PyKwargs)
 			text,
 			tags,
 			alertsender.ParseSeverityFromString(sever),
-		)
+		).WithAttack(tactics, techniques)
 		if err := s.Send(alert); err != nil {
 			log.Warnf("unable to emit alert from filament: %v", err)
 		}
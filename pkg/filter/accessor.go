@@ -20,12 +20,17 @@ package filter
 
 import (
 	"errors"
+	"github.com/rabbitstack/fibratus/pkg/config"
 	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
 	"github.com/rabbitstack/fibratus/pkg/fs"
+	htypes "github.com/rabbitstack/fibratus/pkg/handle/types"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
 	"github.com/rabbitstack/fibratus/pkg/net"
 	"github.com/rabbitstack/fibratus/pkg/pe"
+	"github.com/rabbitstack/fibratus/pkg/ps"
+	pstypes "github.com/rabbitstack/fibratus/pkg/ps/types"
 	"path/filepath"
 	"strings"
 )
@@ -38,10 +43,17 @@ type accessor interface {
 	get(f fields.Field, kevt *kevent.Kevent) (kparams.Value, error)
 }
 
+// elementAccessor is implemented by accessors that yield the elements of multi-valued fields
+// iterated by quantifiers. Each element resolves the values of its own fields.
+type elementAccessor interface {
+	// elements fetches the elements of the multi-valued field.
+	elements(f fields.Field, kevt *kevent.Kevent) ([]ql.Valuer, error)
+}
+
 // getAccessors initializes and returns all available accessors.
-func getAccessors() []accessor {
+func getAccessors(psnap ps.Snapshotter, config *config.Config) []accessor {
 	return []accessor{
-		newPSAccessor(),
+		newPSAccessor(psnap, config.Filters.AncestorsDepth),
 		newPEAccessor(),
 		newFileAccessor(),
 		newKevtAccessor(),
@@ -53,6 +65,40 @@ func getAccessors() []accessor {
 	}
 }
 
+// newAccessors returns the accessors for the event types enabled in the config.
+func newAccessors(psnap ps.Snapshotter, config *config.Config) []accessor {
+	accessors := []accessor{
+		// general event parameters
+		newKevtAccessor(),
+		// process state and parameters
+		newPSAccessor(psnap, config.Filters.AncestorsDepth),
+	}
+	kconfig := config.Kstream
+
+	if kconfig.EnableThreadKevents {
+		accessors = append(accessors, newThreadAccessor())
+	}
+	if kconfig.EnableImageKevents {
+		accessors = append(accessors, newImageAccessor())
+	}
+	if kconfig.EnableFileIOKevents {
+		accessors = append(accessors, newFileAccessor())
+	}
+	if kconfig.EnableRegistryKevents {
+		accessors = append(accessors, newRegistryAccessor())
+	}
+	if kconfig.EnableNetKevents {
+		accessors = append(accessors, newNetworkAccessor())
+	}
+	if kconfig.EnableHandleKevents {
+		accessors = append(accessors, newHandleAccessor())
+	}
+	if config.PE.Enabled {
+		accessors = append(accessors, newPEAccessor())
+	}
+	return accessors
+}
+
 // kevtAccessor extracts kernel event specific values.
 type kevtAccessor struct{}
 
@@ -60,7 +106,6 @@ func newKevtAccessor() accessor {
 	return &kevtAccessor{}
 }
 
-const timeFmt = "15:04:05"
 const dateFmt = "2006-01-02"
 
 func (k *kevtAccessor) get(f fields.Field, kevt *kevent.Kevent) (kparams.Value, error) {
@@ -82,7 +127,7 @@ func (k *kevtAccessor) get(f fields.Field, kevt *kevent.Kevent) (kparams.Value,
 	case fields.KevtHost:
 		return kevt.Host, nil
 	case fields.KevtTime:
-		return kevt.Timestamp.Format(timeFmt), nil
+		return kevt.Timestamp, nil
 	case fields.KevtTimeHour:
 		return uint8(kevt.Timestamp.Hour()), nil
 	case fields.KevtTimeMin:
@@ -109,15 +154,50 @@ func (k *kevtAccessor) get(f fields.Field, kevt *kevent.Kevent) (kparams.Value,
 		return kevt.Timestamp.Weekday().String(), nil
 	case fields.KevtNparams:
 		return uint64(kevt.Kparams.Len()), nil
+	case fields.KevtArg:
+		names := make([]string, 0, len(kevt.Kparams))
+		for name := range kevt.Kparams {
+			names = append(names, name)
+		}
+		return names, nil
 	default:
-		return nil, nil
+		if !strings.HasPrefix(f.String(), fields.KevtArgSubfield) {
+			return nil, nil
+		}
+		// access the raw value of the event parameter
+		name, _ := captureInBrackets(f.String())
+		kpar := kevt.Kparams.Find(name)
+		if kpar == nil {
+			return nil, nil
+		}
+		switch kpar.Type {
+		case kparams.HexInt8, kparams.HexInt16, kparams.HexInt32, kparams.HexInt64:
+			return kpar.Value.(kparams.Hex).String(), nil
+		case kparams.Enum:
+			// enum values are compared by their symbolic names
+			return kpar.String(), nil
+		default:
+			return kpar.Value, nil
+		}
 	}
 }
 
-// psAccessor extracts process's state or kevent specific values.
-type psAccessor struct{}
+// defaultAncestorsDepth is the number of ancestors visited if the depth is not configured
+const defaultAncestorsDepth = 10
 
-func newPSAccessor() accessor { return &psAccessor{} }
+// psAccessor extracts process's state or kevent specific values. The state of
+// the parent and other ancestors is looked up in the process snapshotter.
+type psAccessor struct {
+	psnap          ps.Snapshotter
+	ancestorsDepth int
+}
+
+func newPSAccessor(psnap ps.Snapshotter, ancestorsDepth int) accessor {
+	if ancestorsDepth <= 0 {
+		ancestorsDepth = defaultAncestorsDepth
+	}
+	return &psAccessor{psnap: psnap, ancestorsDepth: ancestorsDepth}
+}
 
 func (ps *psAccessor) get(f fields.Field, kevt *kevent.Kevent) (kparams.Value, error) {
 	switch f {
@@ -191,6 +271,16 @@ func (ps *psAccessor) get(f fields.Field, kevt *kevent.Kevent) (kparams.Value, e
 			mods = append(mods, filepath.Base(m.Name))
 		}
 		return mods, nil
+	case fields.PsRuntime:
+		ps := kevt.PS
+		if ps == nil || ps.StartTime.IsZero() {
+			started, err := kevt.Kparams.GetTime(kparams.StartTime)
+			if err != nil {
+				return nil, err
+			}
+			return kevt.Timestamp.Sub(started), nil
+		}
+		return kevt.Timestamp.Sub(ps.StartTime), nil
 	case fields.PsHandles:
 		ps := kevt.PS
 		if ps == nil {
@@ -214,6 +304,36 @@ func (ps *psAccessor) get(f fields.Field, kevt *kevent.Kevent) (kparams.Value, e
 			types[i] = handle.Type
 		}
 		return types, nil
+	case fields.PsParentName:
+		parent := ps.parent(kevt)
+		if parent == nil {
+			return nil, nil
+		}
+		return parent.Name, nil
+	case fields.PsParentComm:
+		parent := ps.parent(kevt)
+		if parent == nil {
+			return nil, nil
+		}
+		return parent.Comm, nil
+	case fields.PsParentExe:
+		parent := ps.parent(kevt)
+		if parent == nil {
+			return nil, nil
+		}
+		return parent.Exe, nil
+	case fields.PsParentSID:
+		parent := ps.parent(kevt)
+		if parent == nil {
+			return nil, nil
+		}
+		return parent.SID, nil
+	case fields.PsAncestors:
+		ancestors := ps.ancestors(kevt)
+		if len(ancestors) == 0 {
+			return nil, nil
+		}
+		return ancestors, nil
 	default:
 		field := f.String()
 		switch {
@@ -264,6 +384,101 @@ func (ps *psAccessor) get(f fields.Field, kevt *kevent.Kevent) (kparams.Value, e
 	}
 }
 
+// parent finds the state of the parent process in the snapshotter.
+func (ps *psAccessor) parent(kevt *kevent.Kevent) *pstypes.PS {
+	if ps.psnap == nil {
+		return nil
+	}
+	if kevt.PS != nil {
+		return ps.psnap.Find(kevt.PS.Ppid)
+	}
+	ppid, err := kevt.Kparams.GetPpid()
+	if err != nil {
+		return nil
+	}
+	return ps.psnap.Find(ppid)
+}
+
+// ancestors walks up the process tree and collects the image names of the
+// ancestors until the root process or the maximum depth is reached.
+func (ps *psAccessor) ancestors(kevt *kevent.Kevent) []string {
+	ancestors := make([]string, 0)
+	visited := make(map[uint32]bool)
+	if kevt.PS != nil {
+		visited[kevt.PS.PID] = true
+	}
+	for parent := ps.parent(kevt); parent != nil && len(ancestors) < ps.ancestorsDepth; parent = ps.psnap.Find(parent.Ppid) {
+		// the process identifier of the terminated parent can be
+		// reused, so the process may appear as its own ancestor
+		if visited[parent.PID] {
+			break
+		}
+		visited[parent.PID] = true
+		ancestors = append(ancestors, parent.Name)
+	}
+	return ancestors
+}
+
+func (ps *psAccessor) elements(f fields.Field, kevt *kevent.Kevent) ([]ql.Valuer, error) {
+	ps1 := kevt.PS
+	if ps1 == nil {
+		return nil, nil
+	}
+	switch f {
+	case fields.PsModules:
+		mods := make([]ql.Valuer, len(ps1.Modules))
+		for i, m := range ps1.Modules {
+			mods[i] = moduleElement(m)
+		}
+		return mods, nil
+	case fields.PsHandles:
+		handles := make([]ql.Valuer, len(ps1.Handles))
+		for i, h := range ps1.Handles {
+			handles[i] = handleElement(h)
+		}
+		return handles, nil
+	}
+	return nil, nil
+}
+
+// moduleElement resolves the fields of the module loaded by the process.
+type moduleElement pstypes.Module
+
+func (m moduleElement) Value(key string) (interface{}, bool) {
+	switch fields.Subfield(key) {
+	case fields.ModuleName:
+		return filepath.Base(m.Name), true
+	case fields.ModuleLocation:
+		return filepath.Dir(m.Name), true
+	case fields.ModuleSize:
+		return m.Size, true
+	case fields.ModuleChecksum:
+		return m.Checksum, true
+	case fields.ModuleBaseAddress:
+		return m.BaseAddress.String(), true
+	case fields.ModuleDefaultAddress:
+		return m.DefaultBaseAddress.String(), true
+	}
+	return nil, false
+}
+
+// handleElement resolves the fields of the handle allocated by the process.
+type handleElement htypes.Handle
+
+func (h handleElement) Value(key string) (interface{}, bool) {
+	switch fields.Subfield(key) {
+	case fields.HandleNum:
+		return uint64(h.Num), true
+	case fields.HandleObjectAddress:
+		return kparams.NewHex(h.Object).String(), true
+	case fields.HandleObjectName:
+		return h.Name, true
+	case fields.HandleObjectType:
+		return h.Type, true
+	}
+	return nil, false
+}
+
 // threadAccessor fetches thread parameters from thread kernel events.
 type threadAccessor struct{}
 
@@ -545,6 +760,62 @@ func (*peAccessor) get(f fields.Field, kevt *kevent.Kevent) (kparams.Value, erro
 	return nil, nil
 }
 
+func (*peAccessor) elements(f fields.Field, kevt *kevent.Kevent) ([]ql.Valuer, error) {
+	if kevt.PS == nil || kevt.PS.PE == nil {
+		return nil, nil
+	}
+	p := kevt.PS.PE
+
+	switch f {
+	case fields.PeSections:
+		sections := make([]ql.Valuer, len(p.Sections))
+		for i, sec := range p.Sections {
+			sections[i] = sectionElement(sec)
+		}
+		return sections, nil
+	case fields.PeResources:
+		resources := make([]ql.Valuer, 0, len(p.VersionResources))
+		for k, v := range p.VersionResources {
+			resources = append(resources, resourceElement{name: k, value: v})
+		}
+		return resources, nil
+	}
+	return nil, nil
+}
+
+// sectionElement resolves the fields of the PE section.
+type sectionElement pe.Sec
+
+func (s sectionElement) Value(key string) (interface{}, bool) {
+	switch fields.Subfield(key) {
+	case fields.SectionName:
+		return s.Name, true
+	case fields.SectionSize:
+		return s.Size, true
+	case fields.SectionEntropy:
+		return s.Entropy, true
+	case fields.SectionMD5Hash:
+		return s.Md5, true
+	}
+	return nil, false
+}
+
+// resourceElement resolves the name and the value of the PE version resource.
+type resourceElement struct {
+	name  string
+	value string
+}
+
+func (r resourceElement) Value(key string) (interface{}, bool) {
+	switch fields.Subfield(key) {
+	case fields.ResourceName:
+		return r.name, true
+	case fields.ResourceValue:
+		return r.value, true
+	}
+	return nil, false
+}
+
 func captureInBrackets(s string) (string, fields.Subfield) {
 	lbracket := strings.Index(s, "[")
 	if lbracket == -1 {
@@ -21,7 +21,9 @@ package filter
 import (
 	"github.com/rabbitstack/fibratus/pkg/filter/fields"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
 	"github.com/rabbitstack/fibratus/pkg/pe"
+	"github.com/rabbitstack/fibratus/pkg/ps"
 	ptypes "github.com/rabbitstack/fibratus/pkg/ps/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -30,7 +32,7 @@ import (
 )
 
 func TestPSAccessor(t *testing.T) {
-	ps := newPSAccessor()
+	ps := newPSAccessor(nil, 0)
 	kevt := &kevent.Kevent{
 		PS: &ptypes.PS{
 			Envs: map[string]string{"ALLUSERSPROFILE": "C:\\ProgramData", "OS": "Windows_NT", "ProgramFiles(x86)": "C:\\Program Files (x86)"},
@@ -50,6 +52,71 @@ func TestPSAccessor(t *testing.T) {
 	assert.Equal(t, "C:\\Program Files (x86)", env)
 }
 
+func TestPSAccessorAncestors(t *testing.T) {
+	psnap := new(ps.SnapshotterMock)
+	explorer := &ptypes.PS{PID: 2484, Ppid: 2460, Name: "explorer.exe"}
+	winword := &ptypes.PS{
+		PID:  4532,
+		Ppid: 2484,
+		Name: "WINWORD.EXE",
+		Exe:  "C:\\Program Files\\Microsoft Office\\root\\Office16\\WINWORD.EXE",
+		Comm: "\"C:\\Program Files\\Microsoft Office\\root\\Office16\\WINWORD.EXE\" /n",
+		SID:  "archrabbit\\SYSTEM",
+	}
+	psnap.On("Find", uint32(4532)).Return(winword)
+	psnap.On("Find", uint32(2484)).Return(explorer)
+	psnap.On("Find", uint32(2460)).Return((*ptypes.PS)(nil))
+
+	kevt := &kevent.Kevent{
+		PS: &ptypes.PS{PID: 1230, Ppid: 4532, Name: "powershell.exe"},
+	}
+
+	psa := newPSAccessor(psnap, 0)
+	name, err := psa.get(fields.PsParentName, kevt)
+	require.NoError(t, err)
+	assert.Equal(t, "WINWORD.EXE", name)
+	exe, err := psa.get(fields.PsParentExe, kevt)
+	require.NoError(t, err)
+	assert.Equal(t, winword.Exe, exe)
+	comm, err := psa.get(fields.PsParentComm, kevt)
+	require.NoError(t, err)
+	assert.Equal(t, winword.Comm, comm)
+	sid, err := psa.get(fields.PsParentSID, kevt)
+	require.NoError(t, err)
+	assert.Equal(t, "archrabbit\\SYSTEM", sid)
+
+	ancestors, err := psa.get(fields.PsAncestors, kevt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"WINWORD.EXE", "explorer.exe"}, ancestors)
+
+	// depth limits the number of visited ancestors
+	ancestors, err = newPSAccessor(psnap, 1).get(fields.PsAncestors, kevt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"WINWORD.EXE"}, ancestors)
+
+	// the parent is resolved from the event parameters if the process state is not available
+	kevt = &kevent.Kevent{
+		Kparams: kevent.Kparams{
+			kparams.ProcessParentID: {Name: kparams.ProcessParentID, Type: kparams.PID, Value: uint32(2484)},
+		},
+	}
+	name, err = psa.get(fields.PsParentName, kevt)
+	require.NoError(t, err)
+	assert.Equal(t, "explorer.exe", name)
+
+	// process identifier reuse must not cause infinite loops
+	loop := &ptypes.PS{PID: 9000, Ppid: 9001, Name: "loop.exe"}
+	psnap.On("Find", uint32(9001)).Return(&ptypes.PS{PID: 9001, Ppid: 9000, Name: "reused.exe"})
+	psnap.On("Find", uint32(9000)).Return(loop)
+	ancestors, err = psa.get(fields.PsAncestors, &kevent.Kevent{PS: loop})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reused.exe"}, ancestors)
+
+	parent, err := newPSAccessor(nil, 0).get(fields.PsParentName, &kevent.Kevent{PS: winword})
+	require.NoError(t, err)
+	assert.Nil(t, parent)
+}
+
 func TestPEAccessor(t *testing.T) {
 	pea := newPEAccessor()
 	kevt := &kevent.Kevent{
@@ -98,3 +165,21 @@ func TestCaptureInBrackets(t *testing.T) {
 	assert.Equal(t, ".debug$S", v)
 	assert.Equal(t, fields.SectionEntropy, subfield)
 }
+
+// constAccessor resolves every field to the same value.
+type constAccessor struct {
+	v kparams.Value
+}
+
+func (a constAccessor) get(f fields.Field, kevt *kevent.Kevent) (kparams.Value, error) {
+	return a.v, nil
+}
+
+func TestGetValueAccessorPrecedence(t *testing.T) {
+	kevt := &kevent.Kevent{}
+	accessors := []accessor{constAccessor{"kevt"}, constAccessor{"ps"}, constAccessor{nil}}
+
+	// the last accessor that resolves the field wins
+	assert.Equal(t, "ps", getValue(accessors, fields.PsName, kevt))
+	assert.Nil(t, getValue([]accessor{constAccessor{nil}}, fields.PsName, kevt))
+}
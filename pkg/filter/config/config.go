@@ -0,0 +1,154 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/rabbitstack/fibratus/pkg/ioc"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"time"
+)
+
+const (
+	ancestorsDepth    = "filters.ancestors-depth"
+	fuzzyDistance     = "filters.fuzzy-distance"
+	iocReloadInterval = "filters.ioc-reload-interval"
+)
+
+// Macro is the named filter expression that is expanded wherever its name appears in other filters.
+type Macro struct {
+	// Name is the identifier by which the macro is referenced.
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
+	// Expr is the filter expression the macro expands to.
+	Expr string `json:"expr" yaml:"expr" mapstructure:"expr"`
+}
+
+// List is the named list of values that is referenced in filters by the $ prefixed name.
+type List struct {
+	// Name is the identifier by which the list is referenced.
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
+	// Items contains the list values.
+	Items []string `json:"items" yaml:"items" mapstructure:"items"`
+}
+
+// Config stores the settings that influence the evaluation of filter expressions.
+type Config struct {
+	// AncestorsDepth determines the maximum number of ancestors yielded by the ps.ancestors field.
+	AncestorsDepth int `json:"filters.ancestors-depth" yaml:"filters.ancestors-depth"`
+	// FuzzyDistance is the maximum edit distance between strings matched by the fuzzy operator.
+	FuzzyDistance int `json:"filters.fuzzy-distance" yaml:"filters.fuzzy-distance"`
+	// Macros contains the macro definitions.
+	Macros []Macro `json:"filters.macros" yaml:"filters.macros" mapstructure:"macros"`
+	// Lists contains the list definitions.
+	Lists []List `json:"filters.lists" yaml:"filters.lists" mapstructure:"lists"`
+	// IOCs contains the definitions of indicator of compromise lists.
+	IOCs []ioc.Definition `json:"filters.iocs" yaml:"filters.iocs" mapstructure:"iocs"`
+	// IOCReloadInterval specifies how often the files of indicator of compromise lists are checked for changes.
+	IOCReloadInterval time.Duration `json:"filters.ioc-reload-interval" yaml:"filters.ioc-reload-interval"`
+
+	// iocLists contains the opened indicator of compromise lists keyed by name
+	iocLists map[string]*ioc.List
+}
+
+// InitFromViper initializes filters config from Viper. It returns an error if
+// macros, lists or IOC definitions can't be decoded.
+func (c *Config) InitFromViper(v *viper.Viper) error {
+	c.AncestorsDepth = v.GetInt(ancestorsDepth)
+	c.FuzzyDistance = v.GetInt(fuzzyDistance)
+	c.IOCReloadInterval = v.GetDuration(iocReloadInterval)
+
+	filters, ok := v.AllSettings()["filters"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	var (
+		macros []Macro
+		lists  []List
+		iocs   []ioc.Definition
+	)
+	if err := decode(filters["macros"], &macros); err != nil {
+		return fmt.Errorf("couldn't decode filters.macros: %v", err)
+	}
+	if err := decode(filters["lists"], &lists); err != nil {
+		return fmt.Errorf("couldn't decode filters.lists: %v", err)
+	}
+	if err := decode(filters["iocs"], &iocs); err != nil {
+		return fmt.Errorf("couldn't decode filters.iocs: %v", err)
+	}
+	c.Macros = macros
+	c.Lists = lists
+	c.IOCs = iocs
+	return nil
+}
+
+// OpenIOCs loads the indicator of compromise lists, so filters can reference them by name.
+// Lists are shared by all filters and are reloaded when their files change until they are
+// closed. If any of the lists can't be loaded, the lists opened so far are closed.
+func (c *Config) OpenIOCs() error {
+	lists := make(map[string]*ioc.List, len(c.IOCs))
+	for _, def := range c.IOCs {
+		l, err := ioc.Open(def, c.IOCReloadInterval)
+		if err != nil {
+			for _, l := range lists {
+				l.Close()
+			}
+			return err
+		}
+		lists[def.Name] = l
+	}
+	c.CloseIOCs()
+	c.iocLists = lists
+	return nil
+}
+
+// IOCLists returns the opened indicator of compromise lists keyed by name.
+func (c *Config) IOCLists() map[string]*ioc.List { return c.iocLists }
+
+// CloseIOCs stops reloading the indicator of compromise lists.
+func (c *Config) CloseIOCs() {
+	for _, l := range c.iocLists {
+		l.Close()
+	}
+	c.iocLists = nil
+}
+
+// AddFlags registers persistent flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.Int(ancestorsDepth, 10, "Specifies the maximum number of ancestors visited when resolving the ps.ancestors field")
+	flags.Int(fuzzyDistance, 2, "Specifies the maximum number of inserted, deleted or substituted characters between strings matched by the fuzzy operator")
+	flags.Duration(iocReloadInterval, time.Minute, "Specifies how often the files of indicator of compromise lists are checked for changes. Zero disables the reloading")
+}
+
+func decode(input, output interface{}) error {
+	var decoderConfig = &mapstructure.DecoderConfig{
+		Metadata:         nil,
+		Result:           output,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	}
+	decoder, err := mapstructure.NewDecoder(decoderConfig)
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(input)
+}
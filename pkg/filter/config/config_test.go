@@ -0,0 +1,62 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/ioc"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestInitFromViper(t *testing.T) {
+	v := viper.New()
+	v.Set("filters", map[string]interface{}{
+		"macros": []interface{}{map[string]interface{}{"name": "spawn_shell", "expr": "ps.name = 'cmd.exe'"}},
+		"lists":  []interface{}{map[string]interface{}{"name": "shells", "items": []interface{}{"cmd.exe", "powershell.exe"}}},
+	})
+	var c Config
+	require.NoError(t, c.InitFromViper(v))
+	assert.Equal(t, []Macro{{Name: "spawn_shell", Expr: "ps.name = 'cmd.exe'"}}, c.Macros)
+	assert.Equal(t, []List{{Name: "shells", Items: []string{"cmd.exe", "powershell.exe"}}}, c.Lists)
+
+	v.Set("filters", map[string]interface{}{
+		"lists": []interface{}{map[string]interface{}{"name": "shells", "items": map[string]interface{}{"cmd": "cmd.exe"}}},
+	})
+	err := c.InitFromViper(v)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "couldn't decode filters.lists")
+}
+
+func TestOpenIOCs(t *testing.T) {
+	c := Config{IOCs: []ioc.Definition{
+		{Name: "c2_ips", Type: "ip", Path: "../../ioc/_fixtures/c2_ips.txt"},
+		{Name: "samples", Type: "hash", Path: "../../ioc/_fixtures/hashes.csv", Column: "sha256"},
+	}}
+	require.NoError(t, c.OpenIOCs())
+	require.Len(t, c.IOCLists(), 2)
+	assert.Equal(t, 3, c.IOCLists()["c2_ips"].Len())
+	c.CloseIOCs()
+	assert.Empty(t, c.IOCLists())
+
+	c.IOCs = append(c.IOCs, ioc.Definition{Name: "bad_paths", Type: "path", Path: "../../ioc/_fixtures/missing.txt"})
+	require.Error(t, c.OpenIOCs())
+	assert.Empty(t, c.IOCLists())
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/ps"
+	"strings"
+)
+
+// FieldInfo describes the field referenced in the filter expression.
+type FieldInfo struct {
+	Field fields.Field
+	// Accessor is the name of the accessor that resolves the field value
+	Accessor string
+	// Enabled indicates whether the accessor is enabled in the config. Disabled accessors
+	// never resolve the field value, so the predicates referencing the field never match.
+	Enabled bool
+}
+
+// Explanation is the result of evaluating the filter against the event.
+type Explanation struct {
+	Matches bool
+	// Mismatches contains the predicates that prevented the filter from matching the event
+	Mismatches []ql.Expr
+	// Values contains the resolved values of the fields referenced in the filter
+	Values map[fields.Field]interface{}
+}
+
+// Explainer helps to diagnose filter expressions. It exposes the expression tree and the
+// fields involved in the filter, and explains why the filter doesn't match the given event.
+type Explainer struct {
+	f       *filter
+	enabled []accessor
+}
+
+// NewExplainer parses and validates the filter expression. The expression is evaluated
+// with all the accessors, regardless of which of them are enabled in the config.
+func NewExplainer(expr string, psnap ps.Snapshotter, config *config.Config) (*Explainer, error) {
+	f := &filter{
+		parser:    ql.NewParserWithConfig(expr, &config.Filters),
+		accessors: getAccessors(psnap, config),
+	}
+	if err := f.Compile(); err != nil {
+		return nil, err
+	}
+	return &Explainer{f: f, enabled: newAccessors(psnap, config)}, nil
+}
+
+// Normalized returns the expression with the operator precedence made explicit.
+func (e *Explainer) Normalized() string { return ql.Normalize(e.f.expr) }
+
+// Tree returns the tree of logical operators of the expression.
+func (e *Explainer) Tree() string { return ql.Tree(e.f.expr) }
+
+// Fields returns the fields referenced in the expression along with their accessors.
+func (e *Explainer) Fields() []FieldInfo {
+	infos := make([]FieldInfo, 0, len(e.f.fields)+len(e.f.multiValued))
+	for _, field := range append(append([]fields.Field{}, e.f.fields...), e.f.multiValued...) {
+		name := accessorName(field)
+		info := FieldInfo{Field: field, Accessor: name}
+		for _, accessor := range e.enabled {
+			if nameOf(accessor) == name {
+				info.Enabled = true
+				break
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Explain evaluates the filter against the event and reports the predicates that
+// evaluated to false along with the values of all fields referenced in the filter.
+func (e *Explainer) Explain(kevt *kevent.Kevent) *Explanation {
+	m := make(ql.MapValuer)
+	values := make(map[fields.Field]interface{})
+	for _, field := range e.f.fields {
+		v := getValue(e.f.accessors, field, kevt)
+		m[field.String()], values[field] = v, v
+	}
+	for _, field := range e.f.multiValued {
+		elems := getElements(e.f.accessors, field, kevt)
+		m[field.String()], values[field] = elems, elems
+	}
+	mismatches := ql.Mismatches(e.f.expr, m)
+	return &Explanation{Matches: len(mismatches) == 0, Mismatches: mismatches, Values: values}
+}
+
+// accessorName returns the name of the accessor that resolves the field. Accessors
+// are named after the prefix of the fields they resolve.
+func accessorName(field fields.Field) string {
+	return strings.SplitN(field.String(), ".", 2)[0]
+}
+
+func nameOf(accessor accessor) string {
+	switch accessor.(type) {
+	case *kevtAccessor:
+		return "kevt"
+	case *psAccessor:
+		return "ps"
+	case *threadAccessor:
+		return "thread"
+	case *imageAccessor:
+		return "image"
+	case *fileAccessor:
+		return "file"
+	case *registryAccessor:
+		return "registry"
+	case *networkAccessor:
+		return "net"
+	case *handleAccessor:
+		return "handle"
+	case *peAccessor:
+		return "pe"
+	}
+	return ""
+}
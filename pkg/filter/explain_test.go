@@ -0,0 +1,64 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestExplainer(t *testing.T) {
+	e, err := NewExplainer(`kevt.name = 'CreateFile' and (file.name endswith '.exe' or ps.pid = 4)`, nil, &config.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, `(kevt.name = 'CreateFile') AND ((file.name ENDSWITH '.exe') OR (ps.pid = 4))`, e.Normalized())
+
+	assert.Equal(t, []FieldInfo{
+		{Field: "kevt.name", Accessor: "kevt", Enabled: true},
+		{Field: "file.name", Accessor: "file", Enabled: false},
+		{Field: "ps.pid", Accessor: "ps", Enabled: true},
+	}, e.Fields())
+
+	kevt := &kevent.Kevent{
+		Type: ktypes.CreateFile,
+		Name: "CreateFile",
+		PID:  1234,
+		Kparams: kevent.Kparams{
+			kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "C:\\Windows\\system32\\kernel32.dll"},
+		},
+	}
+
+	expl := e.Explain(kevt)
+	require.False(t, expl.Matches)
+	require.Len(t, expl.Mismatches, 2)
+	assert.Equal(t, `file.name ENDSWITH '.exe'`, ql.Normalize(expl.Mismatches[0]))
+	assert.Equal(t, `ps.pid = 4`, ql.Normalize(expl.Mismatches[1]))
+	assert.Equal(t, "C:\\Windows\\system32\\kernel32.dll", expl.Values["file.name"])
+	assert.Equal(t, uint32(1234), expl.Values["ps.pid"])
+
+	kevt.Kparams[kparams.FileName].Value = "C:\\Windows\\system32\\cmd.exe"
+	expl = e.Explain(kevt)
+	require.True(t, expl.Matches)
+	require.Empty(t, expl.Mismatches)
+}
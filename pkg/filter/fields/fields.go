@@ -24,7 +24,7 @@ import (
 	"sort"
 )
 
-var subfieldRegexp = regexp.MustCompile(`(pe.sections|pe.resources|ps.envs|ps.modules)\[.+\s*].?(.*)`)
+var subfieldRegexp = regexp.MustCompile(`(pe.sections|pe.resources|ps.envs|ps.modules|kevt.arg)\[.+\s*].?(.*)`)
 
 // Field represents the type alias for the field
 type Field string
@@ -58,6 +58,18 @@ const (
 	PsDTB Field = "ps.dtb"
 	// PsModules represents the process modules
 	PsModules Field = "ps.modules"
+	// PsRuntime represents the time elapsed since the process was started
+	PsRuntime Field = "ps.runtime"
+	// PsParentName represents the parent process image name
+	PsParentName Field = "ps.parent.name"
+	// PsParentComm represents the parent process command line
+	PsParentComm Field = "ps.parent.comm"
+	// PsParentExe represents the parent process image path
+	PsParentExe Field = "ps.parent.exe"
+	// PsParentSID represents the parent process security identifier
+	PsParentSID Field = "ps.parent.sid"
+	// PsAncestors represents the image names of the process ancestors
+	PsAncestors Field = "ps.ancestors"
 
 	// ThreadBasePrio is the base thread priority
 	ThreadBasePrio Field = "thread.prio"
@@ -139,6 +151,8 @@ const (
 	KevtMeta Field = "kevt.meta"
 	// KevtNparams is the number of event parameters
 	KevtNparams Field = "kevt.nparams"
+	// KevtArg represents the event parameters
+	KevtArg Field = "kevt.arg"
 
 	// HandleID represents the handle identifier within the process address space
 	HandleID Field = "handle.id"
@@ -235,8 +249,54 @@ const (
 	ModuleBaseAddress Subfield = "address.base"
 	// ModuleDefaultAddress is the module address
 	ModuleDefaultAddress Subfield = "address.default"
+
+	// ModuleName is the module file name
+	ModuleName Subfield = "name"
+	// SectionName is the section name
+	SectionName Subfield = "name"
+	// HandleNum is the handle identifier
+	HandleNum Subfield = "id"
+	// HandleObjectAddress is the address of the kernel object referenced by the handle
+	HandleObjectAddress Subfield = "object"
+	// HandleObjectName is the name of the object referenced by the handle
+	HandleObjectName Subfield = "name"
+	// HandleObjectType is the type of the object referenced by the handle
+	HandleObjectType Subfield = "type"
+	// ResourceName is the name of the version resource
+	ResourceName Subfield = "name"
+	// ResourceValue is the value of the version resource
+	ResourceValue Subfield = "value"
 )
 
+// elements contains the fields of the elements that make up multi-valued fields. These
+// fields are accessed through the variable bound by the any/all quantifiers.
+var elements = map[Field]map[Subfield]kparams.Type{
+	PsModules: {
+		ModuleName:           kparams.UnicodeString,
+		ModuleLocation:       kparams.UnicodeString,
+		ModuleSize:           kparams.Uint32,
+		ModuleChecksum:       kparams.Uint32,
+		ModuleBaseAddress:    kparams.HexInt64,
+		ModuleDefaultAddress: kparams.HexInt64,
+	},
+	PsHandles: {
+		HandleNum:           kparams.Uint64,
+		HandleObjectAddress: kparams.HexInt64,
+		HandleObjectName:    kparams.UnicodeString,
+		HandleObjectType:    kparams.AnsiString,
+	},
+	PeSections: {
+		SectionName:    kparams.AnsiString,
+		SectionSize:    kparams.Uint32,
+		SectionEntropy: kparams.Double,
+		SectionMD5Hash: kparams.AnsiString,
+	},
+	PeResources: {
+		ResourceName:  kparams.UnicodeString,
+		ResourceValue: kparams.UnicodeString,
+	},
+}
+
 const (
 	// PsEnvsSubfield is the process environment variable property indexer
 	PsEnvsSubfield = "ps.envs["
@@ -246,6 +306,8 @@ const (
 	PeSectionsSubfield = "pe.sections["
 	// PeResourcesSubfield is the PE resource property indexer
 	PeResourcesSubfield = "pe.resources["
+	// KevtArgSubfield is the event parameter indexer
+	KevtArgSubfield = "kevt.arg["
 )
 
 // FieldInfo is the field metadata descriptor.
@@ -265,19 +327,20 @@ var fields = map[Field]FieldInfo{
 	KevtCategory:    {KevtCategory, "event category", kparams.AnsiString, []string{"kevt.category = 'registry'"}},
 	KevtDesc:        {KevtDesc, "event description", kparams.AnsiString, []string{"kevt.desc contains 'Creates a new process'"}},
 	KevtHost:        {KevtHost, "host name on which the event was produced", kparams.UnicodeString, []string{"kevt.host contains 'kitty'"}},
-	KevtTime:        {KevtTime, "event timestamp as a time string", kparams.Time, []string{"kevt.time = '17:05:32'"}},
-	KevtTimeHour:    {KevtTimeHour, "hour within the day on which the event occurred", kparams.Time, []string{"kevt.time.h = 23"}},
-	KevtTimeMin:     {KevtTimeMin, "minute offset within the hour on which the event occurred", kparams.Time, []string{"kevt.time.m = 54"}},
-	KevtTimeSec:     {KevtTimeSec, "second offset within the minute  on which the event occurred", kparams.Time, []string{"kevt.time.s = 0"}},
+	KevtTime:        {KevtTime, "event timestamp", kparams.Time, []string{"kevt.time = '17:05:32'", "kevt.time > now() - 10m"}},
+	KevtTimeHour:    {KevtTimeHour, "hour within the day on which the event occurred", kparams.Uint8, []string{"kevt.time.h = 23"}},
+	KevtTimeMin:     {KevtTimeMin, "minute offset within the hour on which the event occurred", kparams.Uint8, []string{"kevt.time.m = 54"}},
+	KevtTimeSec:     {KevtTimeSec, "second offset within the minute  on which the event occurred", kparams.Uint8, []string{"kevt.time.s = 0"}},
 	KevtTimeNs:      {KevtTimeNs, "nanoseconds specified by event timestamp", kparams.Int64, []string{"kevt.time.ns > 1591191629102337000"}},
-	KevtDate:        {KevtDate, "event timestamp as a date string", kparams.Time, []string{"kevt.date = '2018-03-03'"}},
-	KevtDateDay:     {KevtDateDay, "day of the month on which the event occurred", kparams.Time, []string{"kevt.date.d = 12"}},
-	KevtDateMonth:   {KevtDateMonth, "month of the year on which the event occurred", kparams.Time, []string{"kevt.date.m = 11"}},
+	KevtDate:        {KevtDate, "event timestamp as a date string", kparams.AnsiString, []string{"kevt.date = '2018-03-03'"}},
+	KevtDateDay:     {KevtDateDay, "day of the month on which the event occurred", kparams.Uint8, []string{"kevt.date.d = 12"}},
+	KevtDateMonth:   {KevtDateMonth, "month of the year on which the event occurred", kparams.Uint8, []string{"kevt.date.m = 11"}},
 	KevtDateYear:    {KevtDateYear, "year on which the event occurred", kparams.Uint32, []string{"kevt.date.y = 2020"}},
 	KevtDateTz:      {KevtDateTz, "time zone associated with the event timestamp", kparams.AnsiString, []string{"kevt.date.tz = 'UTC'"}},
 	KevtDateWeek:    {KevtDateWeek, "week number within the year on which the event occurred", kparams.Uint8, []string{"kevt.date.week = 2"}},
 	KevtDateWeekday: {KevtDateWeekday, "week day on which the event occurred", kparams.AnsiString, []string{"kevt.date.weekday = 'Monday'"}},
 	KevtNparams:     {KevtNparams, "number of parameters", kparams.Int8, []string{"kevt.nparams > 2"}},
+	KevtArg:         {KevtArg, "event parameter names or the value of the parameter given in brackets", kparams.Slice, []string{"kevt.arg in ('exit_status')", "kevt.arg[exit_status] != 0", "kevt.arg[irp] = 'ffffb88c4b1a6010'"}},
 
 	PsPid:         {PsPid, "process identifier", kparams.PID, []string{"ps.pid = 1024"}},
 	PsPpid:        {PsPpid, "parent process identifier", kparams.PID, []string{"ps.ppid = 45"}},
@@ -289,10 +352,16 @@ var fields = map[Field]FieldInfo{
 	PsSID:         {PsSID, "security identifier under which this process is run", kparams.UnicodeString, []string{"ps.sid contains 'SYSTEM'"}},
 	PsSessionID:   {PsSessionID, "unique identifier for the current session", kparams.Int16, []string{"ps.sessionid = 1"}},
 	PsEnvs:        {PsEnvs, "process environment variables", kparams.Slice, []string{"ps.envs in ('MOZ_CRASHREPORTER_DATA_DIRECTORY')"}},
-	PsHandles:     {PsHandles, "allocated process handle names", kparams.Slice, []string{"ps.handles in ('\\BaseNamedObjects\\__ComCatalogCache__')"}},
+	PsHandles:     {PsHandles, "allocated process handle names", kparams.Slice, []string{"ps.handles in ('\\BaseNamedObjects\\__ComCatalogCache__')", "any(ps.handles, h, h.type = 'Mutant' and h.name endswith 'WMI')"}},
 	PsHandleTypes: {PsHandleTypes, "allocated process handle types", kparams.Slice, []string{"ps.handle.types in ('Key', 'Mutant', 'Section')"}},
 	PsDTB:         {PsDTB, "process directory table base address", kparams.HexInt64, []string{"ps.dtb = '7ffe0000'"}},
-	PsModules:     {PsModules, "modules loaded by the process", kparams.Slice, []string{"ps.modules in ('crypt32.dll', 'xul.dll')"}},
+	PsModules:     {PsModules, "modules loaded by the process", kparams.Slice, []string{"ps.modules in ('crypt32.dll', 'xul.dll')", "any(ps.modules, m, m.name endswith 'clr.dll')"}},
+	PsRuntime:     {PsRuntime, "time elapsed since the process was started", kparams.Duration, []string{"ps.runtime < 2s"}},
+	PsParentName:  {PsParentName, "parent process image name including the file extension", kparams.UnicodeString, []string{"ps.parent.name = 'winword.exe'"}},
+	PsParentComm:  {PsParentComm, "parent process command line", kparams.UnicodeString, []string{"ps.parent.comm contains '/Embedding'"}},
+	PsParentExe:   {PsParentExe, "full name of the parent process' executable", kparams.UnicodeString, []string{"ps.parent.exe = 'C:\\Program Files\\Microsoft Office\\root\\Office16\\WINWORD.EXE'"}},
+	PsParentSID:   {PsParentSID, "security identifier under which the parent process is run", kparams.UnicodeString, []string{"ps.parent.sid contains 'SYSTEM'"}},
+	PsAncestors:   {PsAncestors, "image names of the process ancestors starting from the parent", kparams.Slice, []string{"ps.ancestors in ('winword.exe', 'excel.exe')"}},
 
 	ThreadBasePrio:    {ThreadBasePrio, "scheduler priority of the thread", kparams.Int8, []string{"thread.prio = 5"}},
 	ThreadIOPrio:      {ThreadIOPrio, "I/O priority hint for scheduling I/O operations", kparams.Int8, []string{"thread.io.prio = 4"}},
@@ -320,7 +389,7 @@ var fields = map[Field]FieldInfo{
 
 	RegistryKeyName:   {RegistryKeyName, "fully qualified key name", kparams.UnicodeString, []string{"registry.key.name contains 'HKEY_LOCAL_MACHINE'"}},
 	RegistryKeyHandle: {RegistryKeyHandle, "registry key object address", kparams.HexInt64, []string{"registry.key.handle = 'FFFFB905D60C2268'"}},
-	RegistryValue:     {RegistryValue, "registry value content", kparams.UnicodeString, []string{"registry.value = '%SystemRoot%\\system32'"}},
+	RegistryValue:     {RegistryValue, "registry value content", kparams.Unknown, []string{"registry.value = '%SystemRoot%\\system32'"}},
 	RegistryValueType: {RegistryValueType, "type of registry value", kparams.UnicodeString, []string{"registry.value.type = 'REG_SZ'"}},
 	RegistryStatus:    {RegistryStatus, "status of registry operation", kparams.UnicodeString, []string{"registry.status != 'success'"}},
 
@@ -342,10 +411,10 @@ var fields = map[Field]FieldInfo{
 	PeNumSymbols:  {PeNumSymbols, "number of entries in the symbol table", kparams.Uint32, []string{"pe.nsymbols > 230"}},
 	PeBaseAddress: {PeBaseAddress, "image base address", kparams.HexInt64, []string{"pe.address.base = '140000000'"}},
 	PeEntrypoint:  {PeEntrypoint, "address of the entrypoint function", kparams.HexInt64, []string{"pe.address.entrypoint = '20110'"}},
-	PeSections:    {PeSections, "PE sections", kparams.Object, []string{"pe.sections[.text].entropy > 6.2"}},
+	PeSections:    {PeSections, "PE sections", kparams.Object, []string{"pe.sections[.text].entropy > 6.2", "all(pe.sections, s, s.entropy < 7)"}},
 	PeSymbols:     {PeSymbols, "imported symbols", kparams.Slice, []string{"pe.symbols in ('GetTextFaceW', 'GetProcessHeap')"}},
 	PeImports:     {PeImports, "imported dynamic linked libraries", kparams.Slice, []string{"pe.imports in ('msvcrt.dll', 'GDI32.dll'"}},
-	PeResources:   {PeResources, "version and other resources", kparams.Map, []string{"pe.resources[FileDescription] = 'Notepad'"}},
+	PeResources:   {PeResources, "version and other resources", kparams.Map, []string{"pe.resources[FileDescription] = 'Notepad'", "any(pe.resources, r, r.value icontains 'mimikatz')"}},
 }
 
 // Get returns a slice of field information.
@@ -358,6 +427,67 @@ func Get() []FieldInfo {
 	return fi
 }
 
+// Type returns the type of the value the field evaluates to. The type of
+// nested fields is resolved from the subfield. Unknown is returned if the
+// field doesn't exist or its value type can only be determined at runtime.
+func (f Field) Type() kparams.Type {
+	if fi, ok := fields[f]; ok {
+		return fi.Type
+	}
+	groups := subfieldRegexp.FindStringSubmatch(string(f))
+	if len(groups) != 3 {
+		return kparams.Unknown
+	}
+	switch Field(groups[1]) {
+	case PeSections:
+		switch Subfield(groups[2]) {
+		case SectionEntropy:
+			return kparams.Double
+		case SectionMD5Hash:
+			return kparams.AnsiString
+		case SectionSize:
+			return kparams.Uint32
+		}
+	case PeResources, PsEnvs:
+		return kparams.UnicodeString
+	case PsModules:
+		switch Subfield(groups[2]) {
+		case ModuleSize, ModuleChecksum:
+			return kparams.Uint32
+		case ModuleLocation:
+			return kparams.UnicodeString
+		case ModuleBaseAddress, ModuleDefaultAddress:
+			return kparams.HexInt64
+		}
+	}
+	return kparams.Unknown
+}
+
+// IsMultiValued determines whether the field consists of elements that can be iterated by quantifiers.
+func (f Field) IsMultiValued() bool {
+	_, ok := elements[f]
+	return ok
+}
+
+// ElementType returns the type of the element field for multi-valued fields. Unknown is returned
+// if the field is not multi-valued or its elements don't have the given field.
+func (f Field) ElementType(name Subfield) kparams.Type {
+	if typ, ok := elements[f][name]; ok {
+		return typ
+	}
+	return kparams.Unknown
+}
+
+// MultiValued returns all fields that can be iterated by quantifiers sorted by name.
+func MultiValued() []Field {
+	fields := make([]Field, 0, len(elements))
+	for f := range elements {
+		fields = append(fields, f)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i] < fields[j] })
+	return fields
+}
+
 // Lookup finds the field literal in the map. For the nested fields, it checks the pattern matches
 // the expected one and compares the subfields. If all checks pass, the full nested field literal
 // is returned.
@@ -387,6 +517,8 @@ func Lookup(name string) Field {
 		return Field(name)
 	case PsEnvs:
 		return Field(name)
+	case KevtArg:
+		return Field(name)
 	case PsModules:
 		switch Subfield(subfield) {
 		case ModuleSize:
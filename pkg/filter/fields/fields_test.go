@@ -19,6 +19,7 @@
 package fields
 
 import (
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -35,4 +36,30 @@ func TestLookup(t *testing.T) {
 	assert.Empty(t, Lookup("ps.pe.sections[.debug$S]"))
 	assert.Empty(t, Lookup("ps.pe.sections[.debug$S]."))
 	assert.Empty(t, Lookup("ps.pe.sections[.debug$S].e"))
+	assert.Equal(t, Field("kevt.arg[exit_status]"), Lookup("kevt.arg[exit_status]"))
+	assert.Equal(t, KevtArg, Lookup("kevt.arg"))
+	assert.Empty(t, Lookup("kevt.arg[]"))
+}
+
+func TestType(t *testing.T) {
+	assert.Equal(t, kparams.UnicodeString, PsName.Type())
+	assert.Equal(t, kparams.Uint16, NetDport.Type())
+	assert.Equal(t, kparams.Double, Field("pe.sections[.text].entropy").Type())
+	assert.Equal(t, kparams.UnicodeString, Field("pe.resources[FileDescription]").Type())
+	assert.Equal(t, kparams.Uint32, Field("ps.modules[kernel32.dll].size").Type())
+	assert.Equal(t, kparams.HexInt64, Field("ps.modules[kernel32.dll].address.base").Type())
+	assert.Equal(t, kparams.Unknown, Field("ps.nmae").Type())
+	assert.Equal(t, kparams.Unknown, Field("kevt.arg[exit_status]").Type())
+}
+
+func TestElementType(t *testing.T) {
+	assert.True(t, PsModules.IsMultiValued())
+	assert.False(t, PsName.IsMultiValued())
+	assert.Equal(t, []Field{PeResources, PeSections, PsHandles, PsModules}, MultiValued())
+	assert.Equal(t, kparams.UnicodeString, PsModules.ElementType(ModuleName))
+	assert.Equal(t, kparams.HexInt64, PsModules.ElementType(ModuleBaseAddress))
+	assert.Equal(t, kparams.Double, PeSections.ElementType(SectionEntropy))
+	assert.Equal(t, kparams.AnsiString, PsHandles.ElementType(HandleObjectType))
+	assert.Equal(t, kparams.Unknown, PsModules.ElementType("entropy"))
+	assert.Equal(t, kparams.Unknown, PsName.ElementType(ModuleName))
 }
@@ -27,7 +27,10 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/filter/fields"
 	"github.com/rabbitstack/fibratus/pkg/filter/ql"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/ps"
 	"strings"
+	"sync"
 )
 
 var (
@@ -45,59 +48,32 @@ type Filter interface {
 
 type filter struct {
 	expr      ql.Expr
+	prog      *ql.Program
 	parser    *ql.Parser
 	accessors []accessor
 	fields    []fields.Field
+	// multiValued contains the fields iterated by quantifiers
+	multiValued []fields.Field
+	valuers     sync.Pool
 }
 
 // New creates a new filter with the specified filter expression. The consumers must ensure
 // the expression is correctly parsed before executing the filter. This is achieved by calling the
 // Compile` method after constructing the filter.
-func New(expr string, config *config.Config) Filter {
-	accessors := []accessor{
-		// general event parameters
-		newKevtAccessor(),
-		// process state and parameters
-		newPSAccessor(),
-	}
-	kconfig := config.Kstream
-
-	if kconfig.EnableThreadKevents {
-		accessors = append(accessors, newThreadAccessor())
-	}
-	if kconfig.EnableImageKevents {
-		accessors = append(accessors, newImageAccessor())
-	}
-	if kconfig.EnableFileIOKevents {
-		accessors = append(accessors, newFileAccessor())
-	}
-	if kconfig.EnableRegistryKevents {
-		accessors = append(accessors, newRegistryAccessor())
-	}
-	if kconfig.EnableNetKevents {
-		accessors = append(accessors, newNetworkAccessor())
-	}
-	if kconfig.EnableHandleKevents {
-		accessors = append(accessors, newHandleAccessor())
-	}
-	if config.PE.Enabled {
-		accessors = append(accessors, newPEAccessor())
-	}
-
+func New(expr string, psnap ps.Snapshotter, config *config.Config) Filter {
 	return &filter{
-		parser:    ql.NewParser(expr),
-		accessors: accessors,
-		fields:    make([]fields.Field, 0),
+		parser:    ql.NewParserWithConfig(expr, &config.Filters),
+		accessors: newAccessors(psnap, config),
 	}
 }
 
 // NewFromCLI builds and compiles a filter by joining all the command line arguments into the filter expression.
-func NewFromCLI(args []string, config *config.Config) (Filter, error) {
+func NewFromCLI(args []string, psnap ps.Snapshotter, config *config.Config) (Filter, error) {
 	expr := strings.Join(args, " ")
 	if expr == "" {
 		return nil, nil
 	}
-	filter := New(expr, config)
+	filter := New(expr, psnap, config)
 	if err := filter.Compile(); err != nil {
 		return nil, fmt.Errorf("bad filter: \n  %v", err)
 	}
@@ -105,15 +81,14 @@ func NewFromCLI(args []string, config *config.Config) (Filter, error) {
 }
 
 // NewFromCLIWithAllAccessors builds and compiles a filter with all field accessors enabled.
-func NewFromCLIWithAllAccessors(args []string) (Filter, error) {
+func NewFromCLIWithAllAccessors(args []string, psnap ps.Snapshotter, config *config.Config) (Filter, error) {
 	expr := strings.Join(args, " ")
 	if expr == "" {
 		return nil, nil
 	}
 	filter := &filter{
-		parser:    ql.NewParser(expr),
-		accessors: getAccessors(),
-		fields:    make([]fields.Field, 0),
+		parser:    ql.NewParserWithConfig(expr, &config.Filters),
+		accessors: getAccessors(psnap, config),
 	}
 	if err := filter.Compile(); err != nil {
 		return nil, fmt.Errorf("bad filter: \n  %v", err)
@@ -123,53 +98,148 @@ func NewFromCLIWithAllAccessors(args []string) (Filter, error) {
 
 // Compile parsers the filter expression and builds a binary expression tree
 // where leaf nodes represent constants/variables while internal nodes are
-// operators. Operators can be binary (=) or unary (not). Fields in filter
-// expressions are replaced with respective event parameters via map valuer.
-// Matching the filter involves descending the binary expression tree recursively
-// until all nodes are visited.
+// operators. Operators can be binary (=) or unary (not). Once the tree is built,
+// the semantic validation ensures operators are applied to operands of compatible
+// types, so filters that would never match fail early. Finally, the tree is compiled
+// into closures that fetch field values lazily, only when the evaluation reaches the
+// node that references the field.
 func (f *filter) Compile() error {
 	var err error
 	f.expr, err = f.parser.ParseExpr()
 	if err != nil {
 		return err
 	}
+	var nfields int
 	ql.WalkFunc(f.expr, func(n ql.Node) {
-		if expr, ok := n.(*ql.BinaryExpr); ok {
-			if lhs, ok := expr.LHS.(*ql.FieldLiteral); ok {
-				f.fields = append(f.fields, fields.Field(lhs.Value))
+		switch expr := n.(type) {
+		case *ql.BinaryExpr:
+			if _, ok := expr.LHS.(*ql.FieldLiteral); ok {
+				nfields++
+			}
+			if _, ok := expr.RHS.(*ql.FieldLiteral); ok {
+				nfields++
 			}
-			if rhs, ok := expr.RHS.(*ql.FieldLiteral); ok {
-				f.fields = append(f.fields, fields.Field(rhs.Value))
+		case *ql.Quantifier:
+			nfields++
+		case *ql.Function:
+			// fields given as function arguments
+			for _, arg := range expr.Args {
+				if _, ok := arg.(*ql.FieldLiteral); ok {
+					nfields++
+				}
 			}
 		}
 	})
-	if len(f.fields) == 0 {
+	if nfields == 0 {
 		return errNoFields
 	}
+	if err := f.parser.Validate(f.expr); err != nil {
+		return err
+	}
+	f.prog = ql.Compile(f.expr)
+	f.fields = make([]fields.Field, len(f.prog.Fields()))
+	for i, field := range f.prog.Fields() {
+		f.fields[i] = fields.Field(field)
+	}
+	f.multiValued = make([]fields.Field, len(f.prog.MultiValuedFields()))
+	for i, field := range f.prog.MultiValuedFields() {
+		f.multiValued[i] = fields.Field(field)
+	}
 	return nil
 }
 
 func (f *filter) Run(kevt *kevent.Kevent) bool {
-	if f.expr == nil {
+	if f.prog == nil {
 		return false
 	}
-	valuer := make(map[string]interface{})
-	// for each field present in the AST, we run the
-	// accessors and extract the field vales that are
-	// supplied to the valuer. The valuer feeds the
-	// expression with correct values.
-	for _, field := range f.fields {
-		for _, accessor := range f.accessors {
-			v, err := accessor.get(field, kevt)
-			if err != nil && !kerrors.IsKparamNotFound(err) {
-				accessorErrors.Add(err.Error(), 1)
-				continue
-			}
-			if v == nil {
-				continue
-			}
-			valuer[field.String()] = v
+	v, ok := f.valuers.Get().(*valuer)
+	if !ok {
+		v = &valuer{
+			values:        make([]kparams.Value, len(f.fields)),
+			resolved:      make([]bool, len(f.fields)),
+			elems:         make([][]ql.Valuer, len(f.multiValued)),
+			elemsResolved: make([]bool, len(f.multiValued)),
+		}
+	}
+	v.f, v.kevt = f, kevt
+	matches := f.prog.Run(v)
+	v.reset()
+	f.valuers.Put(v)
+	return matches
+}
+
+// valuer lazily resolves field values for the event being filtered. Each field is
+// fetched from the accessors at most once per event.
+type valuer struct {
+	f             *filter
+	kevt          *kevent.Kevent
+	values        []kparams.Value
+	resolved      []bool
+	elems         [][]ql.Valuer
+	elemsResolved []bool
+}
+
+func (v *valuer) ValueAt(i int) interface{} {
+	if !v.resolved[i] {
+		v.values[i] = getValue(v.f.accessors, v.f.fields[i], v.kevt)
+		v.resolved[i] = true
+	}
+	return v.values[i]
+}
+
+func (v *valuer) ElementsAt(i int) []ql.Valuer {
+	if !v.elemsResolved[i] {
+		v.elems[i] = getElements(v.f.accessors, v.f.multiValued[i], v.kevt)
+		v.elemsResolved[i] = true
+	}
+	return v.elems[i]
+}
+
+// reset drops the references to the event and its values before the valuer is reused.
+func (v *valuer) reset() {
+	v.f, v.kevt = nil, nil
+	for i := range v.values {
+		v.values[i] = nil
+		v.resolved[i] = false
+	}
+	for i := range v.elems {
+		v.elems[i] = nil
+		v.elemsResolved[i] = false
+	}
+}
+
+// getValue runs the accessors until one of them produces the value for the field. Accessors
+// are run in reverse order, so the value produced by the last accessor that resolves the field
+// takes precedence.
+func getValue(accessors []accessor, field fields.Field, kevt *kevent.Kevent) kparams.Value {
+	for i := len(accessors) - 1; i >= 0; i-- {
+		v, err := accessors[i].get(field, kevt)
+		if err != nil && !kerrors.IsKparamNotFound(err) {
+			accessorErrors.Add(err.Error(), 1)
+			continue
+		}
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// getElements asks the accessors for the elements of the multi-valued field.
+func getElements(accessors []accessor, field fields.Field, kevt *kevent.Kevent) []ql.Valuer {
+	for _, accessor := range accessors {
+		accessor, ok := accessor.(elementAccessor)
+		if !ok {
+			continue
+		}
+		elems, err := accessor.elements(field, kevt)
+		if err != nil {
+			accessorErrors.Add(err.Error(), 1)
+			continue
+		}
+		if elems != nil {
+			return elems
 		}
 	}
-	return ql.Eval(f.expr, valuer)
+	return nil
 }
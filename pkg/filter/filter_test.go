@@ -20,6 +20,9 @@ package filter
 
 import (
 	"github.com/rabbitstack/fibratus/pkg/config"
+	filters "github.com/rabbitstack/fibratus/pkg/filter/config"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
+	htypes "github.com/rabbitstack/fibratus/pkg/handle/types"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
 	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
@@ -41,17 +44,33 @@ var cfg = &config.Config{
 		EnableThreadKevents:   true,
 	},
 	PE: pe.Config{Enabled: true},
+	Filters: filters.Config{
+		Macros: []filters.Macro{
+			{Name: "spawn_svc", Expr: "kevt.name = 'CreateProcess' and ps.name in $services"},
+		},
+		Lists: []filters.List{
+			{Name: "services", Items: []string{"svchost.exe", "services.exe"}},
+		},
+	},
 }
 
 func TestFilterCompile(t *testing.T) {
-	f := New(`ps.name = 'cmd.exe'`, cfg)
+	f := New(`ps.name = 'cmd.exe'`, nil, cfg)
 	require.NoError(t, f.Compile())
-	f = New(`'cmd.exe'`, cfg)
+	f = New(`'cmd.exe'`, nil, cfg)
 	require.EqualError(t, f.Compile(), "expected at least one field or operator but zero found")
-	f = New(`ps.name`, cfg)
+	f = New(`ps.name`, nil, cfg)
 	require.EqualError(t, f.Compile(), "expected at least one field or operator but zero found")
-	f = New(`ps.name =`, cfg)
+	f = New(`ps.name =`, nil, cfg)
 	require.EqualError(t, f.Compile(), "ps.name =\n          ^ expected field, string, number, bool, ip")
+	f = New(`ps.nmae = 'cmd.exe'`, nil, cfg)
+	require.EqualError(t, f.Compile(), "ps.nmae = 'cmd.exe'\n ^ unknown field ps.nmae")
+	f = New(`net.dport = 'http'`, nil, cfg)
+	require.EqualError(t, f.Compile(), "net.dport = 'http'\n            ^ expected number but found string")
+	f = New(`spawn_svc and ps.pid > 4`, nil, cfg)
+	require.NoError(t, f.Compile())
+	f = New(`spawn_shell and ps.pid > 4`, nil, cfg)
+	require.EqualError(t, f.Compile(), "spawn_shell and ps.pid > 4\n ^ undefined macro spawn_shell")
 }
 
 func TestFilterRunProcessKevent(t *testing.T) {
@@ -68,14 +87,20 @@ func TestFilterRunProcessKevent(t *testing.T) {
 		PID:     1023,
 		PS: &pstypes.PS{
 			Ppid: 345,
+			Comm: "C:\\Windows\\system32\\svchost.exe -k RPCSS",
 			Envs: map[string]string{"ALLUSERSPROFILE": "C:\\ProgramData", "OS": "Windows_NT", "ProgramFiles(x86)": "C:\\Program Files (x86)"},
 			Modules: []pstypes.Module{
 				{Name: "C:\\Windows\\System32\\kernel32.dll", Size: 12354, Checksum: 23123343, BaseAddress: kparams.Hex("fff23fff"), DefaultBaseAddress: kparams.Hex("fff124fd")},
 				{Name: "C:\\Windows\\System32\\user32.dll", Size: 212354, Checksum: 33123343, BaseAddress: kparams.Hex("fef23fff"), DefaultBaseAddress: kparams.Hex("fff124fd")},
 			},
+			Handles: []htypes.Handle{
+				{Num: 0x24, Object: 0xffffb905dbf61988, Type: "Mutant", Name: "\\Sessions\\1\\BaseNamedObjects\\SM0:1880:304:WilStaging_02"},
+				{Num: 0x28, Object: 0xffffb905dbf61a70, Type: "Key", Name: "\\REGISTRY\\MACHINE\\SYSTEM\\ControlSet001\\Control\\Nls\\Sorting\\Versions"},
+			},
 		},
 	}
 	kevt.Timestamp, _ = time.Parse(time.RFC3339, "2011-05-03T15:04:05.323Z")
+	kevt.PS.StartTime = kevt.Timestamp.Add(-time.Second * 90)
 
 	var tests = []struct {
 		filter  string
@@ -96,10 +121,27 @@ func TestFilterRunProcessKevent(t *testing.T) {
 		{`ps.modules[kernel32.dll].location = 'C:\\Windows\\System32'`, true},
 		{`ps.modules[xul.dll].size = 12354`, false},
 		{`kevt.name = 'CreateProcess' and kevt.pid != ps.ppid`, true},
+		{`length(ps.comm) > 20`, true},
+		{`concat(ps.name, ':', kevt.name) = 'svchost.exe:CreateProcess'`, true},
+		{`ps.runtime > 1m and ps.runtime < 2m`, true},
+		{`ps.runtime < 2s`, false},
+		{`any(ps.modules, m, m.name endswith 'user32.dll')`, true},
+		{`any(ps.modules, m, m.name = 'clr.dll')`, false},
+		{`any(ps.modules, m, m.location = 'C:\\Windows\\System32' and m.size > 200000)`, true},
+		{`all(ps.modules, m, m.checksum > 20000000 and m.address.default = 'fff124fd')`, true},
+		{`all(ps.modules, m, m.name = 'kernel32.dll')`, false},
+		{`any(ps.handles, h, h.type = 'Mutant' and h.name icontains 'WilStaging')`, true},
+		{`any(ps.handles, h, h.id = 40 and h.object = 'ffffb905dbf61a70')`, true},
+		{`kevt.name = 'CreateProcess' and all(ps.handles, h, h.type in ('Key', 'File'))`, false},
+		{`ps.name iin ('SVCHOST.exe') and ps.name istartswith 'SVC' and ps.name iendswith '.EXE'`, true},
+		{`ps.comm regex '-k\\s+RPCSS$' and ps.comm iregex '^c:\\\\windows'`, true},
+		{`ps.name fuzzy 'svch0st.exe' and ps.name not fuzzy 'lsass.exe'`, true},
+		{`spawn_svc`, true},
+		{`spawn_svc and ps.name not in $services`, false},
 	}
 
 	for i, tt := range tests {
-		f := New(tt.filter, cfg)
+		f := New(tt.filter, nil, cfg)
 		err := f.Compile()
 		if err != nil {
 			t.Fatal(err)
@@ -137,7 +179,7 @@ func TestFilterRunThreadKevent(t *testing.T) {
 	}
 
 	for i, tt := range tests {
-		f := New(tt.filter, cfg)
+		f := New(tt.filter, nil, cfg)
 		err := f.Compile()
 		if err != nil {
 			t.Fatal(err)
@@ -189,10 +231,15 @@ func TestFilterRunFileKevent(t *testing.T) {
 		{`file.name contains ('C:\\Windows\\system32\\kernel32.dll', 'C:\\Windows\\system32\\user32.dll')`, true},
 		{`file.name not matches ('C:\\*.exe', 'C:\\Windows\\*.com')`, true},
 		{`file.name endswith ('.exe', 'kernel32.dll', 'user32.dll')`, true},
+		{`lower(file.name) = 'c:\\windows\\system32\\user32.dll'`, true},
+		{`base(file.name) in ('kernel32.dll', 'user32.dll')`, true},
+		{`dir(file.name) = 'C:\\Windows\\system32'`, true},
+		{`ext(file.name) = '.dll'`, true},
+		{`regex(file.name, 'user\\d+\\.dll$')`, true},
 	}
 
 	for i, tt := range tests {
-		f := New(tt.filter, cfg)
+		f := New(tt.filter, nil, cfg)
 		err := f.Compile()
 		if err != nil {
 			t.Fatal(err)
@@ -244,10 +291,50 @@ func TestFilterRunKevent(t *testing.T) {
 
 		{`kevt.date.d = 3 AND kevt.date.m = 5 AND kevt.time.s = 5 AND kevt.time.m = 4 and kevt.time.h = 15`, true},
 		{`kevt.time = '15:04:05'`, true},
+		{`kevt.time > now() - 10m`, false},
+		{`kevt.time < now() - 1h`, true},
+		{`kevt.time > '2011-05-03T15:00:00Z' and kevt.time < '2011-05-03T15:05:00Z'`, true},
 	}
 
 	for i, tt := range tests {
-		f := New(tt.filter, cfg)
+		f := New(tt.filter, nil, cfg)
+		err := f.Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		matches := f.Run(kevt)
+		if matches != tt.matches {
+			t.Errorf("%d. %q kevt filter mismatch: exp=%t got=%t", i, tt.filter, tt.matches, matches)
+		}
+	}
+}
+
+func TestFilterRunKeventArg(t *testing.T) {
+	kevt := &kevent.Kevent{
+		Type:     ktypes.CreateFile,
+		Name:     "CreateFile",
+		Category: ktypes.File,
+		Kparams: kevent.Kparams{
+			kparams.FileObject:    {Name: kparams.FileObject, Type: kparams.Uint64, Value: uint64(12456738026482168384)},
+			kparams.FileType:      {Name: kparams.FileType, Type: kparams.AnsiString, Value: "file"},
+			kparams.FileOperation: {Name: kparams.FileOperation, Type: kparams.AnsiString, Value: "open"},
+			kparams.FileIrpPtr:    {Name: kparams.FileIrpPtr, Type: kparams.HexInt64, Value: kparams.Hex("ffffb88c4b1a6010")},
+		},
+	}
+
+	var tests = []struct {
+		filter  string
+		matches bool
+	}{
+		{`kevt.arg[file_object] = 12456738026482168384`, true},
+		{`kevt.arg[irp] = 'ffffb88c4b1a6010'`, true},
+		{`kevt.arg[type] = 'file' and kevt.arg[operation] = 'open'`, true},
+		{`kevt.arg[exit_status] = 0`, false},
+		{`kevt.arg in ('irp')`, true},
+	}
+
+	for i, tt := range tests {
+		f := New(tt.filter, nil, cfg)
 		err := f.Compile()
 		if err != nil {
 			t.Fatal(err)
@@ -281,10 +368,14 @@ func TestFilterRunNetKevent(t *testing.T) {
 		{`net.dip != 216.58.201.174`, false},
 		{`net.dip != 116.58.201.174`, true},
 		{`net.dip not in ('116.58.201.172', '16.58.201.176')`, true},
+		{`net.dip = 216.58.0.0/16`, true},
+		{`net.dip not in private and net.sip in loopback`, true},
+		{`net.dip in (10.0.0.0/8, 216.58.201.174)`, true},
+		{`net.sip != 127.0.0.0/8`, false},
 	}
 
 	for i, tt := range tests {
-		f := New(tt.filter, cfg)
+		f := New(tt.filter, nil, cfg)
 		err := f.Compile()
 		if err != nil {
 			t.Fatal(err)
@@ -322,7 +413,7 @@ func TestFilterRunRegistryKevent(t *testing.T) {
 	}
 
 	for i, tt := range tests {
-		f := New(tt.filter, cfg)
+		f := New(tt.filter, nil, cfg)
 		err := f.Compile()
 		if err != nil {
 			t.Fatal(err)
@@ -367,10 +458,15 @@ func TestFilterRunPE(t *testing.T) {
 		{`pe.nsymbols = 10 AND pe.nsections = 2`, true},
 		{`pe.nsections > 1`, true},
 		{`pe.address.base = '140000000' AND pe.address.entrypoint = '20110'`, true},
+		{`all(pe.sections, s, s.entropy < 7 and s.size > 1024)`, true},
+		{`any(pe.sections, s, s.name = '.rdata' and s.md5 = 'ffa5c960b421ca9887e54966588e97e8')`, true},
+		{`any(pe.sections, s, s.name = '.reloc')`, false},
+		{`any(pe.resources, r, r.name = 'CompanyName' and r.value startswith 'Microsoft')`, true},
+		{`all(pe.resources, r, r.value = 'Notepad')`, false},
 	}
 
 	for i, tt := range tests {
-		f := New(tt.filter, cfg)
+		f := New(tt.filter, nil, cfg)
 		err := f.Compile()
 		if err != nil {
 			t.Fatal(err)
@@ -384,10 +480,6 @@ func TestFilterRunPE(t *testing.T) {
 }
 
 func BenchmarkFilterRun(b *testing.B) {
-	b.ReportAllocs()
-	f := New(`ps.name = 'mimikatz.exe' or ps.name contains 'svc'`, cfg)
-	require.NoError(b, f.Compile())
-
 	kpars := kevent.Kparams{
 		kparams.Comm:            {Name: kparams.Comm, Type: kparams.UnicodeString, Value: "C:\\Windows\\system32\\svchost.exe -k RPCSS"},
 		kparams.ProcessName:     {Name: kparams.ProcessName, Type: kparams.AnsiString, Value: "svchost.exe"},
@@ -399,9 +491,48 @@ func BenchmarkFilterRun(b *testing.B) {
 		Type:    ktypes.CreateProcess,
 		Kparams: kpars,
 		Name:    "CreateProcess",
+		PID:     1023,
+	}
+
+	var filters = []string{
+		`ps.name = 'mimikatz.exe' or ps.name contains 'svc'`,
+		`kevt.name = 'CreateFile' and ps.name in ('cmd.exe', 'powershell.exe') and ps.comm icontains 'invoke'`,
+		`ps.name in ('cmd.exe', 'powershell.exe', 'svchost.exe') and ps.pid > 1000 and kevt.pid != ps.ppid`,
+		`lower(ps.name) = 'svchost.exe' and ps.comm imatches '*rpcss'`,
 	}
 
-	for i := 0; i < b.N; i++ {
-		f.Run(kevt)
+	for _, expr := range filters {
+		f := New(expr, nil, cfg)
+		require.NoError(b, f.Compile())
+
+		b.Run("compiled/"+expr, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				f.Run(kevt)
+			}
+		})
+
+		b.Run("valuer/"+expr, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				runMapValuer(f.(*filter), kevt)
+			}
+		})
+	}
+}
+
+// runMapValuer evaluates the filter by extracting all fields into the map valuer and
+// walking the expression tree. It serves as the baseline for the compiled filter.
+func runMapValuer(f *filter, kevt *kevent.Kevent) bool {
+	valuer := make(map[string]interface{})
+	for _, field := range f.fields {
+		for _, accessor := range f.accessors {
+			v, err := accessor.get(field, kevt)
+			if err != nil || v == nil {
+				continue
+			}
+			valuer[field.String()] = v
+		}
 	}
+	return ql.Eval(f.expr, valuer)
 }
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/ps"
+	"sync"
+)
+
+// Multi evaluates a set of filters against the same event. Fields referenced by all
+// filters are unioned, and each field value is extracted at most once per event, no
+// matter how many filters reference the field. Filters are indexed by the event names
+// they can match, so the filters whose kevt.name or kevt.category conditions rule out
+// the event type are skipped without being evaluated. Multi is safe for concurrent use.
+//
+// The evaluator pays off when many filters are run against each event, as is the case with
+// rules and exceptions. Single expressions, such as the CLI or filament filters, are run
+// directly by the filter.
+type Multi struct {
+	filters   []*filter
+	accessors []accessor
+	// fields and multiValued contain the union of fields referenced by all filters
+	fields      []fields.Field
+	multiValued []fields.Field
+	// mappings translate the field indices of each filter program to the indices of unioned fields
+	mappings []mapping
+	// index contains the filters that can match the event with the given name
+	index   map[string][]int
+	all     []int
+	valuers sync.Pool
+}
+
+// mapping translates the field indices of the filter program to the indices of unioned fields.
+type mapping struct {
+	fields      []int
+	multiValued []int
+}
+
+// NewMulti creates the evaluator for the given filters. Filters must be created with the
+// New function and compiled before they are passed to the evaluator. Field values are
+// extracted by the accessors for the event types enabled in the config.
+func NewMulti(filters []Filter, psnap ps.Snapshotter, config *config.Config) (*Multi, error) {
+	m := &Multi{
+		filters:   make([]*filter, len(filters)),
+		accessors: newAccessors(psnap, config),
+		mappings:  make([]mapping, len(filters)),
+		index:     make(map[string][]int),
+		all:       make([]int, len(filters)),
+	}
+	indices := make(map[fields.Field]int)
+	multiIndices := make(map[fields.Field]int)
+	for i, flt := range filters {
+		f, ok := flt.(*filter)
+		if !ok || f.prog == nil {
+			return nil, fmt.Errorf("filter #%d is not compiled", i+1)
+		}
+		m.filters[i] = f
+		m.all[i] = i
+		m.mappings[i] = mapping{
+			fields:      union(&m.fields, indices, f.fields),
+			multiValued: union(&m.multiValued, multiIndices, f.multiValued),
+		}
+	}
+	for _, kinfo := range ktypes.GetKtypesMeta() {
+		known := map[string]interface{}{
+			fields.KevtName.String():     kinfo.Name,
+			fields.KevtCategory.String(): string(kinfo.Category),
+		}
+		m.index[kinfo.Name] = make([]int, 0)
+		for i, f := range m.filters {
+			if ql.MayMatch(f.expr, known) {
+				m.index[kinfo.Name] = append(m.index[kinfo.Name], i)
+			}
+		}
+	}
+	return m, nil
+}
+
+// union appends the fields to the set of unioned fields and returns their indices in the set.
+func union(set *[]fields.Field, indices map[fields.Field]int, flds []fields.Field) []int {
+	mapping := make([]int, len(flds))
+	for i, field := range flds {
+		n, ok := indices[field]
+		if !ok {
+			n = len(*set)
+			indices[field] = n
+			*set = append(*set, field)
+		}
+		mapping[i] = n
+	}
+	return mapping
+}
+
+// Fields returns the union of fields referenced by all filters.
+func (m *Multi) Fields() []fields.Field { return m.fields }
+
+// Run evaluates the filters against the event and returns the indices of matching filters
+// in the order the filters were given to the evaluator.
+func (m *Multi) Run(kevt *kevent.Kevent) []int {
+	candidates, ok := m.index[kevt.Name]
+	if !ok {
+		candidates = m.all
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	v, ok := m.valuers.Get().(*multiValuer)
+	if !ok {
+		v = &multiValuer{
+			m:             m,
+			values:        make([]kparams.Value, len(m.fields)),
+			resolved:      make([]bool, len(m.fields)),
+			elems:         make([][]ql.Valuer, len(m.multiValued)),
+			elemsResolved: make([]bool, len(m.multiValued)),
+			views:         make([]view, len(m.filters)),
+		}
+		for i := range v.views {
+			v.views[i] = view{v: v, mapping: &m.mappings[i]}
+		}
+	}
+	v.kevt = kevt
+	var matches []int
+	for _, i := range candidates {
+		if m.filters[i].prog.Run(&v.views[i]) {
+			matches = append(matches, i)
+		}
+	}
+	v.reset()
+	m.valuers.Put(v)
+	return matches
+}
+
+// multiValuer lazily resolves the values of unioned fields for the event being filtered.
+type multiValuer struct {
+	m             *Multi
+	kevt          *kevent.Kevent
+	values        []kparams.Value
+	resolved      []bool
+	elems         [][]ql.Valuer
+	elemsResolved []bool
+	// views contains the valuer of each filter program
+	views []view
+}
+
+func (v *multiValuer) reset() {
+	v.kevt = nil
+	for i := range v.values {
+		v.values[i] = nil
+		v.resolved[i] = false
+	}
+	for i := range v.elems {
+		v.elems[i] = nil
+		v.elemsResolved[i] = false
+	}
+}
+
+// view resolves the fields of a single filter program from the shared values.
+type view struct {
+	v       *multiValuer
+	mapping *mapping
+}
+
+func (w *view) ValueAt(i int) interface{} {
+	v, n := w.v, w.mapping.fields[i]
+	if !v.resolved[n] {
+		v.values[n] = getValue(v.m.accessors, v.m.fields[n], v.kevt)
+		v.resolved[n] = true
+	}
+	return v.values[n]
+}
+
+func (w *view) ElementsAt(i int) []ql.Valuer {
+	v, n := w.v, w.mapping.multiValued[i]
+	if !v.elemsResolved[n] {
+		v.elems[n] = getElements(v.m.accessors, v.m.multiValued[n], v.kevt)
+		v.elemsResolved[n] = true
+	}
+	return v.elems[n]
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// countingAccessor records how many times each field was fetched.
+type countingAccessor struct {
+	accessor
+	counts map[fields.Field]int
+}
+
+func (a *countingAccessor) get(f fields.Field, kevt *kevent.Kevent) (kparams.Value, error) {
+	a.counts[f]++
+	return a.accessor.get(f, kevt)
+}
+
+func TestMulti(t *testing.T) {
+	exprs := []string{
+		`kevt.name = 'CreateProcess' and ps.name = 'cmd.exe'`,
+		`ps.name iin ('CMD.EXE', 'powershell.exe')`,
+		`kevt.category = 'file' and ps.name = 'cmd.exe'`,
+		`kevt.name != 'CreateProcess' and ps.name = 'cmd.exe'`,
+		`ps.name = 'cmd.exe' and ps.comm contains '/c'`,
+	}
+	filters := make([]Filter, len(exprs))
+	for i, expr := range exprs {
+		filters[i] = New(expr, nil, cfg)
+		require.NoError(t, filters[i].Compile())
+	}
+
+	m, err := NewMulti(filters, nil, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []fields.Field{fields.KevtName, fields.PsName, fields.KevtCategory, fields.PsComm}, m.Fields())
+	assert.Equal(t, []int{0, 1, 4}, m.index["CreateProcess"])
+	assert.Equal(t, []int{1, 2, 3, 4}, m.index["CreateFile"])
+	assert.Equal(t, []int{1, 3, 4}, m.index["RegSetValue"])
+
+	kevt := &kevent.Kevent{
+		Type:     ktypes.CreateProcess,
+		Name:     "CreateProcess",
+		Category: ktypes.Process,
+		Kparams: kevent.Kparams{
+			kparams.ProcessName: {Name: kparams.ProcessName, Type: kparams.AnsiString, Value: "cmd.exe"},
+			kparams.Comm:        {Name: kparams.Comm, Type: kparams.UnicodeString, Value: "cmd.exe /c whoami"},
+		},
+	}
+
+	counter := &countingAccessor{accessor: m.accessors[1], counts: make(map[fields.Field]int)}
+	m.accessors = []accessor{m.accessors[0], counter}
+	assert.Equal(t, []int{0, 1, 4}, m.Run(kevt))
+	// ps.name is referenced by all candidate filters but is extracted once
+	assert.Equal(t, 1, counter.counts[fields.PsName])
+	assert.Equal(t, 1, counter.counts[fields.PsComm])
+
+	kevt.Name, kevt.Category = "CreateFile", ktypes.File
+	assert.Equal(t, []int{1, 2, 3, 4}, m.Run(kevt))
+	assert.Equal(t, 2, counter.counts[fields.PsName])
+
+	// events that aren't in the index are evaluated against all filters
+	kevt.Name = "EnumProcess"
+	assert.Equal(t, []int{1, 2, 3, 4}, m.Run(kevt))
+
+	kevt.Name, kevt.Category = "RegSetValue", ktypes.Registry
+	kevt.Kparams = kevent.Kparams{}
+	assert.Empty(t, m.Run(kevt))
+
+	_, err = NewMulti([]Filter{New(`ps.name = 'cmd.exe'`, nil, cfg)}, nil, cfg)
+	require.EqualError(t, err, "filter #1 is not compiled")
+}
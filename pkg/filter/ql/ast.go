@@ -21,11 +21,25 @@
 package ql
 
 import (
+	"github.com/rabbitstack/fibratus/pkg/util/levenshtein"
 	"github.com/rabbitstack/fibratus/pkg/util/wildcard"
 	"net"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 )
 
+// timeOfDayFmt is the layout for comparing timestamps against time strings
+const timeOfDayFmt = "15:04:05"
+
+// FuzzyDistance is the maximum edit distance between strings matched by the fuzzy operator
+var FuzzyDistance = 2
+
+// regexps caches the literal patterns of the regex operators. Patterns are cached when filters are
+// validated or compiled, so the cache only grows with the patterns given in filter expressions
+var regexps sync.Map
+
 // Eval evaluates expr against a map.
 func Eval(expr Expr, m map[string]interface{}) bool {
 	eval := ValuerEval{Valuer: MapValuer(m)}
@@ -101,16 +115,87 @@ func (v *ValuerEval) Eval(expr Expr) interface{} {
 			return nil
 		}
 		return val
+	case *ElementLiteral:
+		// find the element bound to the variable in
+		// the scope of the enclosing quantifiers
+		for e, ok := v.Valuer.(*elementValuer); ok; e, ok = e.Valuer.(*elementValuer) {
+			if e.name != expr.Var {
+				continue
+			}
+			val, ok := e.elem.Value(expr.Subfield)
+			if !ok {
+				return nil
+			}
+			return val
+		}
+		return nil
+	case *Quantifier:
+		return v.evalQuantifier(expr)
 	case *IPLiteral:
 		return expr.Value
+	case *CIDRLiteral:
+		return expr.Value
+	case *DurationLiteral:
+		return expr.Value
+	case *Function:
+		args := make([]interface{}, len(expr.Args))
+		for i, arg := range expr.Args {
+			args[i] = v.Eval(arg)
+		}
+		val, ok := expr.Fn.Call(args)
+		if !ok {
+			return nil
+		}
+		return val
 	default:
 		return nil
 	}
 }
 
 func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
-	lhs := v.Eval(expr.LHS)
-	rhs := v.Eval(expr.RHS)
+	return evalBinary(expr.Op, v.Eval(expr.LHS), v.Eval(expr.RHS))
+}
+
+// evalQuantifier evaluates the predicate against each element of the multi-valued
+// field. The valuer must yield the slice of valuers resolving the element fields.
+func (v *ValuerEval) evalQuantifier(expr *Quantifier) interface{} {
+	val, _ := v.Valuer.Value(expr.Field.Value)
+	elems, ok := val.([]Valuer)
+	if !ok || len(elems) == 0 {
+		return false
+	}
+	all := expr.isAll()
+	eval := ValuerEval{IntegerFloatDivision: v.IntegerFloatDivision}
+	for _, elem := range elems {
+		eval.Valuer = &elementValuer{Valuer: v.Valuer, name: expr.Var, elem: elem}
+		match, ok := eval.Eval(expr.Expr).(bool)
+		if match = ok && match; match != all {
+			return match
+		}
+	}
+	return all
+}
+
+// elementValuer binds the element to the quantifier variable. Fields
+// other than element fields are resolved by the enclosing valuer.
+type elementValuer struct {
+	Valuer
+	name string
+	elem Valuer
+}
+
+// Set is the collection of values matched by the in operators that is too large to
+// be expanded into the list literal, such as the indicator of compromise list.
+type Set interface {
+	// Contains determines whether the value is in the set.
+	Contains(val interface{}) bool
+}
+
+// evalBinary applies the binary operator to already evaluated operands.
+func evalBinary(op token, lhs, rhs interface{}) interface{} {
+	if set, ok := rhs.(Set); ok && (op == in || op == iin) {
+		return set.Contains(lhs)
+	}
 	if lhs == nil && rhs != nil {
 		// when the LHS is nil and the RHS is a boolean, implicitly cast the
 		// nil to false.
@@ -127,7 +212,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 	switch lhs := lhs.(type) {
 	case bool:
 		rhs, ok := rhs.(bool)
-		switch expr.Op {
+		switch op {
 		case and:
 			return ok && (lhs && rhs)
 		case or:
@@ -141,7 +226,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		switch rhs := rhs.(type) {
 		case float64:
 			lhs := float64(lhs)
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == rhs
 			case neq:
@@ -156,7 +241,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case int64:
-			switch expr.Op {
+			switch op {
 			case eq:
 				return int64(lhs) == rhs
 			case neq:
@@ -171,7 +256,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return int64(lhs) >= rhs
 			}
 		case uint64:
-			switch expr.Op {
+			switch op {
 			case eq:
 				return uint64(lhs) == rhs
 			case neq:
@@ -202,7 +287,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		switch rhs := rhs.(type) {
 		case float64:
 			lhs := float64(lhs)
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == rhs
 			case neq:
@@ -217,7 +302,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case int64:
-			switch expr.Op {
+			switch op {
 			case eq:
 				return int64(lhs) == rhs
 			case neq:
@@ -232,7 +317,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return int64(lhs) >= rhs
 			}
 		case uint64:
-			switch expr.Op {
+			switch op {
 			case eq:
 				return uint64(lhs) == rhs
 			case neq:
@@ -260,7 +345,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		}
 
 		rhs := rhsf
-		switch expr.Op {
+		switch op {
 		case eq:
 			return ok && (lhs == rhs)
 		case neq:
@@ -279,7 +364,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		switch rhs := rhs.(type) {
 		case float64:
 			lhs := float64(lhs)
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == rhs
 			case neq:
@@ -294,7 +379,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case int64:
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == rhs
 			case neq:
@@ -309,7 +394,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case uint64:
-			switch expr.Op {
+			switch op {
 			case eq:
 				return uint64(lhs) == rhs
 			case neq:
@@ -341,7 +426,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		switch rhs := rhs.(type) {
 		case float64:
 			lhs := float64(lhs)
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == rhs
 			case neq:
@@ -356,7 +441,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case int64:
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == uint64(rhs)
 			case neq:
@@ -383,7 +468,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= uint64(rhs)
 			}
 		case uint64:
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == rhs
 			case neq:
@@ -402,7 +487,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		switch rhs := rhs.(type) {
 		case float64:
 			lhs := float64(lhs)
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == rhs
 			case neq:
@@ -417,7 +502,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case int32:
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == uint32(rhs)
 			case neq:
@@ -444,7 +529,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= uint32(rhs)
 			}
 		case int64:
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == uint32(rhs)
 			case neq:
@@ -471,7 +556,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= uint32(rhs)
 			}
 		case uint32:
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == rhs
 			case neq:
@@ -490,7 +575,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 		switch rhs := rhs.(type) {
 		case float64:
 			lhs := float64(lhs)
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == rhs
 			case neq:
@@ -505,7 +590,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= rhs
 			}
 		case int32:
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == uint16(rhs)
 			case neq:
@@ -532,7 +617,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= uint16(rhs)
 			}
 		case int64:
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == uint16(rhs)
 			case neq:
@@ -559,7 +644,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				return lhs >= uint16(rhs)
 			}
 		case uint16:
-			switch expr.Op {
+			switch op {
 			case eq:
 				return lhs == rhs
 			case neq:
@@ -575,7 +660,7 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 			}
 		}
 	case string:
-		switch expr.Op {
+		switch op {
 		case eq:
 			rhs, ok := rhs.(string)
 			if !ok {
@@ -683,35 +768,115 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 			default:
 				return false
 			}
+		case iin:
+			rhs, ok := rhs.([]string)
+			if !ok {
+				return false
+			}
+			for _, i := range rhs {
+				if strings.EqualFold(i, lhs) {
+					return true
+				}
+			}
+			return false
+		case istartswith:
+			return matchAny(rhs, func(s string) bool { return strings.HasPrefix(strings.ToLower(lhs), strings.ToLower(s)) })
+		case iendswith:
+			return matchAny(rhs, func(s string) bool { return strings.HasSuffix(strings.ToLower(lhs), strings.ToLower(s)) })
+		case regex, iregex:
+			return matchAny(rhs, func(pat string) bool {
+				re, err := compileRegex(pat, op == iregex)
+				return err == nil && re.MatchString(lhs)
+			})
+		case fuzzy:
+			return matchAny(rhs, func(s string) bool { return levenshtein.Match(strings.ToLower(lhs), strings.ToLower(s), FuzzyDistance) })
 		}
 	case net.IP:
-		switch expr.Op {
+		switch op {
 		case eq:
-			rhs, ok := rhs.(net.IP)
-			if !ok {
+			switch rhs := rhs.(type) {
+			case net.IP:
+				return lhs.Equal(rhs)
+			case *net.IPNet:
+				return rhs.Contains(lhs)
+			default:
 				return false
 			}
-			return lhs.Equal(rhs)
 		case neq:
-			rhs, ok := rhs.(net.IP)
-			if !ok {
+			switch rhs := rhs.(type) {
+			case net.IP:
+				return !lhs.Equal(rhs)
+			case *net.IPNet:
+				return !rhs.Contains(lhs)
+			default:
 				return false
 			}
-			return !lhs.Equal(rhs)
 		case in:
 			rhs, ok := rhs.([]string)
 			if !ok {
 				return false
 			}
-			for _, s := range rhs {
-				if net.ParseIP(s).Equal(lhs) {
-					return true
-				}
+			ips, nets := parseAddrs(rhs)
+			return matchesAddr(lhs, ips, nets)
+		}
+	case time.Time:
+		switch rhs := rhs.(type) {
+		case time.Time:
+			return compareTime(op, lhs, rhs)
+		case time.Duration:
+			switch op {
+			case add:
+				return lhs.Add(rhs)
+			case sub:
+				return lhs.Add(-rhs)
+			}
+		case string:
+			// the string is either a timestamp or
+			// a time of the day (e.g. 15:04:05)
+			if t, err := time.Parse(time.RFC3339, rhs); err == nil {
+				return compareTime(op, lhs, t)
+			}
+			lhs := lhs.Format(timeOfDayFmt)
+			switch op {
+			case eq:
+				return lhs == rhs
+			case neq:
+				return lhs != rhs
+			case lt:
+				return lhs < rhs
+			case lte:
+				return lhs <= rhs
+			case gt:
+				return lhs > rhs
+			case gte:
+				return lhs >= rhs
 			}
-			return false
+		}
+	case time.Duration:
+		rhs, ok := rhs.(time.Duration)
+		if !ok {
+			break
+		}
+		switch op {
+		case eq:
+			return lhs == rhs
+		case neq:
+			return lhs != rhs
+		case lt:
+			return lhs < rhs
+		case lte:
+			return lhs <= rhs
+		case gt:
+			return lhs > rhs
+		case gte:
+			return lhs >= rhs
+		case add:
+			return lhs + rhs
+		case sub:
+			return lhs - rhs
 		}
 	case []string:
-		switch expr.Op {
+		switch op {
 		case contains:
 			rhs, ok := rhs.(string)
 			if !ok {
@@ -736,14 +901,87 @@ func (v *ValuerEval) evalBinaryExpr(expr *BinaryExpr) interface{} {
 				}
 			}
 			return false
+		case iin:
+			rhs, ok := rhs.([]string)
+			if !ok {
+				return false
+			}
+			for _, i := range lhs {
+				for _, j := range rhs {
+					if strings.EqualFold(i, j) {
+						return true
+					}
+				}
+			}
+			return false
 		}
 	}
 
 	// the types were not comparable. If our operation was an equality operation,
 	// return false instead of true.
-	switch expr.Op {
+	switch op {
 	case eq, neq, lt, lte, gt, gte:
 		return false
 	}
 	return nil
 }
+
+// matchAny applies the match function to the string or each string
+// in the slice and returns true if any of the strings is matched.
+func matchAny(rhs interface{}, match func(s string) bool) bool {
+	switch rhs := rhs.(type) {
+	case string:
+		return match(rhs)
+	case []string:
+		for _, s := range rhs {
+			if match(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compileRegex compiles the pattern of the regex operator. The pattern is made case-insensitive
+// for the iregex operator. Cached literal patterns are reused, but patterns that weren't given in
+// filter expressions, e.g. taken from field values, are compiled without being cached.
+func compileRegex(pat string, insensitive bool) (*regexp.Regexp, error) {
+	if insensitive {
+		pat = "(?i)" + pat
+	}
+	if re, ok := regexps.Load(pat); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	return regexp.Compile(pat)
+}
+
+// cacheRegex compiles the literal pattern of the regex operator and caches it.
+func cacheRegex(pat string, insensitive bool) (*regexp.Regexp, error) {
+	re, err := compileRegex(pat, insensitive)
+	if err != nil {
+		return nil, err
+	}
+	if insensitive {
+		pat = "(?i)" + pat
+	}
+	regexps.Store(pat, re)
+	return re, nil
+}
+
+func compareTime(op token, lhs, rhs time.Time) interface{} {
+	switch op {
+	case eq:
+		return lhs.Equal(rhs)
+	case neq:
+		return !lhs.Equal(rhs)
+	case lt:
+		return lhs.Before(rhs)
+	case lte:
+		return !lhs.After(rhs)
+	case gt:
+		return lhs.After(rhs)
+	case gte:
+		return !lhs.Before(rhs)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql/functions"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"strings"
+)
+
+// valueType designates the type of the value an expression evaluates to.
+type valueType uint8
+
+const (
+	// anyType is assigned to expressions whose type can only be determined at runtime
+	anyType valueType = iota
+	boolType
+	stringType
+	numberType
+	ipType
+	cidrType
+	listType
+	timeType
+	durationType
+)
+
+func (t valueType) String() string {
+	switch t {
+	case boolType:
+		return "bool"
+	case stringType:
+		return "string"
+	case numberType:
+		return "number"
+	case ipType:
+		return "ip"
+	case cidrType:
+		return "cidr"
+	case listType:
+		return "list"
+	case timeType:
+		return "time"
+	case durationType:
+		return "duration"
+	}
+	return "any"
+}
+
+// typeFromKparam maps the parameter type to the value type.
+func typeFromKparam(typ kparams.Type) valueType {
+	switch typ {
+	case kparams.UnicodeString, kparams.AnsiString, kparams.SID, kparams.WbemSID, kparams.GUID,
+		kparams.HexInt8, kparams.HexInt16, kparams.HexInt32, kparams.HexInt64:
+		return stringType
+	case kparams.Int8, kparams.Uint8, kparams.Int16, kparams.Uint16, kparams.Int32, kparams.Uint32,
+		kparams.Int64, kparams.Uint64, kparams.Float, kparams.Double, kparams.PID, kparams.TID, kparams.Port:
+		return numberType
+	case kparams.IP, kparams.IPv4, kparams.IPv6:
+		return ipType
+	case kparams.Time:
+		return timeType
+	case kparams.Duration:
+		return durationType
+	case kparams.Slice:
+		return listType
+	case kparams.Bool:
+		return boolType
+	}
+	return anyType
+}
+
+// typeOf infers the type of the value produced by the expression.
+func typeOf(expr Expr) valueType {
+	switch expr := expr.(type) {
+	case *FieldLiteral:
+		return typeFromKparam(fields.Field(expr.Value).Type())
+	case *ElementLiteral:
+		return typeFromKparam(fields.Field(expr.Field).ElementType(fields.Subfield(expr.Subfield)))
+	case *StringLiteral:
+		return stringType
+	case *IntegerLiteral, *UnsignedLiteral, *DecimalLiteral:
+		return numberType
+	case *IPLiteral:
+		return ipType
+	case *CIDRLiteral:
+		return cidrType
+	case *ListLiteral:
+		return listType
+	case *DurationLiteral:
+		return durationType
+	case *Function:
+		return typeFromKparam(expr.Fn.Desc().ReturnType)
+	case *ParenExpr:
+		return typeOf(expr.Expr)
+	case *NotExpr, *Quantifier:
+		return boolType
+	case *BinaryExpr:
+		if expr.Op == add || expr.Op == sub {
+			return typeOf(expr.LHS)
+		}
+		return boolType
+	}
+	return anyType
+}
+
+// Validate performs the semantic analysis of the expression tree produced by
+// the parser. It ensures logical operators are applied to boolean expressions
+// and that operands of other operators have compatible types. For example,
+// comparing the port number with the string literal yields an error pointing
+// at the offending operand. Patterns given to regex operators are compiled, so
+// invalid regular expressions are reported at the pattern literal.
+func (p *Parser) Validate(expr Expr) error {
+	var err error
+	WalkFunc(expr, func(n Node) {
+		if err != nil {
+			return
+		}
+		switch expr := n.(type) {
+		case *BinaryExpr:
+			err = p.checkBinaryExpr(expr)
+			if err == nil && (expr.Op == regex || expr.Op == iregex) {
+				err = p.checkRegex(expr)
+			}
+		case *Quantifier:
+			if typ := typeOf(expr.Expr); typ != boolType && typ != anyType {
+				err = p.errorAt(expr.Expr, "expected boolean expression but found %s", typ)
+			}
+		}
+	})
+	return err
+}
+
+func (p *Parser) checkBinaryExpr(expr *BinaryExpr) error {
+	ltyp, rtyp := typeOf(expr.LHS), typeOf(expr.RHS)
+
+	switch expr.Op {
+	case and, or:
+		if ltyp != boolType && ltyp != anyType {
+			return p.errorAt(expr.LHS, "expected boolean expression but found %s", ltyp)
+		}
+		if rtyp != boolType && rtyp != anyType {
+			return p.errorAt(expr.RHS, "expected boolean expression but found %s", rtyp)
+		}
+		return nil
+	}
+
+	if fn, ok := expr.RHS.(*Function); ok && fn.Name == functions.IOCFn.String() && expr.Op != in && expr.Op != iin {
+		return p.errorAt(expr.RHS, "IOC list can only be matched with in or iin operators")
+	}
+
+	if ltyp == anyType || rtyp == anyType {
+		return nil
+	}
+
+	switch expr.Op {
+	case eq, neq:
+		if ltyp == rtyp && ltyp != listType {
+			return nil
+		}
+		if ltyp == timeType && rtyp == stringType {
+			return nil
+		}
+		if ltyp == ipType && rtyp == cidrType {
+			return nil
+		}
+		if ltyp == listType {
+			return p.errorAt(expr, "operator %s is not applicable to %s", strings.ToLower(expr.Op.String()), ltyp)
+		}
+		return p.errorAt(expr.RHS, "expected %s but found %s", ltyp, rtyp)
+	case lt, lte, gt, gte:
+		switch ltyp {
+		case numberType, durationType:
+			if rtyp == ltyp {
+				return nil
+			}
+		case timeType:
+			if rtyp == timeType || rtyp == stringType {
+				return nil
+			}
+		default:
+			return p.errorAt(expr, "operator %s is not applicable to %s", strings.ToLower(expr.Op.String()), ltyp)
+		}
+		return p.errorAt(expr.RHS, "expected %s but found %s", ltyp, rtyp)
+	case add, sub:
+		if (ltyp == timeType || ltyp == durationType) && rtyp == durationType {
+			return nil
+		}
+		if ltyp != timeType && ltyp != durationType {
+			return p.errorAt(expr, "operator %s is not applicable to %s", strings.ToLower(expr.Op.String()), ltyp)
+		}
+		return p.errorAt(expr.RHS, "expected duration but found %s", rtyp)
+	case in:
+		if ltyp != stringType && ltyp != ipType && ltyp != listType {
+			return p.errorAt(expr, "operator %s is not applicable to %s", strings.ToLower(expr.Op.String()), ltyp)
+		}
+		if rtyp != listType {
+			return p.errorAt(expr.RHS, "expected list but found %s", rtyp)
+		}
+	case iin:
+		if ltyp != stringType && ltyp != listType {
+			return p.errorAt(expr, "operator %s is not applicable to %s", strings.ToLower(expr.Op.String()), ltyp)
+		}
+		if rtyp != listType {
+			return p.errorAt(expr.RHS, "expected list but found %s", rtyp)
+		}
+	case contains, icontains:
+		if ltyp != stringType && ltyp != listType {
+			return p.errorAt(expr, "operator %s is not applicable to %s", strings.ToLower(expr.Op.String()), ltyp)
+		}
+		if rtyp != stringType && rtyp != listType {
+			return p.errorAt(expr.RHS, "expected string or list but found %s", rtyp)
+		}
+	case startswith, istartswith, endswith, iendswith, matches, imatches, regex, iregex, fuzzy:
+		if ltyp != stringType {
+			return p.errorAt(expr, "operator %s is not applicable to %s", strings.ToLower(expr.Op.String()), ltyp)
+		}
+		if rtyp != stringType && rtyp != listType {
+			return p.errorAt(expr.RHS, "expected string or list but found %s", rtyp)
+		}
+	}
+	return nil
+}
+
+// checkRegex ensures the patterns of the regex operators are valid regular expressions.
+func (p *Parser) checkRegex(expr *BinaryExpr) error {
+	var pats []string
+	switch lit := expr.RHS.(type) {
+	case *StringLiteral:
+		pats = []string{lit.Value}
+	case *ListLiteral:
+		pats = lit.Values
+	}
+	for _, pat := range pats {
+		if _, err := cacheRegex(pat, expr.Op == iregex); err != nil {
+			return p.errorAt(expr.RHS, "invalid regular expression: %v", err)
+		}
+	}
+	return nil
+}
+
+// errorAt builds the parse error positioned at the node offset.
+func (p *Parser) errorAt(n Node, format string, args ...interface{}) error {
+	return &ParseError{Message: fmt.Sprintf(format, args...), Pos: p.positions[n], Expr: p.expr}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		expr string
+		err  string
+	}{
+		{expr: `ps.name = 'cmd.exe'`},
+		{expr: `ps.pid = 1024 and kevt.pid != ps.ppid`},
+		{expr: `net.dip = 172.17.0.9 and net.dip not in ('172.15.9.2')`},
+		{expr: `ps.modules in ('kernel32.dll') or ps.modules contains 'user32.dll'`},
+		{expr: `pe.sections[.text].entropy > 6.2`},
+		{expr: `registry.value = 10234 or registry.value = 'REG_SZ'`},
+		{expr: `kevt.time > now() - 10m and kevt.time = '15:04:05'`},
+		{expr: `file.name not matches ('C:\\*.exe', 'C:\\Windows\\*.com')`},
+		{expr: `length(ps.comm) > 1024 and lower(ps.name) in ('cmd.exe')`},
+		{expr: `regex(ps.name, '^svc') and (ps.name = 'svchost.exe' or ps.name = 'lsass.exe')`},
+		{expr: `any(ps.modules, m, m.size > 1024 and m.name = ps.name) or all(pe.sections, s, s.entropy < 6.5)`},
+		{expr: `net.dip = 10.0.0.0/8 or net.sip != fe80::/10 or net.dip in multicast`},
+		{expr: `ps.name iin ('CMD.exe') and ps.modules iin ('KERNEL32.dll') and file.name iendswith ('.EXE', '.dll')`},
+		{expr: `ps.name regex '^svc.*' or ps.comm iregex ('-k\\s+rpcss', 'netsvcs') or regex(ps.name, 'svc')`},
+		{expr: `ps.name fuzzy ('svchost.exe', 'lsass.exe') and ps.name not in ('svchost.exe', 'lsass.exe')`},
+
+		{`net.dport = 'http'`, "net.dport = 'http'\n            ^ expected number but found string"},
+		{`ps.name = 123`, "ps.name = 123\n           ^ expected string but found number"},
+		{`ps.name > 'cmd.exe'`, "ps.name > 'cmd.exe'\n         ^ operator > is not applicable to string"},
+		{`kevt.name = 'CreateProcess' and ps.pid in ('1024')`, "kevt.name = 'CreateProcess' and ps.pid in ('1024')\n                                        ^ operator in is not applicable to number"},
+		{`ps.name in 'cmd.exe'`, "ps.name in 'cmd.exe'\n           ^ expected list but found string"},
+		{`ps.name = 'cmd.exe' and ps.exe`, "ps.name = 'cmd.exe' and ps.exe\n                         ^ expected boolean expression but found string"},
+		{`net.dport contains 'http'`, "net.dport contains 'http'\n           ^ operator contains is not applicable to number"},
+		{`file.name not startswith 1`, "file.name not startswith 1\n                          ^ expected string or list but found number"},
+		{`ps.runtime < 10`, "ps.runtime < 10\n              ^ expected duration but found number"},
+		{`ps.pid iin ('1024')`, "ps.pid iin ('1024')\n        ^ operator iin is not applicable to number"},
+		{`ps.name istartswith 1`, "ps.name istartswith 1\n                     ^ expected string or list but found number"},
+		{`ps.name regex 'svc(.*'`, "ps.name regex 'svc(.*'\n              ^ invalid regular expression: error parsing regexp: missing closing ): `svc(.*`"},
+		{`kevt.arg[image_name] iregex ('cmd', '[a-')`, "kevt.arg[image_name] iregex ('cmd', '[a-')\n                             ^ invalid regular expression: error parsing regexp: missing closing ]: `[a-`"},
+		{`ps.name = 10.0.0.0/8`, "ps.name = 10.0.0.0/8\n           ^ expected string but found cidr"},
+		{`net.dip in 10.0.0.0/8`, "net.dip in 10.0.0.0/8\n            ^ expected list but found cidr"},
+		{`kevt.time > now() - 10`, "kevt.time > now() - 10\n                     ^ expected duration but found number"},
+		{`length(ps.name) = 'cmd.exe'`, "length(ps.name) = 'cmd.exe'\n                  ^ expected number but found string"},
+		{`any(ps.modules, m, m.size = 'big')`, "any(ps.modules, m, m.size = 'big')\n                            ^ expected number but found string"},
+		{`all(ps.handles, h, h.name)`, "all(ps.handles, h, h.name)\n                    ^ expected boolean expression but found string"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(tt.expr)
+		expr, err := p.ParseExpr()
+		require.NoError(t, err)
+		err = p.Validate(expr)
+		if tt.err == "" {
+			assert.NoError(t, err, tt.expr)
+			continue
+		}
+		require.Error(t, err, tt.expr)
+		assert.Equal(t, tt.err, err.Error())
+	}
+}
+
+func TestUnknownField(t *testing.T) {
+	_, err := NewParser(`ps.name = 'cmd.exe' or ps.nmae = 'cmd.exe'`).ParseExpr()
+	require.Error(t, err)
+	assert.Equal(t, "ps.name = 'cmd.exe' or ps.nmae = 'cmd.exe'\n                        ^ unknown field ps.nmae", err.Error())
+}
@@ -0,0 +1,428 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/filter/ql/functions"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/util/levenshtein"
+	"github.com/rabbitstack/fibratus/pkg/util/wildcard"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// IndexedValuer resolves field values by the index the field was assigned
+// when the expression was compiled.
+type IndexedValuer interface {
+	// ValueAt returns the value of the field at the given index or nil if the value is not available.
+	ValueAt(i int) interface{}
+	// ElementsAt returns the elements of the multi-valued field at the given index
+	// or nil if the field is not available. Each element resolves its own fields.
+	ElementsAt(i int) []Valuer
+}
+
+// evaluator is the compiled form of the expression node.
+type evaluator func(IndexedValuer) interface{}
+
+// Program is the expression compiled into a tree of closures. Contrary to the
+// valuer evaluator, field values are only requested when the evaluation reaches
+// the node that references the field, so short-circuited branches never fetch
+// the values of their fields.
+type Program struct {
+	eval        evaluator
+	fields      []string
+	multiValued []string
+}
+
+// Compile compiles the expression into the program. Every distinct field is
+// assigned an index in the order of appearance in the expression.
+func Compile(expr Expr) *Program {
+	c := &compiler{indices: make(map[string]int), multiIndices: make(map[string]int)}
+	return &Program{eval: c.compile(expr), fields: c.fields, multiValued: c.multiValued}
+}
+
+// Fields returns distinct fields referenced by the program. The position of the
+// field in the slice is the index passed to the valuer.
+func (p *Program) Fields() []string { return p.fields }
+
+// MultiValuedFields returns distinct multi-valued fields iterated by quantifiers. The
+// position of the field in the slice is the index passed to the valuer when requesting
+// the elements of the field.
+func (p *Program) MultiValuedFields() []string { return p.multiValued }
+
+// Run evaluates the program and returns true if the expression yields the true value.
+func (p *Program) Run(v IndexedValuer) bool {
+	val, ok := p.eval(v).(bool)
+	return ok && val
+}
+
+type compiler struct {
+	fields       []string
+	indices      map[string]int
+	multiValued  []string
+	multiIndices map[string]int
+}
+
+func (c *compiler) compile(expr Expr) evaluator {
+	switch expr := expr.(type) {
+	case *BinaryExpr:
+		return c.compileBinaryExpr(expr)
+	case *NotExpr:
+		bexpr, ok := expr.Expr.(*BinaryExpr)
+		if !ok {
+			return constant(nil)
+		}
+		eval := c.compileBinaryExpr(bexpr)
+		return func(v IndexedValuer) interface{} {
+			if val, ok := eval(v).(bool); ok {
+				return !val
+			}
+			return nil
+		}
+	case *ParenExpr:
+		return c.compile(expr.Expr)
+	case *FieldLiteral:
+		i := c.index(expr.Value)
+		return func(v IndexedValuer) interface{} { return v.ValueAt(i) }
+	case *Quantifier:
+		return c.compileQuantifier(expr)
+	case *ElementLiteral:
+		name, subfield := expr.Var, expr.Subfield
+		return func(v IndexedValuer) interface{} {
+			for s, ok := v.(*scope); ok; s, ok = s.IndexedValuer.(*scope) {
+				if s.name != name {
+					continue
+				}
+				val, ok := s.elem.Value(subfield)
+				if !ok {
+					return nil
+				}
+				return val
+			}
+			return nil
+		}
+	case *IntegerLiteral:
+		return constant(expr.Value)
+	case *UnsignedLiteral:
+		return constant(expr.Value)
+	case *DecimalLiteral:
+		return constant(expr.Value)
+	case *StringLiteral:
+		return constant(expr.Value)
+	case *ListLiteral:
+		return constant(expr.Values)
+	case *IPLiteral:
+		return constant(expr.Value)
+	case *CIDRLiteral:
+		return constant(expr.Value)
+	case *DurationLiteral:
+		return constant(expr.Value)
+	case *Function:
+		// the ioc list is bound when the expression is
+		// parsed, so there is no need to call the function
+		if fn, ok := expr.Fn.(*functions.IOC); ok && fn.List != nil {
+			return constant(fn.List)
+		}
+		args := make([]evaluator, len(expr.Args))
+		for i, arg := range expr.Args {
+			args[i] = c.compile(arg)
+		}
+		fn := expr.Fn
+		return func(v IndexedValuer) interface{} {
+			vals := make([]interface{}, len(args))
+			for i, arg := range args {
+				vals[i] = arg(v)
+			}
+			val, ok := fn.Call(vals)
+			if !ok {
+				return nil
+			}
+			return val
+		}
+	default:
+		return constant(nil)
+	}
+}
+
+func (c *compiler) compileBinaryExpr(expr *BinaryExpr) evaluator {
+	op := expr.Op
+	lhs, rhs := c.compile(expr.LHS), c.compile(expr.RHS)
+
+	switch op {
+	case and:
+		return func(v IndexedValuer) interface{} {
+			l := lhs(v)
+			if val, ok := l.(bool); ok && !val {
+				return false
+			}
+			return evalBinary(op, l, rhs(v))
+		}
+	case or:
+		// the right operand can only be skipped if it yields a boolean
+		// value, since other values cause the whole expression to fail
+		if !yieldsBool(expr.RHS) {
+			break
+		}
+		return func(v IndexedValuer) interface{} {
+			l := lhs(v)
+			if val, ok := l.(bool); ok && val {
+				return true
+			}
+			return evalBinary(op, l, rhs(v))
+		}
+	}
+
+	// comparing against the string constant is by far the most common
+	// pattern, so it gets the typed predicate that bypasses the generic
+	// evaluation for string values. Likewise, the addresses and networks
+	// are parsed once for matching IP values
+	smatch, ipmatch := stringPredicate(op, expr.RHS), ipPredicate(op, expr.RHS)
+	if smatch != nil || ipmatch != nil {
+		r := rhs(nil)
+		return func(v IndexedValuer) interface{} {
+			l := lhs(v)
+			if s, ok := l.(string); ok && smatch != nil {
+				return smatch(s)
+			}
+			if ip, ok := l.(net.IP); ok && ipmatch != nil {
+				return ipmatch(ip)
+			}
+			return evalBinary(op, l, r)
+		}
+	}
+
+	return func(v IndexedValuer) interface{} { return evalBinary(op, lhs(v), rhs(v)) }
+}
+
+func (c *compiler) compileQuantifier(expr *Quantifier) evaluator {
+	i, ok := c.multiIndices[expr.Field.Value]
+	if !ok {
+		i = len(c.multiValued)
+		c.multiIndices[expr.Field.Value] = i
+		c.multiValued = append(c.multiValued, expr.Field.Value)
+	}
+	pred := c.compile(expr.Expr)
+	name, all := expr.Var, expr.isAll()
+
+	return func(v IndexedValuer) interface{} {
+		elems := v.ElementsAt(i)
+		// neither quantifier is satisfied by the
+		// absent or empty multi-valued field
+		if len(elems) == 0 {
+			return false
+		}
+		s := &scope{IndexedValuer: v, name: name}
+		for _, elem := range elems {
+			s.elem = elem
+			match, ok := pred(s).(bool)
+			// any stops at the first matching element,
+			// while all stops at the first mismatch
+			if match = ok && match; match != all {
+				return match
+			}
+		}
+		return all
+	}
+}
+
+// scope binds the element to the quantifier variable while the predicate
+// is evaluated. Other fields are resolved by the enclosing valuer.
+type scope struct {
+	IndexedValuer
+	name string
+	elem Valuer
+}
+
+func (c *compiler) index(field string) int {
+	if i, ok := c.indices[field]; ok {
+		return i
+	}
+	i := len(c.fields)
+	c.indices[field] = i
+	c.fields = append(c.fields, field)
+	return i
+}
+
+// constant returns the evaluator for the literal value. The value is
+// converted to the interface once, so evaluating the literal doesn't
+// allocate.
+func constant(val interface{}) evaluator {
+	return func(IndexedValuer) interface{} { return val }
+}
+
+// yieldsBool determines whether the expression evaluates to a boolean value.
+func yieldsBool(expr Expr) bool {
+	switch expr := expr.(type) {
+	case *BinaryExpr:
+		return expr.Op != add && expr.Op != sub
+	case *NotExpr, *Quantifier:
+		return true
+	case *ParenExpr:
+		return yieldsBool(expr.Expr)
+	case *Function:
+		return expr.Fn.Desc().ReturnType == kparams.Bool
+	default:
+		return false
+	}
+}
+
+// stringPredicate builds the predicate that matches the string value against
+// the string or list literal. It returns nil if the operator has no typed
+// predicate for the given literal.
+func stringPredicate(op token, expr Expr) func(string) bool {
+	switch lit := expr.(type) {
+	case *StringLiteral:
+		c := lit.Value
+		lc := strings.ToLower(c)
+		switch op {
+		case eq:
+			return func(s string) bool { return s == c }
+		case neq:
+			return func(s string) bool { return s != c }
+		case contains:
+			return func(s string) bool { return strings.Contains(s, c) }
+		case icontains:
+			return func(s string) bool { return strings.Contains(strings.ToLower(s), lc) }
+		case startswith:
+			return func(s string) bool { return strings.HasPrefix(s, c) }
+		case endswith:
+			return func(s string) bool { return strings.HasSuffix(s, c) }
+		case matches:
+			return func(s string) bool { return wildcard.Match(c, s) }
+		case imatches:
+			return func(s string) bool { return wildcard.Match(lc, strings.ToLower(s)) }
+		case istartswith:
+			return func(s string) bool { return strings.HasPrefix(strings.ToLower(s), lc) }
+		case iendswith:
+			return func(s string) bool { return strings.HasSuffix(strings.ToLower(s), lc) }
+		case regex, iregex:
+			return regexPredicate(op, []string{c})
+		case fuzzy:
+			return func(s string) bool { return levenshtein.Match(strings.ToLower(s), lc, FuzzyDistance) }
+		}
+	case *ListLiteral:
+		vals := lit.Values
+		lvals := make([]string, len(vals))
+		for i, val := range vals {
+			lvals[i] = strings.ToLower(val)
+		}
+		switch op {
+		case in:
+			set := make(map[string]struct{}, len(vals))
+			for _, val := range vals {
+				set[val] = struct{}{}
+			}
+			return func(s string) bool {
+				_, ok := set[s]
+				return ok
+			}
+		case contains:
+			return anyOf(vals, strings.Contains, false)
+		case icontains:
+			return anyOf(lvals, strings.Contains, true)
+		case startswith:
+			return anyOf(vals, strings.HasPrefix, false)
+		case endswith:
+			return anyOf(vals, strings.HasSuffix, false)
+		case matches:
+			return anyOf(vals, func(s, pat string) bool { return wildcard.Match(pat, s) }, false)
+		case imatches:
+			return anyOf(lvals, func(s, pat string) bool { return wildcard.Match(pat, s) }, true)
+		case iin:
+			set := make(map[string]struct{}, len(lvals))
+			for _, val := range lvals {
+				set[val] = struct{}{}
+			}
+			return func(s string) bool {
+				_, ok := set[strings.ToLower(s)]
+				return ok
+			}
+		case istartswith:
+			return anyOf(lvals, strings.HasPrefix, true)
+		case iendswith:
+			return anyOf(lvals, strings.HasSuffix, true)
+		case regex, iregex:
+			return regexPredicate(op, vals)
+		case fuzzy:
+			return anyOf(lvals, func(s, val string) bool { return levenshtein.Match(s, val, FuzzyDistance) }, true)
+		}
+	}
+	return nil
+}
+
+// ipPredicate builds the predicate that matches the IP address against
+// the network literal or the list of addresses and networks.
+func ipPredicate(op token, expr Expr) func(net.IP) bool {
+	switch lit := expr.(type) {
+	case *CIDRLiteral:
+		n := lit.Value
+		switch op {
+		case eq:
+			return n.Contains
+		case neq:
+			return func(ip net.IP) bool { return !n.Contains(ip) }
+		}
+	case *ListLiteral:
+		if op != in {
+			return nil
+		}
+		ips, nets := parseAddrs(lit.Values)
+		return func(ip net.IP) bool { return matchesAddr(ip, ips, nets) }
+	}
+	return nil
+}
+
+// regexPredicate compiles the patterns once and returns the predicate that is
+// satisfied when any of the patterns matches. Invalid patterns never match.
+func regexPredicate(op token, pats []string) func(string) bool {
+	res := make([]*regexp.Regexp, 0, len(pats))
+	for _, pat := range pats {
+		re, err := cacheRegex(pat, op == iregex)
+		if err != nil {
+			continue
+		}
+		res = append(res, re)
+	}
+	return func(s string) bool {
+		for _, re := range res {
+			if re.MatchString(s) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// anyOf returns the predicate that is satisfied when the match function
+// succeeds for any of the values. If lower is true, the matched string
+// is converted to lowercase before applying the match function.
+func anyOf(vals []string, match func(s, val string) bool, lower bool) func(string) bool {
+	return func(s string) bool {
+		if lower {
+			s = strings.ToLower(s)
+		}
+		for _, val := range vals {
+			if match(s, val) {
+				return true
+			}
+		}
+		return false
+	}
+}
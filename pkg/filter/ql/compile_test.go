@@ -0,0 +1,322 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+// mapIndexedValuer resolves indexed fields from the map and records the fields that were fetched.
+type mapIndexedValuer struct {
+	prog    *Program
+	m       map[string]interface{}
+	fetched map[string]bool
+}
+
+func (v *mapIndexedValuer) ValueAt(i int) interface{} {
+	field := v.prog.Fields()[i]
+	v.fetched[field] = true
+	return v.m[field]
+}
+
+func (v *mapIndexedValuer) ElementsAt(i int) []Valuer {
+	field := v.prog.MultiValuedFields()[i]
+	v.fetched[field] = true
+	elems, _ := v.m[field].([]Valuer)
+	return elems
+}
+
+func TestCompile(t *testing.T) {
+	m := map[string]interface{}{
+		"ps.name":    "svchost.exe",
+		"ps.comm":    "C:\\Windows\\system32\\svchost.exe -k RPCSS",
+		"ps.pid":     uint32(1024),
+		"ps.envs":    []string{"ALLUSERSPROFILE", "OS"},
+		"kevt.name":  "CreateProcess",
+		"kevt.time":  time.Now().Add(-time.Minute),
+		"net.dip":    net.ParseIP("10.0.2.15"),
+		"net.dport":  uint16(443),
+		"ps.runtime": time.Second * 30,
+		"pe.sections": []Valuer{
+			MapValuer{"name": ".text", "entropy": 6.2, "size": uint32(4096)},
+			MapValuer{"name": ".rdata", "entropy": 4.1, "size": uint32(1024)},
+		},
+	}
+
+	var tests = []string{
+		`ps.name = 'svchost.exe'`,
+		`ps.name != 'svchost.exe'`,
+		`ps.name contains 'svc'`,
+		`ps.name icontains 'SVC'`,
+		`ps.name icontains ('cmd', 'SVC')`,
+		`ps.name startswith ('svc', 'cmd')`,
+		`ps.name endswith '.dll'`,
+		`ps.name matches 'svc*.exe'`,
+		`ps.name imatches ('SVC*.EXE', 'cmd*')`,
+		`ps.name in ('cmd.exe', 'svchost.exe')`,
+		`ps.name = ps.comm`,
+		`ps.envs in ('OS')`,
+		`ps.envs contains 'OS'`,
+		`ps.cwd = 'C:\\Windows'`,
+		`ps.cwd not contains 'Windows'`,
+		`ps.name not contains 'svc'`,
+		`ps.pid = 1024 and ps.name = 'svchost.exe'`,
+		`ps.pid > 1000 or ps.name = 'cmd.exe'`,
+		`ps.pid < 1000 or ps.name not in ('cmd.exe')`,
+		`ps.cwd = 'C:\\Windows' or ps.name = 'svchost.exe'`,
+		`net.dip = 10.0.2.15 and net.dport = 443`,
+		`net.dip in ('10.0.2.15', '192.168.1.1')`,
+		`net.dip = 10.0.0.0/8 and net.dip != 10.0.3.0/24`,
+		`net.dip in (192.168.0.0/16, 10.0.2.15)`,
+		`net.dip in private and net.dip not in loopback`,
+		`net.dip = fe80::/10 or net.dip in multicast`,
+		`kevt.time > now() - 5m`,
+		`ps.runtime < 1m`,
+		`lower(kevt.name) = 'createprocess' and length(ps.name) = 11`,
+		`regex(ps.comm, 'RPC') or ps.pid = 1`,
+		`any(pe.sections, s, s.name = '.text')`,
+		`any(pe.sections, s, s.entropy > 7 or s.name = ps.name)`,
+		`all(pe.sections, s, s.size >= 1024) and ps.pid = 1024`,
+		`all(pe.sections, s, s.name startswith '.r')`,
+		`any(ps.handles, h, h.name = 'cmd.exe')`,
+	}
+
+	for i, tt := range tests {
+		expr, err := NewParser(tt).ParseExpr()
+		require.NoError(t, err)
+		prog := Compile(expr)
+		v := &mapIndexedValuer{prog: prog, m: m, fetched: make(map[string]bool)}
+		if prog.Run(v) != Eval(expr, m) {
+			t.Errorf("%d. %q compiled program mismatch: exp=%t got=%t", i, tt, Eval(expr, m), !Eval(expr, m))
+		}
+	}
+}
+
+func TestCompileShortCircuit(t *testing.T) {
+	m := map[string]interface{}{
+		"ps.name": "svchost.exe",
+		"ps.pid":  uint32(1024),
+	}
+
+	var tests = []struct {
+		expr    string
+		fields  []string
+		fetched []string
+	}{
+		{`ps.name = 'cmd.exe' and ps.pid = 1024`, []string{"ps.name", "ps.pid"}, []string{"ps.name"}},
+		{`ps.name = 'svchost.exe' or ps.pid = 1024`, []string{"ps.name", "ps.pid"}, []string{"ps.name"}},
+		{`ps.name = 'svchost.exe' and ps.pid = 1024`, []string{"ps.name", "ps.pid"}, []string{"ps.name", "ps.pid"}},
+		{`ps.name = 'cmd.exe' or ps.name = 'svchost.exe'`, []string{"ps.name"}, []string{"ps.name"}},
+	}
+
+	for _, tt := range tests {
+		expr, err := NewParser(tt.expr).ParseExpr()
+		require.NoError(t, err)
+		prog := Compile(expr)
+		assert.Equal(t, tt.fields, prog.Fields())
+		v := &mapIndexedValuer{prog: prog, m: m, fetched: make(map[string]bool)}
+		prog.Run(v)
+		fetched := make([]string, 0)
+		for _, field := range prog.Fields() {
+			if v.fetched[field] {
+				fetched = append(fetched, field)
+			}
+		}
+		assert.Equal(t, tt.fetched, fetched, tt.expr)
+	}
+}
+
+func TestCompileQuantifier(t *testing.T) {
+	m := map[string]interface{}{
+		"ps.name": "svchost.exe",
+		"ps.modules": []Valuer{
+			MapValuer{"name": "kernel32.dll", "size": uint32(12354)},
+			MapValuer{"name": "clr.dll", "size": uint32(2048)},
+		},
+		"ps.handles":   []Valuer{},
+		"pe.resources": []Valuer(nil),
+	}
+
+	var tests = []struct {
+		expr    string
+		matches bool
+	}{
+		{`any(ps.modules, m, m.name endswith 'clr.dll')`, true},
+		{`any(ps.modules, m, m.name = 'ntdll.dll')`, false},
+		{`all(ps.modules, m, m.name endswith '.dll')`, true},
+		{`all(ps.modules, m, m.size > 4096)`, false},
+		{`any(ps.modules, m, m.name = 'clr.dll' and m.size > 4096)`, false},
+		{`any(ps.modules, m, m.location = 'C:\\Windows')`, false},
+		{`all(ps.handles, h, h.type = 'Mutant')`, false},
+		{`any(ps.handles, h, h.type = 'Mutant')`, false},
+		{`all(pe.resources, r, r.name = 'CompanyName')`, false},
+		{`any(pe.resources, r, r.name = 'CompanyName')`, false},
+		{`all(pe.sections, s, s.entropy < 7)`, false},
+		{`any(ps.modules, m, any(ps.handles, h, h.name = m.name) or m.size = 2048)`, true},
+	}
+
+	for _, tt := range tests {
+		expr, err := NewParser(tt.expr).ParseExpr()
+		require.NoError(t, err)
+		prog := Compile(expr)
+		v := &mapIndexedValuer{prog: prog, m: m, fetched: make(map[string]bool)}
+		assert.Equal(t, tt.matches, prog.Run(v), tt.expr)
+		assert.Equal(t, tt.matches, Eval(expr, m), tt.expr)
+	}
+}
+
+func TestCompileStringOperators(t *testing.T) {
+	m := map[string]interface{}{
+		"ps.name": "SvcHost.exe",
+		"ps.comm": "C:\\Windows\\system32\\svchost.exe -k RPCSS",
+		"ps.envs": []string{"ALLUSERSPROFILE", "OS"},
+	}
+
+	var tests = []struct {
+		expr    string
+		matches bool
+	}{
+		{`ps.name iin ('cmd.exe', 'svchost.exe')`, true},
+		{`ps.name in ('cmd.exe', 'svchost.exe')`, false},
+		{`ps.envs iin ('os')`, true},
+		{`ps.name istartswith 'svc'`, true},
+		{`ps.name istartswith ('cmd', 'SVCH')`, true},
+		{`ps.name iendswith '.EXE'`, true},
+		{`ps.name iendswith ('.dll', '.com')`, false},
+		{`ps.comm regex '-k\\s+RPC'`, true},
+		{`ps.comm regex '-k\\s+rpc'`, false},
+		{`ps.comm iregex '-k\\s+rpc'`, true},
+		{`ps.name regex ('^cmd', '^Svc.*\\.exe$')`, true},
+		{`ps.name iregex ('^lsass', '^svchost\\.com$')`, false},
+		{`ps.name fuzzy 'svchost.exe'`, true},
+		{`ps.name fuzzy 'svch0st.exe'`, true},
+		{`ps.name fuzzy 'scvh0st.exe'`, false},
+		{`ps.name fuzzy ('lsass.exe', 'svhost.exe')`, true},
+		{`ps.name fuzzy ('lsass.exe', 'svc.exe')`, false},
+	}
+
+	for _, tt := range tests {
+		expr, err := NewParser(tt.expr).ParseExpr()
+		require.NoError(t, err)
+		prog := Compile(expr)
+		v := &mapIndexedValuer{prog: prog, m: m, fetched: make(map[string]bool)}
+		assert.Equal(t, tt.matches, prog.Run(v), tt.expr)
+		assert.Equal(t, tt.matches, Eval(expr, m), tt.expr)
+	}
+}
+
+func TestCompileFuzzyDistance(t *testing.T) {
+	defer func(d int) { FuzzyDistance = d }(FuzzyDistance)
+	FuzzyDistance = 3
+
+	m := map[string]interface{}{"ps.name": "svchost.exe"}
+	for _, tt := range []struct {
+		expr    string
+		matches bool
+	}{
+		{`ps.name fuzzy 'scvh0st.exe'`, true},
+		{`ps.name fuzzy ('lsass.exe', 'svc.exe')`, false},
+		{`ps.name fuzzy 'ｓｖch0st.exe'`, true},
+	} {
+		expr, err := NewParser(tt.expr).ParseExpr()
+		require.NoError(t, err)
+		prog := Compile(expr)
+		v := &mapIndexedValuer{prog: prog, m: m, fetched: make(map[string]bool)}
+		assert.Equal(t, tt.matches, prog.Run(v), tt.expr)
+		assert.Equal(t, tt.matches, Eval(expr, m), tt.expr)
+	}
+}
+
+func TestCompileNetworks(t *testing.T) {
+	m := map[string]interface{}{
+		"net.dip": net.ParseIP("10.0.2.15"),
+		"net.sip": net.ParseIP("fe80::1ff:fe23:4567:890a"),
+	}
+
+	var tests = []struct {
+		expr    string
+		matches bool
+	}{
+		{`net.dip = 10.0.0.0/8`, true},
+		{`net.dip = 10.0.3.0/24`, false},
+		{`net.dip != 10.0.3.0/24`, true},
+		{`net.dip in (192.168.0.0/16, 10.0.2.15)`, true},
+		{`net.dip in ('192.168.0.0/16', '10.0.2.0/24')`, true},
+		{`net.dip in (172.16.0.0/12, 10.0.2.16)`, false},
+		{`net.dip in private`, true},
+		{`net.dip not in private`, false},
+		{`net.dip in (loopback, multicast)`, false},
+		{`net.sip = fe80::/10`, true},
+		{`net.sip in linklocal and net.sip not in private`, true},
+		{`net.sip = fe80::1ff:fe23:4567:890a`, true},
+	}
+
+	for _, tt := range tests {
+		expr, err := NewParser(tt.expr).ParseExpr()
+		require.NoError(t, err)
+		prog := Compile(expr)
+		v := &mapIndexedValuer{prog: prog, m: m, fetched: make(map[string]bool)}
+		assert.Equal(t, tt.matches, prog.Run(v), tt.expr)
+		assert.Equal(t, tt.matches, Eval(expr, m), tt.expr)
+	}
+}
+
+func BenchmarkEval(b *testing.B) {
+	b.ReportAllocs()
+	expr, err := NewParser(`ps.name in ('cmd.exe', 'powershell.exe') or ps.comm icontains 'rpcss' and ps.pid > 1000`).ParseExpr()
+	require.NoError(b, err)
+	m := map[string]interface{}{
+		"ps.name": "svchost.exe",
+		"ps.comm": "C:\\Windows\\system32\\svchost.exe -k RPCSS",
+		"ps.pid":  uint32(1024),
+	}
+
+	b.Run("valuer", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Eval(expr, m)
+		}
+	})
+
+	b.Run("compiled", func(b *testing.B) {
+		prog := Compile(expr)
+		v := &mapIndexedValuer{prog: prog, m: m, fetched: make(map[string]bool)}
+		for i := 0; i < b.N; i++ {
+			prog.Run(v)
+		}
+	})
+}
+
+func TestRegexCache(t *testing.T) {
+	expr, err := NewParser(`ps.name iregex '^svchost-literal'`).ParseExpr()
+	require.NoError(t, err)
+	Compile(expr)
+	_, ok := regexps.Load("(?i)^svchost-literal")
+	assert.True(t, ok)
+
+	// patterns that aren't given in filters, e.g. resolved from field values, aren't cached
+	re, err := compileRegex("^svchost-runtime", false)
+	require.NoError(t, err)
+	assert.True(t, re.MatchString("svchost-runtime.exe"))
+	_, ok = regexps.Load("^svchost-runtime")
+	assert.False(t, ok)
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDurationEval(t *testing.T) {
+	ts := time.Now().Add(-time.Minute * 5)
+	m := map[string]interface{}{
+		"kevt.time":  ts,
+		"ps.runtime": time.Second * 3,
+	}
+
+	var tests = []struct {
+		expr    string
+		matches bool
+	}{
+		{`kevt.time > now() - 10m`, true},
+		{`kevt.time > now() - 2m`, false},
+		{`kevt.time < now() - 250ms`, true},
+		{`now() - 10m < kevt.time`, true},
+		{`kevt.time + 1h > now()`, true},
+		{`kevt.time = kevt.time`, true},
+		{`ps.runtime < 2s`, false},
+		{`ps.runtime >= 3000ms`, true},
+		{`ps.runtime + 1m = 63s`, true},
+		{`ps.runtime < 1d and ps.runtime > 1s`, true},
+		{`ps.runtime > 1m or kevt.time > now() - 1h30m`, true},
+		{`kevt.time = '` + ts.Format("15:04:05") + `'`, true},
+		{`kevt.time > '` + ts.Add(-time.Hour).Format(time.RFC3339) + `'`, true},
+	}
+
+	for i, tt := range tests {
+		expr, err := NewParser(tt.expr).ParseExpr()
+		require.NoError(t, err)
+		matches := Eval(expr, m)
+		if matches != tt.matches {
+			t.Errorf("%d. %q duration mismatch: exp=%t got=%t", i, tt.expr, tt.matches, matches)
+		}
+	}
+}
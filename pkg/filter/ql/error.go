@@ -30,6 +30,8 @@ type ParseError struct {
 	Found    string
 	Expected []string
 	Pos      int
+	// macro indicates the error occurred while expanding the macro
+	macro bool
 }
 
 // newParseError returns a new instance of ParseError.
@@ -39,9 +41,10 @@ func newParseError(found string, expected []string, pos int, expr string) *Parse
 
 // Error returns the string representation of the error.
 func (e *ParseError) Error() string {
-	if e.Message != "" {
+	if e.Message != "" && e.Expr == "" {
 		return fmt.Sprintf("%s at line %d, char %d", e.Message, e.Pos+1, e.Pos+1)
 	}
+	msg := e.msg()
 	l := e.Pos + 1
 	var sb strings.Builder
 	sb.WriteString(e.Expr)
@@ -50,8 +53,16 @@ func (e *ParseError) Error() string {
 		l--
 		sb.WriteRune(' ')
 		if l == 0 {
-			sb.WriteString(fmt.Sprintf("^ expected %s", strings.Join(e.Expected, ", ")))
+			sb.WriteString("^ " + msg)
 		}
 	}
 	return sb.String()
 }
+
+// msg returns the error message without the expression.
+func (e *ParseError) msg() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf("expected %s", strings.Join(e.Expected, ", "))
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Normalize renders the expression by enclosing every operand that is itself a binary
+// expression in parentheses, so the operator precedence applied by the parser is explicit.
+func Normalize(expr Expr) string {
+	switch expr := expr.(type) {
+	case *ParenExpr:
+		return Normalize(expr.Expr)
+	case *BinaryExpr:
+		return fmt.Sprintf("%s %s %s", normalizeOperand(expr.LHS), expr.Op.String(), normalizeOperand(expr.RHS))
+	case *NotExpr:
+		bexpr, ok := expr.Expr.(*BinaryExpr)
+		if !ok {
+			return expr.String()
+		}
+		return fmt.Sprintf("%s %s %s %s", normalizeOperand(bexpr.LHS), not.String(), bexpr.Op.String(), normalizeOperand(bexpr.RHS))
+	case *Function:
+		args := make([]string, len(expr.Args))
+		for i, arg := range expr.Args {
+			args[i] = Normalize(arg)
+		}
+		return fmt.Sprintf("%s(%s)", expr.Name, strings.Join(args, ", "))
+	case *Quantifier:
+		return fmt.Sprintf("%s(%s, %s, %s)", expr.Name, expr.Field.String(), expr.Var, Normalize(expr.Expr))
+	case *StringLiteral:
+		return Quote(expr.Value)
+	case *ListLiteral:
+		vals := make([]string, len(expr.Values))
+		for i, val := range expr.Values {
+			vals[i] = Quote(val)
+		}
+		return fmt.Sprintf("(%s)", strings.Join(vals, ", "))
+	default:
+		return expr.String()
+	}
+}
+
+func normalizeOperand(expr Expr) string {
+	expr = unparen(expr)
+	switch expr.(type) {
+	case *BinaryExpr, *NotExpr:
+		return fmt.Sprintf("(%s)", Normalize(expr))
+	}
+	return Normalize(expr)
+}
+
+// Tree renders the expression as the tree of logical operators. The leaves are
+// the predicates joined by the operators.
+func Tree(expr Expr) string {
+	var b strings.Builder
+	writeTree(&b, expr, "", "")
+	return b.String()
+}
+
+func writeTree(b *strings.Builder, expr Expr, prefix, childPrefix string) {
+	expr = unparen(expr)
+	bexpr, ok := expr.(*BinaryExpr)
+	if !ok || (bexpr.Op != and && bexpr.Op != or) {
+		b.WriteString(prefix + Normalize(expr) + "\n")
+		return
+	}
+	b.WriteString(prefix + bexpr.Op.String() + "\n")
+	writeTree(b, bexpr.LHS, childPrefix+"├── ", childPrefix+"│   ")
+	writeTree(b, bexpr.RHS, childPrefix+"└── ", childPrefix+"    ")
+}
+
+// Mismatches evaluates the expression using the valuer and returns the predicates
+// that prevent the expression from matching. If the expression matches, the nil
+// slice is returned. Contrary to the evaluation, the operands of the and operator
+// are not short-circuited, so all of the offending predicates are reported.
+func Mismatches(expr Expr, valuer Valuer) []Expr {
+	eval := ValuerEval{Valuer: valuer}
+	if isTrue(eval, expr) {
+		return nil
+	}
+	return mismatches(eval, expr)
+}
+
+func mismatches(eval ValuerEval, expr Expr) []Expr {
+	expr = unparen(expr)
+	bexpr, ok := expr.(*BinaryExpr)
+	if !ok || (bexpr.Op != and && bexpr.Op != or) {
+		return []Expr{expr}
+	}
+	exprs := make([]Expr, 0)
+	for _, e := range []Expr{bexpr.LHS, bexpr.RHS} {
+		if !isTrue(eval, e) {
+			exprs = append(exprs, mismatches(eval, e)...)
+		}
+	}
+	return exprs
+}
+
+func isTrue(eval ValuerEval, expr Expr) bool {
+	v, ok := eval.Eval(expr).(bool)
+	return ok && v
+}
+
+// unparen strips the parentheses enclosing the expression.
+func unparen(expr Expr) Expr {
+	for {
+		paren, ok := expr.(*ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = paren.Expr
+	}
+}
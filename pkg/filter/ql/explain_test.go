@@ -0,0 +1,92 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	var tests = []struct {
+		expr       string
+		normalized string
+	}{
+		{`ps.name = 'cmd.exe'`, `ps.name = 'cmd.exe'`},
+		{`ps.name = 'cmd.exe' or ps.pid > 4 and ps.ppid = 1`, `(ps.name = 'cmd.exe') OR ((ps.pid > 4) AND (ps.ppid = 1))`},
+		{`(ps.name = 'cmd.exe' or ps.pid > 4) and ps.ppid = 1`, `((ps.name = 'cmd.exe') OR (ps.pid > 4)) AND (ps.ppid = 1)`},
+		{`ps.name not contains 'svc' and length(ps.comm) > 20`, `(ps.name NOT CONTAINS 'svc') AND (length(ps.comm) > 20)`},
+		{`ps.cwd = 'C:\\Windows' or ps.cwd in ('C:\\Temp')`, `(ps.cwd = 'C:\\Windows') OR (ps.cwd IN ('C:\\Temp'))`},
+		{`any(ps.modules, m, m.name = 'clr.dll' or m.size > 4096)`, `any(ps.modules, m, (m.name = 'clr.dll') OR (m.size > 4096))`},
+	}
+
+	for _, tt := range tests {
+		expr, err := NewParser(tt.expr).ParseExpr()
+		require.NoError(t, err)
+		assert.Equal(t, tt.normalized, Normalize(expr))
+		// the normalized expression is parsed into the same tree
+		nexpr, err := NewParser(Normalize(expr)).ParseExpr()
+		require.NoError(t, err)
+		assert.Equal(t, tt.normalized, Normalize(nexpr))
+	}
+}
+
+func TestTree(t *testing.T) {
+	expr, err := NewParser(`ps.name = 'cmd.exe' or ps.pid > 4 and (ps.ppid = 1 or ps.sid contains 'SYSTEM')`).ParseExpr()
+	require.NoError(t, err)
+	assert.Equal(t, `OR
+├── ps.name = 'cmd.exe'
+└── AND
+    ├── ps.pid > 4
+    └── OR
+        ├── ps.ppid = 1
+        └── ps.sid CONTAINS 'SYSTEM'
+`, Tree(expr))
+}
+
+func TestMismatches(t *testing.T) {
+	m := MapValuer{
+		"ps.name": "svchost.exe",
+		"ps.pid":  uint32(1024),
+		"ps.ppid": uint32(345),
+	}
+
+	var tests = []struct {
+		expr       string
+		mismatches []string
+	}{
+		{`ps.name = 'svchost.exe'`, nil},
+		{`ps.name = 'cmd.exe'`, []string{`ps.name = 'cmd.exe'`}},
+		{`ps.name = 'cmd.exe' and ps.pid > 4 and ps.ppid = 1`, []string{`ps.name = 'cmd.exe'`, `ps.ppid = 1`}},
+		{`ps.name = 'cmd.exe' or (ps.pid < 4 and ps.ppid = 345)`, []string{`ps.name = 'cmd.exe'`, `ps.pid < 4`}},
+		{`ps.name = 'cmd.exe' or ps.pid > 4`, nil},
+		{`ps.name in ('cmd.exe', 'lsass.exe')`, []string{`ps.name IN ('cmd.exe', 'lsass.exe')`}},
+	}
+
+	for _, tt := range tests {
+		expr, err := NewParser(tt.expr).ParseExpr()
+		require.NoError(t, err)
+		var mismatches []string
+		for _, e := range Mismatches(expr, m) {
+			mismatches = append(mismatches, Normalize(e))
+		}
+		assert.Equal(t, tt.mismatches, mismatches, tt.expr)
+	}
+}
@@ -18,7 +18,11 @@
 
 package ql
 
-import "fmt"
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql/functions"
+	"strings"
+)
 
 // Node represents a node in the abstract syntax tree.
 type Node interface {
@@ -57,3 +61,44 @@ type NotExpr struct {
 
 // String returns a string representation of the not expression.
 func (e *NotExpr) String() string { return fmt.Sprintf("(%s)", e.Expr.String()) }
+
+// Function represents the function call expression.
+type Function struct {
+	Name string
+	Args []Expr
+	Fn   functions.FunctionDef
+}
+
+// String returns a string representation of the function call expression.
+func (f *Function) String() string {
+	args := make([]string, len(f.Args))
+	for i, arg := range f.Args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("%s(%s)", f.Name, strings.Join(args, ", "))
+}
+
+// Quantifier represents the any/all quantifier expression. The quantifier binds
+// each element of the multi-valued field to the variable and evaluates the
+// predicate against it. The any quantifier is satisfied if the predicate holds
+// for at least one element, while the all quantifier requires every element to
+// satisfy the predicate.
+type Quantifier struct {
+	Name  string
+	Field *FieldLiteral
+	Var   string
+	Expr  Expr
+}
+
+// String returns a string representation of the quantifier expression.
+func (q *Quantifier) String() string {
+	return fmt.Sprintf("%s(%s, %s, %s)", q.Name, q.Field.String(), q.Var, q.Expr.String())
+}
+
+// isAll determines if the quantifier requires all elements to satisfy the predicate.
+func (q *Quantifier) isAll() bool { return q.Name == allQuantifier }
+
+const (
+	anyQuantifier = "any"
+	allQuantifier = "all"
+)
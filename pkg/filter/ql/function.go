@@ -0,0 +1,103 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql/functions"
+	"sort"
+	"strings"
+)
+
+// funcs contains the registry of built-in functions. Each call site
+// gets its own function instance, so functions are free to keep the
+// state such as compiled regular expressions.
+var funcs = map[string]func() functions.FunctionDef{
+	functions.LowerFn.String():  func() functions.FunctionDef { return &functions.Lower{} },
+	functions.BaseFn.String():   func() functions.FunctionDef { return &functions.Base{} },
+	functions.DirFn.String():    func() functions.FunctionDef { return &functions.Dir{} },
+	functions.ExtFn.String():    func() functions.FunctionDef { return &functions.Ext{} },
+	functions.LengthFn.String(): func() functions.FunctionDef { return &functions.Length{} },
+	functions.ConcatFn.String(): func() functions.FunctionDef { return &functions.Concat{} },
+	functions.RegexFn.String():  func() functions.FunctionDef { return functions.NewRegex() },
+	functions.NowFn.String():    func() functions.FunctionDef { return &functions.Now{} },
+	functions.IOCFn.String():    func() functions.FunctionDef { return &functions.IOC{} },
+}
+
+// Functions returns the descriptors of all built-in functions sorted by name.
+func Functions() []functions.FunctionDesc {
+	descs := make([]functions.FunctionDesc, 0, len(funcs))
+	for _, fn := range funcs {
+		descs = append(descs, fn().Desc())
+	}
+	sort.Slice(descs, func(i, j int) bool { return descs[i].Name.String() < descs[j].Name.String() })
+	return descs
+}
+
+// lookupFunction finds the built-in function by its name. Function names are case-insensitive.
+func lookupFunction(name string) (functions.FunctionDef, bool) {
+	fn, ok := funcs[strings.ToLower(name)]
+	if !ok {
+		return nil, false
+	}
+	return fn(), true
+}
+
+// validate checks the function call arity and ensures argument types are accepted by the function.
+func (f *Function) validate() error {
+	desc := f.Fn.Desc()
+	nargs := len(f.Args)
+	if nargs < desc.RequiredArgs() {
+		return fmt.Errorf("%s function requires at least %d argument(s) but %d given", f.Name, desc.RequiredArgs(), nargs)
+	}
+	if nargs > len(desc.Args) && !desc.Variadic {
+		return fmt.Errorf("%s function accepts at most %d argument(s) but %d given", f.Name, len(desc.Args), nargs)
+	}
+	for i, arg := range f.Args {
+		// arguments beyond the declared ones are
+		// checked against the last argument descriptor
+		argDesc := desc.Args[len(desc.Args)-1]
+		if i < len(desc.Args) {
+			argDesc = desc.Args[i]
+		}
+		typ := argType(arg)
+		if !argDesc.ContainsType(typ) {
+			types := make([]string, len(argDesc.Types))
+			for i, t := range argDesc.Types {
+				types[i] = t.String()
+			}
+			return fmt.Errorf("argument #%d (%s) in function %s should be one of: %s", i+1, argDesc.Keyword, f.Name, strings.Join(types, "|"))
+		}
+	}
+	return nil
+}
+
+func argType(expr Expr) functions.ArgType {
+	switch expr.(type) {
+	case *FieldLiteral:
+		return functions.Field
+	case *StringLiteral:
+		return functions.String
+	case *IntegerLiteral, *UnsignedLiteral, *DecimalLiteral:
+		return functions.Number
+	case *Function:
+		return functions.Func
+	}
+	return functions.Unknown
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/filter/config"
+	"github.com/rabbitstack/fibratus/pkg/ioc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFunctionEval(t *testing.T) {
+	m := map[string]interface{}{
+		"ps.name":   "SVCHOST.exe",
+		"ps.exe":    "C:/Windows/System32/svchost.exe",
+		"ps.comm":   "C:/Windows/System32/svchost.exe -k RPCSS",
+		"ps.args":   []string{"-k", "RPCSS"},
+		"kevt.name": "CreateProcess",
+		"ps.pid":    uint32(1024),
+	}
+
+	var tests = []struct {
+		expr    string
+		matches bool
+	}{
+		{`lower(ps.name) = 'svchost.exe'`, true},
+		{`lower(ps.name) = 'SVCHOST.exe'`, false},
+		{`base(ps.exe) in ('cmd.exe', 'svchost.exe')`, true},
+		{`ext(ps.exe) = '.exe'`, true},
+		{`length(ps.comm) > 20`, true},
+		{`length(ps.args) = 2`, true},
+		{`length(ps.cwd) = 0`, false},
+		{`concat(ps.name, ':', kevt.name) = 'SVCHOST.exe:CreateProcess'`, true},
+		{`concat(ps.name, ':', ps.pid) = 'SVCHOST.exe:1024'`, true},
+		{`lower(base(ps.exe)) = 'svchost.exe'`, true},
+		{`regex(ps.name, '^svc', '^SVC.*\\.exe$')`, true},
+		{`regex(ps.name, '^cmd')`, false},
+		{`regex(ps.name, '^SVC') and kevt.name = 'CreateProcess'`, true},
+		{`kevt.name = 'CreateProcess' and (lower(ps.name) contains 'svc')`, true},
+	}
+
+	for i, tt := range tests {
+		expr, err := NewParser(tt.expr).ParseExpr()
+		require.NoError(t, err)
+		matches := Eval(expr, m)
+		if matches != tt.matches {
+			t.Errorf("%d. %q function mismatch: exp=%t got=%t", i, tt.expr, tt.matches, matches)
+		}
+	}
+}
+
+func TestFunctionValidation(t *testing.T) {
+	var tests = []struct {
+		expr string
+		err  string
+	}{
+		{`lowr(ps.name) = 'cmd.exe'`, "lowr(ps.name) = 'cmd.exe'\n ^ undefined function lowr"},
+		{`ps.name = 'cmd.exe' or lower(ps.name, ps.exe) = 'cmd.exe'`, "ps.name = 'cmd.exe' or lower(ps.name, ps.exe) = 'cmd.exe'\n" +
+			"                        ^ lower function accepts at most 1 argument(s) but 2 given"},
+		{`concat(ps.name) = 'cmd.exe'`, "concat(ps.name) = 'cmd.exe'\n ^ concat function requires at least 2 argument(s) but 1 given"},
+		{`regex(ps.name, ps.exe)`, "regex(ps.name, ps.exe)\n ^ argument #2 (pattern) in function regex should be one of: string"},
+		{`base(1) = 'cmd.exe'`, "base(1) = 'cmd.exe'\n ^ argument #1 (path) in function base should be one of: field|string|function"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewParser(tt.expr).ParseExpr()
+		require.Error(t, err)
+		assert.Equal(t, tt.err, err.Error())
+	}
+}
+
+func TestIOCFunction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "iocs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "c2.txt"), []byte("185.220.101.4\n10.0.2.0/24\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "paths.txt"), []byte("C:\\Users\\Public\\\n"), 0644))
+
+	cfg := &config.Config{IOCs: []ioc.Definition{
+		{Name: "c2_ips", Type: "ip", Path: filepath.Join(dir, "c2.txt")},
+		{Name: "bad_paths", Type: "path", Path: filepath.Join(dir, "paths.txt")},
+	}}
+	require.NoError(t, cfg.OpenIOCs())
+	defer cfg.CloseIOCs()
+	m := map[string]interface{}{
+		"net.dip":   net.ParseIP("10.0.2.15"),
+		"net.sip":   net.ParseIP("192.168.1.2"),
+		"file.name": "C:\\Users\\Public\\payload.dll",
+	}
+
+	var tests = []struct {
+		expr    string
+		matches bool
+	}{
+		{`net.dip in ioc('c2_ips')`, true},
+		{`net.sip in ioc('c2_ips')`, false},
+		{`net.sip not in ioc('c2_ips') and file.name iin ioc('bad_paths')`, true},
+		{`file.name in ioc('c2_ips')`, false},
+		{`ps.name in ioc('bad_paths')`, false},
+	}
+
+	for _, tt := range tests {
+		p := NewParserWithConfig(tt.expr, cfg)
+		expr, err := p.ParseExpr()
+		require.NoError(t, err)
+		require.NoError(t, p.Validate(expr))
+		prog := Compile(expr)
+		v := &mapIndexedValuer{prog: prog, m: m, fetched: make(map[string]bool)}
+		assert.Equal(t, tt.matches, prog.Run(v), tt.expr)
+		assert.Equal(t, tt.matches, Eval(expr, m), tt.expr)
+	}
+
+	var errs = []struct {
+		expr string
+		err  string
+	}{
+		{`net.dip in ioc('c2')`, "net.dip in ioc('c2')\n            ^ undefined IOC list c2"},
+		{`net.dip in ioc(net.sip)`, "net.dip in ioc(net.sip)\n            ^ argument #1 (list) in function ioc should be one of: string"},
+		{`file.name contains ioc('bad_paths')`, "file.name contains ioc('bad_paths')\n                    ^ IOC list can only be matched with in or iin operators"},
+	}
+
+	for _, tt := range errs {
+		p := NewParserWithConfig(tt.expr, cfg)
+		expr, err := p.ParseExpr()
+		if err == nil {
+			err = p.Validate(expr)
+		}
+		require.Error(t, err)
+		assert.Equal(t, tt.err, err.Error())
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+)
+
+// Indicators is the indicator of compromise list the values are matched against.
+type Indicators interface {
+	// Contains determines whether the value matches any of the indicators.
+	Contains(val interface{}) bool
+}
+
+// IOC yields the indicator of compromise list given by its name. The list is bound
+// to the function when the expression is parsed and is only meaningful on the right
+// side of the in and iin operators.
+type IOC struct {
+	List Indicators
+}
+
+func (f *IOC) Call(args []interface{}) (interface{}, bool) {
+	if f.List == nil {
+		return nil, false
+	}
+	return f.List, true
+}
+
+func (f *IOC) Desc() FunctionDesc {
+	return FunctionDesc{
+		Name:       IOCFn,
+		ReturnType: kparams.Slice,
+		Args: []FunctionArgDesc{
+			{Keyword: "list", Types: []ArgType{String}, Required: true},
+		},
+	}
+}
+
+func (f *IOC) Name() Fn { return IOCFn }
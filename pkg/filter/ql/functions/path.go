@@ -0,0 +1,85 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"path/filepath"
+)
+
+// Base returns the last element of the path.
+type Base struct{}
+
+func (f Base) Call(args []interface{}) (interface{}, bool) {
+	path, ok := pathArg(args)
+	if !ok {
+		return false, false
+	}
+	return filepath.Base(path), true
+}
+
+func (f Base) Desc() FunctionDesc {
+	return FunctionDesc{Name: BaseFn, Args: []FunctionArgDesc{pathArgDesc}, ReturnType: kparams.UnicodeString}
+}
+
+func (f Base) Name() Fn { return BaseFn }
+
+// Dir returns all but the last element of the path.
+type Dir struct{}
+
+func (f Dir) Call(args []interface{}) (interface{}, bool) {
+	path, ok := pathArg(args)
+	if !ok {
+		return false, false
+	}
+	return filepath.Dir(path), true
+}
+
+func (f Dir) Desc() FunctionDesc {
+	return FunctionDesc{Name: DirFn, Args: []FunctionArgDesc{pathArgDesc}, ReturnType: kparams.UnicodeString}
+}
+
+func (f Dir) Name() Fn { return DirFn }
+
+// Ext returns the file name extension including the leading dot.
+type Ext struct{}
+
+func (f Ext) Call(args []interface{}) (interface{}, bool) {
+	path, ok := pathArg(args)
+	if !ok {
+		return false, false
+	}
+	return filepath.Ext(path), true
+}
+
+func (f Ext) Desc() FunctionDesc {
+	return FunctionDesc{Name: ExtFn, Args: []FunctionArgDesc{pathArgDesc}, ReturnType: kparams.UnicodeString}
+}
+
+func (f Ext) Name() Fn { return ExtFn }
+
+var pathArgDesc = FunctionArgDesc{Keyword: "path", Types: []ArgType{Field, String, Func}, Required: true}
+
+func pathArg(args []interface{}) (string, bool) {
+	if len(args) != 1 {
+		return "", false
+	}
+	path, ok := args[0].(string)
+	return path, ok
+}
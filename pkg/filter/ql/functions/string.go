@@ -0,0 +1,185 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Lower converts the string to lower case.
+type Lower struct{}
+
+func (f Lower) Call(args []interface{}) (interface{}, bool) {
+	if len(args) != 1 {
+		return false, false
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return false, false
+	}
+	return strings.ToLower(s), true
+}
+
+func (f Lower) Desc() FunctionDesc {
+	return FunctionDesc{
+		Name:       LowerFn,
+		ReturnType: kparams.UnicodeString,
+		Args: []FunctionArgDesc{
+			{Keyword: "string", Types: []ArgType{Field, String, Func}, Required: true},
+		},
+	}
+}
+
+func (f Lower) Name() Fn { return LowerFn }
+
+// Length returns the number of characters in the string or the number of items in the list.
+type Length struct{}
+
+func (f Length) Call(args []interface{}) (interface{}, bool) {
+	if len(args) != 1 {
+		return false, false
+	}
+	switch v := args[0].(type) {
+	case string:
+		return int64(utf8.RuneCountInString(v)), true
+	case []string:
+		return int64(len(v)), true
+	}
+	return false, false
+}
+
+func (f Length) Desc() FunctionDesc {
+	return FunctionDesc{
+		Name:       LengthFn,
+		ReturnType: kparams.Int64,
+		Args: []FunctionArgDesc{
+			{Keyword: "string|list", Types: []ArgType{Field, String, Func}, Required: true},
+		},
+	}
+}
+
+func (f Length) Name() Fn { return LengthFn }
+
+// Concat joins string or number arguments into a single string.
+type Concat struct{}
+
+func (f Concat) Call(args []interface{}) (interface{}, bool) {
+	if len(args) < 2 {
+		return false, false
+	}
+	var sb strings.Builder
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			sb.WriteString(v)
+		case nil:
+			return false, false
+		default:
+			sb.WriteString(fmt.Sprintf("%v", v))
+		}
+	}
+	return sb.String(), true
+}
+
+func (f Concat) Desc() FunctionDesc {
+	return FunctionDesc{
+		Name:       ConcatFn,
+		ReturnType: kparams.UnicodeString,
+		Args: []FunctionArgDesc{
+			{Keyword: "string", Types: []ArgType{Field, String, Number, Func}, Required: true},
+			{Keyword: "string", Types: []ArgType{Field, String, Number, Func}, Required: true},
+		},
+		Variadic: true,
+	}
+}
+
+func (f Concat) Name() Fn { return ConcatFn }
+
+// Regex applies one or more regular expressions on the string argument and
+// evaluates to true if any of the expressions match. Compiled expressions are
+// cached on the first call.
+type Regex struct {
+	mu    sync.RWMutex
+	cache map[string]*regexp.Regexp
+}
+
+// NewRegex creates a new regex function.
+func NewRegex() *Regex {
+	return &Regex{cache: make(map[string]*regexp.Regexp)}
+}
+
+func (f *Regex) Call(args []interface{}) (interface{}, bool) {
+	if len(args) < 2 {
+		return false, false
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return false, false
+	}
+	for _, arg := range args[1:] {
+		pat, ok := arg.(string)
+		if !ok {
+			continue
+		}
+		re, err := f.compile(pat)
+		if err != nil {
+			continue
+		}
+		if re.MatchString(s) {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+func (f *Regex) compile(pat string) (*regexp.Regexp, error) {
+	f.mu.RLock()
+	re, ok := f.cache[pat]
+	f.mu.RUnlock()
+	if ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pat)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	f.cache[pat] = re
+	f.mu.Unlock()
+	return re, nil
+}
+
+func (f *Regex) Desc() FunctionDesc {
+	return FunctionDesc{
+		Name:       RegexFn,
+		ReturnType: kparams.Bool,
+		Args: []FunctionArgDesc{
+			{Keyword: "string", Types: []ArgType{Field, String, Func}, Required: true},
+			{Keyword: "pattern", Types: []ArgType{String}, Required: true},
+		},
+		Variadic: true,
+	}
+}
+
+func (f *Regex) Name() Fn { return RegexFn }
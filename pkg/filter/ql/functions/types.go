@@ -0,0 +1,156 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package functions
+
+import "github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+
+// Fn is the type alias for the built-in function identifiers.
+type Fn uint16
+
+const (
+	// LowerFn converts the string to lower case
+	LowerFn Fn = iota + 1
+	// BaseFn returns the last element of the path
+	BaseFn
+	// DirFn returns all but the last element of the path
+	DirFn
+	// ExtFn returns the file name extension
+	ExtFn
+	// LengthFn returns the number of characters in a string or the number of elements in a list
+	LengthFn
+	// ConcatFn concatenates string/number arguments
+	ConcatFn
+	// RegexFn applies regular expressions on the string and returns true if any of the expressions match
+	RegexFn
+	// NowFn returns the current local time
+	NowFn
+	// IOCFn yields the indicator of compromise list matched by the in operators
+	IOCFn
+)
+
+// ArgType is the type alias for the function argument type.
+type ArgType uint8
+
+const (
+	// Field represents the field argument type (e.g. ps.name)
+	Field ArgType = iota
+	// String represents the string literal argument type
+	String
+	// Number represents the integer or decimal literal argument type
+	Number
+	// Func represents the nested function call argument type
+	Func
+	// Unknown represents an unknown argument type
+	Unknown
+)
+
+// String returns the argument type name.
+func (typ ArgType) String() string {
+	switch typ {
+	case Field:
+		return "field"
+	case String:
+		return "string"
+	case Number:
+		return "number"
+	case Func:
+		return "function"
+	}
+	return "unknown"
+}
+
+// String returns the function name.
+func (f Fn) String() string {
+	switch f {
+	case LowerFn:
+		return "lower"
+	case BaseFn:
+		return "base"
+	case DirFn:
+		return "dir"
+	case ExtFn:
+		return "ext"
+	case LengthFn:
+		return "length"
+	case ConcatFn:
+		return "concat"
+	case RegexFn:
+		return "regex"
+	case NowFn:
+		return "now"
+	case IOCFn:
+		return "ioc"
+	}
+	return "unknown"
+}
+
+// FunctionArgDesc describes the function argument.
+type FunctionArgDesc struct {
+	// Keyword is the argument name as shown in error messages
+	Keyword string
+	// Types contains the argument types accepted by the function
+	Types []ArgType
+	// Required indicates whether the argument is mandatory
+	Required bool
+}
+
+// ContainsType determines if the argument accepts the specified type.
+func (arg FunctionArgDesc) ContainsType(typ ArgType) bool {
+	for _, t := range arg.Types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// FunctionDesc contains the function signature.
+type FunctionDesc struct {
+	// Name is the function name
+	Name Fn
+	// Args represents the function argument descriptors
+	Args []FunctionArgDesc
+	// Variadic indicates the last argument can be repeated an arbitrary number of times
+	Variadic bool
+	// ReturnType is the type of the value produced by the function
+	ReturnType kparams.Type
+}
+
+// RequiredArgs returns the number of mandatory function arguments.
+func (d FunctionDesc) RequiredArgs() int {
+	var n int
+	for _, arg := range d.Args {
+		if arg.Required {
+			n++
+		}
+	}
+	return n
+}
+
+// FunctionDef is the interface that all built-in functions have to satisfy.
+type FunctionDef interface {
+	// Call executes the function with the given arguments. The boolean return
+	// value indicates whether the function was able to produce a result for
+	// the given arguments.
+	Call(args []interface{}) (interface{}, bool)
+	// Desc returns the function descriptor.
+	Desc() FunctionDesc
+	// Name returns the function identifier.
+	Name() Fn
+}
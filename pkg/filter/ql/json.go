@@ -0,0 +1,322 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// jsonNode is the JSON representation of the expression node. The type attribute
+// identifies the node, and the rest of the attributes depend on the node type:
+//
+//	binary     {"type": "binary", "op": "and", "lhs": {...}, "rhs": {...}}
+//	not        {"type": "not", "expr": {"type": "binary", ...}}
+//	paren      {"type": "paren", "expr": {...}}
+//	function   {"type": "function", "name": "lower", "args": [{...}]}
+//	quantifier {"type": "quantifier", "name": "any", "field": "ps.modules", "var": "m", "expr": {...}}
+//	field      {"type": "field", "value": "ps.name"}
+//	element    {"type": "element", "var": "m", "subfield": "name", "field": "ps.modules"}
+//	string     {"type": "string", "value": "cmd.exe"}
+//	integer    {"type": "integer", "value": -1}
+//	unsigned   {"type": "unsigned", "value": 18446744073709551615}
+//	decimal    {"type": "decimal", "value": 7.5}
+//	ip         {"type": "ip", "value": "10.0.2.15"}
+//	cidr       {"type": "cidr", "value": "10.0.0.0/8"}
+//	duration   {"type": "duration", "value": "5m0s"}
+//	list       {"type": "list", "values": ["cmd.exe", "powershell.exe"]}
+//
+// Binary operators are given in lowercase, e.g. and, or, =, !=, in, iin, icontains.
+// The not expression negates the binary expression, e.g. ps.name not in ('cmd.exe').
+type jsonNode struct {
+	Type     string      `json:"type"`
+	Op       string      `json:"op,omitempty"`
+	LHS      *jsonNode   `json:"lhs,omitempty"`
+	RHS      *jsonNode   `json:"rhs,omitempty"`
+	Expr     *jsonNode   `json:"expr,omitempty"`
+	Name     string      `json:"name,omitempty"`
+	Args     []*jsonNode `json:"args,omitempty"`
+	Field    string      `json:"field,omitempty"`
+	Var      string      `json:"var,omitempty"`
+	Subfield string      `json:"subfield,omitempty"`
+	Value    interface{} `json:"value,omitempty"`
+	Values   []string    `json:"values,omitempty"`
+}
+
+// MarshalJSON encodes the binary expression to JSON.
+func (e *BinaryExpr) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(e)) }
+
+// MarshalJSON encodes the not expression to JSON.
+func (e *NotExpr) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(e)) }
+
+// MarshalJSON encodes the parenthesized expression to JSON.
+func (e *ParenExpr) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(e)) }
+
+// MarshalJSON encodes the function call expression to JSON.
+func (f *Function) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(f)) }
+
+// MarshalJSON encodes the quantifier expression to JSON.
+func (q *Quantifier) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(q)) }
+
+// MarshalJSON encodes the field literal to JSON.
+func (f FieldLiteral) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(&f)) }
+
+// MarshalJSON encodes the element literal to JSON.
+func (e ElementLiteral) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(&e)) }
+
+// MarshalJSON encodes the string literal to JSON.
+func (s StringLiteral) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(&s)) }
+
+// MarshalJSON encodes the integer literal to JSON.
+func (i IntegerLiteral) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(&i)) }
+
+// MarshalJSON encodes the unsigned literal to JSON.
+func (u UnsignedLiteral) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(&u)) }
+
+// MarshalJSON encodes the decimal literal to JSON.
+func (d DecimalLiteral) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(&d)) }
+
+// MarshalJSON encodes the IP literal to JSON.
+func (i IPLiteral) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(&i)) }
+
+// MarshalJSON encodes the CIDR literal to JSON.
+func (c CIDRLiteral) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(&c)) }
+
+// MarshalJSON encodes the duration literal to JSON.
+func (d DurationLiteral) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(&d)) }
+
+// MarshalJSON encodes the list literal to JSON.
+func (s *ListLiteral) MarshalJSON() ([]byte, error) { return json.Marshal(toJSON(s)) }
+
+func toJSON(expr Expr) *jsonNode {
+	switch expr := expr.(type) {
+	case *BinaryExpr:
+		return &jsonNode{Type: "binary", Op: strings.ToLower(expr.Op.String()), LHS: toJSON(expr.LHS), RHS: toJSON(expr.RHS)}
+	case *NotExpr:
+		return &jsonNode{Type: "not", Expr: toJSON(expr.Expr)}
+	case *ParenExpr:
+		return &jsonNode{Type: "paren", Expr: toJSON(expr.Expr)}
+	case *Function:
+		args := make([]*jsonNode, len(expr.Args))
+		for i, arg := range expr.Args {
+			args[i] = toJSON(arg)
+		}
+		return &jsonNode{Type: "function", Name: expr.Name, Args: args}
+	case *Quantifier:
+		return &jsonNode{Type: "quantifier", Name: expr.Name, Field: expr.Field.Value, Var: expr.Var, Expr: toJSON(expr.Expr)}
+	case *FieldLiteral:
+		return &jsonNode{Type: "field", Value: expr.Value}
+	case *ElementLiteral:
+		return &jsonNode{Type: "element", Var: expr.Var, Subfield: expr.Subfield, Field: expr.Field}
+	case *StringLiteral:
+		return &jsonNode{Type: "string", Value: expr.Value}
+	case *IntegerLiteral:
+		return &jsonNode{Type: "integer", Value: expr.Value}
+	case *UnsignedLiteral:
+		return &jsonNode{Type: "unsigned", Value: expr.Value}
+	case *DecimalLiteral:
+		return &jsonNode{Type: "decimal", Value: expr.Value}
+	case *IPLiteral:
+		return &jsonNode{Type: "ip", Value: expr.Value.String()}
+	case *CIDRLiteral:
+		return &jsonNode{Type: "cidr", Value: expr.Value.String()}
+	case *DurationLiteral:
+		return &jsonNode{Type: "duration", Value: expr.Value.String()}
+	case *ListLiteral:
+		return &jsonNode{Type: "list", Values: expr.Values}
+	}
+	return &jsonNode{Type: fmt.Sprintf("%T", expr)}
+}
+
+// binaryOps maps the lowercase names of binary operators to their tokens.
+var binaryOps = make(map[string]token)
+
+func init() {
+	for tok := opBeg + 1; tok < opEnd; tok++ {
+		if tok != not {
+			binaryOps[strings.ToLower(tok.String())] = tok
+		}
+	}
+}
+
+// ParseJSON builds the expression from its JSON representation. The expression is
+// rendered to the canonical filter string, which is then parsed and validated like
+// any other filter, so the returned expression is the same as the one produced by
+// the parser from the canonical filter string.
+func ParseJSON(b []byte) (Expr, string, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var n jsonNode
+	if err := dec.Decode(&n); err != nil {
+		return nil, "", err
+	}
+	expr, err := fromJSON(&n)
+	if err != nil {
+		return nil, "", err
+	}
+	s := Normalize(expr)
+	p := NewParser(s)
+	expr, err = p.ParseExpr()
+	if err != nil {
+		return nil, "", err
+	}
+	if err := p.Validate(expr); err != nil {
+		return nil, "", err
+	}
+	return expr, s, nil
+}
+
+func fromJSON(n *jsonNode) (Expr, error) {
+	if n == nil {
+		return nil, fmt.Errorf("missing expression node")
+	}
+	switch n.Type {
+	case "binary":
+		op, ok := binaryOps[strings.ToLower(n.Op)]
+		if !ok {
+			return nil, fmt.Errorf("unknown operator %q", n.Op)
+		}
+		lhs, err := fromJSON(n.LHS)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := fromJSON(n.RHS)
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryExpr{Op: op, LHS: lhs, RHS: rhs}, nil
+	case "not":
+		expr, err := fromJSON(n.Expr)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := unparen(expr).(*BinaryExpr); !ok {
+			return nil, fmt.Errorf("not expression requires the binary expression")
+		}
+		return &NotExpr{Expr: unparen(expr)}, nil
+	case "paren":
+		expr, err := fromJSON(n.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &ParenExpr{Expr: expr}, nil
+	case "function":
+		fn, ok := lookupFunction(n.Name)
+		if !ok {
+			return nil, fmt.Errorf("undefined function %s", n.Name)
+		}
+		args := make([]Expr, len(n.Args))
+		for i, arg := range n.Args {
+			expr, err := fromJSON(arg)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = expr
+		}
+		return &Function{Name: fn.Name().String(), Args: args, Fn: fn}, nil
+	case "quantifier":
+		expr, err := fromJSON(n.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &Quantifier{Name: strings.ToLower(n.Name), Field: &FieldLiteral{Value: n.Field}, Var: n.Var, Expr: expr}, nil
+	case "element":
+		return &ElementLiteral{Var: n.Var, Subfield: n.Subfield, Field: n.Field}, nil
+	case "field":
+		s, err := n.str()
+		if err != nil {
+			return nil, err
+		}
+		return &FieldLiteral{Value: s}, nil
+	case "string":
+		s, err := n.str()
+		if err != nil {
+			return nil, err
+		}
+		return &StringLiteral{Value: s}, nil
+	case "integer", "unsigned":
+		num, ok := n.Value.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("%s node requires the numeric value", n.Type)
+		}
+		if v, err := num.Int64(); err == nil && n.Type == "integer" {
+			return &IntegerLiteral{Value: v}, nil
+		}
+		var v uint64
+		if _, err := fmt.Sscan(num.String(), &v); err != nil {
+			return nil, fmt.Errorf("invalid %s value %s", n.Type, num)
+		}
+		return &UnsignedLiteral{Value: v}, nil
+	case "decimal":
+		num, ok := n.Value.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("decimal node requires the numeric value")
+		}
+		v, err := num.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid decimal value %s", num)
+		}
+		return &DecimalLiteral{Value: v}, nil
+	case "ip":
+		s, err := n.str()
+		if err != nil {
+			return nil, err
+		}
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %s", s)
+		}
+		return &IPLiteral{Value: ip}, nil
+	case "cidr":
+		s, err := n.str()
+		if err != nil {
+			return nil, err
+		}
+		_, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		return &CIDRLiteral{Value: ipnet}, nil
+	case "duration":
+		s, err := n.str()
+		if err != nil {
+			return nil, err
+		}
+		d, err := ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		return &DurationLiteral{Value: d}, nil
+	case "list":
+		return &ListLiteral{Values: n.Values}, nil
+	}
+	return nil, fmt.Errorf("unknown node type %q", n.Type)
+}
+
+// str returns the string value of the node.
+func (n *jsonNode) str() (string, error) {
+	s, ok := n.Value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s node requires the string value", n.Type)
+	}
+	return s, nil
+}
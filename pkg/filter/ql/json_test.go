@@ -0,0 +1,101 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	var tests = []struct {
+		expr string
+		json string
+	}{
+		{`ps.name = 'cmd.exe'`, `{"type":"binary","op":"=","lhs":{"type":"field","value":"ps.name"},"rhs":{"type":"string","value":"cmd.exe"}}`},
+		{`ps.name not in ('cmd.exe', '')`, `{"type":"not","expr":{"type":"binary","op":"in","lhs":{"type":"field","value":"ps.name"},"rhs":{"type":"list","values":["cmd.exe",""]}}}`},
+		{`ps.pid = 0 or (net.dip = 10.0.2.15 and net.sip = 10.0.0.0/8)`, `{"type":"binary","op":"or","lhs":{"type":"binary","op":"=","lhs":{"type":"field","value":"ps.pid"},"rhs":{"type":"integer","value":0}},` +
+			`"rhs":{"type":"paren","expr":{"type":"binary","op":"and","lhs":{"type":"binary","op":"=","lhs":{"type":"field","value":"net.dip"},"rhs":{"type":"ip","value":"10.0.2.15"}},` +
+			`"rhs":{"type":"binary","op":"=","lhs":{"type":"field","value":"net.sip"},"rhs":{"type":"cidr","value":"10.0.0.0/8"}}}}}`},
+		{`kevt.time > now() - 1d`, `{"type":"binary","op":">","lhs":{"type":"field","value":"kevt.time"},"rhs":{"type":"binary","op":"-","lhs":{"type":"function","name":"now"},"rhs":{"type":"duration","value":"24h0m0s"}}}`},
+		{`any(pe.sections, s, s.entropy > 7.5)`, `{"type":"quantifier","name":"any","field":"pe.sections","var":"s","expr":{"type":"binary","op":">","lhs":{"type":"element","field":"pe.sections","var":"s","subfield":"entropy"},"rhs":{"type":"decimal","value":7.5}}}`},
+	}
+
+	for _, tt := range tests {
+		expr, err := NewParser(tt.expr).ParseExpr()
+		require.NoError(t, err)
+		b, err := json.Marshal(expr)
+		require.NoError(t, err)
+		assert.JSONEq(t, tt.json, string(b), tt.expr)
+	}
+}
+
+func TestParseJSONRoundTrip(t *testing.T) {
+	for _, tt := range parserTests {
+		if tt.err != nil {
+			continue
+		}
+		p := NewParser(tt.expr)
+		expr, err := p.ParseExpr()
+		require.NoError(t, err, tt.expr)
+		b, err := json.Marshal(expr)
+		require.NoError(t, err, tt.expr)
+
+		nexpr, s, err := ParseJSON(b)
+		// expressions rejected by the checker are rejected when built from JSON
+		if p.Validate(expr) != nil {
+			require.Error(t, err, tt.expr)
+			continue
+		}
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, Normalize(expr), s, tt.expr)
+		assert.Equal(t, s, Normalize(nexpr), tt.expr)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	expr, s, err := ParseJSON([]byte(`{"type":"binary","op":"AND","lhs":{"type":"binary","op":"iin","lhs":{"type":"field","value":"ps.name"},"rhs":{"type":"list","values":["cmd.exe","it's"]}},` +
+		`"rhs":{"type":"not","expr":{"type":"paren","expr":{"type":"binary","op":">","lhs":{"type":"function","name":"length","args":[{"type":"field","value":"ps.comm"}]},"rhs":{"type":"unsigned","value":18446744073709551615}}}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, `(ps.name IIN ('cmd.exe', 'it\'s')) AND (length(ps.comm) NOT > 18446744073709551615)`, s)
+	assert.IsType(t, &BinaryExpr{}, expr)
+
+	var tests = []struct {
+		json string
+		err  string
+	}{
+		{`{"type":"binary","op":"like","lhs":{"type":"field","value":"ps.name"},"rhs":{"type":"string","value":"cmd.exe"}}`, `unknown operator "like"`},
+		{`{"type":"binary","op":"=","lhs":{"type":"field","value":"ps.name"}}`, `missing expression node`},
+		{`{"type":"binary","op":"=","lhs":{"type":"field","value":"ps.name"},"rhs":{"type":"str","value":"cmd.exe"}}`, `unknown node type "str"`},
+		{`{"type":"binary","op":"=","lhs":{"type":"field","value":"ps.name"},"rhs":{"type":"string","value":1}}`, `string node requires the string value`},
+		{`{"type":"binary","op":"=","lhs":{"type":"field","value":"net.dip"},"rhs":{"type":"ip","value":"10.0.2"}}`, `invalid IP address 10.0.2`},
+		{`{"type":"binary","op":"=","lhs":{"type":"function","name":"lowr"},"rhs":{"type":"string","value":"cmd.exe"}}`, `undefined function lowr`},
+		{`{"type":"not","expr":{"type":"field","value":"ps.name"}}`, `not expression requires the binary expression`},
+		{`{"type":"binary","op":"=","lhs":{"type":"field","value":"ps.nmae"},"rhs":{"type":"string","value":"cmd.exe"}}`, "ps.nmae = 'cmd.exe'\n ^ unknown field ps.nmae"},
+		{`{"type":"binary","op":"=","lhs":{"type":"field","value":"ps.pid"},"rhs":{"type":"string","value":"cmd.exe"}}`, "ps.pid = 'cmd.exe'\n         ^ expected number but found string"},
+	}
+
+	for _, tt := range tests {
+		_, _, err := ParseJSON([]byte(tt.json))
+		require.Error(t, err, tt.json)
+		assert.Equal(t, tt.err, err.Error(), tt.json)
+	}
+}
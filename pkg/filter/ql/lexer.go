@@ -25,13 +25,14 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"net"
 	"strconv"
 	"strings"
 )
 
 // scanner is responsible for splitting up the filter expression into individual tokens. This code is mostly borrowed
 // from the influxql repository (https://github.com/influxdata/influxql) with some changes to support the lexing
-// of additional tokens such as IP addresses.
+// of additional tokens such as IP addresses and networks.
 type scanner struct {
 	r *reader
 }
@@ -68,6 +69,11 @@ func (s *scanner) scan() (tok token, pos int, lit string) {
 		return s.scanIdent()
 	case '\'':
 		return s.scanString()
+	case ':':
+		// IPv6 address with the leading zero groups omitted (e.g. ::1)
+		return s.scanIPv6(pos, string(ch0))
+	case '$':
+		return s.scanListRef(pos)
 	case '.':
 		ch1, _ := s.r.read()
 		s.r.unread()
@@ -77,6 +83,10 @@ func (s *scanner) scan() (tok token, pos int, lit string) {
 		return dot, pos, ""
 	case '=':
 		return eq, pos, ""
+	case '+':
+		return add, pos, ""
+	case '-':
+		return sub, pos, ""
 	case '!':
 		if ch1, _ := s.r.read(); ch1 == '=' {
 			return neq, pos, ""
@@ -155,6 +165,11 @@ func (s *scanner) scanIdent() (tok token, pos int, lit string) {
 	}
 	lit = buf.String()
 
+	// hex digits followed by the colon start the IPv6 address
+	if isHex(lit) && s.peek() == ':' {
+		return s.scanIPv6(pos, lit)
+	}
+
 	if tok, lit = lookup(lit); tok != ident {
 		return tok, pos, lit
 	}
@@ -233,7 +248,7 @@ func (s *scanner) scanNumber() (tok token, pos int, lit string) {
 				return badip, pos, buf.String()
 			}
 		}
-		return ip, pos, buf.String()
+		return s.scanPrefixLen(ip, pos, buf.String())
 	}
 	// unread the previously read char
 	s.r.unread()
@@ -261,15 +276,80 @@ func (s *scanner) scanNumber() (tok token, pos int, lit string) {
 					break
 				}
 			}
+			if isHex(buf.String()) && s.peek() == ':' {
+				return s.scanIPv6(pos, buf.String())
+			}
 			return duration, pos, buf.String()
 		}
 		s.r.unread()
+		if s.peek() == ':' {
+			return s.scanIPv6(pos, buf.String())
+		}
 		return integer, pos, buf.String()
 	}
 
 	return dec, pos, buf.String()
 }
 
+// scanListRef consumes the name of the list referenced by the $ prefix.
+func (s *scanner) scanListRef(pos int) (tok token, p int, lit string) {
+	var buf bytes.Buffer
+	for {
+		ch, _ := s.r.read()
+		if !isLetter(ch) && !isDigit(ch) && ch != '_' {
+			s.r.unread()
+			break
+		}
+		_, _ = buf.WriteRune(ch)
+	}
+	if buf.Len() == 0 {
+		return illegal, pos, "$"
+	}
+	return listref, pos, buf.String()
+}
+
+// scanIPv6 consumes the remaining groups of the IPv6 address. The prefix
+// holds the runes of the address that were already consumed.
+func (s *scanner) scanIPv6(pos int, prefix string) (tok token, p int, lit string) {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString(prefix)
+	for {
+		ch, _ := s.r.read()
+		if !isHexDigit(ch) && ch != ':' && ch != '.' {
+			s.r.unread()
+			break
+		}
+		_, _ = buf.WriteRune(ch)
+	}
+	tok = ip
+	if net.ParseIP(buf.String()) == nil {
+		tok = badip
+	}
+	return s.scanPrefixLen(tok, pos, buf.String())
+}
+
+// scanPrefixLen consumes the prefix length if the IP address is followed by
+// the slash, in which case the network in CIDR notation is returned (e.g. 10.0.0.0/8).
+// Otherwise, the address token is returned as is.
+func (s *scanner) scanPrefixLen(tok token, pos int, addr string) (token, int, string) {
+	if ch, _ := s.r.read(); ch != '/' {
+		s.r.unread()
+		return tok, pos, addr
+	}
+	lit := addr + "/" + s.scanDigits()
+	if _, _, err := net.ParseCIDR(lit); err != nil {
+		return badcidr, pos, lit
+	}
+	return cidr, pos, lit
+}
+
+// peek returns the next rune without consuming it.
+func (s *scanner) peek() rune {
+	ch, _ := s.r.read()
+	s.r.unread()
+	return ch
+}
+
 // scanDigits consumes a contiguous series of digits.
 func (s *scanner) scanDigits() string {
 	var buf bytes.Buffer
@@ -358,6 +438,14 @@ func ScanString(r io.RuneScanner) (string, error) {
 	}
 }
 
+// Quote encloses the string in quotes escaping the characters as expected
+// by ScanString, so the string is always scanned as a single string literal.
+func Quote(s string) string {
+	return "'" + quoteReplacer.Replace(s) + "'"
+}
+
+var quoteReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`)
+
 // bufScanner represents a wrapper for scanner to add a buffer.
 // It provides a fixed-length circular buffer that can be unread.
 type bufScanner struct {
@@ -500,6 +588,21 @@ func isLetter(ch rune) bool {
 // isDigit returns true if the rune is a digit.
 func isDigit(ch rune) bool { return ch >= '0' && ch <= '9' }
 
+// isHexDigit returns true if the rune is a hexadecimal digit.
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
+
+// isHex returns true if the string consists of hexadecimal digits.
+func isHex(s string) bool {
+	for _, ch := range s {
+		if !isHexDigit(ch) {
+			return false
+		}
+	}
+	return s != ""
+}
+
 // isIdentChar returns true if the rune can be used in an unquoted identifier. $ rune is for special PE section names (e.g. .debug$ | .tls$)
 func isIdentChar(ch rune) bool {
 	return isLetter(ch) || isDigit(ch) || ch == '_' || ch == '.' || ch == '[' || ch == ']' || ch == '$'
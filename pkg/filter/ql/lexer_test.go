@@ -51,6 +51,12 @@ func TestScanner(t *testing.T) {
 		{s: `>=`, tok: gte},
 		{s: `IN`, tok: in},
 		{s: `in`, tok: in},
+		{s: `iin`, tok: iin},
+		{s: `istartswith`, tok: istartswith},
+		{s: `iendswith`, tok: iendswith},
+		{s: `regex`, tok: regex},
+		{s: `IREGEX`, tok: iregex},
+		{s: `fuzzy`, tok: fuzzy},
 
 		// misc tokens
 		{s: `(`, tok: lparen},
@@ -68,11 +74,30 @@ func TestScanner(t *testing.T) {
 		{s: `Zx12_3U_-`, tok: ident, lit: `Zx12_3U_`},
 		{s: `"foo\"bar\""`, tok: ident, lit: `foo"bar"`},
 
+		// lists
+		{s: `$shells`, tok: listref, lit: `shells`},
+		{s: `$shell_1)`, tok: listref, lit: `shell_1`},
+		{s: `$ `, tok: illegal, lit: `$`},
+
 		// IP address
 		{s: "172.17.0.1", tok: ip, lit: "172.17.0.1"},
 		{s: "172.17.1", tok: badip, lit: "172.17.1"},
 		{s: "172.317.1.2", tok: badip, lit: "172.317.1.2"},
 		{s: "172.2.266.2", tok: badip, lit: "172.2.266.2"},
+		{s: "fe80::1", tok: ip, lit: "fe80::1"},
+		{s: "::1", tok: ip, lit: "::1"},
+		{s: "2001:db8::ff00:42:8329", tok: ip, lit: "2001:db8::ff00:42:8329"},
+		{s: "::ffff:192.0.2.128", tok: ip, lit: "::ffff:192.0.2.128"},
+		{s: "2001:db8:::1", tok: badip, lit: "2001:db8:::1"},
+
+		// networks
+		{s: "10.0.0.0/8", tok: cidr, lit: "10.0.0.0/8"},
+		{s: "192.168.1.0/24)", tok: cidr, lit: "192.168.1.0/24"},
+		{s: "fe80::/10", tok: cidr, lit: "fe80::/10"},
+		{s: "2001:db8::/32", tok: cidr, lit: "2001:db8::/32"},
+		{s: "10.0.0.0/33", tok: badcidr, lit: "10.0.0.0/33"},
+		{s: "10.0.0.0/", tok: badcidr, lit: "10.0.0.0/"},
+		{s: "fe80::/129", tok: badcidr, lit: "fe80::/129"},
 
 		// strings
 		{s: `'testing 123!'`, tok: str, lit: `testing 123!`},
@@ -81,6 +106,15 @@ func TestScanner(t *testing.T) {
 
 		// numbers
 		{s: "6.2323", tok: dec, lit: "6.2323"},
+
+		// durations
+		{s: "10m", tok: duration, lit: "10m"},
+		{s: "250ms", tok: duration, lit: "250ms"},
+		{s: "1h30m", tok: duration, lit: "1h30m"},
+
+		// arithmetic operators
+		{s: `+`, tok: add},
+		{s: `-`, tok: sub},
 	}
 
 	for i, tt := range tests {
@@ -95,3 +129,19 @@ func TestScanner(t *testing.T) {
 		}
 	}
 }
+
+func TestQuote(t *testing.T) {
+	var tests = []string{
+		`C:\Windows\System32\cmd.exe`,
+		`cmd.exe' or ps.name != '`,
+		"cmd.exe\n/c whoami",
+		`\\backup01\`,
+	}
+
+	for i, s := range tests {
+		tok, _, lit := newScanner(strings.NewReader(Quote(s))).scan()
+		if tok != str || lit != s {
+			t.Errorf("%d. %q quote mismatch: exp=%q got=%q <%q>", i, s, s, lit, tok)
+		}
+	}
+}
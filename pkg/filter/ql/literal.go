@@ -22,6 +22,8 @@ import (
 	"bytes"
 	"net"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // StringLiteral represents a string literal.
@@ -34,6 +36,15 @@ type FieldLiteral struct {
 	Value string
 }
 
+// ElementLiteral represents the field of the multi-valued field element
+// bound to the quantifier variable (e.g. m.name).
+type ElementLiteral struct {
+	Var      string
+	Subfield string
+	// Field is the multi-valued field iterated by the quantifier
+	Field string
+}
+
 // IntegerLiteral represents a signed number literal.
 type IntegerLiteral struct {
 	Value int64
@@ -54,10 +65,28 @@ type IPLiteral struct {
 	Value net.IP
 }
 
+// CIDRLiteral represents the network literal in CIDR notation (e.g. 10.0.0.0/8).
+type CIDRLiteral struct {
+	Value *net.IPNet
+}
+
+// DurationLiteral represents a duration literal (e.g. 10m).
+type DurationLiteral struct {
+	Value time.Duration
+}
+
+func (d DurationLiteral) String() string {
+	return d.Value.String()
+}
+
 func (i IPLiteral) String() string {
 	return i.Value.String()
 }
 
+func (c CIDRLiteral) String() string {
+	return c.Value.String()
+}
+
 func (i IntegerLiteral) String() string {
 	return strconv.Itoa(int(i.Value))
 }
@@ -70,12 +99,21 @@ func (f FieldLiteral) String() string {
 	return f.Value
 }
 
+func (e ElementLiteral) String() string {
+	return e.Var + "." + e.Subfield
+}
+
 func (u UnsignedLiteral) String() string {
-	return strconv.Itoa(int(u.Value))
+	return strconv.FormatUint(u.Value, 10)
 }
 
 func (d DecimalLiteral) String() string {
-	return strconv.FormatFloat(d.Value, 'e', -1, 64)
+	s := strconv.FormatFloat(d.Value, 'f', -1, 64)
+	// keep the fractional part, so the literal isn't parsed as integer
+	if !strings.Contains(s, ".") {
+		return s + ".0"
+	}
+	return s
 }
 
 // ListLiteral represents a list of tag key literals.
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"net"
+	"strings"
+)
+
+// networks are the named sets of address ranges that can be used in place of the list literal (e.g. net.dip in private).
+var networks = map[string][]string{
+	"private":   {"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"},
+	"loopback":  {"127.0.0.0/8", "::1/128"},
+	"multicast": {"224.0.0.0/4", "ff00::/8"},
+	"linklocal": {"169.254.0.0/16", "fe80::/10"},
+}
+
+// lookupNetworks returns the address ranges of the named network set.
+func lookupNetworks(name string) ([]string, bool) {
+	nets, ok := networks[strings.ToLower(name)]
+	return nets, ok
+}
+
+// parseAddrs splits the list values into IP addresses and networks. Values
+// that are neither valid addresses nor networks are ignored.
+func parseAddrs(vals []string) ([]net.IP, []*net.IPNet) {
+	ips := make([]net.IP, 0)
+	nets := make([]*net.IPNet, 0)
+	for _, val := range vals {
+		if strings.Contains(val, "/") {
+			if _, n, err := net.ParseCIDR(val); err == nil {
+				nets = append(nets, n)
+			}
+			continue
+		}
+		if ip := net.ParseIP(val); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nets
+}
+
+// matchesAddr determines whether the IP address equals any of the addresses or belongs to any of the networks.
+func matchesAddr(ip net.IP, ips []net.IP, nets []*net.IPNet) bool {
+	for _, addr := range ips {
+		if addr.Equal(ip) {
+			return true
+		}
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -21,20 +21,65 @@
 package ql
 
 import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/filter/config"
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql/functions"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
 	"net"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Parser builds the binary expression tree from the filter string.
 type Parser struct {
 	s    *bufScanner
 	expr string
+	// positions keeps the offsets of the parsed nodes in the
+	// expression string so semantic errors can be reported
+	// at the offending position
+	positions map[Node]int
+	// vars contains the quantifier variables in scope
+	// mapped to the multi-valued field they iterate
+	vars map[string]fields.Field
+	// macros and lists contain the definitions
+	// that can be referenced in the expression
+	macros map[string]string
+	lists  map[string][]string
+	// iocs contains the indicator of compromise lists loaded
+	// by the config. Parsers without the config leave the ioc
+	// function unbound
+	iocs map[string]functions.Indicators
+	// expanding is the chain of macros being
+	// expanded and is used to detect cycles
+	expanding []string
 }
 
 // NewParser builds a new parser instance from the expression string.
 func NewParser(expr string) *Parser {
-	return &Parser{s: newBufScanner(strings.NewReader(expr)), expr: expr}
+	return &Parser{s: newBufScanner(strings.NewReader(expr)), expr: expr, positions: make(map[Node]int), vars: make(map[string]fields.Field)}
+}
+
+// NewParserWithConfig builds a new parser instance that expands the macros and lists defined in the filters config.
+func NewParserWithConfig(expr string, config *config.Config) *Parser {
+	p := NewParser(expr)
+	if config == nil {
+		return p
+	}
+	p.macros = make(map[string]string, len(config.Macros))
+	for _, m := range config.Macros {
+		p.macros[m.Name] = m.Expr
+	}
+	p.lists = make(map[string][]string, len(config.Lists))
+	for _, l := range config.Lists {
+		p.lists[l.Name] = l.Items
+	}
+	p.iocs = make(map[string]functions.Indicators, len(config.IOCLists()))
+	for name, l := range config.IOCLists() {
+		p.iocs[name] = l
+	}
+	return p
 }
 
 // ParseExpr parses an expression by building the binary expression tree.
@@ -74,6 +119,7 @@ func (p *Parser) ParseExpr() (Expr, error) {
 				return nil, err
 			}
 			rhs = &BinaryExpr{RHS: rhs1, Op: op1}
+			p.positions[rhs] = pos
 		} else {
 			// otherwise parse the next expression
 			rhs, err = p.parseUnaryExpr()
@@ -93,10 +139,12 @@ func (p *Parser) ParseExpr() (Expr, error) {
 					r := rhs.(*BinaryExpr)
 					r.LHS = node.RHS
 					node.RHS = &NotExpr{Expr: r}
+					p.positions[node.RHS] = pos
 					break
 				}
 				// Add the new expression here and break.
 				node.RHS = &BinaryExpr{LHS: node.RHS, RHS: rhs, Op: op}
+				p.positions[node.RHS] = pos
 				break
 			}
 			node = r
@@ -104,8 +152,20 @@ func (p *Parser) ParseExpr() (Expr, error) {
 	}
 }
 
-// parseUnaryExpr parses an non-binary expression.
+// parseUnaryExpr parses an non-binary expression and records its position.
 func (p *Parser) parseUnaryExpr() (Expr, error) {
+	_, pos, _ := p.scanIgnoreWhitespace()
+	p.unscan()
+	expr, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	p.positions[expr] = pos
+	return expr, nil
+}
+
+// parseOperand parses the grouped expression, list, literal, field or the function call.
+func (p *Parser) parseOperand() (Expr, error) {
 	// If the first token is a LPAREN then parse it as its own grouped expression.
 	if tok, _, _ := p.scanIgnoreWhitespace(); tok == lparen {
 		// parse a comma-separated list if this looks like a list
@@ -138,10 +198,53 @@ func (p *Parser) parseUnaryExpr() (Expr, error) {
 	switch tok {
 	case ip:
 		return &IPLiteral{Value: net.ParseIP(lit)}, nil
+	case cidr:
+		_, n, _ := net.ParseCIDR(lit)
+		return &CIDRLiteral{Value: n}, nil
+	case listref:
+		items, ok := p.lists[lit]
+		if !ok {
+			return nil, &ParseError{Message: fmt.Sprintf("undefined list %s", lit), Pos: pos, Expr: p.expr}
+		}
+		return &ListLiteral{Values: items}, nil
+	case regex:
+		// the regex function shares the name with the operator
+		if tok, _, _ := p.scanIgnoreWhitespace(); tok == lparen {
+			return p.parseFunction(regex.String(), pos)
+		}
+		p.unscan()
 	case str:
 		return &StringLiteral{Value: lit}, nil
 	case field:
 		return &FieldLiteral{Value: lit}, nil
+	case ident:
+		// identifier followed by the left parenthesis is a quantifier or the function call
+		if tok, _, _ := p.scanIgnoreWhitespace(); tok == lparen {
+			if name := strings.ToLower(lit); name == anyQuantifier || name == allQuantifier {
+				return p.parseQuantifier(name, pos)
+			}
+			return p.parseFunction(lit, pos)
+		}
+		p.unscan()
+		if expr, ok := p.macros[lit]; ok {
+			return p.expandMacro(lit, expr, pos)
+		}
+		// named network sets expand to the list of their address ranges
+		if nets, ok := lookupNetworks(lit); ok {
+			return &ListLiteral{Values: nets}, nil
+		}
+		// dotted identifiers prefixed with the quantifier variable access the element fields
+		if n := strings.Index(lit, "."); n > 0 {
+			if f, ok := p.vars[lit[:n]]; ok {
+				return p.parseElement(f, lit[:n], lit[n+1:], pos)
+			}
+		}
+		// dotted identifiers are most likely misspelled field names,
+		// while other identifiers can only reference macros
+		if strings.Contains(lit, ".") {
+			return nil, &ParseError{Message: fmt.Sprintf("unknown field %s", lit), Pos: pos, Expr: p.expr}
+		}
+		return nil, &ParseError{Message: fmt.Sprintf("undefined macro %s", lit), Pos: pos, Expr: p.expr}
 	case integer:
 		v, err := strconv.ParseInt(lit, 10, 64)
 		if err != nil {
@@ -159,37 +262,202 @@ func (p *Parser) parseUnaryExpr() (Expr, error) {
 			return nil, &ParseError{Message: "unable to parse decimal", Pos: pos}
 		}
 		return &DecimalLiteral{Value: v}, nil
+	case duration:
+		v, err := ParseDuration(lit)
+		if err != nil {
+			return nil, &ParseError{Message: "unable to parse duration", Pos: pos}
+		}
+		return &DurationLiteral{Value: v}, nil
 	}
 
 	expectations := []string{"field", "string", "number", "bool", "ip"}
-	if tok == badip {
+	switch tok {
+	case badip:
 		expectations = []string{"a valid IP address"}
+	case badcidr:
+		expectations = []string{"a valid CIDR"}
 	}
 
 	return nil, newParseError(tokstr(tok, lit), expectations, pos, p.expr)
 }
 
-func (p *Parser) parseList() ([]string, error) {
-	tok, pos, lit := p.scanIgnoreWhitespace()
-	if tok != str {
-		return []string{}, newParseError(tokstr(tok, lit), []string{"identifier"}, pos, p.expr)
+// expandMacro parses the macro expression that replaces the macro reference. Nodes of
+// the expanded expression are positioned at the reference, so semantic errors point at
+// the macro. Likewise, errors in the macro expression are reported at the reference.
+func (p *Parser) expandMacro(name, expr string, pos int) (Expr, error) {
+	for _, m := range p.expanding {
+		if m == name {
+			chain := strings.Join(append(p.expanding, name), " -> ")
+			return nil, &ParseError{Message: fmt.Sprintf("cyclic macro reference %s", chain), Pos: pos, Expr: p.expr, macro: true}
+		}
+	}
+	mp := NewParser(expr)
+	mp.macros, mp.lists = p.macros, p.lists
+	mp.iocs = p.iocs
+	mp.expanding = append(append([]string{}, p.expanding...), name)
+
+	e, err := mp.ParseExpr()
+	if err != nil {
+		perr, ok := err.(*ParseError)
+		if !ok {
+			return nil, err
+		}
+		msg := perr.msg()
+		// errors from the nested macros already name the offending macro
+		if !perr.macro {
+			msg = fmt.Sprintf("invalid macro %s: %s", name, msg)
+		}
+		return nil, &ParseError{Message: msg, Pos: pos, Expr: p.expr, macro: true}
+	}
+	WalkFunc(e, func(n Node) { p.positions[n] = pos })
+	return &ParenExpr{Expr: e}, nil
+}
+
+// parseFunction parses the comma-separated function arguments up to the closing
+// parenthesis and validates the arguments against the function signature.
+func (p *Parser) parseFunction(name string, pos int) (Expr, error) {
+	fn, ok := lookupFunction(name)
+	if !ok {
+		return nil, &ParseError{Message: fmt.Sprintf("undefined function %s", name), Pos: pos, Expr: p.expr}
+	}
+	args := make([]Expr, 0)
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok != rparen {
+		p.unscan()
+		for {
+			arg, err := p.parseUnaryExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+
+			tok, pos, lit := p.scanIgnoreWhitespace()
+			if tok == rparen {
+				break
+			}
+			if tok != comma {
+				return nil, newParseError(tokstr(tok, lit), []string{",", ")"}, pos, p.expr)
+			}
+		}
+	}
+	f := &Function{Name: fn.Name().String(), Args: args, Fn: fn}
+	if err := f.validate(); err != nil {
+		return nil, &ParseError{Message: err.Error(), Pos: pos, Expr: p.expr}
+	}
+	if fn, ok := fn.(*functions.IOC); ok && p.iocs != nil {
+		if err := p.bindIOC(fn, f.Args[0].(*StringLiteral).Value); err != nil {
+			return nil, &ParseError{Message: err.Error(), Pos: pos, Expr: p.expr}
+		}
+	}
+	return f, nil
+}
+
+// bindIOC binds the indicator of compromise list to the ioc function.
+func (p *Parser) bindIOC(fn *functions.IOC, name string) error {
+	l, ok := p.iocs[name]
+	if !ok {
+		return fmt.Errorf("undefined IOC list %s", name)
+	}
+	fn.List = l
+	return nil
+}
+
+// parseQuantifier parses the multi-valued field, the variable and the predicate
+// of the any/all quantifier. The variable is in scope only within the predicate.
+func (p *Parser) parseQuantifier(name string, pos int) (Expr, error) {
+	tok, fpos, lit := p.scanIgnoreWhitespace()
+	if tok != field {
+		return nil, newParseError(tokstr(tok, lit), []string{"field"}, fpos, p.expr)
+	}
+	f := fields.Field(lit)
+	if !f.IsMultiValued() {
+		multiValued := make([]string, 0)
+		for _, f := range fields.MultiValued() {
+			multiValued = append(multiValued, f.String())
+		}
+		return nil, &ParseError{Message: fmt.Sprintf("%s quantifier expects one of %s but found %s", name, strings.Join(multiValued, ", "), lit), Pos: fpos, Expr: p.expr}
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != comma {
+		return nil, newParseError(tokstr(tok, lit), []string{","}, pos, p.expr)
+	}
+
+	tok, vpos, v := p.scanIgnoreWhitespace()
+	if tok != ident || strings.Contains(v, ".") {
+		return nil, newParseError(tokstr(tok, v), []string{"variable"}, vpos, p.expr)
+	}
+	if _, ok := p.vars[v]; ok {
+		return nil, &ParseError{Message: fmt.Sprintf("variable %s is already declared", v), Pos: vpos, Expr: p.expr}
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != comma {
+		return nil, newParseError(tokstr(tok, lit), []string{","}, pos, p.expr)
+	}
+
+	p.vars[v] = f
+	expr, err := p.ParseExpr()
+	delete(p.vars, v)
+	if err != nil {
+		return nil, err
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != rparen {
+		return nil, newParseError(tokstr(tok, lit), []string{")"}, pos, p.expr)
 	}
-	idents := []string{lit}
 
-	// parse remaining identifiers
+	fieldLiteral := &FieldLiteral{Value: f.String()}
+	p.positions[fieldLiteral] = fpos
+	return &Quantifier{Name: name, Field: fieldLiteral, Var: v, Expr: expr}, nil
+}
+
+// parseElement validates the element field accessed through the quantifier variable.
+func (p *Parser) parseElement(f fields.Field, v, subfield string, pos int) (Expr, error) {
+	if f.ElementType(fields.Subfield(subfield)) == kparams.Unknown {
+		return nil, &ParseError{Message: fmt.Sprintf("unknown field %s in %s elements", subfield, f), Pos: pos, Expr: p.expr}
+	}
+	return &ElementLiteral{Var: v, Subfield: subfield, Field: f.String()}, nil
+}
+
+func (p *Parser) parseList() ([]string, error) {
+	idents := make([]string, 0)
+
 	for {
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		switch tok {
+		case str, ip, cidr:
+			idents = append(idents, lit)
+		case listref:
+			items, ok := p.lists[lit]
+			if !ok {
+				return []string{}, &ParseError{Message: fmt.Sprintf("undefined list %s", lit), Pos: pos, Expr: p.expr}
+			}
+			idents = append(idents, items...)
+		case ident:
+			// named network sets are expanded in place
+			nets, ok := lookupNetworks(lit)
+			if !ok {
+				return []string{}, newParseError(tokstr(tok, lit), []string{"identifier"}, pos, p.expr)
+			}
+			idents = append(idents, nets...)
+		default:
+			return []string{}, newParseError(tokstr(tok, lit), []string{"identifier"}, pos, p.expr)
+		}
+
+		// parse remaining identifiers
 		if tok, _, _ := p.scanIgnoreWhitespace(); tok != comma {
 			p.unscan()
 			return idents, nil
 		}
+	}
+}
 
-		tok, pos, lit := p.scanIgnoreWhitespace()
-		if tok != str {
-			return []string{}, newParseError(tokstr(tok, lit), []string{"identifier"}, pos, p.expr)
+// ParseDuration parses the duration literal. Apart from units recognized
+// by time.ParseDuration, the d unit can be used to express days (e.g. 2d).
+func ParseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		n, err := strconv.ParseInt(strings.TrimSuffix(s, "d"), 10, 64)
+		if err != nil {
+			return 0, err
 		}
-
-		idents = append(idents, lit)
+		return time.Duration(n) * 24 * time.Hour, nil
 	}
+	return time.ParseDuration(s)
 }
 
 // scan returns the next token from the underlying scanner.
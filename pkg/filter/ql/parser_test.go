@@ -20,48 +20,81 @@ package ql
 
 import (
 	"errors"
+	"github.com/rabbitstack/fibratus/pkg/filter/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"testing"
 )
 
-func TestParser(t *testing.T) {
-	var tests = []struct {
-		expr string
-		err  error
-	}{
-		{expr: "ps.name = 'cmd.exe'"},
-		{expr: "ps.name != 'cmd.exe'"},
-		{expr: "ps.name <> 'cmd.exe'"},
-		{expr: "ps.name <> 'cmd.exe", err: errors.New("ps.name <> 'cmd.exe\n" +
-			"           ^ expected field, string, number, bool, ip")},
-		{expr: "ps.name = 123"},
-		{expr: "net.dip = 172.17.0.9"},
-		{expr: "net.dip = 172.17.0.9 and net.dip in ('172.15.9.2')"},
-		{expr: "net.dip = 172.17.0.9 and (net.dip not in ('172.15.9.2'))"},
+// parserTests is the corpus of expressions along with the expected parser errors.
+var parserTests = []struct {
+	expr string
+	err  error
+}{
+	{expr: "ps.name = 'cmd.exe'"},
+	{expr: "ps.name != 'cmd.exe'"},
+	{expr: "ps.name <> 'cmd.exe'"},
+	{expr: "ps.name <> 'cmd.exe", err: errors.New("ps.name <> 'cmd.exe\n" +
+		"           ^ expected field, string, number, bool, ip")},
+	{expr: "ps.name = 123"},
+	{expr: "net.dip = 172.17.0.9"},
+	{expr: "net.dip = 172.17.0.9 and net.dip in ('172.15.9.2')"},
+	{expr: "net.dip = 172.17.0.9 and (net.dip not in ('172.15.9.2'))"},
 
-		{expr: "net.dip = 172.17.0", err: errors.New("net.dip = 172.17.0\n" +
-			"           ^ expected a valid IP address")},
+	{expr: "net.dip = 172.17.0", err: errors.New("net.dip = 172.17.0\n" +
+		"           ^ expected a valid IP address")},
+	{expr: "net.dip = 10.0.0.0/8 or net.dip = fe80::/10"},
+	{expr: "net.dip in (10.0.0.0/8, 172.16.0.0/12, '192.168.1.1', ::1)"},
+	{expr: "net.dip not in private and net.sip in (loopback, linklocal)"},
+	{expr: "net.dip = 10.0.0.0/40", err: errors.New("net.dip = 10.0.0.0/40\n" +
+		"           ^ expected a valid CIDR")},
+	{expr: "net.dip in (10.0.0.0/8, publik)", err: errors.New("net.dip in (10.0.0.0/8, publik)\n" +
+		"                        ^ expected identifier")},
 
-		{expr: "ps.name = 'cmd.exe' OR ps.name contains 'svc'"},
-		{expr: "ps.name = 'cmd.exe' AND (ps.name contains 'svc' OR ps.name != 'lsass')"},
-		{expr: "ps.name = 'cmd.exe' AND (ps.name contains 'svc' OR ps.name != 'lsass'", err: errors.New("ps.name = 'cmd.exe' AND (ps.name contains 'svc' OR ps.name != 'lsass'" +
-			"^ expected")},
+	{expr: "ps.name = 'cmd.exe' OR ps.name contains 'svc'"},
+	{expr: "ps.name = 'cmd.exe' AND (ps.name contains 'svc' OR ps.name != 'lsass')"},
+	{expr: "ps.name = 'cmd.exe' AND (ps.name contains 'svc' OR ps.name != 'lsass'", err: errors.New("ps.name = 'cmd.exe' AND (ps.name contains 'svc' OR ps.name != 'lsass'" +
+		"^ expected")},
 
-		{expr: "ps.name = 'cmd.exe' OR ((ps.name contains 'svc' AND ps.name != 'lsass') AND ps.ppid != 1)"},
+	{expr: "ps.name = 'cmd.exe' OR ((ps.name contains 'svc' AND ps.name != 'lsass') AND ps.ppid != 1)"},
 
-		{expr: "ps.name = 'cmd.exe' OR ((ps.name contains 'svc' AND ps.name != 'lsass' AND ps.ppid != 1)", err: errors.New("ps.name = 'cmd.exe' OR ((ps.name contains 'svc' AND ps.name != 'lsass' AND ps.ppid != 1)" +
-			"	^ expected )")},
+	{expr: "ps.name = 'cmd.exe' OR ((ps.name contains 'svc' AND ps.name != 'lsass' AND ps.ppid != 1)", err: errors.New("ps.name = 'cmd.exe' OR ((ps.name contains 'svc' AND ps.name != 'lsass' AND ps.ppid != 1)" +
+		"	^ expected )")},
 
-		{expr: "ps.name = 'cmd.exe' OR ((ps.name contains 'svc' AND ps.name != 'lsass') AND ps.ppid != 1", err: errors.New("ps.name = 'cmd.exe' OR ((ps.name contains 'svc' AND ps.name != 'lsass') AND ps.ppid != 1" +
-			"	^ expected )")},
+	{expr: "ps.name = 'cmd.exe' OR ((ps.name contains 'svc' AND ps.name != 'lsass') AND ps.ppid != 1", err: errors.New("ps.name = 'cmd.exe' OR ((ps.name contains 'svc' AND ps.name != 'lsass') AND ps.ppid != 1" +
+		"	^ expected )")},
 
-		{expr: "ps.none = 'cmd.exe'", err: errors.New("ps.none = 'cmd.exe'" +
-			"	^ expected field, string, number, bool, ip")},
+	{expr: "ps.none = 'cmd.exe'", err: errors.New("ps.none = 'cmd.exe'" +
+		"	^ expected field, string, number, bool, ip")},
 
-		{expr: "ps.name = 'cmd.exe' AND ps.name IN ('exe') ps.name", err: errors.New("ps.name = 'cmd.exe' AND ps.name IN ('exe') ps.name" +
-			"	^ expected operator")},
-	}
+	{expr: "ps.name = 'cmd.exe' AND ps.name IN ('exe') ps.name", err: errors.New("ps.name = 'cmd.exe' AND ps.name IN ('exe') ps.name" +
+		"	^ expected operator")},
+
+	{expr: "kevt.time > now() - 10m"},
+	{expr: "ps.runtime < 2s"},
+	{expr: "ps.runtime < 2x", err: errors.New("unable to parse duration at line 14, char 14")},
+	{expr: "kevt.time > now() - ", err: errors.New("kevt.time > now() - \n" +
+		"                     ^ expected field, string, number, bool, ip")},
 
-	for i, tt := range tests {
+	{expr: "lower(ps.name) = 'cmd.exe'"},
+	{expr: "base(ps.exe) in ('cmd.exe') and length(ps.comm) > 1024"},
+	{expr: "concat(ps.name, ':', kevt.name) = 'cmd.exe:CreateProcess'"},
+	{expr: "lower(base(file.name)) = 'cmd.exe'"},
+	{expr: "regex(ps.name, 'svc.*', 'cmd.exe')"},
+	{expr: "lowr(ps.name) = 'cmd.exe'", err: errors.New("lowr(ps.name) = 'cmd.exe'\n ^ undefined function lowr")},
+	{expr: "lower(ps.name, ps.exe) = 'cmd.exe'", err: errors.New("lower(ps.name, ps.exe) = 'cmd.exe'\n ^ lower function accepts at most 1 argument(s) but 2 given")},
+	{expr: "concat(ps.name) = 'cmd.exe'", err: errors.New("concat(ps.name) = 'cmd.exe'\n ^ concat function requires at least 2 argument(s) but 1 given")},
+	{expr: "regex(ps.name, ps.exe)", err: errors.New("regex(ps.name, ps.exe)\n ^ argument #2 (pattern) in function regex should be one of: string")},
+	{expr: "lower(ps.name = 'cmd.exe'", err: errors.New("lower(ps.name = 'cmd.exe'" +
+		"	^ expected ,, )")},
+
+	{expr: "any(ps.modules, m, m.name endswith 'clr.dll')"},
+	{expr: "all(pe.sections, s, s.entropy < 7.5 and s.name != '.text') and ps.name = 'cmd.exe'"},
+	{expr: "ANY(ps.handles, h, h.type = 'Mutant' and any(pe.resources, r, r.value = h.name))"},
+}
+
+func TestParser(t *testing.T) {
+	for i, tt := range parserTests {
 		p := NewParser(tt.expr)
 		_, err := p.ParseExpr()
 		if err == nil && tt.err != nil {
@@ -71,3 +104,84 @@ func TestParser(t *testing.T) {
 		}
 	}
 }
+
+func TestParseQuantifier(t *testing.T) {
+	var tests = []struct {
+		expr string
+		err  string
+	}{
+		{"any(ps.name, m, m.name = 'cmd.exe')", "any(ps.name, m, m.name = 'cmd.exe')\n" +
+			"     ^ any quantifier expects one of pe.resources, pe.sections, ps.handles, ps.modules but found ps.name"},
+		{"any(ps.modules, m, m.nme = 'clr.dll')", "any(ps.modules, m, m.nme = 'clr.dll')\n" +
+			"                    ^ unknown field nme in ps.modules elements"},
+		{"any(ps.modules, m.name, m.name = 'clr.dll')", "any(ps.modules, m.name, m.name = 'clr.dll')\n" +
+			"                 ^ expected variable"},
+		{"any(ps.modules, m, any(ps.handles, m, m.name = 'clr.dll'))", "any(ps.modules, m, any(ps.handles, m, m.name = 'clr.dll'))\n" +
+			"                                    ^ variable m is already declared"},
+		{"any(ps.modules, m, m.name = 'clr.dll') and m.name = 'clr.dll'", "any(ps.modules, m, m.name = 'clr.dll') and m.name = 'clr.dll'\n" +
+			"                                            ^ unknown field m.name"},
+		{"all(ps.modules, m, m.name = 'clr.dll'", "all(ps.modules, m, m.name = 'clr.dll'\n" +
+			"                                      ^ expected )"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewParser(tt.expr).ParseExpr()
+		require.Error(t, err, tt.expr)
+		assert.Equal(t, tt.err, err.Error())
+	}
+}
+
+func TestParseMacros(t *testing.T) {
+	c := &config.Config{
+		Macros: []config.Macro{
+			{Name: "spawn_shell", Expr: "kevt.name = 'CreateProcess' and ps.name in $shells"},
+			{Name: "spawn_interpreter", Expr: "spawn_shell or ps.name in ($scripts, 'python.exe')"},
+			{Name: "typo", Expr: "ps.name = "},
+			{Name: "wrong_type", Expr: "ps.pid = 'cmd.exe'"},
+			{Name: "nested_typo", Expr: "typo and ps.pid > 4"},
+			{Name: "loop1", Expr: "ps.pid > 4 and loop2"},
+			{Name: "loop2", Expr: "ps.pid < 1024 or loop1"},
+		},
+		Lists: []config.List{
+			{Name: "shells", Items: []string{"cmd.exe", "powershell.exe"}},
+			{Name: "scripts", Items: []string{"wscript.exe", "cscript.exe"}},
+		},
+	}
+
+	var tests = []struct {
+		expr string
+		str  string
+		err  string
+	}{
+		{expr: "spawn_shell", str: "(kevt.name = CreateProcess AND ps.name IN (cmd.exe, powershell.exe))"},
+		{expr: "ps.pid > 4 and spawn_interpreter", str: "ps.pid > 4 AND ((kevt.name = CreateProcess AND ps.name IN (cmd.exe, powershell.exe)) OR ps.name IN (wscript.exe, cscript.exe, python.exe))"},
+		{expr: "ps.name in $scripts", str: "ps.name IN (wscript.exe, cscript.exe)"},
+		{expr: "spawn_shel and ps.pid > 4", err: "spawn_shel and ps.pid > 4\n" +
+			" ^ undefined macro spawn_shel"},
+		{expr: "ps.name in $shell", err: "ps.name in $shell\n" +
+			"            ^ undefined list shell"},
+		{expr: "ps.pid > 4 and typo", err: "ps.pid > 4 and typo\n" +
+			"                ^ invalid macro typo: expected field, string, number, bool, ip"},
+		{expr: "nested_typo", err: "nested_typo\n" +
+			" ^ invalid macro typo: expected field, string, number, bool, ip"},
+		{expr: "ps.name = 'cmd.exe' or loop1", err: "ps.name = 'cmd.exe' or loop1\n" +
+			"                        ^ cyclic macro reference loop1 -> loop2 -> loop1"},
+		{expr: "ps.name = 'cmd.exe' or wrong_type", err: "ps.name = 'cmd.exe' or wrong_type\n" +
+			"                        ^ expected number but found string"},
+	}
+
+	for _, tt := range tests {
+		p := NewParserWithConfig(tt.expr, c)
+		expr, err := p.ParseExpr()
+		if err == nil {
+			err = p.Validate(expr)
+		}
+		if tt.err != "" {
+			require.Error(t, err, tt.expr)
+			assert.Equal(t, tt.err, err.Error())
+			continue
+		}
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.str, expr.String())
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+// tristate is the outcome of evaluating the expression when only some field values are known.
+type tristate uint8
+
+const (
+	unknown tristate = iota
+	yes
+	no
+)
+
+// MayMatch determines whether the expression could evaluate to true when the fields
+// in the map have the given values. Comparisons that reference other fields are assumed
+// to match, so false is only returned when the known values alone rule out the match.
+// This allows for discarding expressions that can't match the event of the given type,
+// e.g. the kevt.name = 'CreateProcess' and ps.name = 'cmd.exe' expression can't match
+// if the kevt.name field is known to have the CreateFile value.
+func MayMatch(expr Expr, known map[string]interface{}) bool {
+	return partialEval(expr, known) != no
+}
+
+func partialEval(expr Expr, known map[string]interface{}) tristate {
+	switch expr := expr.(type) {
+	case *ParenExpr:
+		return partialEval(expr.Expr, known)
+	case *NotExpr:
+		switch partialEval(expr.Expr, known) {
+		case yes:
+			return no
+		case no:
+			return yes
+		}
+		return unknown
+	case *BinaryExpr:
+		switch expr.Op {
+		case and:
+			lhs, rhs := partialEval(expr.LHS, known), partialEval(expr.RHS, known)
+			if lhs == no || rhs == no {
+				return no
+			}
+			if lhs == yes && rhs == yes {
+				return yes
+			}
+			return unknown
+		case or:
+			lhs, rhs := partialEval(expr.LHS, known), partialEval(expr.RHS, known)
+			if lhs == yes || rhs == yes {
+				return yes
+			}
+			if lhs == no && rhs == no {
+				return no
+			}
+			return unknown
+		}
+	}
+	if !isResolvable(expr, known) {
+		return unknown
+	}
+	if Eval(expr, known) {
+		return yes
+	}
+	return no
+}
+
+// isResolvable determines if all fields referenced by the expression have known values.
+// Expressions without fields and those iterating over multi-valued fields are never
+// resolvable, since their outcome can't be decided without the event.
+func isResolvable(expr Expr, known map[string]interface{}) bool {
+	var nfields int
+	resolvable := true
+	WalkFunc(expr, func(n Node) {
+		switch n := n.(type) {
+		case *FieldLiteral:
+			nfields++
+			if _, ok := known[n.Value]; !ok {
+				resolvable = false
+			}
+		case *Quantifier, *ElementLiteral:
+			resolvable = false
+		}
+	})
+	return resolvable && nfields > 0
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ql
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMayMatch(t *testing.T) {
+	known := map[string]interface{}{
+		"kevt.name":     "CreateFile",
+		"kevt.category": "file",
+	}
+
+	var tests = []struct {
+		expr     string
+		mayMatch bool
+	}{
+		{`kevt.name = 'CreateFile'`, true},
+		{`kevt.name = 'CreateProcess'`, false},
+		{`kevt.name = 'CreateProcess' and ps.name = 'cmd.exe'`, false},
+		{`ps.name = 'cmd.exe' and kevt.name in ('CreateFile', 'DeleteFile')`, true},
+		{`ps.name = 'cmd.exe' and kevt.name iin ('createfile')`, true},
+		{`ps.name = 'cmd.exe'`, true},
+		{`kevt.name = 'CreateProcess' or ps.name = 'cmd.exe'`, true},
+		{`kevt.name = 'CreateProcess' or kevt.category = 'registry'`, false},
+		{`(kevt.name = 'CreateProcess' or kevt.category = 'file') and file.name endswith '.exe'`, true},
+		{`kevt.name != 'CreateFile' and ps.pid > 4`, false},
+		{`kevt.name not in ('CreateProcess') and ps.pid > 4`, true},
+		{`kevt.category = 'net' and net.dport = 443`, false},
+		{`lower(kevt.name) = 'createfile'`, true},
+		{`lower(kevt.name) = 'createprocess' and ps.pid > 4`, false},
+		{`kevt.name = ps.name and ps.pid > 4`, true},
+		{`any(ps.modules, m, m.name = 'clr.dll') and kevt.name = 'LoadImage'`, false},
+		{`any(ps.modules, m, m.name = 'clr.dll') or kevt.name = 'LoadImage'`, true},
+	}
+
+	for _, tt := range tests {
+		expr, err := NewParser(tt.expr).ParseExpr()
+		require.NoError(t, err)
+		assert.Equal(t, tt.mayMatch, MayMatch(expr, known), tt.expr)
+	}
+}
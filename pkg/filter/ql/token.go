@@ -41,24 +41,35 @@ const (
 	duration // 13h
 	ip       // 192.168.1.23
 	badip    // 192.156.300.12
+	cidr     // 10.0.0.0/8
+	badcidr  // 10.0.0.0/33
+	listref  // $shells
 
 	opBeg
-	and        // and
-	or         // or
-	in         // in
-	not        // not
-	contains   // contains
-	icontains  // icontains
-	startswith // startswith
-	endswith   // endswith
-	matches    // matches
-	imatches   // imatches
-	eq         // =
-	neq        // !=
-	lt         // <
-	lte        // <=
-	gt         // >
-	gte        // >=
+	and         // and
+	or          // or
+	in          // in
+	iin         // iin
+	not         // not
+	contains    // contains
+	icontains   // icontains
+	startswith  // startswith
+	istartswith // istartswith
+	endswith    // endswith
+	iendswith   // iendswith
+	matches     // matches
+	imatches    // imatches
+	regex       // regex
+	iregex      // iregex
+	fuzzy       // fuzzy
+	eq          // =
+	neq         // !=
+	lt          // <
+	lte         // <=
+	gt          // >
+	gte         // >=
+	add         // +
+	sub         // -
 	opEnd
 
 	lparen // (
@@ -71,7 +82,7 @@ var keywords map[string]token
 
 func init() {
 	keywords = make(map[string]token)
-	for _, tok := range []token{and, or, contains, icontains, in, not, startswith, endswith, matches, imatches} {
+	for _, tok := range []token{and, or, contains, icontains, in, iin, not, startswith, istartswith, endswith, iendswith, matches, imatches, regex, iregex, fuzzy} {
 		keywords[strings.ToLower(tokens[tok])] = tok
 	}
 }
@@ -91,17 +102,26 @@ var tokens = [...]string{
 	badesc:   "BADESCAPE",
 	ip:       "IPADDRESS",
 	badip:    "BADIPADDRESS",
-
-	and:        "AND",
-	or:         "OR",
-	contains:   "CONTAINS",
-	icontains:  "ICONTAINS",
-	in:         "IN",
-	not:        "NOT",
-	startswith: "STARTSWITH",
-	endswith:   "ENDSWITH",
-	matches:    "MATCHES",
-	imatches:   "IMATCHES",
+	cidr:     "CIDR",
+	badcidr:  "BADCIDR",
+	listref:  "LIST",
+
+	and:         "AND",
+	or:          "OR",
+	contains:    "CONTAINS",
+	icontains:   "ICONTAINS",
+	in:          "IN",
+	iin:         "IIN",
+	not:         "NOT",
+	startswith:  "STARTSWITH",
+	istartswith: "ISTARTSWITH",
+	endswith:    "ENDSWITH",
+	iendswith:   "IENDSWITH",
+	matches:     "MATCHES",
+	imatches:    "IMATCHES",
+	regex:       "REGEX",
+	iregex:      "IREGEX",
+	fuzzy:       "FUZZY",
 
 	eq:  "=",
 	neq: "!=",
@@ -109,6 +129,8 @@ var tokens = [...]string{
 	lte: "<=",
 	gt:  ">",
 	gte: ">=",
+	add: "+",
+	sub: "-",
 
 	lparen: "(",
 	rparen: ")",
@@ -138,8 +160,10 @@ func (tok token) precedence() int {
 		return 3
 	case eq, neq, lt, lte, gt, gte:
 		return 4
-	case in, contains, icontains, startswith, endswith, matches, imatches:
+	case in, iin, contains, icontains, startswith, istartswith, endswith, iendswith, matches, imatches, regex, iregex, fuzzy:
 		return 5
+	case add, sub:
+		return 6
 	}
 	return 0
 }
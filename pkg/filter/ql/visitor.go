@@ -38,6 +38,15 @@ func Walk(v Visitor, node Node) {
 		Walk(v, n.RHS)
 	case *NotExpr:
 		Walk(v, n.Expr)
+	case *ParenExpr:
+		Walk(v, n.Expr)
+	case *Function:
+		for _, arg := range n.Args {
+			Walk(v, arg)
+		}
+	case *Quantifier:
+		Walk(v, n.Field)
+		Walk(v, n.Expr)
 	}
 }
 
@@ -0,0 +1,274 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/ps"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	// partialsExpired counts the number of partial matches discarded after the max span elapsed
+	partialsExpired = expvar.NewInt("filter.sequence.partials.expired")
+	// partialsEvicted counts the number of partial matches evicted to honor the partials limit
+	partialsEvicted = expvar.NewInt("filter.sequence.partials.evicted")
+
+	errNoSequenceKeyword = errors.New("expected sequence keyword at the beginning of the expression")
+	errSequenceTooShort  = errors.New("sequence requires at least two filters")
+)
+
+// DefaultMaxPartials is the default limit of partial matches a sequence can track.
+const DefaultMaxPartials = 1000
+
+// Sequence correlates events that consecutively match a series of filters. The expression
+// has the form of `sequence by ps.pid maxspan=30s |filter1| |filter2| ...`. Each filter is
+// enclosed in pipes and all of them have to match in the given order for the sequence to
+// complete. The optional `by` clause partitions the sequence state by the value of the field,
+// so only events with the same field value are correlated. The `maxspan` clause constrains
+// the time that can elapse between the first and the last event in the sequence.
+//
+// The sequence is not safe for concurrent use.
+type Sequence struct {
+	expr        string
+	by          fields.Field
+	maxSpan     time.Duration
+	accessors   []accessor
+	config      *config.Config
+	steps       []*filter
+	partials    map[interface{}]*partial
+	maxPartials int
+	lastExpire  time.Time
+}
+
+// partial is the state of the sequence that hasn't matched all the filters yet.
+type partial struct {
+	kevts []*kevent.Kevent
+}
+
+func (p *partial) started() time.Time { return p.kevts[0].Timestamp }
+
+// NewSequence creates a new sequence from the expression. The sequence keeps at most maxPartials
+// partial matches at a time. The `Compile` method has to be called before the sequence is able
+// to process events.
+func NewSequence(expr string, maxPartials int, psnap ps.Snapshotter, config *config.Config) *Sequence {
+	if maxPartials <= 0 {
+		maxPartials = DefaultMaxPartials
+	}
+	return &Sequence{
+		expr:        expr,
+		accessors:   newAccessors(psnap, config),
+		config:      config,
+		partials:    make(map[interface{}]*partial),
+		maxPartials: maxPartials,
+	}
+}
+
+// Compile parses the sequence clauses and compiles the filters of all sequence steps.
+func (s *Sequence) Compile() error {
+	expr := strings.TrimSpace(s.expr)
+	if !strings.HasPrefix(expr, "sequence") {
+		return errNoSequenceKeyword
+	}
+	expr = strings.TrimPrefix(expr, "sequence")
+	i := strings.IndexRune(expr, '|')
+	if i < 0 {
+		return errSequenceTooShort
+	}
+
+	header := strings.Fields(expr[:i])
+	for n := 0; n < len(header); n++ {
+		switch clause := header[n]; {
+		case clause == "by":
+			n++
+			if n == len(header) {
+				return errors.New("expected field after the by clause")
+			}
+			s.by = fields.Lookup(header[n])
+			if s.by == fields.None {
+				return fmt.Errorf("unknown field %s in the by clause", header[n])
+			}
+		case strings.HasPrefix(clause, "maxspan="):
+			var err error
+			s.maxSpan, err = ql.ParseDuration(strings.TrimPrefix(clause, "maxspan="))
+			if err != nil {
+				return fmt.Errorf("invalid maxspan: %v", err)
+			}
+		default:
+			return fmt.Errorf("unexpected %q in the sequence clauses", clause)
+		}
+	}
+
+	exprs, err := splitSequence(expr[i:])
+	if err != nil {
+		return err
+	}
+	if len(exprs) < 2 {
+		return errSequenceTooShort
+	}
+	s.steps = make([]*filter, len(exprs))
+	for n, expr := range exprs {
+		f := &filter{parser: ql.NewParserWithConfig(expr, &s.config.Filters), accessors: s.accessors}
+		if err := f.Compile(); err != nil {
+			return fmt.Errorf("invalid filter #%d in the sequence: \n  %v", n+1, err)
+		}
+		s.steps[n] = f
+	}
+	return nil
+}
+
+// Next advances the state of the sequence with the event. If the event completes the sequence,
+// the events that matched each of the sequence filters are returned. Events are cloned before
+// they are stored in the partial matches, so the caller is free to release them.
+func (s *Sequence) Next(kevt *kevent.Kevent) []*kevent.Kevent {
+	if s.maxSpan > 0 && kevt.Timestamp.Sub(s.lastExpire) > s.maxSpan {
+		s.expire(kevt.Timestamp)
+	}
+	key, ok := s.key(kevt)
+	if !ok {
+		return nil
+	}
+
+	p := s.partials[key]
+	if p != nil && s.isExpired(p, kevt.Timestamp) {
+		delete(s.partials, key)
+		partialsExpired.Add(1)
+		p = nil
+	}
+	if p != nil && s.steps[len(p.kevts)].Run(kevt) {
+		p.kevts = append(p.kevts, kevt.Clone())
+		if len(p.kevts) < len(s.steps) {
+			return nil
+		}
+		delete(s.partials, key)
+		return p.kevts
+	}
+	// a partial that has only matched the first filter is superseded
+	// by the most recent event, since it leaves more room until the
+	// sequence expires
+	if (p == nil || len(p.kevts) == 1) && s.steps[0].Run(kevt) {
+		if p == nil && len(s.partials) >= s.maxPartials {
+			s.evict()
+		}
+		s.partials[key] = &partial{kevts: []*kevent.Kevent{kevt.Clone()}}
+	}
+	return nil
+}
+
+// Partials returns the number of partial matches the sequence is tracking.
+func (s *Sequence) Partials() int { return len(s.partials) }
+
+// String returns the sequence expression.
+func (s *Sequence) String() string { return s.expr }
+
+// key returns the value that partitions the sequence state. Events lacking
+// the value of the by field can't be correlated.
+func (s *Sequence) key(kevt *kevent.Kevent) (interface{}, bool) {
+	if s.by == "" {
+		return struct{}{}, true
+	}
+	switch v := getValue(s.accessors, s.by, kevt).(type) {
+	case nil:
+		return nil, false
+	case net.IP:
+		return v.String(), true
+	case []string:
+		return strings.Join(v, ","), true
+	default:
+		if !reflect.TypeOf(v).Comparable() {
+			return nil, false
+		}
+		return v, true
+	}
+}
+
+func (s *Sequence) isExpired(p *partial, now time.Time) bool {
+	return s.maxSpan > 0 && now.Sub(p.started()) > s.maxSpan
+}
+
+// expire discards all partial matches whose max span has elapsed.
+func (s *Sequence) expire(now time.Time) {
+	for key, p := range s.partials {
+		if s.isExpired(p, now) {
+			delete(s.partials, key)
+			partialsExpired.Add(1)
+		}
+	}
+	s.lastExpire = now
+}
+
+// evict discards the oldest partial match.
+func (s *Sequence) evict() {
+	var (
+		oldest interface{}
+		ts     time.Time
+	)
+	for key, p := range s.partials {
+		if oldest == nil || p.started().Before(ts) {
+			oldest, ts = key, p.started()
+		}
+	}
+	if oldest != nil {
+		delete(s.partials, oldest)
+		partialsEvicted.Add(1)
+	}
+}
+
+// splitSequence extracts the filters enclosed in pipes. Pipes inside string literals
+// are considered part of the filter expression.
+func splitSequence(s string) ([]string, error) {
+	var (
+		exprs  []string
+		start  = -1
+		quoted bool
+	)
+	for i, c := range s {
+		switch {
+		case start >= 0 && c == '\'':
+			quoted = !quoted
+		case c == '|' && !quoted:
+			if start < 0 {
+				start = i + 1
+				continue
+			}
+			expr := strings.TrimSpace(s[start:i])
+			if expr == "" {
+				return nil, errors.New("empty filter in the sequence")
+			}
+			exprs = append(exprs, expr)
+			start = -1
+		case start < 0 && !unicode.IsSpace(c):
+			return nil, fmt.Errorf("unexpected %q outside of the sequence filter", c)
+		}
+	}
+	if start >= 0 {
+		return nil, errors.New("sequence filter is not terminated with the pipe")
+	}
+	return exprs, nil
+}
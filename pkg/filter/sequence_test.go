@@ -0,0 +1,134 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filter
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSequenceCompile(t *testing.T) {
+	var tests = []struct {
+		expr string
+		err  string
+	}{
+		{`sequence by kevt.pid maxspan=30s |kevt.name = 'CreateFile'| |kevt.name = 'Send'|`, ""},
+		{`sequence |kevt.name = 'CreateFile'| |file.name contains '|'|`, ""},
+		{`sequence maxspan=1d by ps.name |kevt.name = 'CreateFile'| |kevt.name = 'Send'| |kevt.name = 'Recv'|`, ""},
+		{`by kevt.pid |kevt.name = 'CreateFile'| |kevt.name = 'Send'|`, "expected sequence keyword at the beginning of the expression"},
+		{`sequence by kevt.pid |kevt.name = 'CreateFile'|`, "sequence requires at least two filters"},
+		{`sequence by kevt.pid`, "sequence requires at least two filters"},
+		{`sequence by |kevt.name = 'CreateFile'| |kevt.name = 'Send'|`, "expected field after the by clause"},
+		{`sequence by kevt.pdi |kevt.name = 'CreateFile'| |kevt.name = 'Send'|`, "unknown field kevt.pdi in the by clause"},
+		{`sequence maxspan=30 |kevt.name = 'CreateFile'| |kevt.name = 'Send'|`, "invalid maxspan: time: missing unit in duration \"30\""},
+		{`sequence within 30s |kevt.name = 'CreateFile'| |kevt.name = 'Send'|`, "unexpected \"within\" in the sequence clauses"},
+		{`sequence |kevt.name = 'CreateFile'| and |kevt.name = 'Send'|`, "unexpected 'a' outside of the sequence filter"},
+		{`sequence |kevt.name = 'CreateFile'| |kevt.name = 'Send'`, "sequence filter is not terminated with the pipe"},
+		{`sequence |kevt.name = 'CreateFile'| | |`, "empty filter in the sequence"},
+		{`sequence |kevt.name = 'CreateFile'| |kevt.nam = 'Send'|`, "invalid filter #2 in the sequence: \n  kevt.nam = 'Send'\n ^ unknown field kevt.nam"},
+	}
+
+	for _, tt := range tests {
+		err := NewSequence(tt.expr, 0, nil, cfg).Compile()
+		if tt.err == "" {
+			require.NoError(t, err, tt.expr)
+		} else {
+			require.EqualError(t, err, tt.err, tt.expr)
+		}
+	}
+}
+
+func TestSequenceNext(t *testing.T) {
+	seq := NewSequence(`sequence by kevt.pid maxspan=30s |kevt.name = 'CreateFile' and file.name endswith '.exe'| |kevt.name = 'Send' and net.dport = 443|`, 0, nil, cfg)
+	require.NoError(t, seq.Compile())
+
+	now := time.Now()
+	createFile := func(pid uint32, ts time.Time) *kevent.Kevent {
+		return &kevent.Kevent{
+			Type:      ktypes.CreateFile,
+			Name:      "CreateFile",
+			PID:       pid,
+			Timestamp: ts,
+			Kparams: kevent.Kparams{
+				kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "C:\\Temp\\dropper.exe"},
+			},
+			Metadata: make(map[string]string),
+		}
+	}
+	send := func(pid uint32, ts time.Time) *kevent.Kevent {
+		return &kevent.Kevent{
+			Type:      ktypes.SendTCPv4,
+			Name:      "Send",
+			PID:       pid,
+			Timestamp: ts,
+			Kparams: kevent.Kparams{
+				kparams.NetDport: {Name: kparams.NetDport, Type: kparams.Uint16, Value: uint16(443)},
+				kparams.NetDIP:   {Name: kparams.NetDIP, Type: kparams.IPv4, Value: net.ParseIP("216.58.201.174")},
+			},
+			Metadata: make(map[string]string),
+		}
+	}
+
+	// the second step alone doesn't start the sequence
+	assert.Nil(t, seq.Next(send(1234, now)))
+	assert.Equal(t, 0, seq.Partials())
+
+	// events from different processes are not correlated
+	assert.Nil(t, seq.Next(createFile(1234, now)))
+	assert.Nil(t, seq.Next(send(4321, now.Add(time.Second))))
+	assert.Equal(t, 1, seq.Partials())
+
+	kevts := seq.Next(send(1234, now.Add(time.Second*2)))
+	require.Len(t, kevts, 2)
+	assert.Equal(t, "CreateFile", kevts[0].Name)
+	assert.Equal(t, "Send", kevts[1].Name)
+	assert.Equal(t, 0, seq.Partials())
+
+	// the partial match expires after the max span
+	assert.Nil(t, seq.Next(createFile(1234, now)))
+	assert.Nil(t, seq.Next(send(1234, now.Add(time.Minute))))
+	assert.Equal(t, 0, seq.Partials())
+
+	// the most recent event that matches the first step supersedes the partial match
+	assert.Nil(t, seq.Next(createFile(1234, now)))
+	assert.Nil(t, seq.Next(createFile(1234, now.Add(time.Second*25))))
+	require.Len(t, seq.Next(send(1234, now.Add(time.Second*40))), 2)
+}
+
+func TestSequenceMaxPartials(t *testing.T) {
+	seq := NewSequence(`sequence by kevt.pid |kevt.name = 'CreateFile'| |kevt.name = 'Send'|`, 2, nil, cfg)
+	require.NoError(t, seq.Compile())
+
+	now := time.Now()
+	for i, pid := range []uint32{1, 2, 3} {
+		kevt := &kevent.Kevent{Type: ktypes.CreateFile, Name: "CreateFile", PID: pid, Timestamp: now.Add(time.Second * time.Duration(i)), Kparams: kevent.Kparams{}}
+		assert.Nil(t, seq.Next(kevt))
+	}
+	assert.Equal(t, 2, seq.Partials())
+
+	// the oldest partial match was evicted
+	assert.Nil(t, seq.Next(&kevent.Kevent{Type: ktypes.SendTCPv4, Name: "Send", PID: 1, Timestamp: now.Add(time.Second * 5), Kparams: kevent.Kparams{}}))
+	assert.Len(t, seq.Next(&kevent.Kevent{Type: ktypes.SendTCPv4, Name: "Send", PID: 3, Timestamp: now.Add(time.Second * 5), Kparams: kevent.Kparams{}}), 2)
+}
@@ -0,0 +1,359 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcase
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	pstypes "github.com/rabbitstack/fibratus/pkg/ps/types"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is the inline event declared in the test case. The layout mirrors
+// the JSON representation of the kernel event.
+type Event struct {
+	Seq         uint64            `yaml:"seq"`
+	PID         uint32            `yaml:"pid"`
+	Tid         uint32            `yaml:"tid"`
+	CPU         uint8             `yaml:"cpu"`
+	Name        string            `yaml:"name"`
+	Category    string            `yaml:"category"`
+	Description string            `yaml:"description"`
+	Host        string            `yaml:"host"`
+	Timestamp   time.Time         `yaml:"timestamp"`
+	Params      map[string]Param  `yaml:"params"`
+	Metadata    map[string]string `yaml:"metadata"`
+	PS          *Process          `yaml:"ps"`
+}
+
+// Process is the state of the process that generated the inline event.
+type Process struct {
+	PID       uint32            `yaml:"pid"`
+	Ppid      uint32            `yaml:"ppid"`
+	Name      string            `yaml:"name"`
+	Comm      string            `yaml:"comm"`
+	Exe       string            `yaml:"exe"`
+	Cwd       string            `yaml:"cwd"`
+	SID       string            `yaml:"sid"`
+	Args      []string          `yaml:"args"`
+	SessionID uint8             `yaml:"session"`
+	Envs      map[string]string `yaml:"envs"`
+}
+
+// Param is the inline event parameter. The parameter is either given as a plain value
+// or as an object with the type and the value keys, e.g. {type: hex64, value: 7ffe0000}.
+// The type of plain values is derived from the parameter name or the value itself.
+type Param struct {
+	Type  string
+	Value interface{}
+}
+
+// UnmarshalYAML decodes the parameter from the plain value or the typed object.
+func (p *Param) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var typed struct {
+		Type  string      `yaml:"type"`
+		Value interface{} `yaml:"value"`
+	}
+	if err := unmarshal(&typed); err == nil && typed.Type != "" {
+		p.Type, p.Value = typed.Type, typed.Value
+		return nil
+	}
+	return unmarshal(&p.Value)
+}
+
+// types maps the type names to parameter types.
+var types = map[string]kparams.Type{
+	"unicode":  kparams.UnicodeString,
+	"ansi":     kparams.AnsiString,
+	"int8":     kparams.Int8,
+	"uint8":    kparams.Uint8,
+	"int16":    kparams.Int16,
+	"uint16":   kparams.Uint16,
+	"int32":    kparams.Int32,
+	"uint32":   kparams.Uint32,
+	"int64":    kparams.Int64,
+	"uint64":   kparams.Uint64,
+	"hex8":     kparams.HexInt8,
+	"hex16":    kparams.HexInt16,
+	"hex32":    kparams.HexInt32,
+	"hex64":    kparams.HexInt64,
+	"sid":      kparams.SID,
+	"pid":      kparams.PID,
+	"tid":      kparams.TID,
+	"port":     kparams.Port,
+	"ipv4":     kparams.IPv4,
+	"ipv6":     kparams.IPv6,
+	"bool":     kparams.Bool,
+	"double":   kparams.Double,
+	"time":     kparams.Time,
+	"duration": kparams.Duration,
+}
+
+// paramTypes contains the types of the well-known parameters whose values
+// are accessed with a specific type by the filter fields.
+var paramTypes = map[string]kparams.Type{
+	kparams.ProcessID:        kparams.PID,
+	kparams.ProcessParentID:  kparams.PID,
+	kparams.ThreadID:         kparams.TID,
+	kparams.NetDport:         kparams.Port,
+	kparams.NetSport:         kparams.Port,
+	kparams.NetSize:          kparams.Uint32,
+	kparams.FileIoSize:       kparams.Uint32,
+	kparams.FileObject:       kparams.Uint64,
+	kparams.FileOffset:       kparams.Uint64,
+	kparams.ImageSize:        kparams.Uint32,
+	kparams.ImageCheckSum:    kparams.Uint32,
+	kparams.ImageBase:        kparams.HexInt64,
+	kparams.ImageDefaultBase: kparams.HexInt64,
+	kparams.BasePrio:         kparams.Uint8,
+	kparams.IOPrio:           kparams.Uint8,
+	kparams.PagePrio:         kparams.Uint8,
+	kparams.StartTime:        kparams.Time,
+	kparams.HandleID:         kparams.HexInt32,
+	kparams.HandleObject:     kparams.HexInt64,
+	kparams.RegKeyHandle:     kparams.HexInt64,
+	kparams.ThreadEntrypoint: kparams.HexInt64,
+	kparams.KstackBase:       kparams.HexInt64,
+	kparams.KstackLimit:      kparams.HexInt64,
+	kparams.UstackBase:       kparams.HexInt64,
+	kparams.UstackLimit:      kparams.HexInt64,
+}
+
+// Kevent builds the kernel event from the inline event.
+func (e Event) Kevent() (*kevent.Kevent, error) {
+	ktype := ktypes.KeventNameToKtype(e.Name)
+	if ktype == ktypes.UnknownKtype {
+		return nil, fmt.Errorf("unknown event %q", e.Name)
+	}
+	info := ktypes.KtypeToKeventInfo(ktype)
+	kevt := &kevent.Kevent{
+		Seq:         e.Seq,
+		PID:         e.PID,
+		Tid:         e.Tid,
+		CPU:         e.CPU,
+		Type:        ktype,
+		Name:        info.Name,
+		Category:    info.Category,
+		Description: info.Description,
+		Host:        e.Host,
+		Timestamp:   e.Timestamp,
+		Kparams:     make(kevent.Kparams),
+		Metadata:    make(kevent.Metadata),
+	}
+	if e.Category != "" {
+		kevt.Category = ktypes.Category(e.Category)
+	}
+	if e.Description != "" {
+		kevt.Description = e.Description
+	}
+	for k, v := range e.Metadata {
+		kevt.AddMeta(k, v)
+	}
+	for name, p := range e.Params {
+		typ, value, err := p.convert(name)
+		if err != nil {
+			return nil, fmt.Errorf("%q parameter: %v", name, err)
+		}
+		kevt.Kparams.AppendFromKcap(name, typ, value)
+	}
+	if e.PS != nil {
+		kevt.PS = &pstypes.PS{
+			PID:       e.PS.PID,
+			Ppid:      e.PS.Ppid,
+			Name:      e.PS.Name,
+			Comm:      e.PS.Comm,
+			Exe:       e.PS.Exe,
+			Cwd:       e.PS.Cwd,
+			SID:       e.PS.SID,
+			Args:      e.PS.Args,
+			SessionID: e.PS.SessionID,
+			Envs:      e.PS.Envs,
+			Threads:   make(map[uint32]pstypes.Thread),
+			Modules:   make([]pstypes.Module, 0),
+		}
+	}
+	return kevt, nil
+}
+
+// convert resolves the parameter type and coerces the value to its representation.
+func (p Param) convert(name string) (kparams.Type, kparams.Value, error) {
+	typ, ok := types[p.Type]
+	switch {
+	case p.Type != "" && !ok:
+		return kparams.Unknown, nil, fmt.Errorf("unknown type %q", p.Type)
+	case p.Type == "":
+		typ = inferType(name, p.Value)
+	}
+
+	switch typ {
+	case kparams.UnicodeString, kparams.AnsiString, kparams.SID:
+		return typ, fmt.Sprintf("%v", p.Value), nil
+	case kparams.Int8, kparams.Int16, kparams.Int32, kparams.Int64:
+		n, err := toInt(p.Value, typeBits(typ))
+		if err != nil {
+			return typ, nil, err
+		}
+		switch typ {
+		case kparams.Int8:
+			return typ, int8(n), nil
+		case kparams.Int16:
+			return typ, int16(n), nil
+		case kparams.Int32:
+			return typ, int32(n), nil
+		}
+		return typ, n, nil
+	case kparams.Uint8, kparams.Uint16, kparams.Port, kparams.Uint32, kparams.PID, kparams.TID, kparams.Uint64:
+		n, err := toUint(p.Value, typeBits(typ))
+		if err != nil {
+			return typ, nil, err
+		}
+		switch typ {
+		case kparams.Uint8:
+			return typ, uint8(n), nil
+		case kparams.Uint16, kparams.Port:
+			return typ, uint16(n), nil
+		case kparams.Uint32, kparams.PID, kparams.TID:
+			return typ, uint32(n), nil
+		}
+		return typ, n, nil
+	case kparams.HexInt8, kparams.HexInt16, kparams.HexInt32, kparams.HexInt64:
+		if s, ok := p.Value.(string); ok {
+			n, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(s), "0x"), 16, typeBits(typ))
+			if err != nil {
+				return typ, nil, err
+			}
+			return typ, kparams.NewHex(n), nil
+		}
+		n, err := toUint(p.Value, typeBits(typ))
+		if err != nil {
+			return typ, nil, err
+		}
+		return typ, kparams.NewHex(n), nil
+	case kparams.IPv4, kparams.IPv6:
+		ip := net.ParseIP(fmt.Sprintf("%v", p.Value))
+		if ip == nil {
+			return typ, nil, fmt.Errorf("%v is not a valid IP address", p.Value)
+		}
+		if p.Type == "" && ip.To4() == nil {
+			typ = kparams.IPv6
+		}
+		return typ, ip, nil
+	case kparams.Bool:
+		v, ok := p.Value.(bool)
+		if !ok {
+			return typ, nil, fmt.Errorf("%v is not a boolean", p.Value)
+		}
+		return typ, v, nil
+	case kparams.Double:
+		switch v := p.Value.(type) {
+		case float64:
+			return typ, v, nil
+		case int:
+			return typ, float64(v), nil
+		}
+		return typ, nil, fmt.Errorf("%v is not a number", p.Value)
+	case kparams.Time:
+		switch v := p.Value.(type) {
+		case time.Time:
+			return typ, v, nil
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, v)
+			return typ, t, err
+		}
+		return typ, nil, fmt.Errorf("%v is not a timestamp", p.Value)
+	case kparams.Duration:
+		d, err := time.ParseDuration(fmt.Sprintf("%v", p.Value))
+		return typ, d, err
+	}
+	return typ, nil, fmt.Errorf("unsupported value %v", p.Value)
+}
+
+// inferType derives the parameter type from the well-known parameter names or the value.
+func inferType(name string, value interface{}) kparams.Type {
+	if typ, ok := paramTypes[name]; ok {
+		return typ
+	}
+	if name == kparams.NetDIP || name == kparams.NetSIP {
+		return kparams.IPv4
+	}
+	switch v := value.(type) {
+	case string:
+		return kparams.UnicodeString
+	case bool:
+		return kparams.Bool
+	case int:
+		if v < 0 {
+			return kparams.Int64
+		}
+		if v > math.MaxUint32 {
+			return kparams.Uint64
+		}
+		return kparams.Uint32
+	case uint64:
+		return kparams.Uint64
+	case float64:
+		return kparams.Double
+	case time.Time:
+		return kparams.Time
+	}
+	return kparams.Unknown
+}
+
+func typeBits(typ kparams.Type) int {
+	switch typ {
+	case kparams.Int8, kparams.Uint8, kparams.HexInt8:
+		return 8
+	case kparams.Int16, kparams.Uint16, kparams.Port, kparams.HexInt16:
+		return 16
+	case kparams.Int32, kparams.Uint32, kparams.PID, kparams.TID, kparams.HexInt32:
+		return 32
+	}
+	return 64
+}
+
+func toUint(v interface{}, bits int) (uint64, error) {
+	switch n := v.(type) {
+	case int:
+		if n < 0 {
+			return 0, fmt.Errorf("%d is not an unsigned number", n)
+		}
+		return strconv.ParseUint(strconv.Itoa(n), 10, bits)
+	case uint64:
+		return strconv.ParseUint(strconv.FormatUint(n, 10), 10, bits)
+	case string:
+		return strconv.ParseUint(n, 0, bits)
+	}
+	return 0, fmt.Errorf("%v is not an unsigned number", v)
+}
+
+func toInt(v interface{}, bits int) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return strconv.ParseInt(strconv.Itoa(n), 10, bits)
+	case string:
+		return strconv.ParseInt(n, 0, bits)
+	}
+	return 0, fmt.Errorf("%v is not a signed number", v)
+}
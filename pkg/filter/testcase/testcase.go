@@ -0,0 +1,190 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcase
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/ps"
+	"github.com/rabbitstack/fibratus/pkg/util/yamlfile"
+	"gopkg.in/yaml.v2"
+	"path/filepath"
+	"sort"
+)
+
+// TestCase declares the filter expression along with the events it is evaluated against
+// and the sequence numbers of the events that are expected to match the filter.
+type TestCase struct {
+	// Name is the unique name of the test case.
+	Name string `yaml:"name"`
+	// Filter is the filter expression under test.
+	Filter string `yaml:"filter"`
+	// Kcap is the path of the kcap file with the events. Relative paths are resolved
+	// against the directory of the test case file.
+	Kcap string `yaml:"kcap,omitempty"`
+	// Events contains inline events that are used in place of the kcap file.
+	Events []Event `yaml:"events,omitempty"`
+	// Matches contains the sequence numbers of the events the filter is expected to match.
+	Matches []uint64 `yaml:"matches,omitempty"`
+	// File is the path of the file where the test case is declared.
+	File string `yaml:"-"`
+}
+
+// KcapFile returns the path of the kcap file resolved against the directory of the test case file.
+func (tc TestCase) KcapFile() string {
+	if tc.Kcap == "" || filepath.IsAbs(tc.Kcap) {
+		return tc.Kcap
+	}
+	return filepath.Join(filepath.Dir(tc.File), tc.Kcap)
+}
+
+// Kevents converts inline events to kernel events. Events without the sequence
+// number are numbered after their position in the list starting from one.
+func (tc TestCase) Kevents() ([]*kevent.Kevent, error) {
+	kevents := make([]*kevent.Kevent, 0, len(tc.Events))
+	for i, e := range tc.Events {
+		if e.Seq == 0 {
+			e.Seq = uint64(i + 1)
+		}
+		kevt, err := e.Kevent()
+		if err != nil {
+			return nil, fmt.Errorf("event #%d: %v", i+1, err)
+		}
+		kevents = append(kevents, kevt)
+	}
+	return kevents, nil
+}
+
+// Result is the outcome of running the test case.
+type Result struct {
+	// Name is the name of the test case.
+	Name string
+	// File is the path of the file where the test case is declared.
+	File string
+	// Matches contains the sequence numbers of the events that matched the filter.
+	Matches []uint64
+	// Missing contains the sequence numbers of the expected events that didn't match the filter.
+	Missing []uint64
+	// Unexpected contains the sequence numbers of the events that matched the filter but weren't expected.
+	Unexpected []uint64
+	// Err is the error that prevented the test case from running.
+	Err error
+}
+
+// Passed determines whether the filter matched exactly the expected events.
+func (r Result) Passed() bool { return r.Err == nil && len(r.Missing) == 0 && len(r.Unexpected) == 0 }
+
+// Run compiles the filter of the test case and evaluates it against the given events. The
+// sequence numbers of the matching events are compared with the expected matches.
+func Run(tc TestCase, kevents []*kevent.Kevent, psnap ps.Snapshotter, config *config.Config) Result {
+	r, err := NewRunner(tc, psnap, config)
+	if err != nil {
+		return Result{Name: tc.Name, File: tc.File, Err: err}
+	}
+	for _, kevt := range kevents {
+		r.Next(kevt)
+	}
+	return r.Result()
+}
+
+// Runner evaluates the filter of the test case against events as they are received, so
+// the events don't have to be held in memory until the test case completes.
+type Runner struct {
+	tc      TestCase
+	f       filter.Filter
+	matches []uint64
+}
+
+// NewRunner compiles the filter of the test case and creates a new runner.
+func NewRunner(tc TestCase, psnap ps.Snapshotter, config *config.Config) (*Runner, error) {
+	f := filter.New(tc.Filter, psnap, config)
+	if err := f.Compile(); err != nil {
+		return nil, fmt.Errorf("bad filter: \n  %v", err)
+	}
+	return &Runner{tc: tc, f: f, matches: make([]uint64, 0)}, nil
+}
+
+// Next evaluates the filter against the event and records the sequence number of the matching event.
+func (r *Runner) Next(kevt *kevent.Kevent) {
+	if r.f.Run(kevt) {
+		r.matches = append(r.matches, kevt.Seq)
+	}
+}
+
+// Result compares the sequence numbers of the events matched so far with the expected matches.
+func (r *Runner) Result() Result {
+	res := Result{Name: r.tc.Name, File: r.tc.File, Matches: r.matches}
+	matches := make(map[uint64]bool)
+	for _, seq := range res.Matches {
+		matches[seq] = true
+	}
+	expected := make(map[uint64]bool)
+	for _, seq := range r.tc.Matches {
+		expected[seq] = true
+		if !matches[seq] {
+			res.Missing = append(res.Missing, seq)
+		}
+	}
+	for _, seq := range res.Matches {
+		if !expected[seq] {
+			res.Unexpected = append(res.Unexpected, seq)
+		}
+	}
+	sort.Slice(res.Missing, func(i, j int) bool { return res.Missing[i] < res.Missing[j] })
+	return res
+}
+
+// Load reads the test cases from the YAML files found in the given paths.
+func Load(paths []string) ([]TestCase, error) {
+	tests := make([]TestCase, 0)
+	err := yamlfile.Load(paths, "test", func(file string, b []byte) error {
+		tcs, err := decode(file, b)
+		if err != nil {
+			return err
+		}
+		tests = append(tests, tcs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tests, nil
+}
+
+// decode unmarshals the list of test cases defined in the file and validates them.
+func decode(file string, b []byte) ([]TestCase, error) {
+	var tests []TestCase
+	if err := yaml.UnmarshalStrict(b, &tests); err != nil {
+		return nil, err
+	}
+	for i, tc := range tests {
+		switch {
+		case tc.Filter == "":
+			return nil, fmt.Errorf("test %q has no filter", tc.Name)
+		case tc.Kcap == "" && len(tc.Events) == 0:
+			return nil, fmt.Errorf("test %q has neither kcap file nor events", tc.Name)
+		case tc.Kcap != "" && len(tc.Events) > 0:
+			return nil, fmt.Errorf("test %q has both kcap file and events", tc.Name)
+		}
+		tests[i].File = file
+	}
+	return tests, nil
+}
@@ -0,0 +1,197 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcase
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests, err := Load([]string{"_fixtures/tests"})
+	require.NoError(t, err)
+	require.Len(t, tests, 3)
+
+	names := make(map[string]TestCase)
+	for _, tc := range tests {
+		names[tc.Name] = tc
+	}
+	require.Contains(t, names, "Outbound SMB connection")
+	tc := names["Outbound SMB connection"]
+	assert.Equal(t, filepath.Join("_fixtures", "tests", "network", "smb.yml"), tc.File)
+	assert.Equal(t, []uint64{10, 12}, tc.Matches)
+	assert.Len(t, tc.Events, 3)
+
+	tc.Kcap = "smb.kcap"
+	assert.Equal(t, filepath.Join("_fixtures", "tests", "network", "smb.kcap"), tc.KcapFile())
+}
+
+func TestLoadErrors(t *testing.T) {
+	var tests = []struct {
+		tests string
+		err   string
+	}{
+		{"- name: Test\n  filter: ps.name = 'cmd.exe'\n  kcap: cmd.kcap\n- name: Test\n  filter: ps.name = 'svchost.exe'\n  kcap: svchost.kcap", "test \"Test\" is already defined in"},
+		{"- filter: ps.name = 'cmd.exe'\n  kcap: cmd.kcap", "test #1 has no name"},
+		{"- name: Test\n  kcap: cmd.kcap", "test \"Test\" has no filter"},
+		{"- name: Test\n  filter: ps.name = 'cmd.exe'", "test \"Test\" has neither kcap file nor events"},
+		{"- name: Test\n  filter: ps.name = 'cmd.exe'\n  kcap: cmd.kcap\n  events:\n    - name: CreateProcess", "test \"Test\" has both kcap file and events"},
+		{"- name: Test\n  condition: ps.name = 'cmd.exe'", "field condition not found in type testcase.TestCase"},
+	}
+
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "tests")
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "tests.yml"), []byte(tt.tests), 0644))
+		_, err = Load([]string{dir})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), tt.err)
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestKevents(t *testing.T) {
+	var events []Event
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+- name: Connect
+  pid: 1024
+  params:
+    dip: 10.0.2.15
+    sip: fe80::1
+    dport: 445
+    size: 512
+    l4_proto: TCP
+- seq: 7
+  name: LoadImage
+  params:
+    base_address: {type: hex64, value: 0x7ffe0000}
+    checksum: {type: uint32, value: 4096}
+    image_name: C:\Windows\System32\kernel32.dll
+  ps:
+    name: svchost.exe
+    args: [-k, RPCSS]
+`), &events))
+	tc := TestCase{Events: events}
+	kevents, err := tc.Kevents()
+	require.NoError(t, err)
+	require.Len(t, kevents, 2)
+
+	kevt := kevents[0]
+	assert.Equal(t, uint64(1), kevt.Seq)
+	assert.Equal(t, "Connect", kevt.Name)
+	assert.Equal(t, "net", string(kevt.Category))
+	dip, err := kevt.Kparams.GetIPv4(kparams.NetDIP)
+	require.NoError(t, err)
+	assert.Equal(t, net.ParseIP("10.0.2.15"), dip)
+	sip, err := kevt.Kparams.GetIPv6(kparams.NetSIP)
+	require.NoError(t, err)
+	assert.Equal(t, net.ParseIP("fe80::1"), sip)
+	dport, err := kevt.Kparams.GetUint16(kparams.NetDport)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(445), dport)
+	size, err := kevt.Kparams.GetUint32(kparams.NetSize)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(512), size)
+
+	kevt = kevents[1]
+	assert.Equal(t, uint64(7), kevt.Seq)
+	base, err := kevt.Kparams.GetHex(kparams.ImageBase)
+	require.NoError(t, err)
+	assert.Equal(t, kparams.Hex("7ffe0000"), base)
+	require.NotNil(t, kevt.PS)
+	assert.Equal(t, []string{"-k", "RPCSS"}, kevt.PS.Args)
+
+	var tests = []struct {
+		event string
+		err   string
+	}{
+		{"name: CreateProces", "unknown event \"CreateProces\""},
+		{"name: Connect\nparams:\n  dport: 65536", "\"dport\" parameter"},
+		{"name: Connect\nparams:\n  dip: 10.0.2", "10.0.2 is not a valid IP address"},
+		{"name: Connect\nparams:\n  dip: {type: ip, value: 10.0.2.15}", "unknown type \"ip\""},
+	}
+	for _, tt := range tests {
+		var e Event
+		require.NoError(t, yaml.UnmarshalStrict([]byte(tt.event), &e))
+		_, err := e.Kevent()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), tt.err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests, err := Load([]string{"_fixtures/tests"})
+	require.NoError(t, err)
+	cfg := &config.Config{}
+
+	for _, tc := range tests {
+		kevents, err := tc.Kevents()
+		require.NoError(t, err)
+		res := Run(tc, kevents, nil, cfg)
+		assert.True(t, res.Passed(), "%s: missing=%v unexpected=%v err=%v", tc.Name, res.Missing, res.Unexpected, res.Err)
+	}
+
+	var tc TestCase
+	for _, tc = range tests {
+		if tc.Name == "Svchost without service group" {
+			break
+		}
+	}
+	tc.Matches = []uint64{2, 3}
+	kevents, err := tc.Kevents()
+	require.NoError(t, err)
+	res := Run(tc, kevents, nil, cfg)
+	assert.False(t, res.Passed())
+	assert.Equal(t, []uint64{2, 3}, res.Missing)
+	assert.Equal(t, []uint64{1}, res.Unexpected)
+
+	tc.Filter = "ps.nmae = 'svchost.exe'"
+	res = Run(tc, kevents, nil, cfg)
+	require.Error(t, res.Err)
+	assert.False(t, res.Passed())
+}
+
+func TestRunner(t *testing.T) {
+	tests, err := Load([]string{"_fixtures/tests"})
+	require.NoError(t, err)
+	cfg := &config.Config{}
+
+	for _, tc := range tests {
+		kevents, err := tc.Kevents()
+		require.NoError(t, err)
+		r, err := NewRunner(tc, nil, cfg)
+		require.NoError(t, err)
+		for _, kevt := range kevents {
+			r.Next(kevt)
+		}
+		assert.Equal(t, Run(tc, kevents, nil, cfg), r.Result())
+	}
+
+	tc := TestCase{Name: "Bad filter", Filter: "ps.nmae = 'svchost.exe'"}
+	_, err = NewRunner(tc, nil, cfg)
+	require.Error(t, err)
+}
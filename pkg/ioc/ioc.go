@@ -0,0 +1,213 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import (
+	"expvar"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// indicatorsLoaded represents the number of indicators in each list
+	indicatorsLoaded = expvar.NewMap("ioc.indicators")
+	// reloads counts the number of times the lists were reloaded
+	reloads = expvar.NewInt("ioc.reloads")
+	// reloadErrors counts the number of reloads that failed
+	reloadErrors = expvar.NewInt("ioc.reload.errors")
+)
+
+// Type is the type of indicators in the list.
+type Type string
+
+const (
+	// Hash designates lists of MD5, SHA-1, SHA-256 or SHA-512 digests.
+	Hash Type = "hash"
+	// IP designates lists of IP addresses and networks in CIDR notation.
+	IP Type = "ip"
+	// Domain designates lists of domain names.
+	Domain Type = "domain"
+	// Path designates lists of file and directory paths.
+	Path Type = "path"
+)
+
+// Definition describes the named list of indicators of compromise loaded from the file. Filters match
+// values against the list with the ioc function, e.g. net.dip in ioc('c2_ips').
+type Definition struct {
+	// Name is the identifier by which the list is referenced.
+	Name string `json:"name" yaml:"name" mapstructure:"name"`
+	// Type determines how indicators are matched. Possible values are hash, ip, domain and path.
+	Type string `json:"type" yaml:"type" mapstructure:"type"`
+	// Path is the location of the file with indicators.
+	Path string `json:"path" yaml:"path" mapstructure:"path"`
+	// Format is the format of the file. Possible values are text, csv and stix. The format is derived
+	// from the file extension if not given.
+	Format string `json:"format" yaml:"format" mapstructure:"format"`
+	// Column is the header name or the zero-based index of the CSV column with indicators.
+	Column string `json:"column" yaml:"column" mapstructure:"column"`
+}
+
+// List is the named list of indicators of compromise. Values are matched against
+// the indicators without expanding them into the filter expression. The list is
+// safe for concurrent use and can be reloaded while filters are evaluated.
+type List struct {
+	Definition
+	typ    Type
+	format Format
+	set    atomic.Value
+	mtime  time.Time
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// Open loads the list for the given definition. If the interval is positive, the list
+// file is periodically checked for changes and reloaded until the list is closed.
+func Open(def Definition, interval time.Duration) (*List, error) {
+	typ := Type(strings.ToLower(def.Type))
+	if newSet(typ) == nil {
+		return nil, fmt.Errorf("unknown indicator type %q", def.Type)
+	}
+	format := Format(strings.ToLower(def.Format))
+	if format == "" {
+		format = formatFromFile(def.Path)
+	}
+	l := &List{Definition: def, typ: typ, format: format, stop: make(chan struct{})}
+	if err := l.reload(); err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		l.wg.Add(1)
+		go l.watch(interval)
+	}
+	return l, nil
+}
+
+// Contains determines whether the value matches any of the indicators. The value is
+// either a string, IP address, or a slice of strings where any of the strings has to
+// match.
+func (l *List) Contains(val interface{}) bool {
+	if l == nil {
+		return false
+	}
+	s := l.set.Load().(set)
+	switch v := val.(type) {
+	case string:
+		return s.contains(v)
+	case net.IP:
+		if ips, ok := s.(*ipSet); ok {
+			return ips.containsIP(v)
+		}
+		return s.contains(v.String())
+	case []string:
+		for _, e := range v {
+			if s.contains(e) {
+				return true
+			}
+		}
+	case fmt.Stringer:
+		return s.contains(v.String())
+	}
+	return false
+}
+
+// Len returns the number of indicators in the list.
+func (l *List) Len() int { return l.set.Load().(set).len() }
+
+// Close stops reloading the list. It waits for the reload in progress to complete.
+func (l *List) Close() {
+	close(l.stop)
+	l.wg.Wait()
+}
+
+// reload reads the indicators from the list file. If the file can't be read, the
+// previously loaded indicators remain in effect. Invalid indicators are skipped.
+func (l *List) reload() error {
+	f, err := os.Open(l.Path)
+	if err != nil {
+		return fmt.Errorf("couldn't load %s IOC list: %v", l.Name, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("couldn't load %s IOC list: %v", l.Name, err)
+	}
+	indicators, err := read(f, l.format, l.typ, l.Column)
+	if err != nil {
+		return fmt.Errorf("couldn't load %s IOC list: %s: %v", l.Name, l.Path, err)
+	}
+	s := newSet(l.typ)
+	var invalid int
+	for _, indicator := range indicators {
+		if !s.add(indicator) {
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		log.Warnf("skipped %d invalid indicator(s) in %s IOC list", invalid, l.Name)
+	}
+
+	l.set.Store(s)
+	l.mtime = info.ModTime()
+	indicatorsLoaded.Set(l.Name, expvarInt(s.len()))
+	reloads.Add(1)
+	log.Infof("loaded %d indicator(s) in %s IOC list", s.len(), l.Name)
+
+	return nil
+}
+
+// watch reloads the list when the list file is modified.
+func (l *List) watch(interval time.Duration) {
+	defer l.wg.Done()
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-tick.C:
+			info, err := os.Stat(l.Path)
+			if err != nil {
+				reloadErrors.Add(1)
+				log.Warnf("couldn't stat %s IOC list: %v", l.Name, err)
+				continue
+			}
+			if info.ModTime().Equal(l.mtime) {
+				continue
+			}
+			if err := l.reload(); err != nil {
+				reloadErrors.Add(1)
+				log.Warnf("couldn't reload IOC list: %v", err)
+				// don't retry until the file changes again
+				l.mtime = info.ModTime()
+			}
+		}
+	}
+}
+
+func expvarInt(n int) *expvar.Int {
+	v := new(expvar.Int)
+	v.Set(int64(n))
+	return v
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpen(t *testing.T) {
+	ips, err := Open(Definition{Name: "c2_ips", Type: "ip", Path: "_fixtures/c2_ips.txt"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, ips.Len())
+
+	hashes, err := Open(Definition{Name: "samples", Type: "hash", Path: "_fixtures/hashes.csv", Column: "sha256"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, hashes.Len())
+
+	_, err = Open(Definition{Name: "samples", Type: "hash", Path: "_fixtures/hashes.csv", Column: "md5"}, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "column \"md5\" not found in the header")
+	_, err = Open(Definition{Name: "bad_paths", Type: "path", Path: "_fixtures/missing.txt"}, 0)
+	require.Error(t, err)
+	_, err = Open(Definition{Name: "urls", Type: "url", Path: "_fixtures/c2_ips.txt"}, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown indicator type \"url\"")
+}
+
+func TestContains(t *testing.T) {
+	ips, err := Open(Definition{Name: "c2_ips", Type: "ip", Path: "_fixtures/c2_ips.txt"}, 0)
+	require.NoError(t, err)
+	hashes, err := Open(Definition{Name: "samples", Type: "hash", Path: "_fixtures/hashes.csv", Column: "sha256"}, 0)
+	require.NoError(t, err)
+	domains, err := Open(Definition{Name: "domains", Type: "domain", Path: "_fixtures/bundle.json"}, 0)
+	require.NoError(t, err)
+	paths, err := Open(Definition{Name: "bad_paths", Type: "path", Path: "_fixtures/bundle.json", Format: "stix"}, 0)
+	require.NoError(t, err)
+	stixHashes, err := Open(Definition{Name: "stix_hashes", Type: "hash", Path: "_fixtures/bundle.json"}, 0)
+	require.NoError(t, err)
+
+	var tests = []struct {
+		l        *List
+		val      interface{}
+		contains bool
+	}{
+		{ips, net.ParseIP("185.220.101.4"), true},
+		{ips, "185.220.101.4", true},
+		{ips, net.ParseIP("185.220.101.5"), false},
+		{ips, net.ParseIP("10.0.2.15"), true},
+		{ips, net.ParseIP("10.0.3.15"), false},
+		{ips, net.ParseIP("2001:db8::1"), true},
+		{ips, net.ParseIP("2001:db9::1"), false},
+		{ips, "10.0.2", false},
+		{ips, nil, false},
+		{hashes, "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855", true},
+		{hashes, "5f4dcc3b5aa765d61d8327deb882cf99", true},
+		{hashes, "5f4dcc3b5aa765d61d8327deb882cf98", false},
+		{domains, "evil.com", true},
+		{domains, "WWW.Evil.com.", true},
+		{domains, "notevil.com", false},
+		{domains, "com", false},
+		{domains, "c2.example.org", true},
+		{domains, "example.org", false},
+		{paths, "c:\\users\\public\\SVCH0ST.exe", true},
+		{paths, "C:\\Users\\Public\\Temp\\payload.dll", true},
+		{paths, "C:\\Users\\Public\\Temp.dll", false},
+		{paths, []string{"C:\\Windows\\notepad.exe", "C:\\Users\\Public\\svch0st.exe"}, true},
+		{stixHashes, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", true},
+		{stixHashes, "evil.com", false},
+	}
+
+	for i, tt := range tests {
+		assert.Equal(t, tt.contains, tt.l.Contains(tt.val), "%d. %s contains %v", i, tt.l.Name, tt.val)
+	}
+
+	var l *List
+	assert.False(t, l.Contains("evil.com"))
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "iocs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "domains.txt")
+	require.NoError(t, ioutil.WriteFile(file, []byte("evil.com\n"), 0644))
+
+	l, err := Open(Definition{Name: "domains", Type: "domain", Path: file}, time.Millisecond*10)
+	require.NoError(t, err)
+	defer l.Close()
+	assert.True(t, l.Contains("evil.com"))
+	assert.False(t, l.Contains("c2.example.org"))
+
+	require.NoError(t, ioutil.WriteFile(file, []byte(strings.Join([]string{"evil.com", "example.org"}, "\n")), 0644))
+	// make sure the modification time changes on file systems with coarse timestamps
+	require.NoError(t, os.Chtimes(file, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	assert.Eventually(t, func() bool { return l.Contains("c2.example.org") }, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, 2, l.Len())
+}
+
+func TestClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "iocs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "domains.txt")
+	require.NoError(t, ioutil.WriteFile(file, []byte("evil.com\n"), 0644))
+
+	l, err := Open(Definition{Name: "domains", Type: "domain", Path: file}, time.Millisecond*10)
+	require.NoError(t, err)
+	l.Close()
+
+	// closed lists are no longer reloaded
+	require.NoError(t, ioutil.WriteFile(file, []byte("example.org\n"), 0644))
+	require.NoError(t, os.Chtimes(file, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	time.Sleep(time.Millisecond * 100)
+	assert.True(t, l.Contains("evil.com"))
+	assert.False(t, l.Contains("example.org"))
+}
@@ -0,0 +1,208 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Format is the format of the file with indicators.
+type Format string
+
+const (
+	// Text files contain one indicator per line. Blank lines and lines starting with # are ignored.
+	Text Format = "text"
+	// CSV files contain indicators in the column given by the header name or the index.
+	CSV Format = "csv"
+	// STIX files contain the bundle or the array of STIX 2 indicators and cyber observables in JSON format.
+	STIX Format = "stix"
+)
+
+// formatFromFile derives the format from the file extension.
+func formatFromFile(file string) Format {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		return CSV
+	case ".json":
+		return STIX
+	}
+	return Text
+}
+
+// read parses the indicators from the reader in the given format. Only indicators of the
+// list type are extracted from STIX files.
+func read(r io.Reader, format Format, typ Type, column string) ([]string, error) {
+	switch format {
+	case Text:
+		return readText(r)
+	case CSV:
+		return readCSV(r, column)
+	case STIX:
+		return readSTIX(r, typ)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func readText(r io.Reader) ([]string, error) {
+	indicators := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		indicators = append(indicators, line)
+	}
+	return indicators, scanner.Err()
+}
+
+func readCSV(r io.Reader, column string) ([]string, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	col, err := strconv.Atoi(column)
+	if column == "" {
+		col, err = 0, nil
+	}
+	if err != nil {
+		// the column is given by the header name
+		header, err := cr.Read()
+		if err != nil {
+			return nil, err
+		}
+		col = -1
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				col = i
+				break
+			}
+		}
+		if col < 0 {
+			return nil, fmt.Errorf("column %q not found in the header", column)
+		}
+	}
+
+	indicators := make([]string, 0)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if col < len(record) {
+			if v := strings.TrimSpace(record[col]); v != "" {
+				indicators = append(indicators, v)
+			}
+		}
+	}
+	return indicators, nil
+}
+
+// stixObject contains the attributes of STIX indicators and cyber observables
+// that carry indicator values. Bundles contain the objects in the objects array.
+type stixObject struct {
+	Type    string            `json:"type"`
+	Pattern string            `json:"pattern"`
+	Value   string            `json:"value"`
+	Name    string            `json:"name"`
+	Path    string            `json:"path"`
+	Hashes  map[string]string `json:"hashes"`
+	Objects []stixObject      `json:"objects"`
+}
+
+// stixComparison matches the equality comparisons of STIX patterns, e.g. [ipv4-addr:value = '10.0.2.15']
+var stixComparison = regexp.MustCompile(`([a-z0-9-]+):([a-zA-Z0-9_.'-]+)\s*=\s*'((?:[^'\\]|\\.)*)'`)
+
+func readSTIX(r io.Reader, typ Type) ([]string, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var objects []stixObject
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '[' {
+		err = json.Unmarshal(b, &objects)
+	} else {
+		var o stixObject
+		err = json.Unmarshal(b, &o)
+		objects = []stixObject{o}
+	}
+	if err != nil {
+		return nil, err
+	}
+	indicators := make([]string, 0)
+	for _, o := range objects {
+		indicators = append(indicators, o.indicators(typ)...)
+	}
+	return indicators, nil
+}
+
+// indicators returns the values of the object and the nested objects that are of the given type.
+func (o stixObject) indicators(typ Type) []string {
+	indicators := make([]string, 0)
+	for _, m := range stixComparison.FindAllStringSubmatch(o.Pattern, -1) {
+		if stixType(m[1], m[2]) == typ {
+			indicators = append(indicators, strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace(m[3]))
+		}
+	}
+	switch {
+	case o.Type == "file" && typ == Hash:
+		for _, h := range o.Hashes {
+			indicators = append(indicators, h)
+		}
+	case o.Type == "file" && o.Name != "" && typ == Path:
+		indicators = append(indicators, o.Name)
+	case o.Type == "directory" && o.Path != "" && typ == Path:
+		indicators = append(indicators, o.Path)
+	case o.Value != "" && stixType(o.Type, "value") == typ:
+		indicators = append(indicators, o.Value)
+	}
+	for _, obj := range o.Objects {
+		indicators = append(indicators, obj.indicators(typ)...)
+	}
+	return indicators
+}
+
+// stixType maps the STIX object type and the property path to the indicator type.
+func stixType(object, path string) Type {
+	switch {
+	case object == "file" && strings.HasPrefix(path, "hashes."):
+		return Hash
+	case (object == "ipv4-addr" || object == "ipv6-addr") && path == "value":
+		return IP
+	case object == "domain-name" && path == "value":
+		return Domain
+	case object == "file" && path == "name", object == "directory" && path == "path":
+		return Path
+	}
+	return ""
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ioc
+
+import (
+	"encoding/hex"
+	"net"
+	"strings"
+)
+
+// set stores the indicators of the single type.
+type set interface {
+	// add adds the indicator to the set. It returns false if the indicator is not valid for the set type.
+	add(indicator string) bool
+	// contains determines whether the value matches any of the indicators.
+	contains(val string) bool
+	// len returns the number of indicators in the set.
+	len() int
+}
+
+func newSet(typ Type) set {
+	switch typ {
+	case Hash:
+		return make(hashSet)
+	case IP:
+		return &ipSet{ips: make(map[string]struct{})}
+	case Domain:
+		return &domainSet{root: &domainNode{}}
+	case Path:
+		return &pathSet{paths: make(map[string]struct{}), dirs: make(map[string]struct{})}
+	}
+	return nil
+}
+
+// hashSet stores MD5, SHA-1, SHA-256 and SHA-512 digests. Digests are matched regardless of the case.
+type hashSet map[string]struct{}
+
+func (s hashSet) add(indicator string) bool {
+	h := strings.ToLower(indicator)
+	switch len(h) {
+	case 32, 40, 64, 128:
+	default:
+		return false
+	}
+	if _, err := hex.DecodeString(h); err != nil {
+		return false
+	}
+	s[h] = struct{}{}
+	return true
+}
+
+func (s hashSet) contains(val string) bool {
+	_, ok := s[strings.ToLower(val)]
+	return ok
+}
+
+func (s hashSet) len() int { return len(s) }
+
+// ipSet stores addresses and networks. Addresses are looked up in the map, while networks
+// are stored in the binary trie of their prefixes. IPv4 addresses and networks are stored
+// in their IPv6 form, so both families are matched in the same trie.
+type ipSet struct {
+	ips  map[string]struct{}
+	nets *ipNode
+	// n is the number of networks in the trie
+	n int
+}
+
+// ipNode is the node of the binary trie. Every node represents one bit of the prefix.
+type ipNode struct {
+	children [2]*ipNode
+	// terminal marks the last bit of the network prefix
+	terminal bool
+}
+
+func (s *ipSet) add(indicator string) bool {
+	if ip := net.ParseIP(indicator); ip != nil {
+		s.ips[string(ip.To16())] = struct{}{}
+		return true
+	}
+	_, n, err := net.ParseCIDR(indicator)
+	if err != nil {
+		return false
+	}
+	ones, bits := n.Mask.Size()
+	if bits == net.IPv4len*8 {
+		ones += (net.IPv6len - net.IPv4len) * 8
+	}
+	if s.nets == nil {
+		s.nets = &ipNode{}
+	}
+	node, ip := s.nets, n.IP.To16()
+	for i := 0; i < ones; i++ {
+		b := ip[i/8] >> (7 - uint(i%8)) & 1
+		if node.children[b] == nil {
+			node.children[b] = &ipNode{}
+		}
+		node = node.children[b]
+	}
+	if !node.terminal {
+		node.terminal = true
+		s.n++
+	}
+	return true
+}
+
+func (s *ipSet) contains(val string) bool {
+	ip := net.ParseIP(val)
+	if ip == nil {
+		return false
+	}
+	return s.containsIP(ip)
+}
+
+func (s *ipSet) containsIP(ip net.IP) bool {
+	ip = ip.To16()
+	if ip == nil {
+		return false
+	}
+	if _, ok := s.ips[string(ip)]; ok {
+		return true
+	}
+	node := s.nets
+	for i := 0; node != nil; i++ {
+		if node.terminal {
+			return true
+		}
+		if i == net.IPv6len*8 {
+			break
+		}
+		node = node.children[ip[i/8]>>(7-uint(i%8))&1]
+	}
+	return false
+}
+
+func (s *ipSet) len() int { return len(s.ips) + s.n }
+
+// domainSet stores domain names in the trie of their labels in reverse order. The
+// domain matches itself and all its subdomains, so evil.com matches www.evil.com.
+type domainSet struct {
+	root *domainNode
+	n    int
+}
+
+type domainNode struct {
+	children map[string]*domainNode
+	terminal bool
+}
+
+func (s *domainSet) add(indicator string) bool {
+	labels := domainLabels(indicator)
+	if len(labels) == 0 {
+		return false
+	}
+	node := s.root
+	for i := len(labels) - 1; i >= 0; i-- {
+		if node.children == nil {
+			node.children = make(map[string]*domainNode)
+		}
+		child, ok := node.children[labels[i]]
+		if !ok {
+			child = &domainNode{}
+			node.children[labels[i]] = child
+		}
+		node = child
+	}
+	if !node.terminal {
+		node.terminal = true
+		s.n++
+	}
+	return true
+}
+
+func (s *domainSet) contains(val string) bool {
+	labels := domainLabels(val)
+	node := s.root
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = node.children[labels[i]]
+		if node == nil {
+			return false
+		}
+		if node.terminal {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *domainSet) len() int { return s.n }
+
+func domainLabels(domain string) []string {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if domain == "" || strings.ContainsAny(domain, " /\\") {
+		return nil
+	}
+	return strings.Split(domain, ".")
+}
+
+// pathSet stores file and directory paths. Paths are matched regardless of the case.
+// Indicators ending with the path separator are directories that match all the paths
+// beneath them.
+type pathSet struct {
+	paths map[string]struct{}
+	dirs  map[string]struct{}
+}
+
+func (s *pathSet) add(indicator string) bool {
+	path := strings.ToLower(indicator)
+	if path == "" {
+		return false
+	}
+	if strings.HasSuffix(path, "\\") || strings.HasSuffix(path, "/") {
+		s.dirs[path] = struct{}{}
+		return true
+	}
+	s.paths[path] = struct{}{}
+	return true
+}
+
+func (s *pathSet) contains(val string) bool {
+	path := strings.ToLower(val)
+	if _, ok := s.paths[path]; ok {
+		return true
+	}
+	if len(s.dirs) == 0 {
+		return false
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] != '\\' && path[i] != '/' {
+			continue
+		}
+		if _, ok := s.dirs[path[:i+1]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *pathSet) len() int { return len(s.paths) + len(s.dirs) }
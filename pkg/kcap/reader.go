@@ -26,6 +26,7 @@ import (
 	"expvar"
 	"fmt"
 	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/exceptions"
 	"github.com/rabbitstack/fibratus/pkg/filter"
 	"github.com/rabbitstack/fibratus/pkg/handle"
 	htypes "github.com/rabbitstack/fibratus/pkg/handle/types"
@@ -61,6 +62,7 @@ type reader struct {
 	psnapshotter ps.Snapshotter
 	hsnapshotter handle.Snapshotter
 	filter       filter.Filter
+	allowlist    *exceptions.Allowlist
 	config       *config.Config
 	mu           sync.Mutex // guards the underlying zstd byte buffer
 }
@@ -116,12 +118,16 @@ func NewReader(filename string, config *config.Config) (Reader, error) {
 
 func (r *reader) SetFilter(f filter.Filter) { r.filter = f }
 
+func (r *reader) SetAllowlist(a *exceptions.Allowlist) { r.allowlist = a }
+
 func (r *reader) Read(ctx context.Context) (chan *kevent.Kevent, chan error) {
 	errsc := make(chan error, 100)
 	keventsc := make(chan *kevent.Kevent, 2000)
 	go func() {
 		r.mu.Lock()
 		defer r.mu.Unlock()
+		// signal the end of the capture to the consumers
+		defer close(keventsc)
 		for {
 			select {
 			case <-ctx.Done():
@@ -159,7 +165,7 @@ func (r *reader) Read(ctx context.Context) (chan *kevent.Kevent, chan error) {
 				log.Warn(err)
 			}
 			// push the event to the chanel
-			r.read(kevt, keventsc)
+			r.read(ctx, kevt, keventsc)
 		}
 	}()
 
@@ -178,7 +184,7 @@ func (r *reader) Close() error {
 	return nil
 }
 
-func (r *reader) read(kevt *kevent.Kevent, keventsc chan *kevent.Kevent) {
+func (r *reader) read(ctx context.Context, kevt *kevent.Kevent, keventsc chan *kevent.Kevent) {
 	if kevt.Type.Dropped(false) {
 		return
 	}
@@ -186,8 +192,17 @@ func (r *reader) read(kevt *kevent.Kevent, keventsc chan *kevent.Kevent) {
 		kcapDroppedByFilter.Add(1)
 		return
 	}
-	keventsc <- kevt
-	kcapReadKevents.Add(1)
+	// exempted events are marked, so they are received by
+	// rules and filaments, but not published to outputs
+	if r.allowlist != nil {
+		r.allowlist.Exempt(kevt)
+	}
+	// don't block on consumers that stopped receiving
+	select {
+	case keventsc <- kevt:
+		kcapReadKevents.Add(1)
+	case <-ctx.Done():
+	}
 }
 
 func (r *reader) updateSnapshotters(kevt *kevent.Kevent) error {
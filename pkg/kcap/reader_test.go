@@ -25,6 +25,7 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/config"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestRead(t *testing.T) {
@@ -56,3 +57,30 @@ func TestRead(t *testing.T) {
 	}
 
 }
+
+func TestReadUntilEOF(t *testing.T) {
+	r, err := NewReader("_fixtures/cap1.kcap", &config.Config{})
+	require.NoError(t, err)
+	defer r.Close()
+	_, _, err = r.RecoverSnapshotters()
+	require.NoError(t, err)
+
+	kevtsc, errs := r.Read(context.Background())
+	i := 0
+	for {
+		select {
+		case kevt, ok := <-kevtsc:
+			if !ok {
+				// the channel is closed when the last event is read
+				require.True(t, i > 0)
+				return
+			}
+			require.NotNil(t, kevt)
+			i++
+		case err := <-errs:
+			t.Fatal(err)
+		case <-time.After(time.Second * 10):
+			t.Fatal("event channel wasn't closed")
+		}
+	}
+}
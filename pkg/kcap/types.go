@@ -20,6 +20,7 @@ package kcap
 
 import (
 	"context"
+	"github.com/rabbitstack/fibratus/pkg/exceptions"
 	"github.com/rabbitstack/fibratus/pkg/filter"
 	"github.com/rabbitstack/fibratus/pkg/handle"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
@@ -52,7 +53,8 @@ type Writer interface {
 // Reader offers the mechanism for recovering the state of the kcapture and replaying all captured events.
 type Reader interface {
 	// Read returns two channels. The event channel is poplated with event instances pulled from the kcap. If
-	// any error occurs during kcap processing, it is pushed to the error channel.
+	// any error occurs during kcap processing, it is pushed to the error channel. The event channel is closed
+	// when the end of the kcap is reached or the context is cancelled.
 	Read(ctx context.Context) (chan *kevent.Kevent, chan error)
 	// Close shutdowns the reader gracefully.
 	Close() error
@@ -60,4 +62,6 @@ type Reader interface {
 	RecoverSnapshotters() (handle.Snapshotter, ps.Snapshotter, error)
 	// SetFilter sets the filter that's is applied to each event coming out of the kcap.
 	SetFilter(f filter.Filter)
+	// SetAllowlist sets the allowlist that exempts events matching the exceptions.
+	SetAllowlist(a *exceptions.Allowlist)
 }
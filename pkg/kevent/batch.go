@@ -18,9 +18,17 @@
 
 package kevent
 
+import "sync/atomic"
+
 // Batch contains a sequence of kernel events.
 type Batch struct {
 	Events []*Kevent
+	// parent is the batch whose events are shared with this batch
+	parent *Batch
+	// refs is the number of batches sharing the events of this batch
+	refs int32
+	// released is set when the events are returned to the pool
+	released int32
 }
 
 // NewBatch produces a new batch from the group of events.
@@ -31,13 +39,42 @@ func NewBatch(evts ...*Kevent) *Batch {
 // Len returns the length of the batch.
 func (b *Batch) Len() int64 { return int64(len(b.Events)) }
 
-// Release releases all events from the batch and returns them to the pool.
+// Share produces a new batch with a subset of events from this batch. Once shared, events
+// are owned by the sharing batches and returned to the pool after all of them are released.
+func (b *Batch) Share(evts ...*Kevent) *Batch {
+	root := b.root()
+	atomic.AddInt32(&root.refs, 1)
+	return &Batch{Events: evts, parent: root}
+}
+
+// Release releases all events from the batch and returns them to the pool. Releasing the batch
+// whose events are shared has no effect, since the events are returned to the pool when the
+// last batch sharing them is released.
 func (b *Batch) Release() {
-	for _, e := range b.Events {
+	root := b.root()
+	if b != root {
+		if atomic.AddInt32(&root.refs, -1) > 0 {
+			return
+		}
+	} else if atomic.LoadInt32(&root.refs) > 0 {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&root.released, 0, 1) {
+		return
+	}
+	for _, e := range root.Events {
 		e.Release()
 	}
 }
 
+// root returns the batch that owns the events.
+func (b *Batch) root() *Batch {
+	if b.parent != nil {
+		return b.parent
+	}
+	return b
+}
+
 // MarshalJSON serializes the batch of events to JSON format.
 func (b *Batch) MarshalJSON() []byte {
 	buf := make([]byte, 0)
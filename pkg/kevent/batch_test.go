@@ -250,3 +250,52 @@ func TestBatchMarshalJSON(t *testing.T) {
 	assert.Equal(t, uint32(459), kevts[1].PID)
 	assert.Equal(t, uint32(829), kevts[2].PID)
 }
+
+func TestBatchShare(t *testing.T) {
+	kevt1 := &Kevent{Type: ktypes.CreateFile, Name: "CreateFile", Kparams: Kparams{}}
+	kevt2 := &Kevent{Type: ktypes.CloseFile, Name: "CloseFile", Kparams: Kparams{}}
+
+	b := NewBatch(kevt1, kevt2)
+	b1 := b.Share(kevt1)
+	b2 := b.Share(kevt1, kevt2)
+
+	require.Equal(t, int64(1), b1.Len())
+	b1.Release()
+	require.Equal(t, "CreateFile", kevt1.Name)
+	require.Equal(t, "CloseFile", kevt2.Name)
+
+	b2.Release()
+	require.Empty(t, kevt1.Name)
+	require.Empty(t, kevt2.Name)
+}
+
+func TestBatchShareReleaseOwner(t *testing.T) {
+	kevt1 := &Kevent{Type: ktypes.CreateFile, Name: "CreateFile", Kparams: Kparams{}}
+	kevt2 := &Kevent{Type: ktypes.CloseFile, Name: "CloseFile", Kparams: Kparams{}}
+
+	b := NewBatch(kevt1, kevt2)
+	b1 := b.Share(kevt1)
+	b2 := b1.Share(kevt1, kevt2)
+
+	// the owner batch is released while its events are shared
+	b.Release()
+	require.Equal(t, "CreateFile", kevt1.Name)
+	require.Equal(t, "CloseFile", kevt2.Name)
+
+	b1.Release()
+	require.Equal(t, "CreateFile", kevt1.Name)
+	require.Equal(t, "CloseFile", kevt2.Name)
+
+	b2.Release()
+	require.Empty(t, kevt1.Name)
+	require.Empty(t, kevt2.Name)
+
+	// events aren't released twice when the owner batch is released after its shares
+	kevt3 := &Kevent{Type: ktypes.CreateFile, Name: "CreateFile", Kparams: Kparams{}}
+	b = NewBatch(kevt3)
+	b.Share(kevt3).Release()
+	require.Empty(t, kevt3.Name)
+	kevt3.Name = "CreateFile"
+	b.Release()
+	require.Equal(t, "CreateFile", kevt3.Name)
+}
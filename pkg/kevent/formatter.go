@@ -20,6 +20,7 @@ package kevent
 
 import (
 	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/mitre"
 	"github.com/rabbitstack/fibratus/pkg/util/fasttemplate"
 	"regexp"
 	"sort"
@@ -52,6 +53,7 @@ const (
 	meta          = ".Meta"
 	host          = ".Host"
 	pe            = ".PE"
+	attack        = ".Mitre"
 	kparsAccessor = ".Kparams."
 )
 
@@ -84,6 +86,7 @@ var kfields = map[string]bool{
 	meta:        true,
 	host:        true,
 	pe:          true,
+	attack:      true,
 }
 
 func hintFields() string {
@@ -202,6 +205,7 @@ func (f *Formatter) Format(kevt *Kevent) []byte {
 		host:        kevt.Host,
 		meta:        kevt.Metadata.String(),
 		kparameters: kevt.Kparams.String(),
+		attack:      formatAttack(kevt.Metadata),
 	}
 
 	// add process' metadata
@@ -228,3 +232,15 @@ func (f *Formatter) Format(kevt *Kevent) []byte {
 
 	return f.t.ExecuteString(values)
 }
+
+// formatAttack renders the MITRE ATT&CK tactics and techniques found in the metadata.
+func formatAttack(md Metadata) string {
+	attack := make([]string, 0)
+	for _, tactic := range mitre.Tactics(md[mitre.TacticsMeta]) {
+		attack = append(attack, tactic.String())
+	}
+	for _, tech := range mitre.Techniques(md[mitre.TechniquesMeta]) {
+		attack = append(attack, tech.String())
+	}
+	return strings.Join(attack, ", ")
+}
@@ -61,6 +61,15 @@ func TestFormat(t *testing.T) {
 	assert.Equal(t, "1999 4 -  (CreateProcess) -- pid: 0x36c (pid➜ 0x36c) key1:value1", string(s))
 }
 
+func TestFormatAttack(t *testing.T) {
+	f, err := NewFormatter("{{ .Seq }} {{ .Type }} [{{ .Mitre }}]")
+	require.NoError(t, err)
+	kevt := &Kevent{Name: "CreateProcess", Seq: uint64(1999), Metadata: map[string]string{}}
+	assert.Equal(t, "1999 CreateProcess []", string(f.Format(kevt)))
+	kevt.AddAttack([]string{"TA0002"}, []string{"T1059.001"})
+	assert.Equal(t, "1999 CreateProcess [TA0002 Execution, T1059.001 PowerShell]", string(f.Format(kevt)))
+}
+
 func TestFormatPS(t *testing.T) {
 	template := "{{ .Seq }} {{ .Process }} ({{ .Cwd }}) {{ .Ppid }} ({{ .Sid }})"
 	f, err := NewFormatter(template)
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	kcapver "github.com/rabbitstack/fibratus/pkg/kcap/version"
 	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/mitre"
 	pstypes "github.com/rabbitstack/fibratus/pkg/ps/types"
 	"github.com/rabbitstack/fibratus/pkg/util/hostname"
 	"strings"
@@ -39,6 +40,9 @@ var pool = sync.Pool{
 // TimestampFormat is the Go valid format for the kernel event timestamp
 var TimestampFormat string
 
+// ExceptionMeta is the metadata key under which the name of the exception that exempts the event is stored.
+const ExceptionMeta = "exception.name"
+
 // Metadata is a type alias for event metadata. Any tag, i.e. key/value pair could be attached to metadata.
 type Metadata map[string]string
 
@@ -181,6 +185,23 @@ func (kevt *Kevent) AddMeta(k, v string) {
 	kevt.Metadata[k] = v
 }
 
+// IsExempted determines whether the event is exempted from outputs and alerts by any of the exceptions.
+func (kevt *Kevent) IsExempted() bool {
+	_, ok := kevt.Metadata[ExceptionMeta]
+	return ok
+}
+
+// AddAttack attaches the identifiers of MITRE ATT&CK tactics and techniques to the event
+// metadata. Identifiers are appended to those already present in the metadata.
+func (kevt *Kevent) AddAttack(tactics, techniques []string) {
+	if len(tactics) > 0 {
+		kevt.AddMeta(mitre.TacticsMeta, mitre.Join(kevt.Metadata[mitre.TacticsMeta], tactics))
+	}
+	if len(techniques) > 0 {
+		kevt.AddMeta(mitre.TechniquesMeta, mitre.Join(kevt.Metadata[mitre.TechniquesMeta], techniques))
+	}
+}
+
 // Release returns an event to the pool.
 func (kevt *Kevent) Release() {
 	for _, kpar := range kevt.Kparams {
@@ -189,3 +210,18 @@ func (kevt *Kevent) Release() {
 	*kevt = Kevent{} // clear kevent
 	pool.Put(kevt)
 }
+
+// Clone returns a copy of the event that remains valid after the original event is returned to the pool.
+func (kevt *Kevent) Clone() *Kevent {
+	clone := *kevt
+	clone.Kparams = make(Kparams, len(kevt.Kparams))
+	for name, kpar := range kevt.Kparams {
+		kparam := *kpar
+		clone.Kparams[name] = &kparam
+	}
+	clone.Metadata = make(map[string]string, len(kevt.Metadata))
+	for k, v := range kevt.Metadata {
+		clone.Metadata[k] = v
+	}
+	return &clone
+}
@@ -151,6 +151,8 @@ const (
 	Map
 	// Object is the generic object
 	Object
+	// Duration represents the time interval
+	Duration
 	// Unknown represent an unknown parameter type
 	Unknown
 )
@@ -198,6 +200,8 @@ func (t Type) String() string {
 		return "ipv6"
 	case IPv4:
 		return "ipv4"
+	case Duration:
+		return "duration"
 	default:
 		return "unknown"
 	}
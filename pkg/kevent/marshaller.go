@@ -26,6 +26,7 @@ import (
 	kcapver "github.com/rabbitstack/fibratus/pkg/kcap/version"
 	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
 	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/mitre"
 	knet "github.com/rabbitstack/fibratus/pkg/net"
 	ptypes "github.com/rabbitstack/fibratus/pkg/ps/types"
 	"github.com/rabbitstack/fibratus/pkg/util/bytes"
@@ -500,6 +501,48 @@ func (kevt *Kevent) MarshalJSON() []byte {
 
 	// end metadata
 	js.writeObjectEnd()
+
+	// start MITRE ATT&CK tactics and techniques
+	tactics, techniques := kevt.Metadata[mitre.TacticsMeta], kevt.Metadata[mitre.TechniquesMeta]
+	if tactics != "" || techniques != "" {
+		js.writeMore()
+		js.writeObjectField("mitre")
+		js.writeObjectStart()
+
+		js.writeObjectField("tactics")
+		js.writeArrayStart()
+		tacts := mitre.Tactics(tactics)
+		for i, tactic := range tacts {
+			writeMore := js.shouldWriteMore(i, len(tacts))
+			js.writeObjectStart()
+			js.writeObjectField("id").writeEscapeString(tactic.ID).writeMore()
+			js.writeObjectField("name").writeEscapeString(tactic.Name)
+			js.writeObjectEnd()
+			if writeMore {
+				js.writeMore()
+			}
+		}
+		js.writeArrayEnd().writeMore()
+
+		js.writeObjectField("techniques")
+		js.writeArrayStart()
+		techs := mitre.Techniques(techniques)
+		for i, tech := range techs {
+			writeMore := js.shouldWriteMore(i, len(techs))
+			js.writeObjectStart()
+			js.writeObjectField("id").writeEscapeString(tech.ID).writeMore()
+			js.writeObjectField("name").writeEscapeString(tech.Name)
+			js.writeObjectEnd()
+			if writeMore {
+				js.writeMore()
+			}
+		}
+		js.writeArrayEnd()
+
+		js.writeObjectEnd()
+	}
+	// end MITRE ATT&CK tactics and techniques
+
 	ps := kevt.PS
 	if ps != nil {
 		js.writeMore()
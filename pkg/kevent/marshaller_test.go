@@ -204,6 +204,36 @@ func TestKeventMarshalJSON(t *testing.T) {
 	assert.Len(t, newKevt.PS.PE.VersionResources, 3)
 }
 
+func TestKeventMarshalJSONWithAttack(t *testing.T) {
+	kevt := &Kevent{
+		Type:      ktypes.CreateProcess,
+		Name:      "CreateProcess",
+		Category:  ktypes.Process,
+		Timestamp: time.Now(),
+		Kparams:   Kparams{},
+		Metadata:  map[string]string{"foo": "bar"},
+	}
+	kevt.AddAttack([]string{"TA0002"}, []string{"T1059.001", "T1218"})
+	kevt.AddAttack(nil, []string{"T1059.001", "T9999"})
+
+	var m struct {
+		Meta  map[string]string `json:"meta"`
+		Mitre struct {
+			Tactics    []map[string]string `json:"tactics"`
+			Techniques []map[string]string `json:"techniques"`
+		} `json:"mitre"`
+	}
+	require.NoError(t, json.Unmarshal(kevt.MarshalJSON(), &m))
+
+	assert.Equal(t, "T1059.001,T1218,T9999", m.Meta["mitre.technique.id"])
+	assert.Equal(t, []map[string]string{{"id": "TA0002", "name": "Execution"}}, m.Mitre.Tactics)
+	assert.Equal(t, []map[string]string{
+		{"id": "T1059.001", "name": "PowerShell"},
+		{"id": "T1218", "name": "System Binary Proxy Execution"},
+		{"id": "T9999", "name": ""},
+	}, m.Mitre.Techniques)
+}
+
 func TestUnmarshalHugeHandles(t *testing.T) {
 	b, err := ioutil.ReadFile("_fixtures\\handles.json")
 	require.NoError(t, err)
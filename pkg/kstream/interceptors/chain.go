@@ -28,8 +28,6 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/ps"
 	"github.com/rabbitstack/fibratus/pkg/util/multierror"
-	"github.com/rabbitstack/fibratus/pkg/yara"
-	log "github.com/sirupsen/logrus"
 )
 
 // interceptorFailures counts the number of failures caused by interceptors while processing kernel events
@@ -52,18 +50,9 @@ func NewChain(psnap ps.Snapshotter, hsnap handle.Snapshotter, rundownFn func() e
 	var (
 		chain     = &chain{interceptors: make([]KstreamInterceptor, 0)}
 		devMapper = fs.NewDevMapper()
-		scanner   yara.Scanner
 	)
 
-	if config.Yara.Enabled {
-		var err error
-		scanner, err = yara.NewScanner(psnap, config.Yara)
-		if err != nil {
-			log.Warnf("unable to start YARA scanner: %v", err)
-		}
-	}
-
-	chain.addInterceptor(newPsInterceptor(psnap, scanner))
+	chain.addInterceptor(newPsInterceptor(psnap))
 
 	if config.Kstream.EnableFileIOKevents {
 		chain.addInterceptor(newFsInterceptor(devMapper, hsnap, config, rundownFn))
@@ -72,7 +61,7 @@ func NewChain(psnap ps.Snapshotter, hsnap handle.Snapshotter, rundownFn func() e
 		chain.addInterceptor(newRegistryInterceptor(hsnap))
 	}
 	if config.Kstream.EnableImageKevents {
-		chain.addInterceptor(newImageInterceptor(psnap, devMapper, scanner))
+		chain.addInterceptor(newImageInterceptor(psnap, devMapper))
 	}
 	if config.Kstream.EnableNetKevents {
 		chain.addInterceptor(newNetInterceptor())
@@ -19,27 +19,20 @@
 package interceptors
 
 import (
-	"expvar"
 	"github.com/rabbitstack/fibratus/pkg/fs"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
 	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
 	"github.com/rabbitstack/fibratus/pkg/ps"
-	"github.com/rabbitstack/fibratus/pkg/yara"
-	log "github.com/sirupsen/logrus"
 )
 
-// imageYaraScans stores the total count of yara image scans
-var imageYaraScans = expvar.NewInt("yara.image.scans")
-
 type imageInterceptor struct {
 	devMapper fs.DevMapper
 	snap      ps.Snapshotter
-	yara      yara.Scanner
 }
 
-func newImageInterceptor(snap ps.Snapshotter, devMapper fs.DevMapper, yara yara.Scanner) KstreamInterceptor {
-	return &imageInterceptor{snap: snap, devMapper: devMapper, yara: yara}
+func newImageInterceptor(snap ps.Snapshotter, devMapper fs.DevMapper) KstreamInterceptor {
+	return &imageInterceptor{snap: snap, devMapper: devMapper}
 }
 
 func (imageInterceptor) Name() InterceptorType { return Image }
@@ -63,16 +56,6 @@ func (i *imageInterceptor) Intercept(kevt *kevent.Kevent) (*kevent.Kevent, bool,
 		if err := kevt.Kparams.Set(kparams.ImageFilename, i.devMapper.Convert(filename), kparams.UnicodeString); err != nil {
 			return kevt, true, err
 		}
-		if i.yara != nil && kevt.Type == ktypes.LoadImage {
-			// scan the the target filename
-			go func() {
-				imageYaraScans.Add(1)
-				err := i.yara.ScanFile(filename)
-				if err != nil {
-					log.Warnf("unable to run yara scanner on %s image: %v", filename, err)
-				}
-			}()
-		}
 		if kevt.Type != ktypes.UnloadImage {
 			return kevt, false, i.snap.Write(kevt)
 		}
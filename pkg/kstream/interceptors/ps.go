@@ -19,13 +19,11 @@
 package interceptors
 
 import (
-	"expvar"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
 	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
 	"github.com/rabbitstack/fibratus/pkg/ps"
 	"github.com/rabbitstack/fibratus/pkg/syscall/process"
-	"github.com/rabbitstack/fibratus/pkg/yara"
 	log "github.com/sirupsen/logrus"
 	"os"
 	"path/filepath"
@@ -37,12 +35,8 @@ import (
 // systemRootRegexp is the regular expression for detecting path with unexpanded SystemRoot environment variable
 var systemRootRegexp = regexp.MustCompile(`%SystemRoot%|\\SystemRoot`)
 
-// procYaraScans stores the total count of yara process scans
-var procYaraScans = expvar.NewInt("yara.proc.scans")
-
 type psInterceptor struct {
 	snap ps.Snapshotter
-	yara yara.Scanner
 }
 
 var sysProcs = []string{
@@ -57,8 +51,8 @@ var sysProcs = []string{
 }
 
 // newPsInterceptor creates a new kstream interceptor for process events.
-func newPsInterceptor(snap ps.Snapshotter, yara yara.Scanner) KstreamInterceptor {
-	return psInterceptor{snap: snap, yara: yara}
+func newPsInterceptor(snap ps.Snapshotter) KstreamInterceptor {
+	return psInterceptor{snap: snap}
 }
 
 func (ps psInterceptor) Intercept(kevt *kevent.Kevent) (*kevent.Kevent, bool, error) {
@@ -114,16 +108,6 @@ func (ps psInterceptor) Intercept(kevt *kevent.Kevent) (*kevent.Kevent, bool, er
 					_ = kevt.Kparams.Append(kparams.StartTime, kparams.Time, started)
 				}
 			}
-			if ps.yara != nil && typ == ktypes.CreateProcess {
-				// run yara scanner on the target process
-				go func() {
-					procYaraScans.Add(1)
-					err := ps.yara.ScanProc(pid)
-					if err != nil {
-						log.Warnf("unable to run yara scanner on pid %d: %v", pid, err)
-					}
-				}()
-			}
 			return kevt, false, ps.snap.Write(kevt)
 		}
 
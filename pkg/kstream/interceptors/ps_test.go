@@ -32,7 +32,7 @@ import (
 
 func TestPsInterceptorIntercept(t *testing.T) {
 	psnap := new(ps.SnapshotterMock)
-	psi := newPsInterceptor(psnap, nil)
+	psi := newPsInterceptor(psnap)
 
 	kpars := kevent.Kparams{
 		kparams.Comm:            {Name: kparams.Comm, Type: kparams.UnicodeString, Value: "C:\\Windows\\system32\\svchost.exe -k RPCSS"},
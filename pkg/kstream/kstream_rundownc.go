@@ -21,6 +21,7 @@ package kstream
 import (
 	"fmt"
 	kerrors "github.com/rabbitstack/fibratus/pkg/errors"
+	"github.com/rabbitstack/fibratus/pkg/exceptions"
 	"github.com/rabbitstack/fibratus/pkg/filter"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
@@ -76,6 +77,9 @@ func (k *kstreamRundownConsumer) OpenKstream() error {
 // SetFilter initializes the filter that's applied on the kernel events.
 func (k *kstreamRundownConsumer) SetFilter(filter filter.Filter) {}
 
+// SetAllowlist initializes the allowlist that exempts events matching the exceptions.
+func (k *kstreamRundownConsumer) SetAllowlist(allowlist *exceptions.Allowlist) {}
+
 // CloseKstream shutdowns the currently running kernel rundown consumer by closing the corresponding
 // session.
 func (k *kstreamRundownConsumer) CloseKstream() error {
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"github.com/rabbitstack/fibratus/pkg/config"
 	kerrors "github.com/rabbitstack/fibratus/pkg/errors"
+	"github.com/rabbitstack/fibratus/pkg/exceptions"
 	"github.com/rabbitstack/fibratus/pkg/filter"
 	"github.com/rabbitstack/fibratus/pkg/handle"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
@@ -38,6 +39,7 @@ import (
 	"github.com/rabbitstack/fibratus/pkg/syscall/utf16"
 	"github.com/rabbitstack/fibratus/pkg/syscall/winerrno"
 	"github.com/rabbitstack/fibratus/pkg/util/filetime"
+	"github.com/rabbitstack/fibratus/pkg/yara"
 	log "github.com/sirupsen/logrus"
 	"os"
 	"strings"
@@ -66,6 +68,11 @@ var (
 	upstreamCancellations = expvar.NewInt("kstream.upstream.cancellations")
 
 	buffersRead = expvar.NewInt("kstream.kbuffers.read")
+
+	// procYaraScans stores the total count of yara process scans
+	procYaraScans = expvar.NewInt("yara.proc.scans")
+	// imageYaraScans stores the total count of yara image scans
+	imageYaraScans = expvar.NewInt("yara.image.scans")
 )
 
 var (
@@ -93,6 +100,8 @@ type Consumer interface {
 	Events() chan *kevent.Kevent
 	// SetFilter initializes the filter that's applied on the kernel events.
 	SetFilter(filter filter.Filter)
+	// SetAllowlist initializes the allowlist that exempts events matching the exceptions.
+	SetAllowlist(allowlist *exceptions.Allowlist)
 }
 
 type blacklist map[ktypes.Ktype]string
@@ -117,8 +126,10 @@ type kstreamConsumer struct {
 	psnapshotter ps.Snapshotter
 	sequencer    *kevent.Sequencer
 
-	filter  filter.Filter
-	capture bool
+	filter    filter.Filter
+	allowlist *exceptions.Allowlist
+	yara      yara.Scanner
+	capture   bool
 }
 
 // NewConsumer constructs a new kernel event stream consumer.
@@ -139,6 +150,15 @@ func NewConsumer(ktraceController KtraceController, psnap ps.Snapshotter, hsnap
 
 	kconsumer.interceptorChain = interceptors.NewChain(psnap, hsnap, kconsumer.startRundown, config)
 
+	if config.Yara.Enabled {
+		scanner, err := yara.NewScanner(psnap, config.Yara)
+		if err != nil {
+			log.Warnf("unable to start YARA scanner: %v", err)
+		} else {
+			kconsumer.yara = scanner
+		}
+	}
+
 	return kconsumer
 }
 
@@ -169,6 +189,9 @@ func (k *kstreamConsumer) init() {
 // SetFilter initializes the filter that's applied on the kernel events.
 func (k *kstreamConsumer) SetFilter(filter filter.Filter) { k.filter = filter }
 
+// SetAllowlist initializes the allowlist that exempts events matching the exceptions.
+func (k *kstreamConsumer) SetAllowlist(allowlist *exceptions.Allowlist) { k.allowlist = allowlist }
+
 // OpenKstream initializes the kernel event stream by setting the event record callback and instructing it
 // to consume events from log buffers. This operation can fail if opening the kernel logger session results
 // in an invalid trace handler. Errors returned by `ProcessTrace` are sent to the channel since this function
@@ -402,7 +425,15 @@ func (k *kstreamConsumer) processKevent(evt *etw.EventRecord) error {
 	if kevt.PS == nil {
 		kevt.PS = k.psnapshotter.Find(kevt.PID)
 	}
-	if k.isDropped(kevt) {
+	dropped := k.isDropped(kevt)
+	// exceptions are evaluated once for each event that is either forwarded or scanned.
+	// Exempted events are marked, so they are still received by rules and filaments,
+	// but they don't trigger YARA scans, alerts or get published to outputs
+	if k.allowlist != nil && (!dropped || k.isScannable(kevt)) {
+		k.allowlist.Exempt(kevt)
+	}
+	k.scan(kevt)
+	if dropped {
 		kevt.Release()
 		return nil
 	}
@@ -598,6 +629,47 @@ func (k *kstreamConsumer) isDropped(kevt *kevent.Kevent) bool {
 	return !k.filter.Run(kevt)
 }
 
+// isScannable determines whether the event triggers the YARA scan.
+func (k *kstreamConsumer) isScannable(kevt *kevent.Kevent) bool {
+	return k.yara != nil && (kevt.Type == ktypes.CreateProcess || kevt.Type == ktypes.LoadImage)
+}
+
+// scan runs the YARA scanner on the process created by the event or the image
+// loaded by the event. Events exempted by exceptions aren't scanned.
+func (k *kstreamConsumer) scan(kevt *kevent.Kevent) {
+	if !k.isScannable(kevt) || kevt.IsExempted() {
+		return
+	}
+	switch kevt.Type {
+	case ktypes.CreateProcess:
+		pid, err := kevt.Kparams.GetPid()
+		if err != nil {
+			return
+		}
+		// run yara scanner on the target process
+		go func() {
+			procYaraScans.Add(1)
+			err := k.yara.ScanProc(pid)
+			if err != nil {
+				log.Warnf("unable to run yara scanner on pid %d: %v", pid, err)
+			}
+		}()
+	case ktypes.LoadImage:
+		filename, err := kevt.Kparams.GetString(kparams.ImageFilename)
+		if err != nil {
+			return
+		}
+		// scan the the target filename
+		go func() {
+			imageYaraScans.Add(1)
+			err := k.yara.ScanFile(filename)
+			if err != nil {
+				log.Warnf("unable to run yara scanner on %s image: %v", filename, err)
+			}
+		}()
+	}
+}
+
 // dropBlacklistProc drops the events from the blacklist if it is linked to particular process name.
 func (k *kstreamConsumer) dropBlacklistProc(pid uint32) bool {
 	if len(k.procsBlacklist) == 0 {
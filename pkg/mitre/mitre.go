@@ -0,0 +1,184 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package mitre contains the catalog of MITRE ATT&CK tactics and techniques. Tactic and
+// technique identifiers attached to alerts and events are validated against the catalog,
+// which also resolves their names.
+package mitre
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// TacticsMeta is the metadata key under which the tactic identifiers are stored.
+	TacticsMeta = "mitre.tactic.id"
+	// TechniquesMeta is the metadata key under which the technique identifiers are stored.
+	TechniquesMeta = "mitre.technique.id"
+)
+
+// Tactic represents the adversary's tactical goal.
+type Tactic struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Technique represents how the adversary achieves the tactical goal.
+type Technique struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// String returns the tactic identifier followed by its name.
+func (t Tactic) String() string { return t.ID + " " + t.Name }
+
+// String returns the technique identifier followed by its name.
+func (t Technique) String() string { return t.ID + " " + t.Name }
+
+var (
+	tacticRegexp    = regexp.MustCompile(`^TA\d{4}$`)
+	techniqueRegexp = regexp.MustCompile(`^T\d{4}(\.\d{3})?$`)
+)
+
+// LookupTactic finds the tactic by its identifier.
+func LookupTactic(id string) (Tactic, bool) {
+	name, ok := tactics[strings.ToUpper(id)]
+	if !ok {
+		return Tactic{}, false
+	}
+	return Tactic{ID: strings.ToUpper(id), Name: name}, true
+}
+
+// LookupTacticByName finds the tactic by its name. The name is matched case-insensitively,
+// and spaces, hyphens and underscores are interchangeable, so the defense_evasion name
+// resolves to the Defense Evasion tactic.
+func LookupTacticByName(name string) (Tactic, bool) {
+	name = normalizeName(name)
+	for id, n := range tactics {
+		if normalizeName(n) == name {
+			return Tactic{ID: id, Name: n}, true
+		}
+	}
+	return Tactic{}, false
+}
+
+// LookupTechnique finds the technique or sub-technique by its identifier. Sub-techniques
+// that are absent from the catalog resolve to the name of their parent technique.
+func LookupTechnique(id string) (Technique, bool) {
+	id = strings.ToUpper(id)
+	if !techniqueRegexp.MatchString(id) {
+		return Technique{}, false
+	}
+	if name, ok := techniques[id]; ok {
+		return Technique{ID: id, Name: name}, true
+	}
+	if i := strings.IndexByte(id, '.'); i > 0 {
+		if name, ok := techniques[id[:i]]; ok {
+			return Technique{ID: id, Name: name}, true
+		}
+	}
+	return Technique{}, false
+}
+
+// Validate ensures all tactic and technique identifiers are present in the catalog.
+func Validate(tactics, techniques []string) error {
+	for _, id := range tactics {
+		if !tacticRegexp.MatchString(strings.ToUpper(id)) {
+			return fmt.Errorf("%q is not a valid tactic identifier", id)
+		}
+		if _, ok := LookupTactic(id); !ok {
+			return fmt.Errorf("unknown tactic %s", id)
+		}
+	}
+	for _, id := range techniques {
+		if !techniqueRegexp.MatchString(strings.ToUpper(id)) {
+			return fmt.Errorf("%q is not a valid technique identifier", id)
+		}
+		if _, ok := LookupTechnique(id); !ok {
+			return fmt.Errorf("unknown technique %s", id)
+		}
+	}
+	return nil
+}
+
+// Tactics resolves the tactics from the comma-separated list of identifiers. Unknown
+// identifiers are returned without the name.
+func Tactics(ids string) []Tactic {
+	tacts := make([]Tactic, 0)
+	for _, id := range split(ids) {
+		t, ok := LookupTactic(id)
+		if !ok {
+			t = Tactic{ID: id}
+		}
+		tacts = append(tacts, t)
+	}
+	return tacts
+}
+
+// Techniques resolves the techniques from the comma-separated list of identifiers. Unknown
+// identifiers are returned without the name.
+func Techniques(ids string) []Technique {
+	techs := make([]Technique, 0)
+	for _, id := range split(ids) {
+		t, ok := LookupTechnique(id)
+		if !ok {
+			t = Technique{ID: id}
+		}
+		techs = append(techs, t)
+	}
+	return techs
+}
+
+// Join appends the identifiers to the comma-separated list of identifiers
+// skipping those that are already present in the list.
+func Join(ids string, add []string) string {
+	list := split(ids)
+	for _, id := range add {
+		id = strings.ToUpper(strings.TrimSpace(id))
+		if id == "" || contains(list, id) {
+			continue
+		}
+		list = append(list, id)
+	}
+	return strings.Join(list, ",")
+}
+
+func split(ids string) []string {
+	list := make([]string, 0)
+	for _, id := range strings.Split(ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			list = append(list, id)
+		}
+	}
+	return list
+}
+
+func contains(list []string, id string) bool {
+	for _, s := range list {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeName(name string) string {
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mitre
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tactic, ok := LookupTactic("TA0002")
+	require.True(t, ok)
+	assert.Equal(t, Tactic{ID: "TA0002", Name: "Execution"}, tactic)
+	_, ok = LookupTactic("TA9999")
+	assert.False(t, ok)
+
+	tactic, ok = LookupTacticByName("defense_evasion")
+	require.True(t, ok)
+	assert.Equal(t, "TA0005", tactic.ID)
+	tactic, ok = LookupTacticByName("Command-and-Control")
+	require.True(t, ok)
+	assert.Equal(t, "TA0011", tactic.ID)
+	_, ok = LookupTacticByName("t1059")
+	assert.False(t, ok)
+
+	var tests = []struct {
+		id   string
+		name string
+		ok   bool
+	}{
+		{"T1059", "Command and Scripting Interpreter", true},
+		{"t1059.001", "PowerShell", true},
+		// sub-techniques absent from the catalog resolve to the parent technique name
+		{"T1059.009", "Command and Scripting Interpreter", true},
+		{"T9999", "", false},
+		{"T9999.001", "", false},
+		{"T1059.1", "", false},
+		{"TA0002", "", false},
+	}
+	for _, tt := range tests {
+		tech, ok := LookupTechnique(tt.id)
+		assert.Equal(t, tt.ok, ok, tt.id)
+		assert.Equal(t, tt.name, tech.Name, tt.id)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate([]string{"TA0002", "ta0005"}, []string{"T1059.001", "T1218"}))
+	require.NoError(t, Validate(nil, nil))
+	assert.EqualError(t, Validate([]string{"Execution"}, nil), `"Execution" is not a valid tactic identifier`)
+	assert.EqualError(t, Validate([]string{"TA0099"}, nil), "unknown tactic TA0099")
+	assert.EqualError(t, Validate(nil, []string{"T1059-001"}), `"T1059-001" is not a valid technique identifier`)
+	assert.EqualError(t, Validate(nil, []string{"T0001"}), "unknown technique T0001")
+}
+
+func TestResolve(t *testing.T) {
+	assert.Equal(t, []Tactic{{ID: "TA0002", Name: "Execution"}, {ID: "TA0099"}}, Tactics("TA0002, TA0099"))
+	assert.Equal(t, []Technique{{ID: "T1059.001", Name: "PowerShell"}}, Techniques("T1059.001,"))
+	assert.Empty(t, Techniques(""))
+}
+
+func TestJoin(t *testing.T) {
+	assert.Equal(t, "T1059", Join("", []string{"t1059"}))
+	assert.Equal(t, "T1059,T1218.011", Join("T1059", []string{"T1059", " T1218.011", ""}))
+}
@@ -19,9 +19,39 @@
 package outputs
 
 import (
+	"errors"
+	"fmt"
 	"github.com/rabbitstack/fibratus/pkg/kevent"
 )
 
+// ErrConnectionLost is returned by clients that lost the connection to the remote endpoint while publishing
+// the batch. The client is connected again before publishing subsequent batches.
+var ErrConnectionLost = errors.New("connection lost")
+
+// PartialError is returned by clients that published only a part of the batch before failing. Clients
+// publish events in the order they appear in the batch, so only the events past the first Published
+// events of the batch are undelivered.
+type PartialError struct {
+	Err error
+	// Published is the number of leading batch events that were published
+	Published int
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("%v (%d events were published)", e.Err, e.Published)
+}
+
+func (e *PartialError) Unwrap() error { return e.Err }
+
+// Unpublished returns the batch events that weren't published due to the client publish error.
+func Unpublished(b *kevent.Batch, err error) []*kevent.Kevent {
+	var perr *PartialError
+	if errors.As(err, &perr) && perr.Published <= len(b.Events) {
+		return b.Events[perr.Published:]
+	}
+	return b.Events
+}
+
 // Client represents the minimal interface all output implementors have to satisfy.
 type Client interface {
 	Close() error
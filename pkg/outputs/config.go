@@ -20,6 +20,7 @@ package outputs
 
 import (
 	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/aggregator/transformers"
 	"github.com/spf13/pflag"
 )
 
@@ -27,6 +28,10 @@ import (
 type Config struct {
 	Type   Type
 	Output interface{}
+	// Filter is the expression that events have to match in order to get published to the output.
+	Filter string
+	// Transformers contains the transformers that are only applied to the events published to the output.
+	Transformers []transformers.Config
 }
 
 // TLSConfig stores the client TLS parameters.
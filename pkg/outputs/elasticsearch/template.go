@@ -66,6 +66,23 @@ const indexTemplate = `
 					"sid": { "type": "keyword" },
 					"sessionid": { "type": "short" }
 				}
+			},
+
+			"mitre": {
+				"properties": {
+					"tactics": {
+						"properties": {
+							"id": { "type": "keyword" },
+							"name": { "type": "keyword" }
+						}
+					},
+					"techniques": {
+						"properties": {
+							"id": { "type": "keyword" },
+							"name": { "type": "keyword" }
+						}
+					}
+				}
 			}
 			
 		}
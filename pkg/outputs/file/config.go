@@ -0,0 +1,63 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file
+
+import (
+	"github.com/spf13/pflag"
+	"time"
+)
+
+const (
+	enabled       = "output.file.enabled"
+	path          = "output.file.path"
+	maxSize       = "output.file.max-size"
+	maxAge        = "output.file.max-age"
+	maxBackups    = "output.file.max-backups"
+	compression   = "output.file.compression"
+	fsyncInterval = "output.file.fsync-interval"
+)
+
+// Config contains the tweaks that influence the behaviour of the file output.
+type Config struct {
+	// Enabled indicates if the file output is enabled.
+	Enabled bool `mapstructure:"enabled"`
+	// Path is the template of the path where events are written. It allows time and host specifiers.
+	Path string `mapstructure:"path"`
+	// MaxSize is the maximum size in megabytes the file can grow before it gets rotated.
+	MaxSize int `mapstructure:"max-size"`
+	// MaxAge is the maximum time the file is written before it gets rotated.
+	MaxAge time.Duration `mapstructure:"max-age"`
+	// MaxBackups represents the maximum number of rotated files to retain.
+	MaxBackups int `mapstructure:"max-backups"`
+	// Compression is the algorithm for compressing rotated files. It can be gzip or zstd.
+	Compression string `mapstructure:"compression"`
+	// FsyncInterval specifies how often written events are committed to disk.
+	FsyncInterval time.Duration `mapstructure:"fsync-interval"`
+}
+
+// AddFlags registers persistent flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.Bool(enabled, false, "Indicates if the file output is enabled")
+	flags.String(path, "", "Represents the template of the path where events are written. It allows time and host specifiers")
+	flags.Int(maxSize, 100, "Specifies the maximum size in megabytes the file can grow before it gets rotated")
+	flags.Duration(maxAge, time.Hour*24, "Specifies the maximum time the file is written before it gets rotated")
+	flags.Int(maxBackups, 10, "Represents the maximum number of rotated files to retain")
+	flags.String(compression, "", "Specifies the algorithm for compressing rotated files. Choose between gzip|zstd")
+	flags.Duration(fsyncInterval, time.Second, "Specifies how often written events are committed to disk")
+}
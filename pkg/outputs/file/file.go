@@ -0,0 +1,269 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	fileErrors   = expvar.NewInt("output.file.errors")
+	bytesWritten = expvar.NewInt("output.file.bytes.written")
+	rotations    = expvar.NewInt("output.file.rotations")
+)
+
+const (
+	gzipCompression = "gzip"
+	zstdCompression = "zstd"
+)
+
+var nl = []byte("\n")
+
+// file output writes newline delimited JSON events to the file that is rotated by size and age.
+type file struct {
+	config Config
+	// host is the name of the host that replaces the %h specifier
+	host string
+	// maxSize is the maximum size of the file in bytes
+	maxSize int64
+
+	f      *os.File
+	w      *bufio.Writer
+	name   string
+	size   int64
+	opened time.Time
+
+	syncer *time.Ticker
+	stop   chan struct{}
+	// archives receives the rotated files and the files of previous periods
+	archives chan archive
+	// backups matches the names of the files produced by the path template
+	backups *regexp.Regexp
+	// wg waits for the archiver to compress and prune pending files
+	wg sync.WaitGroup
+	// mu protects the file and the buffered writer
+	mu sync.Mutex
+}
+
+func init() {
+	outputs.Register(outputs.File, initFile)
+}
+
+func initFile(config outputs.Config) (outputs.OutputGroup, error) {
+	cfg, ok := config.Output.(Config)
+	if !ok {
+		return outputs.Fail(outputs.ErrInvalidConfig(outputs.File, config.Output))
+	}
+	switch cfg.Compression {
+	case "", gzipCompression, zstdCompression:
+	default:
+		return outputs.Fail(fmt.Errorf("unsupported compression %q. Choose between gzip|zstd", cfg.Compression))
+	}
+	if cfg.Path == "" {
+		cfg.Path = filepath.Join(os.Getenv("PROGRAMFILES"), "fibratus", "events", "fibratus-%Y-%m-%d.json")
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return outputs.Fail(err)
+	}
+	f := &file{
+		config:   cfg,
+		host:     host,
+		maxSize:  int64(cfg.MaxSize) * 1024 * 1024,
+		stop:     make(chan struct{}),
+		archives: make(chan archive, 64),
+		backups:  backupsPattern(cfg.Path, host),
+	}
+	return outputs.Success(f), nil
+}
+
+func (f *file) Connect() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.open(f.expand(time.Now())); err != nil {
+		return err
+	}
+	f.wg.Add(1)
+	go f.archiver()
+	if f.config.FsyncInterval > 0 {
+		f.syncer = time.NewTicker(f.config.FsyncInterval)
+		go f.sync()
+	}
+	return nil
+}
+
+func (f *file) Close() error {
+	if f.syncer != nil {
+		f.syncer.Stop()
+		f.stop <- struct{}{}
+	}
+	f.mu.Lock()
+	err := f.close()
+	close(f.archives)
+	f.mu.Unlock()
+	f.wg.Wait()
+	return err
+}
+
+func (f *file) Publish(batch *kevent.Batch) error {
+	defer batch.Release()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// switch to the new file when the time specifiers
+	// expand to a different path or rotate the file
+	// if it exceeded the max age
+	now := time.Now()
+	if name := f.expand(now); f.f == nil || name != f.name {
+		prev := f.name
+		if err := f.close(); err != nil {
+			fileErrors.Add(1)
+		}
+		if err := f.open(name); err != nil {
+			fileErrors.Add(1)
+			return err
+		}
+		// the file of the previous period is archived
+		// like the rotated file
+		if prev != "" && prev != name {
+			f.archives <- archive{name: prev, active: name}
+		}
+	} else if f.config.MaxAge > 0 && now.Sub(f.opened) >= f.config.MaxAge && f.size > 0 {
+		if err := f.rotate(); err != nil {
+			fileErrors.Add(1)
+			return err
+		}
+	}
+
+	for _, kevt := range batch.Events {
+		if err := f.write(kevt.MarshalJSON()); err != nil {
+			fileErrors.Add(1)
+			return err
+		}
+		if f.maxSize > 0 && f.size >= f.maxSize {
+			if err := f.rotate(); err != nil {
+				fileErrors.Add(1)
+				return err
+			}
+		}
+	}
+
+	if err := f.w.Flush(); err != nil {
+		fileErrors.Add(1)
+		return err
+	}
+
+	return nil
+}
+
+// expand replaces the time and host specifiers in the path template.
+func (f *file) expand(t time.Time) string {
+	if !strings.Contains(f.config.Path, "%") {
+		return f.config.Path
+	}
+	return strings.NewReplacer(
+		"%Y", t.UTC().Format("2006"),
+		"%y", t.UTC().Format("06"),
+		"%m", t.UTC().Format("01"),
+		"%d", t.UTC().Format("02"),
+		"%H", t.UTC().Format("15"),
+		"%h", f.host).Replace(f.config.Path)
+}
+
+func (f *file) open(name string) error {
+	if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+		return err
+	}
+	fd, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := fd.Stat()
+	if err != nil {
+		_ = fd.Close()
+		return err
+	}
+	f.f, f.name, f.size, f.opened = fd, name, fi.Size(), time.Now()
+	if f.w == nil {
+		f.w = bufio.NewWriterSize(fd, 64*1024)
+	} else {
+		f.w.Reset(fd)
+	}
+	return nil
+}
+
+func (f *file) close() error {
+	if f.f == nil {
+		return nil
+	}
+	defer func() { f.f = nil }()
+	if err := f.w.Flush(); err != nil {
+		_ = f.f.Close()
+		return err
+	}
+	if err := f.f.Sync(); err != nil {
+		_ = f.f.Close()
+		return err
+	}
+	return f.f.Close()
+}
+
+func (f *file) write(buf []byte) error {
+	if _, err := f.w.Write(buf); err != nil {
+		return err
+	}
+	if _, err := f.w.Write(nl); err != nil {
+		return err
+	}
+	n := int64(len(buf) + len(nl))
+	f.size += n
+	bytesWritten.Add(n)
+	return nil
+}
+
+// sync periodically commits written events to disk.
+func (f *file) sync() {
+	for {
+		select {
+		case <-f.syncer.C:
+			f.mu.Lock()
+			if f.f != nil {
+				if err := f.w.Flush(); err != nil {
+					fileErrors.Add(1)
+				} else if err := f.f.Sync(); err != nil {
+					fileErrors.Add(1)
+				}
+			}
+			f.mu.Unlock()
+		case <-f.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,238 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newBatch(n int) *kevent.Batch {
+	kevts := make([]*kevent.Kevent, n)
+	for i := range kevts {
+		kevts[i] = &kevent.Kevent{
+			Seq:       uint64(i + 1),
+			Name:      "CreateFile",
+			Timestamp: time.Now(),
+			Kparams:   kevent.Kparams{},
+			Metadata:  make(map[string]string),
+		}
+	}
+	return kevent.NewBatch(kevts...)
+}
+
+func newFile(t *testing.T, config Config) *file {
+	group, err := initFile(outputs.Config{Type: outputs.File, Output: config})
+	require.NoError(t, err)
+	require.Len(t, group.Clients, 1)
+	return group.Clients[0].(*file)
+}
+
+func readLines(t *testing.T, name string) []string {
+	f, err := os.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+	var r = bufio.NewScanner(f)
+	if strings.HasSuffix(name, ".gz") {
+		gr, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		r = bufio.NewScanner(gr)
+	}
+	lines := make([]string, 0)
+	for r.Scan() {
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal(r.Bytes(), &m))
+		lines = append(lines, r.Text())
+	}
+	return lines
+}
+
+func TestPublish(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fibratus-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f := newFile(t, Config{Path: filepath.Join(dir, "%h", "events-%Y.json")})
+	require.NoError(t, f.Connect())
+	require.NoError(t, f.Publish(newBatch(2)))
+	require.NoError(t, f.Publish(newBatch(3)))
+	require.NoError(t, f.Close())
+
+	host, err := os.Hostname()
+	require.NoError(t, err)
+	name := filepath.Join(dir, host, "events-"+time.Now().UTC().Format("2006")+".json")
+	assert.Len(t, readLines(t, name), 5)
+}
+
+func TestRotateBySize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fibratus-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f := newFile(t, Config{Path: filepath.Join(dir, "events.json"), MaxBackups: 2, Compression: "gzip"})
+	// rotate after every second event
+	f.maxSize = 2 * int64(len(newBatch(1).Events[0].MarshalJSON())+1)
+	require.NoError(t, f.Connect())
+	for i := 0; i < 4; i++ {
+		require.NoError(t, f.Publish(newBatch(2)))
+		// keep backup timestamps apart
+		time.Sleep(time.Millisecond * 5)
+	}
+	require.NoError(t, f.Close())
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	assert.Equal(t, "events.json", files[2].Name())
+	assert.Empty(t, readLines(t, filepath.Join(dir, files[2].Name())))
+	for _, fi := range files[:2] {
+		assert.True(t, strings.HasPrefix(fi.Name(), "events-"))
+		assert.True(t, strings.HasSuffix(fi.Name(), ".json.gz"))
+		assert.Len(t, readLines(t, filepath.Join(dir, fi.Name())), 2)
+	}
+}
+
+func TestRotateByAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fibratus-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f := newFile(t, Config{Path: filepath.Join(dir, "events.json"), MaxAge: time.Millisecond * 100})
+	require.NoError(t, f.Connect())
+	require.NoError(t, f.Publish(newBatch(2)))
+	require.NoError(t, f.Publish(newBatch(1)))
+	time.Sleep(time.Millisecond * 150)
+	require.NoError(t, f.Publish(newBatch(1)))
+	require.NoError(t, f.Close())
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Len(t, readLines(t, filepath.Join(dir, files[0].Name())), 3)
+	assert.Len(t, readLines(t, filepath.Join(dir, "events.json")), 1)
+}
+
+func TestInvalidCompression(t *testing.T) {
+	_, err := initFile(outputs.Config{Type: outputs.File, Output: Config{Compression: "lz4"}})
+	require.Error(t, err)
+}
+
+func TestPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fibratus-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := []string{
+		"fibratus-2020-11-01.json",
+		"fibratus-2020-11-02-2020-11-02T10-30-00.000.json.gz",
+		"fibratus-2020-11-03.json.zst",
+		"fibratus-2020-11-04-2020-11-04T08-00-00.000.json",
+		"fibratus-2020-11-04.json",
+		"fibratus.json",
+		"other-2020-11-01.json",
+	}
+	now := time.Now()
+	for i, name := range files {
+		file := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(file, []byte("{}\n"), 0644))
+		mtime := now.Add(time.Duration(i-len(files)) * time.Hour)
+		require.NoError(t, os.Chtimes(file, mtime, mtime))
+	}
+
+	// backups of all days are pruned, except the active file and the files that don't match the template
+	pattern := backupsPattern(filepath.Join(dir, "fibratus-%Y-%m-%d.json"), "archrabbit")
+	require.NoError(t, prune(pattern, filepath.Join(dir, "fibratus-2020-11-04.json"), 2))
+
+	left, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(left))
+	for _, fi := range left {
+		names = append(names, fi.Name())
+	}
+	assert.ElementsMatch(t, []string{
+		"fibratus-2020-11-03.json.zst",
+		"fibratus-2020-11-04-2020-11-04T08-00-00.000.json",
+		"fibratus-2020-11-04.json",
+		"fibratus.json",
+		"other-2020-11-01.json",
+	}, names)
+}
+
+func TestCompressFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fibratus-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// reading the directory fails while the archive is written
+	name := filepath.Join(dir, "events-2020-11-04T10-30-00.000.json")
+	require.NoError(t, os.Mkdir(name, os.ModePerm))
+	require.Error(t, compress(name, gzipCompression))
+
+	_, err = os.Stat(name + ".gz")
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(name)
+	assert.NoError(t, err)
+}
+
+func TestArchiver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fibratus-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f := &file{
+		config:   Config{Compression: gzipCompression, MaxBackups: 2},
+		archives: make(chan archive, 4),
+		backups:  backupsPattern(filepath.Join(dir, "events.json"), "archrabbit"),
+	}
+	active := filepath.Join(dir, "events.json")
+	require.NoError(t, ioutil.WriteFile(active, []byte("{}\n"), 0644))
+	// all backups are queued before the archiver
+	// compresses and prunes the first of them
+	now := time.Now()
+	backups := make([]string, 4)
+	for i := range backups {
+		backups[i] = backupName(active, now.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, ioutil.WriteFile(backups[i], []byte("{}\n"), 0644))
+		mtime := now.Add(time.Duration(i-len(backups)) * time.Hour)
+		require.NoError(t, os.Chtimes(backups[i], mtime, mtime))
+		f.archives <- archive{name: backups[i], active: active}
+	}
+	f.wg.Add(1)
+	go f.archiver()
+	close(f.archives)
+	f.wg.Wait()
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	assert.Equal(t, filepath.Base(backups[2])+".gz", files[0].Name())
+	assert.Equal(t, filepath.Base(backups[3])+".gz", files[1].Name())
+	assert.Equal(t, "events.json", files[2].Name())
+}
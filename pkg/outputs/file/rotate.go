@@ -0,0 +1,206 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file
+
+import (
+	"compress/gzip"
+	zstd "github.com/valyala/gozstd"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupTimeFormat is the layout of the timestamp appended to the name of rotated files
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// archive is the file handed over to the archiver. The file is compressed if the
+// compression is enabled, and the stale backups of the active file are removed.
+type archive struct {
+	name string
+	// active is the file being written that is never removed
+	active string
+}
+
+// rotate renames the current file to the backup file and opens a new file. The backup
+// file is compressed and stale backups are removed in the background.
+func (f *file) rotate() error {
+	name := f.name
+	if err := f.close(); err != nil {
+		return err
+	}
+	backup := backupName(name, time.Now())
+	if err := os.Rename(name, backup); err != nil {
+		return err
+	}
+	rotations.Add(1)
+	f.archives <- archive{name: backup, active: name}
+
+	return f.open(name)
+}
+
+// archiver compresses and prunes the files sent to the archives channel one at a time,
+// so pruning never races with the compression of the rotated file. It returns when the
+// channel is closed.
+func (f *file) archiver() {
+	defer f.wg.Done()
+	for a := range f.archives {
+		if f.config.Compression != "" {
+			if err := compress(a.name, f.config.Compression); err != nil {
+				fileErrors.Add(1)
+			}
+		}
+		if err := prune(f.backups, a.active, f.config.MaxBackups); err != nil {
+			fileErrors.Add(1)
+		}
+	}
+}
+
+// backupName produces the name of the rotated file by appending the
+// timestamp to the file name, e.g. events-2020-11-04T10-30-00.000.json.
+func backupName(name string, t time.Time) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-" + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// backupsPattern builds the expression that matches the file names produced by the path
+// template, along with their rotated and compressed variants. For the fibratus-%Y-%m-%d.json
+// template, it matches the files of previous days, such as fibratus-2020-11-04.json, as well
+// as the backups, e.g. fibratus-2020-11-04-2020-11-04T10-30-00.000.json.gz. Only the file name
+// is matched, so specifiers in the directory part of the template don't apply to pruning.
+func backupsPattern(path, host string) *regexp.Regexp {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	expr := strings.NewReplacer(
+		"%Y", `\d{4}`,
+		"%y", `\d{2}`,
+		"%m", `\d{2}`,
+		"%d", `\d{2}`,
+		"%H", `\d{2}`,
+		"%h", regexp.QuoteMeta(host)).Replace(regexp.QuoteMeta(strings.TrimSuffix(base, ext)))
+	return regexp.MustCompile("^" + expr + `(-\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.\d{3})?` + regexp.QuoteMeta(ext) + `(\.gz|\.zst)?$`)
+}
+
+// compress compresses the rotated file with the given algorithm and removes the original file.
+// If the compression fails, the partially written archive is removed and the original file is
+// left intact. The archive keeps the modification time of the original file, so it retains its
+// place among the backups that are not compressed yet.
+func compress(name, algo string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	ext := ".gz"
+	if algo == zstdCompression {
+		ext = ".zst"
+	}
+	dst, err := os.Create(name + ext)
+	if err != nil {
+		return err
+	}
+	if err := encode(dst, src, algo); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(dst.Name())
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		_ = os.Remove(dst.Name())
+		return err
+	}
+	if err := os.Chtimes(dst.Name(), fi.ModTime(), fi.ModTime()); err != nil {
+		_ = os.Remove(dst.Name())
+		return err
+	}
+
+	// close the source file before removing it
+	_ = src.Close()
+	return os.Remove(name)
+}
+
+// encode writes the compressed content of the source file to the destination file.
+func encode(dst *os.File, src io.Reader, algo string) error {
+	switch algo {
+	case gzipCompression:
+		zw := gzip.NewWriter(dst)
+		if _, err := io.Copy(zw, src); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	case zstdCompression:
+		zw := zstd.NewWriter(dst)
+		defer zw.Release()
+		if _, err := io.Copy(zw, src); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+	return dst.Sync()
+}
+
+// prune removes the oldest backups in the directory of the active file when the number of
+// backups exceeds max backups. Backups are the files matching the pattern other than the
+// active file, and they are ordered by modification time.
+func prune(pattern *regexp.Regexp, active string, maxBackups int) error {
+	if maxBackups <= 0 {
+		return nil
+	}
+	dir := filepath.Dir(active)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	backups := make([]os.FileInfo, 0)
+	for _, fi := range files {
+		if fi.IsDir() || fi.Name() == filepath.Base(active) || !pattern.MatchString(fi.Name()) {
+			continue
+		}
+		backups = append(backups, fi)
+	}
+	if len(backups) <= maxBackups {
+		return nil
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].ModTime().Equal(backups[j].ModTime()) {
+			return backups[i].Name() < backups[j].Name()
+		}
+		return backups[i].ModTime().Before(backups[j].ModTime())
+	})
+	for _, backup := range backups[:len(backups)-maxBackups] {
+		if err := os.Remove(filepath.Join(dir, backup.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
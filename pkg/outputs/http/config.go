@@ -0,0 +1,92 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/spf13/pflag"
+	"time"
+)
+
+const (
+	enabled         = "output.http.enabled"
+	endpoint        = "output.http.endpoint"
+	method          = "output.http.method"
+	timeout         = "output.http.timeout"
+	format          = "output.http.format"
+	username        = "output.http.username"
+	password        = "output.http.password"
+	bearerToken     = "output.http.bearer-token"
+	gzipCompression = "output.http.gzip-compression"
+	maxRetries      = "output.http.max-retries"
+	retryBackoff    = "output.http.retry-backoff"
+	maxRetryAfter   = "output.http.max-retry-after"
+	maxPayloadSize  = "output.http.max-payload-size"
+)
+
+// Config contains the tweaks that influence the behaviour of the HTTP output.
+type Config struct {
+	outputs.TLSConfig
+	// Enabled indicates if the HTTP output is enabled.
+	Enabled bool `mapstructure:"enabled"`
+	// Endpoint is the URL where events are sent.
+	Endpoint string `mapstructure:"endpoint"`
+	// Method is the HTTP method of the request. It can be POST or PUT.
+	Method string `mapstructure:"method"`
+	// Timeout specifies the request timeout.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Format determines how events are serialized in the request body. It can be json or ndjson.
+	Format string `mapstructure:"format"`
+	// Username is the user name for the basic HTTP authentication.
+	Username string `mapstructure:"username"`
+	// Password is the password for the basic HTTP authentication.
+	Password string `mapstructure:"password"`
+	// BearerToken is the token for the bearer HTTP authentication.
+	BearerToken string `mapstructure:"bearer-token"`
+	// GzipCompression specifies if the request body is gzip compressed.
+	GzipCompression bool `mapstructure:"gzip-compression"`
+	// MaxRetries is the maximum number of retries for failed requests.
+	MaxRetries int `mapstructure:"max-retries"`
+	// RetryBackoff is the initial wait time between retries. It is doubled after each retry.
+	RetryBackoff time.Duration `mapstructure:"retry-backoff"`
+	// MaxRetryAfter is the maximum wait time between retries the server can dictate in the Retry-After header.
+	MaxRetryAfter time.Duration `mapstructure:"max-retry-after"`
+	// MaxPayloadSize is the maximum size of the request body in kilobytes. Larger batches are split into multiple requests.
+	MaxPayloadSize int `mapstructure:"max-payload-size"`
+	// Headers contains a list of headers that are added to each request.
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// AddFlags registers persistent flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.Bool(enabled, false, "Indicates if the HTTP output is enabled")
+	flags.String(endpoint, "", "Represents the URL where events are sent")
+	flags.String(method, "POST", "Specifies the HTTP method of the request. Choose between POST|PUT")
+	flags.Duration(timeout, time.Second*5, "Specifies the request timeout")
+	flags.String(format, jsonFormat, "Determines how events are serialized in the request body. Choose between json|ndjson")
+	flags.String(username, "", "Identifies the user name for the basic HTTP authentication")
+	flags.String(password, "", "Specifies the password for the basic HTTP authentication")
+	flags.String(bearerToken, "", "Specifies the token for the bearer HTTP authentication")
+	flags.Bool(gzipCompression, false, "Specifies if the request body is gzip compressed")
+	flags.Int(maxRetries, 3, "Specifies the maximum number of retries for failed requests")
+	flags.Duration(retryBackoff, time.Second, "Specifies the initial wait time between retries. It is doubled after each retry")
+	flags.Duration(maxRetryAfter, time.Minute, "Specifies the maximum wait time between retries the server can dictate in the Retry-After header")
+	flags.Int(maxPayloadSize, 1024, "Specifies the maximum size of the request body in kilobytes. Larger batches are split into multiple requests")
+	outputs.AddTLSFlags(flags, outputs.HTTP)
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"expvar"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	tlsutil "github.com/rabbitstack/fibratus/pkg/util/tls"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	// httpErrors counts the number of payloads that couldn't be delivered
+	httpErrors = expvar.NewInt("output.http.errors")
+	// httpRequests counts the number of successful requests
+	httpRequests = expvar.NewInt("output.http.requests")
+	// httpRetries counts the number of retried requests
+	httpRetries = expvar.NewInt("output.http.retries")
+)
+
+type httpOutput struct {
+	config Config
+	client *http.Client
+	// maxPayloadSize is the maximum size of the request body in bytes
+	maxPayloadSize int
+}
+
+func init() {
+	outputs.Register(outputs.HTTP, initHTTP)
+}
+
+func initHTTP(config outputs.Config) (outputs.OutputGroup, error) {
+	cfg, ok := config.Output.(Config)
+	if !ok {
+		return outputs.Fail(outputs.ErrInvalidConfig(outputs.HTTP, config.Output))
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return outputs.Fail(fmt.Errorf("invalid endpoint: %v", err))
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return outputs.Fail(fmt.Errorf("invalid endpoint %q. The URL scheme must be http or https", cfg.Endpoint))
+	}
+	switch cfg.Method {
+	case http.MethodPost, http.MethodPut:
+	default:
+		return outputs.Fail(fmt.Errorf("unsupported method %q. Choose between POST|PUT", cfg.Method))
+	}
+	switch cfg.Format {
+	case jsonFormat, ndjsonFormat:
+	default:
+		return outputs.Fail(fmt.Errorf("unsupported format %q. Choose between json|ndjson", cfg.Format))
+	}
+	if cfg.BearerToken != "" && (cfg.Username != "" || cfg.Password != "") {
+		return outputs.Fail(errors.New("basic and bearer authentication are mutually exclusive"))
+	}
+
+	tlsConfig, err := tlsutil.MakeConfig(cfg.TLSCert, cfg.TLSKey, cfg.TLSCA, cfg.TLSInsecureSkipVerify)
+	if err != nil {
+		return outputs.Fail(fmt.Errorf("invalid TLS config: %v", err))
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	h := &httpOutput{
+		config:         cfg,
+		client:         &http.Client{Transport: transport, Timeout: cfg.Timeout},
+		maxPayloadSize: cfg.MaxPayloadSize * 1024,
+	}
+	return outputs.Success(h), nil
+}
+
+func (h *httpOutput) Connect() error { return nil }
+
+func (h *httpOutput) Close() error {
+	h.client.CloseIdleConnections()
+	return nil
+}
+
+func (h *httpOutput) Publish(batch *kevent.Batch) error {
+	defer batch.Release()
+	var published int
+	for _, p := range h.split(batch.Events) {
+		if err := h.send(p); err != nil {
+			httpErrors.Add(1)
+			// report the events of the payloads that were already
+			// delivered, so they aren't spooled and published again
+			if published > 0 {
+				return &outputs.PartialError{Err: err, Published: published}
+			}
+			return err
+		}
+		published += p.n
+	}
+	return nil
+}
+
+// split serializes events into one or more payloads, so the size of each
+// payload doesn't exceed the max payload size. The event that is larger
+// than the max payload size is sent in its own payload.
+func (h *httpOutput) split(evts []*kevent.Kevent) []*payload {
+	payloads := make([]*payload, 0, 1)
+	p := newPayload(h.config.Format)
+	for _, kevt := range evts {
+		b := kevt.MarshalJSON()
+		if h.maxPayloadSize > 0 && !p.empty() && p.sizeWith(len(b)) > h.maxPayloadSize {
+			payloads = append(payloads, p)
+			p = newPayload(h.config.Format)
+		}
+		p.add(b)
+	}
+	if !p.empty() {
+		payloads = append(payloads, p)
+	}
+	return payloads
+}
+
+// send delivers the payload to the endpoint. Requests that fail due to network errors or
+// are rejected with 5xx or 429 status codes are retried. The wait time between retries is
+// dictated by the Retry-After response header if present, or exponential backoff otherwise.
+// The wait time requested by the Retry-After header is capped by the max retry after setting.
+func (h *httpOutput) send(p *payload) error {
+	body := p.bytes()
+	if h.config.GzipCompression {
+		var err error
+		body, err = compress(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	backoff := h.config.RetryBackoff
+	for retries := 0; ; retries++ {
+		req, err := h.newRequest(p, body)
+		if err != nil {
+			return err
+		}
+		wait, ok := time.Duration(0), false
+		resp, err := h.client.Do(req)
+		if err == nil {
+			// drain the body to reuse the connection
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			_ = resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				httpRequests.Add(1)
+				return nil
+			}
+			err = fmt.Errorf("%s responded with %s", h.config.Endpoint, resp.Status)
+			if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+				return err
+			}
+			wait, ok = retryAfter(resp.Header.Get("Retry-After"))
+			if ok && h.config.MaxRetryAfter > 0 && wait > h.config.MaxRetryAfter {
+				wait = h.config.MaxRetryAfter
+			}
+		}
+		if retries >= h.config.MaxRetries {
+			return err
+		}
+		if !ok {
+			wait = backoff
+			backoff *= 2
+		}
+		httpRetries.Add(1)
+		log.Warnf("%v. Retrying in %v...", err, wait)
+		time.Sleep(wait)
+	}
+}
+
+func (h *httpOutput) newRequest(p *payload, body []byte) (*http.Request, error) {
+	req, err := http.NewRequest(h.config.Method, h.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range h.config.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", p.contentType())
+	if h.config.GzipCompression {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if h.config.Username != "" || h.config.Password != "" {
+		req.SetBasicAuth(h.config.Username, h.config.Password)
+	}
+	if h.config.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.config.BearerToken)
+	}
+	return req, nil
+}
+
+// retryAfter parses the Retry-After header value that is given
+// either as the number of seconds or the HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	wait := time.Until(t)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
+
+func compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
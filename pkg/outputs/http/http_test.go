@@ -0,0 +1,293 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newKevent(seq uint64) *kevent.Kevent {
+	return &kevent.Kevent{
+		Type:      ktypes.CreateFile,
+		Tid:       2484,
+		PID:       859,
+		CPU:       1,
+		Seq:       seq,
+		Name:      "CreateFile",
+		Timestamp: time.Date(2020, 11, 4, 10, 30, 0, 0, time.UTC),
+		Category:  ktypes.File,
+		Host:      "archrabbit",
+		Kparams: kevent.Kparams{
+			kparams.FileName: {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "C:\\Windows\\system32\\user32.dll"},
+		},
+		Metadata: make(map[string]string),
+	}
+}
+
+func newBatch(n int) *kevent.Batch {
+	evts := make([]*kevent.Kevent, n)
+	for i := range evts {
+		evts[i] = newKevent(uint64(i))
+	}
+	return kevent.NewBatch(evts...)
+}
+
+func newHTTP(t *testing.T, config Config) *httpOutput {
+	if config.Method == "" {
+		config.Method = http.MethodPost
+	}
+	if config.Format == "" {
+		config.Format = jsonFormat
+	}
+	config.Timeout = time.Second
+	config.RetryBackoff = time.Millisecond
+	group, err := initHTTP(outputs.Config{Type: outputs.HTTP, Output: config})
+	require.NoError(t, err)
+	require.Len(t, group.Clients, 1)
+	return group.Clients[0].(*httpOutput)
+}
+
+func TestInitHTTP(t *testing.T) {
+	var tests = []struct {
+		config Config
+		valid  bool
+	}{
+		{Config{Endpoint: "http://localhost:8080/events", Method: "POST", Format: jsonFormat}, true},
+		{Config{Endpoint: "https://collector:8443", Method: "PUT", Format: ndjsonFormat, BearerToken: "token"}, true},
+		{Config{Endpoint: "localhost:8080", Method: "POST", Format: jsonFormat}, false},
+		{Config{Endpoint: "ftp://localhost", Method: "POST", Format: jsonFormat}, false},
+		{Config{Endpoint: "http://localhost:8080", Method: "GET", Format: jsonFormat}, false},
+		{Config{Endpoint: "http://localhost:8080", Method: "POST", Format: "xml"}, false},
+		{Config{Endpoint: "http://localhost:8080", Method: "POST", Format: jsonFormat, Username: "fibratus", BearerToken: "token"}, false},
+	}
+
+	for i, tt := range tests {
+		_, err := initHTTP(outputs.Config{Type: outputs.HTTP, Output: tt.config})
+		if tt.valid {
+			assert.NoError(t, err, i)
+		} else {
+			assert.Error(t, err, i)
+		}
+	}
+}
+
+func TestPublishJSON(t *testing.T) {
+	var req *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	h := newHTTP(t, Config{
+		Endpoint: srv.URL,
+		Username: "fibratus",
+		Password: "secret",
+		Headers:  map[string]string{"X-Fibratus-Host": "archrabbit"},
+	})
+	batch := newBatch(3)
+	expected := batch.MarshalJSON()
+	require.NoError(t, h.Publish(batch))
+
+	require.NotNil(t, req)
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, "archrabbit", req.Header.Get("X-Fibratus-Host"))
+	user, pass, ok := req.BasicAuth()
+	require.True(t, ok)
+	assert.Equal(t, "fibratus", user)
+	assert.Equal(t, "secret", pass)
+
+	assert.Equal(t, expected, body)
+	var evts []map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &evts))
+	assert.Len(t, evts, 3)
+}
+
+func TestPublishNDJSON(t *testing.T) {
+	var req *http.Request
+	var lines int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		scanner := bufio.NewScanner(gz)
+		for scanner.Scan() {
+			var evt map[string]interface{}
+			if json.Unmarshal(scanner.Bytes(), &evt) == nil {
+				lines++
+			}
+		}
+	}))
+	defer srv.Close()
+
+	h := newHTTP(t, Config{
+		Endpoint:        srv.URL,
+		Method:          http.MethodPut,
+		Format:          ndjsonFormat,
+		BearerToken:     "token",
+		GzipCompression: true,
+	})
+	require.NoError(t, h.Publish(newBatch(4)))
+
+	require.NotNil(t, req)
+	assert.Equal(t, http.MethodPut, req.Method)
+	assert.Equal(t, "application/x-ndjson", req.Header.Get("Content-Type"))
+	assert.Equal(t, "gzip", req.Header.Get("Content-Encoding"))
+	assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+	assert.Equal(t, 4, lines)
+}
+
+func TestPublishRetry(t *testing.T) {
+	var tests = []struct {
+		name       string
+		statuses   []int
+		maxRetries int
+		requests   int32
+		err        bool
+	}{
+		{"unavailable", []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK}, 3, 3, false},
+		{"too many requests", []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests}, 1, 2, true},
+		{"bad request", []int{http.StatusBadRequest, http.StatusOK}, 3, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&requests, 1)
+				status := tt.statuses[n-1]
+				if status == http.StatusTooManyRequests {
+					w.Header().Set("Retry-After", "0")
+				}
+				w.WriteHeader(status)
+			}))
+			defer srv.Close()
+
+			h := newHTTP(t, Config{Endpoint: srv.URL, MaxRetries: tt.maxRetries})
+			err := h.Publish(newBatch(1))
+			if tt.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
+
+func TestPublishSplit(t *testing.T) {
+	var payloads [][]map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var evts []map[string]interface{}
+		if err := json.Unmarshal(body, &evts); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		payloads = append(payloads, evts)
+	}))
+	defer srv.Close()
+
+	h := newHTTP(t, Config{Endpoint: srv.URL})
+	// fits exactly two events per payload
+	h.maxPayloadSize = len(newKevent(0).MarshalJSON())*2 + 5
+	require.NoError(t, h.Publish(newBatch(5)))
+
+	require.Len(t, payloads, 3)
+	assert.Len(t, payloads[0], 2)
+	assert.Len(t, payloads[1], 2)
+	assert.Len(t, payloads[2], 1)
+}
+
+func TestPublishSplitFailure(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	h := newHTTP(t, Config{Endpoint: srv.URL})
+	// fits exactly two events per payload
+	h.maxPayloadSize = len(newKevent(0).MarshalJSON())*2 + 5
+	batch := newBatch(5)
+	evts := batch.Events
+	err := h.Publish(batch)
+	require.Error(t, err)
+
+	// only the events of the first payload were published
+	var perr *outputs.PartialError
+	require.True(t, errors.As(err, &perr))
+	assert.Equal(t, 2, perr.Published)
+	assert.Equal(t, evts[2:], outputs.Unpublished(kevent.NewBatch(evts...), err))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestPublishMaxRetryAfter(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	h := newHTTP(t, Config{Endpoint: srv.URL, MaxRetries: 1, MaxRetryAfter: time.Millisecond * 10})
+	start := time.Now()
+	require.NoError(t, h.Publish(newBatch(1)))
+	assert.True(t, time.Since(start) < time.Minute)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestRetryAfter(t *testing.T) {
+	wait, ok := retryAfter("120")
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute*2, wait)
+
+	wait, ok = retryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.InDelta(t, time.Hour.Seconds(), wait.Seconds(), 2)
+
+	_, ok = retryAfter("")
+	assert.False(t, ok)
+	_, ok = retryAfter("soon")
+	assert.False(t, ok)
+}
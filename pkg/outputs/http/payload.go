@@ -0,0 +1,78 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+const (
+	jsonFormat   = "json"
+	ndjsonFormat = "ndjson"
+)
+
+// payload accumulates serialized events in the request body. The json format produces
+// the same body as the batch JSON serializer, while ndjson writes each event in its own line.
+type payload struct {
+	format string
+	buf    []byte
+	n      int
+}
+
+func newPayload(format string) *payload {
+	return &payload{format: format, buf: make([]byte, 0)}
+}
+
+// add appends the serialized event to the payload.
+func (p *payload) add(b []byte) {
+	if p.format == jsonFormat {
+		if p.n == 0 {
+			p.buf = append(p.buf, '[')
+		} else {
+			p.buf = append(p.buf, ',')
+		}
+	}
+	p.buf = append(p.buf, b...)
+	p.buf = append(p.buf, '\n')
+	p.n++
+}
+
+// sizeWith returns the size of the payload body if the event of the given length was appended.
+func (p *payload) sizeWith(n int) int {
+	if p.format == jsonFormat {
+		// array bracket or separator, new line and the closing bracket
+		return len(p.buf) + n + 3
+	}
+	return len(p.buf) + n + 1
+}
+
+// empty determines if no events were appended to the payload.
+func (p *payload) empty() bool { return p.n == 0 }
+
+// bytes returns the payload body.
+func (p *payload) bytes() []byte {
+	if p.format == jsonFormat {
+		return append(p.buf, ']')
+	}
+	return p.buf
+}
+
+// contentType returns the media type of the payload body.
+func (p *payload) contentType() string {
+	if p.format == jsonFormat {
+		return "application/json"
+	}
+	return "application/x-ndjson"
+}
@@ -47,6 +47,12 @@ const (
 	Elasticsearch
 	// Null is the null output.
 	Null
+	// File denotes the rotating file output.
+	File
+	// Syslog denotes the syslog output.
+	Syslog
+	// HTTP denotes the HTTP output.
+	HTTP
 )
 
 // String returns the string representation of the output type.
@@ -60,6 +66,12 @@ func (t Type) String() string {
 		return "elasticsearch"
 	case Null:
 		return "null"
+	case File:
+		return "file"
+	case Syslog:
+		return "syslog"
+	case HTTP:
+		return "http"
 	default:
 		return "unknown"
 	}
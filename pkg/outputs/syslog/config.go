@@ -0,0 +1,78 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syslog
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/spf13/pflag"
+	"time"
+)
+
+const (
+	enabled  = "output.syslog.enabled"
+	network  = "output.syslog.network"
+	address  = "output.syslog.address"
+	timeout  = "output.syslog.timeout"
+	format   = "output.syslog.format"
+	facility = "output.syslog.facility"
+	severity = "output.syslog.severity"
+	appName  = "output.syslog.app-name"
+	hostname = "output.syslog.hostname"
+	tmpl     = "output.syslog.template"
+)
+
+// Config contains the tweaks that influence the behaviour of the syslog output.
+type Config struct {
+	outputs.TLSConfig
+	// Enabled indicates if the syslog output is enabled.
+	Enabled bool `mapstructure:"enabled"`
+	// Network is the transport protocol for sending messages to the syslog server. It can be udp, tcp or tls.
+	Network string `mapstructure:"network"`
+	// Address represents the address of the syslog server.
+	Address string `mapstructure:"address"`
+	// Timeout specifies the connection and write timeout.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Format determines the syslog message format. It can be rfc5424 or rfc3164.
+	Format string `mapstructure:"format"`
+	// Facility is the syslog facility of the message.
+	Facility string `mapstructure:"facility"`
+	// Severity is the syslog severity of the message.
+	Severity string `mapstructure:"severity"`
+	// AppName identifies the application that originated the message.
+	AppName string `mapstructure:"app-name"`
+	// Hostname overrides the event host name in the message header.
+	Hostname string `mapstructure:"hostname"`
+	// Template is the event formatting template that renders the message.
+	Template string `mapstructure:"template"`
+}
+
+// AddFlags registers persistent flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.Bool(enabled, false, "Indicates if the syslog output is enabled")
+	flags.String(network, "udp", "Specifies the transport protocol for sending messages to the syslog server. Choose between udp|tcp|tls")
+	flags.String(address, "localhost:514", "Represents the address of the syslog server")
+	flags.Duration(timeout, time.Second*5, "Specifies the connection and write timeout")
+	flags.String(format, rfc5424, "Determines the syslog message format. Choose between rfc5424|rfc3164")
+	flags.String(facility, "local0", "Specifies the syslog facility of the message")
+	flags.String(severity, "info", "Specifies the syslog severity of the message")
+	flags.String(appName, "fibratus", "Identifies the application that originated the message")
+	flags.String(hostname, "", "Overrides the event host name in the message header")
+	flags.String(tmpl, "", "Event formatting template that renders the message")
+	outputs.AddTLSFlags(flags, outputs.Syslog)
+}
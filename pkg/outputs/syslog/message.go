@@ -0,0 +1,219 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syslog
+
+import (
+	"bytes"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	rfc5424 = "rfc5424"
+	rfc3164 = "rfc3164"
+)
+
+const (
+	rfc5424TimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+	rfc3164TimeFormat = "Jan _2 15:04:05"
+)
+
+// structured data element identifiers. 32473 is the private enterprise
+// number reserved for documentation use by RFC 5612
+const (
+	kevtSDID    = "kevt@32473"
+	kparamsSDID = "kparams@32473"
+)
+
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+var severities = map[string]int{
+	"emerg":   0,
+	"alert":   1,
+	"crit":    2,
+	"err":     3,
+	"warning": 4,
+	"notice":  5,
+	"info":    6,
+	"debug":   7,
+}
+
+// sdValueEscaper escapes the characters that are not allowed in structured data parameter values
+var sdValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// encoder frames events as syslog messages.
+type encoder struct {
+	format    string
+	pri       int
+	appName   string
+	hostname  string
+	procID    string
+	formatter *kevent.Formatter
+}
+
+func (e encoder) encode(kevt *kevent.Kevent) []byte {
+	if e.format == rfc3164 {
+		return e.rfc3164(kevt)
+	}
+	return e.rfc5424(kevt)
+}
+
+// rfc5424 produces the message in the following format:
+//
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [STRUCTURED-DATA] MSG
+func (e encoder) rfc5424(kevt *kevent.Kevent) []byte {
+	var b bytes.Buffer
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(e.pri))
+	b.WriteString(">1 ")
+	b.WriteString(kevt.Timestamp.Format(rfc5424TimeFormat))
+	b.WriteByte(' ')
+	b.WriteString(headerField(e.host(kevt), 255))
+	b.WriteByte(' ')
+	b.WriteString(headerField(e.appName, 48))
+	b.WriteByte(' ')
+	b.WriteString(headerField(e.procID, 128))
+	b.WriteByte(' ')
+	b.WriteString(headerField(kevt.Name, 32))
+	b.WriteByte(' ')
+	writeStructuredData(&b, kevt)
+	b.WriteByte(' ')
+	b.Write(e.formatter.Format(kevt))
+	return b.Bytes()
+}
+
+// rfc3164 produces the message in the following format:
+//
+// <PRI>TIMESTAMP HOSTNAME TAG[PID]: MSG
+func (e encoder) rfc3164(kevt *kevent.Kevent) []byte {
+	var b bytes.Buffer
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(e.pri))
+	b.WriteByte('>')
+	b.WriteString(kevt.Timestamp.Format(rfc3164TimeFormat))
+	b.WriteByte(' ')
+	b.WriteString(headerField(e.host(kevt), 255))
+	b.WriteByte(' ')
+	b.WriteString(headerField(e.appName, 32))
+	b.WriteByte('[')
+	b.WriteString(e.procID)
+	b.WriteString("]: ")
+	b.Write(e.formatter.Format(kevt))
+	return b.Bytes()
+}
+
+func (e encoder) host(kevt *kevent.Kevent) string {
+	if e.hostname != "" {
+		return e.hostname
+	}
+	return kevt.Host
+}
+
+// writeStructuredData writes the structured data elements with event attributes and parameters.
+func writeStructuredData(b *bytes.Buffer, kevt *kevent.Kevent) {
+	b.WriteString("[" + kevtSDID)
+	writeSDParam(b, "seq", strconv.FormatUint(kevt.Seq, 10))
+	writeSDParam(b, "pid", strconv.FormatUint(uint64(kevt.PID), 10))
+	writeSDParam(b, "tid", strconv.FormatUint(uint64(kevt.Tid), 10))
+	writeSDParam(b, "cpu", strconv.FormatUint(uint64(kevt.CPU), 10))
+	writeSDParam(b, "category", string(kevt.Category))
+	b.WriteByte(']')
+
+	if len(kevt.Kparams) == 0 {
+		return
+	}
+	names := make([]string, 0, len(kevt.Kparams))
+	for name := range kevt.Kparams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b.WriteString("[" + kparamsSDID)
+	for _, name := range names {
+		writeSDParam(b, name, kevt.Kparams[name].String())
+	}
+	b.WriteByte(']')
+}
+
+func writeSDParam(b *bytes.Buffer, name, value string) {
+	name = sdName(name)
+	if name == "" {
+		return
+	}
+	b.WriteByte(' ')
+	b.WriteString(name)
+	b.WriteString(`="`)
+	b.WriteString(sdValueEscaper.Replace(value))
+	b.WriteByte('"')
+}
+
+// sdName strips the characters that are not allowed in structured data parameter names.
+func sdName(name string) string {
+	var sb strings.Builder
+	for _, c := range name {
+		if c < 33 || c > 126 || c == '=' || c == ']' || c == '"' {
+			continue
+		}
+		sb.WriteRune(c)
+		if sb.Len() == 32 {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// headerField replaces non-printable characters in the header field and
+// truncates it to the max length. Empty fields are denoted by the nil value.
+func headerField(s string, max int) string {
+	if s == "" {
+		return "-"
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if c < 33 || c > 126 {
+			b[i] = '_'
+		}
+	}
+	if len(b) > max {
+		b = b[:max]
+	}
+	return string(b)
+}
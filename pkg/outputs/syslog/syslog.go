@@ -0,0 +1,190 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syslog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"expvar"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	tlsutil "github.com/rabbitstack/fibratus/pkg/util/tls"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+var (
+	// syslogErrors counts the errors that occurred while sending messages
+	syslogErrors = expvar.NewInt("output.syslog.errors")
+	// syslogMessages counts the total number of sent messages
+	syslogMessages = expvar.NewInt("output.syslog.messages")
+)
+
+// template represents the default template used for rendering the message
+const template = "{{ .Process }} ({{ .Pid }}) - {{ .Type }} ({{ .Kparams }})"
+
+type syslog struct {
+	config    Config
+	enc       encoder
+	tlsConfig *tls.Config
+	conn      net.Conn
+	buf       bytes.Buffer
+}
+
+func init() {
+	outputs.Register(outputs.Syslog, initSyslog)
+}
+
+func initSyslog(config outputs.Config) (outputs.OutputGroup, error) {
+	cfg, ok := config.Output.(Config)
+	if !ok {
+		return outputs.Fail(outputs.ErrInvalidConfig(outputs.Syslog, config.Output))
+	}
+	switch cfg.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return outputs.Fail(fmt.Errorf("unsupported network %q. Choose between udp|tcp|tls", cfg.Network))
+	}
+	switch cfg.Format {
+	case rfc5424, rfc3164:
+	default:
+		return outputs.Fail(fmt.Errorf("unsupported format %q. Choose between rfc5424|rfc3164", cfg.Format))
+	}
+	facility, ok := facilities[cfg.Facility]
+	if !ok {
+		return outputs.Fail(fmt.Errorf("unknown facility %q", cfg.Facility))
+	}
+	severity, ok := severities[cfg.Severity]
+	if !ok {
+		return outputs.Fail(fmt.Errorf("unknown severity %q", cfg.Severity))
+	}
+
+	tmpl := cfg.Template
+	if tmpl == "" {
+		tmpl = template
+	}
+	formatter, err := kevent.NewFormatter(tmpl)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	s := &syslog{
+		config: cfg,
+		enc: encoder{
+			format:    cfg.Format,
+			pri:       facility*8 + severity,
+			appName:   cfg.AppName,
+			hostname:  cfg.Hostname,
+			procID:    strconv.Itoa(os.Getpid()),
+			formatter: formatter,
+		},
+	}
+
+	if cfg.Network == "tls" {
+		s.tlsConfig, err = tlsutil.MakeConfig(cfg.TLSCert, cfg.TLSKey, cfg.TLSCA, cfg.TLSInsecureSkipVerify)
+		if err != nil {
+			return outputs.Fail(fmt.Errorf("invalid TLS config: %v", err))
+		}
+		if s.tlsConfig == nil {
+			s.tlsConfig = &tls.Config{InsecureSkipVerify: cfg.TLSInsecureSkipVerify}
+		}
+		if s.tlsConfig.ServerName == "" {
+			host, _, err := net.SplitHostPort(cfg.Address)
+			if err != nil {
+				return outputs.Fail(err)
+			}
+			s.tlsConfig.ServerName = host
+		}
+	}
+
+	return outputs.Success(s), nil
+}
+
+func (s *syslog) Connect() error {
+	dialer := &net.Dialer{Timeout: s.config.Timeout}
+	var err error
+	if s.config.Network == "tls" {
+		s.conn, err = tls.DialWithDialer(dialer, "tcp", s.config.Address, s.tlsConfig)
+	} else {
+		s.conn, err = dialer.Dial(s.config.Network, s.config.Address)
+	}
+	if err != nil {
+		return err
+	}
+	log.Infof("established connection to syslog server on %s://%s", s.config.Network, s.config.Address)
+	return nil
+}
+
+func (s *syslog) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+func (s *syslog) Publish(batch *kevent.Batch) error {
+	defer batch.Release()
+
+	if s.conn == nil {
+		return outputs.ErrConnectionLost
+	}
+	if s.config.Timeout > 0 {
+		if err := s.conn.SetWriteDeadline(time.Now().Add(s.config.Timeout)); err != nil {
+			return s.fail(err)
+		}
+	}
+
+	s.buf.Reset()
+	for _, kevt := range batch.Events {
+		msg := s.enc.encode(kevt)
+		// each datagram carries a single message
+		if s.config.Network == "udp" {
+			if _, err := s.conn.Write(msg); err != nil {
+				return s.fail(err)
+			}
+			continue
+		}
+		// stream transports use octet-counting framing
+		s.buf.WriteString(strconv.Itoa(len(msg)))
+		s.buf.WriteByte(' ')
+		s.buf.Write(msg)
+	}
+	if s.buf.Len() > 0 {
+		if _, err := s.conn.Write(s.buf.Bytes()); err != nil {
+			return s.fail(err)
+		}
+	}
+
+	syslogMessages.Add(batch.Len())
+
+	return nil
+}
+
+// fail closes the broken connection. The worker connects
+// the client again before publishing the next batch.
+func (s *syslog) fail(err error) error {
+	syslogErrors.Add(1)
+	_ = s.conn.Close()
+	s.conn = nil
+	return fmt.Errorf("%w: %v", outputs.ErrConnectionLost, err)
+}
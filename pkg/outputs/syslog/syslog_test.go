@@ -0,0 +1,195 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syslog
+
+import (
+	"bufio"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/outputs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newKevent() *kevent.Kevent {
+	return &kevent.Kevent{
+		Type:      ktypes.CreateFile,
+		Tid:       2484,
+		PID:       859,
+		CPU:       1,
+		Seq:       2,
+		Name:      "CreateFile",
+		Timestamp: time.Date(2020, 11, 4, 10, 30, 0, 123456000, time.UTC),
+		Category:  ktypes.File,
+		Host:      "archrabbit",
+		Kparams: kevent.Kparams{
+			kparams.FileName:      {Name: kparams.FileName, Type: kparams.UnicodeString, Value: "C:\\Windows\\system32\\user32.dll"},
+			kparams.FileOperation: {Name: kparams.FileOperation, Type: kparams.AnsiString, Value: "open"},
+		},
+		Metadata: make(map[string]string),
+	}
+}
+
+func newEncoder(t *testing.T, format string) encoder {
+	formatter, err := kevent.NewFormatter("{{ .Type }} on {{ .Host }}")
+	require.NoError(t, err)
+	return encoder{
+		format:    format,
+		pri:       facilities["local0"]*8 + severities["info"],
+		appName:   "fibratus",
+		procID:    "4242",
+		formatter: formatter,
+	}
+}
+
+func TestRFC5424(t *testing.T) {
+	enc := newEncoder(t, rfc5424)
+	assert.Equal(t,
+		`<134>1 2020-11-04T10:30:00.123456Z archrabbit fibratus 4242 CreateFile `+
+			`[kevt@32473 seq="2" pid="859" tid="2484" cpu="1" category="file"]`+
+			`[kparams@32473 file_name="C:\\Windows\\system32\\user32.dll" file_operation="open"] CreateFile on archrabbit`,
+		string(enc.encode(newKevent())))
+
+	enc.hostname = "override host"
+	kevt := newKevent()
+	kevt.Kparams = kevent.Kparams{}
+	assert.Equal(t,
+		`<134>1 2020-11-04T10:30:00.123456Z override_host fibratus 4242 CreateFile `+
+			`[kevt@32473 seq="2" pid="859" tid="2484" cpu="1" category="file"] CreateFile on archrabbit`,
+		string(enc.encode(kevt)))
+}
+
+func TestRFC3164(t *testing.T) {
+	enc := newEncoder(t, rfc3164)
+	assert.Equal(t, `<134>Nov  4 10:30:00 archrabbit fibratus[4242]: CreateFile on archrabbit`, string(enc.encode(newKevent())))
+}
+
+func TestInitSyslog(t *testing.T) {
+	var tests = []struct {
+		config Config
+		valid  bool
+	}{
+		{Config{Network: "udp", Format: rfc5424, Facility: "local0", Severity: "info"}, true},
+		{Config{Network: "tls", Address: "siem:6514", Format: rfc3164, Facility: "auth", Severity: "warning"}, true},
+		{Config{Network: "unix", Format: rfc5424, Facility: "local0", Severity: "info"}, false},
+		{Config{Network: "tcp", Format: "rfc822", Facility: "local0", Severity: "info"}, false},
+		{Config{Network: "tcp", Format: rfc5424, Facility: "local9", Severity: "info"}, false},
+		{Config{Network: "tcp", Format: rfc5424, Facility: "local0", Severity: "fatal"}, false},
+		{Config{Network: "tcp", Format: rfc5424, Facility: "local0", Severity: "info", Template: "{{ .Unknown }}"}, false},
+	}
+
+	for i, tt := range tests {
+		_, err := initSyslog(outputs.Config{Type: outputs.Syslog, Output: tt.config})
+		if tt.valid {
+			assert.NoError(t, err, i)
+		} else {
+			assert.Error(t, err, i)
+		}
+	}
+}
+
+func newSyslog(t *testing.T, network, addr string) *syslog {
+	group, err := initSyslog(outputs.Config{
+		Type: outputs.Syslog,
+		Output: Config{
+			Network:  network,
+			Address:  addr,
+			Timeout:  time.Second,
+			Format:   rfc5424,
+			Facility: "local0",
+			Severity: "info",
+			AppName:  "fibratus",
+			Template: "{{ .Type }} on {{ .Host }}",
+		},
+	})
+	require.NoError(t, err)
+	return group.Clients[0].(*syslog)
+}
+
+func TestPublishUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	s := newSyslog(t, "udp", pc.LocalAddr().String())
+	require.NoError(t, s.Connect())
+	defer s.Close()
+	require.NoError(t, s.Publish(kevent.NewBatch(newKevent(), newKevent())))
+
+	buf := make([]byte, 4096)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, pc.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := pc.ReadFrom(buf)
+		require.NoError(t, err)
+		assert.Contains(t, string(buf[:n]), "<134>1 2020-11-04T10:30:00.123456Z archrabbit fibratus")
+	}
+}
+
+func TestPublishTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	msgs := make(chan string, 2)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			// read the octet count that precedes each message
+			count, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(count[:len(count)-1])
+			if err != nil {
+				return
+			}
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+			msgs <- string(msg)
+		}
+	}()
+
+	s := newSyslog(t, "tcp", l.Addr().String())
+	require.NoError(t, s.Connect())
+	defer s.Close()
+	require.NoError(t, s.Publish(kevent.NewBatch(newKevent(), newKevent())))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-msgs:
+			assert.Contains(t, msg, "<134>1 2020-11-04T10:30:00.123456Z archrabbit fibratus")
+			assert.Contains(t, msg, "CreateFile on archrabbit")
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the message")
+		}
+	}
+}
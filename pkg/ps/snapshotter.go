@@ -183,6 +183,7 @@ func (s *snapshotter) Write(kevt *kevent.Kevent) error {
 		}
 
 		ps := pstypes.FromKevent(unwrapParams(pid, kevt))
+		ps.StartTime, _ = kevt.Kparams.GetTime(kparams.StartTime)
 		// enumerate process handles
 		handles, err := s.handleSnap.FindHandles(pid)
 		if err != nil {
@@ -27,6 +27,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 )
 
 // PS encapsulates process' state such as allocated resources and other metadata.
@@ -63,6 +64,8 @@ type PS struct {
 	Handles htypes.Handles `json:"handles"`
 	// PE stores the PE (Portable Executable) metadata.
 	PE *pe.PE `json:"pe"`
+	// StartTime is the time at which the process was started.
+	StartTime time.Time `json:"started"`
 }
 
 // String returns a string representation of the process' state.
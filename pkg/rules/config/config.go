@@ -0,0 +1,56 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+)
+
+const (
+	enabled  = "rules.enabled"
+	paths    = "rules.paths"
+	alertVia = "rules.alert-via"
+)
+
+// Config stores the settings of the detection rules engine.
+type Config struct {
+	// Enabled indicates if the detection rules are evaluated.
+	Enabled bool `json:"rules.enabled" yaml:"rules.enabled"`
+	// Paths contains the directories or files from which the rules are loaded.
+	Paths []string `json:"rules.paths" yaml:"rules.paths"`
+	// AlertVia defines the alert sender for the rules that don't specify it.
+	AlertVia string `json:"rules.alert-via" yaml:"rules.alert-via"`
+}
+
+// InitFromViper initializes rules config from Viper.
+func (c *Config) InitFromViper(v *viper.Viper) {
+	c.Enabled = v.GetBool(enabled)
+	c.Paths = v.GetStringSlice(paths)
+	c.AlertVia = v.GetString(alertVia)
+}
+
+// AddFlags registers persistent flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.Bool(enabled, false, "Specifies if the detection rules are evaluated against the event stream")
+	flags.StringSlice(paths, []string{filepath.Join(os.Getenv("PROGRAMFILES"), "fibratus", "rules")}, "Contains the comma-separated list of directories or files from which the rules are loaded")
+	flags.String(alertVia, "mail", "Defines the alert sender for the rules that don't specify it")
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rules
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/alertsender"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/filter"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/mitre"
+	"github.com/rabbitstack/fibratus/pkg/ps"
+	"github.com/rabbitstack/fibratus/pkg/util/multierror"
+	log "github.com/sirupsen/logrus"
+	"text/template"
+)
+
+var (
+	// ruleMatches counts the number of events matched by each rule
+	ruleMatches = expvar.NewMap("rules.matches")
+	// alertErrors counts the number of rule alerts that couldn't be sent
+	alertErrors = expvar.NewInt("rules.alert.errors")
+)
+
+// RuleNameMeta is the metadata key under which names of matching rules are stored.
+const RuleNameMeta = "rule.name"
+
+const alertTitleTmpl = `{{ .Rule.Name }}`
+
+const alertTextTmpl = `
+	{{ if .Rule.Description }}{{ .Rule.Description }}{{ end }}
+
+	{{ .Kevt.Name }} event matched the rule at {{ .Timestamp }}.
+	{{ with .Kevt.PS }}
+	Process information
+
+	Name: 		{{ .Name }}
+	PID:  		{{ .PID }}
+	PPID: 		{{ .Ppid }}
+	Comm:		{{ .Comm }}
+	Cwd:		{{ .Cwd }}
+	SID:		{{ .SID }}
+	{{ end }}
+	Params: {{ .Kevt.Kparams }}
+`
+
+const tsLayout = "02 Jan 2006 15:04:05 MST"
+
+// AlertContext contains the rule along with the event that matched the rule condition.
+type AlertContext struct {
+	Rule      Rule
+	Kevt      *kevent.Kevent
+	Timestamp string
+}
+
+// compiledRule bundles the rule definition with its compiled filter and alert templates.
+type compiledRule struct {
+	Rule
+	filter   filter.Filter
+	severity alertsender.Severity
+	title    *template.Template
+	text     *template.Template
+}
+
+// Engine evaluates the detection rules against the event stream. Events that match
+// the rule condition are tagged with the rule name and the rule alert is emitted.
+type Engine struct {
+	rules []*compiledRule
+	multi *filter.Multi
+}
+
+// NewEngine loads the rules from the configured paths and compiles their conditions and alert templates.
+func NewEngine(psnap ps.Snapshotter, config *config.Config) (*Engine, error) {
+	rules, err := Load(config.Rules.Paths)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load rules: %v", err)
+	}
+	e := &Engine{rules: make([]*compiledRule, 0, len(rules))}
+	for _, r := range rules {
+		if !r.IsEnabled() {
+			continue
+		}
+		rule, err := compileRule(r, psnap, config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q rule: %v", r.Name, err)
+		}
+		e.rules = append(e.rules, rule)
+	}
+	filters := make([]filter.Filter, len(e.rules))
+	for i, rule := range e.rules {
+		filters[i] = rule.filter
+	}
+	e.multi, err = filter.NewMulti(filters, psnap, config)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("loaded %d rule(s)", len(e.rules))
+	return e, nil
+}
+
+func compileRule(r Rule, psnap ps.Snapshotter, config *config.Config) (*compiledRule, error) {
+	f := filter.New(r.Condition, psnap, config)
+	if err := f.Compile(); err != nil {
+		return nil, fmt.Errorf("bad condition: \n  %v", err)
+	}
+	if err := mitre.Validate(r.Tactics, r.Techniques); err != nil {
+		return nil, err
+	}
+	if r.AlertVia == "" {
+		r.AlertVia = config.Rules.AlertVia
+	}
+	if r.AlertTemplate.Title == "" {
+		r.AlertTemplate.Title = alertTitleTmpl
+	}
+	if r.AlertTemplate.Text == "" {
+		r.AlertTemplate.Text = alertTextTmpl
+	}
+	title, err := template.New(r.Name).Parse(r.AlertTemplate.Title)
+	if err != nil {
+		return nil, fmt.Errorf("title template syntax error: %v", err)
+	}
+	text, err := template.New(r.Name).Parse(r.AlertTemplate.Text)
+	if err != nil {
+		return nil, fmt.Errorf("text template syntax error: %v", err)
+	}
+	return &compiledRule{
+		Rule:     r,
+		filter:   f,
+		severity: alertsender.ParseSeverityFromString(r.Severity),
+		title:    title,
+		text:     text,
+	}, nil
+}
+
+// ProcessEvent evaluates all rules against the event. Field values shared by rule
+// conditions are extracted once, and rules that can't match the event type are skipped.
+func (e *Engine) ProcessEvent(kevt *kevent.Kevent) error {
+	var errs []error
+	for _, i := range e.multi.Run(kevt) {
+		rule := e.rules[i]
+		ruleMatches.Add(rule.Name, 1)
+		if names, ok := kevt.Metadata[RuleNameMeta]; ok {
+			kevt.AddMeta(RuleNameMeta, names+","+rule.Name)
+		} else {
+			kevt.AddMeta(RuleNameMeta, rule.Name)
+		}
+		kevt.AddAttack(rule.Tactics, rule.Techniques)
+		// exempted events match rules, but don't trigger alerts
+		if kevt.IsExempted() {
+			continue
+		}
+		if err := rule.send(kevt); err != nil {
+			errs = append(errs, fmt.Errorf("couldn't send %q rule alert: %v", rule.Name, err))
+		}
+	}
+	return multierror.Wrap(errs...)
+}
+
+func (r *compiledRule) send(kevt *kevent.Kevent) error {
+	ctx := AlertContext{
+		Rule:      r.Rule,
+		Kevt:      kevt,
+		Timestamp: kevt.Timestamp.Format(tsLayout),
+	}
+	var title, text bytes.Buffer
+	if err := r.title.Execute(&title, ctx); err != nil {
+		return fmt.Errorf("couldn't execute template: %v", err)
+	}
+	if err := r.text.Execute(&text, ctx); err != nil {
+		return fmt.Errorf("couldn't execute template: %v", err)
+	}
+
+	sender := alertsender.Find(alertsender.ToType(r.AlertVia))
+	if sender == nil {
+		return fmt.Errorf("%q alert sender is not initialized", r.AlertVia)
+	}
+	alert := alertsender.NewAlert(title.String(), text.String(), r.Tags, r.severity).WithAttack(r.Tactics, r.Techniques)
+
+	log.Infof("emitting rule alert via %q sender: %s", r.AlertVia, alert)
+	alertsender.SendAsync(sender, alert, alertErrors)
+
+	return nil
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rules
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/alertsender"
+	"github.com/rabbitstack/fibratus/pkg/config"
+	"github.com/rabbitstack/fibratus/pkg/kevent"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/kevent/ktypes"
+	"github.com/rabbitstack/fibratus/pkg/mitre"
+	rulesconfig "github.com/rabbitstack/fibratus/pkg/rules/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var alerts = make(chan alertsender.Alert, 1)
+
+type mockSender struct{}
+
+func (s *mockSender) Send(a alertsender.Alert) error {
+	alerts <- a
+	return nil
+}
+
+func makeSender(config alertsender.Config) (alertsender.Sender, error) {
+	return &mockSender{}, nil
+}
+
+func init() {
+	alertsender.Register(alertsender.Noop, makeSender)
+}
+
+func TestEngine(t *testing.T) {
+	require.NoError(t, alertsender.LoadAll([]alertsender.Config{{Type: alertsender.Noop}}))
+
+	e, err := NewEngine(nil, &config.Config{
+		Kstream: config.KstreamConfig{EnableNetKevents: true},
+		Rules: rulesconfig.Config{
+			Enabled:  true,
+			Paths:    []string{"_fixtures/rules"},
+			AlertVia: "noop",
+		},
+	})
+	require.NoError(t, err)
+	// disabled rules are not loaded
+	require.Len(t, e.rules, 2)
+
+	kevt := &kevent.Kevent{
+		Type:      ktypes.CreateProcess,
+		Name:      "CreateProcess",
+		PID:       1234,
+		Timestamp: time.Now(),
+		Kparams: kevent.Kparams{
+			kparams.ProcessName: {Name: kparams.ProcessName, Type: kparams.AnsiString, Value: "svchost.exe"},
+			kparams.Comm:        {Name: kparams.Comm, Type: kparams.UnicodeString, Value: "C:\\Windows\\system32\\svchost.exe"},
+		},
+		Metadata: make(map[string]string),
+	}
+	require.NoError(t, e.ProcessEvent(kevt))
+	assert.Equal(t, "Svchost without service group", kevt.Metadata[RuleNameMeta])
+	assert.Equal(t, "TA0005", kevt.Metadata[mitre.TacticsMeta])
+	assert.Equal(t, "T1036.005", kevt.Metadata[mitre.TechniquesMeta])
+
+	select {
+	case alert := <-alerts:
+		assert.Equal(t, "Svchost without service group (1234)", alert.Title)
+		assert.Contains(t, alert.Text, "svchost.exe process started without the service group argument")
+		assert.Equal(t, alertsender.Critical, alert.Severity)
+		assert.Equal(t, []string{"svchost", "masquerading"}, alert.Tags)
+		assert.Equal(t, []string{"TA0005"}, alert.Tactics)
+		assert.Equal(t, []string{"T1036.005"}, alert.Techniques)
+	case <-time.After(time.Second * 5):
+		t.Fatal("rule alert wasn't sent")
+	}
+
+	kevt = &kevent.Kevent{
+		Type:      ktypes.CreateProcess,
+		Name:      "CreateProcess",
+		PID:       1234,
+		Timestamp: time.Now(),
+		Kparams: kevent.Kparams{
+			kparams.ProcessName: {Name: kparams.ProcessName, Type: kparams.AnsiString, Value: "svchost.exe"},
+			kparams.Comm:        {Name: kparams.Comm, Type: kparams.UnicodeString, Value: "C:\\Windows\\system32\\svchost.exe -k RPCSS"},
+		},
+		Metadata: make(map[string]string),
+	}
+	require.NoError(t, e.ProcessEvent(kevt))
+	assert.Empty(t, kevt.Metadata)
+
+	// the rule alert sender is not initialized
+	kevt = &kevent.Kevent{
+		Type:      ktypes.SendTCPv4,
+		Name:      "Send",
+		PID:       1234,
+		Timestamp: time.Now(),
+		Kparams: kevent.Kparams{
+			kparams.NetDport: {Name: kparams.NetDport, Type: kparams.Uint16, Value: uint16(445)},
+		},
+		Metadata: make(map[string]string),
+	}
+	require.EqualError(t, e.ProcessEvent(kevt), `couldn't send "Outbound SMB connection" rule alert: "slack" alert sender is not initialized`)
+	assert.Equal(t, "Outbound SMB connection", kevt.Metadata[RuleNameMeta])
+}
+
+func TestEngineInvalidRule(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rules")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "rules.yml"), []byte("- name: Broken\n  condition: ps.nmae = 'cmd.exe'"), 0644))
+
+	_, err = NewEngine(nil, &config.Config{
+		Rules: rulesconfig.Config{Paths: []string{dir}},
+	})
+	require.EqualError(t, err, "invalid \"Broken\" rule: bad condition: \n  ps.nmae = 'cmd.exe'\n ^ unknown field ps.nmae")
+}
+
+func TestEngineInvalidAttack(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rules")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "rules.yml"), []byte("- name: Shell\n  condition: ps.name = 'cmd.exe'\n  techniques:\n    - T1059.003\n    - TA0002"), 0644))
+
+	_, err = NewEngine(nil, &config.Config{
+		Rules: rulesconfig.Config{Paths: []string{dir}},
+	})
+	require.EqualError(t, err, "invalid \"Shell\" rule: \"TA0002\" is not a valid technique identifier")
+}
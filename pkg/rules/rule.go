@@ -0,0 +1,81 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rules
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/util/yamlfile"
+	"gopkg.in/yaml.v2"
+)
+
+// AlertTemplate contains the templates for rendering the alert title and text.
+type AlertTemplate struct {
+	Title string `json:"title" yaml:"title,omitempty"`
+	Text  string `json:"text" yaml:"text,omitempty"`
+}
+
+// Rule represents the detection rule. When the rule condition matches the
+// event, the alert is emitted via the designated alert sender.
+type Rule struct {
+	// Name is the unique name of the rule.
+	Name string `json:"name" yaml:"name"`
+	// Description explains the purpose of the rule.
+	Description string `json:"description" yaml:"description,omitempty"`
+	// Condition is the filter expression that events are evaluated against.
+	Condition string `json:"condition" yaml:"condition"`
+	// Severity determines the severity of the alert. Possible values are normal, medium and critical.
+	Severity string `json:"severity" yaml:"severity,omitempty"`
+	// Tags contains a sequence of tags for categorizing the rule alerts.
+	Tags []string `json:"tags" yaml:"tags,omitempty"`
+	// Tactics contains the identifiers of MITRE ATT&CK tactics the rule detects, e.g. TA0002.
+	Tactics []string `json:"tactics" yaml:"tactics,omitempty"`
+	// Techniques contains the identifiers of MITRE ATT&CK techniques the rule detects, e.g. T1059.001.
+	Techniques []string `json:"techniques" yaml:"techniques,omitempty"`
+	// Enabled indicates if the rule is evaluated. Rules are enabled unless stated otherwise.
+	Enabled *bool `json:"enabled" yaml:"enabled,omitempty"`
+	// AlertVia defines which alert sender is used to emit the alert.
+	AlertVia string `json:"alert-via" yaml:"alert-via,omitempty"`
+	// AlertTemplate contains the templates for the alert title and text.
+	AlertTemplate AlertTemplate `json:"alert-template" yaml:"alert-template,omitempty"`
+}
+
+// IsEnabled determines whether the rule is enabled.
+func (r Rule) IsEnabled() bool { return r.Enabled == nil || *r.Enabled }
+
+// Load reads the rule definitions from the YAML files found in the given paths.
+func Load(paths []string) ([]Rule, error) {
+	rules := make([]Rule, 0)
+	err := yamlfile.Load(paths, "rule", func(file string, b []byte) error {
+		var rs []Rule
+		if err := yaml.UnmarshalStrict(b, &rs); err != nil {
+			return err
+		}
+		for _, r := range rs {
+			if r.Condition == "" {
+				return fmt.Errorf("rule %q has no condition", r.Name)
+			}
+		}
+		rules = append(rules, rs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
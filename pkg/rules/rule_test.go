@@ -0,0 +1,77 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rules
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	rules, err := Load([]string{"_fixtures/rules"})
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+
+	names := make(map[string]Rule)
+	for _, r := range rules {
+		names[r.Name] = r
+	}
+	require.Contains(t, names, "Svchost without service group")
+	require.Contains(t, names, "Command shell spawned")
+	require.Contains(t, names, "Outbound SMB connection")
+
+	r := names["Svchost without service group"]
+	assert.Equal(t, "svchost.exe process started without the service group argument", r.Description)
+	assert.Equal(t, "critical", r.Severity)
+	assert.Equal(t, []string{"svchost", "masquerading"}, r.Tags)
+	assert.True(t, r.IsEnabled())
+	assert.Equal(t, "{{ .Rule.Name }} ({{ .Kevt.PID }})", r.AlertTemplate.Title)
+
+	assert.False(t, names["Command shell spawned"].IsEnabled())
+	assert.Equal(t, "slack", names["Outbound SMB connection"].AlertVia)
+}
+
+func TestLoadErrors(t *testing.T) {
+	var tests = []struct {
+		rules string
+		err   string
+	}{
+		{"- name: Rule\n  condition: ps.name = 'cmd.exe'\n- name: Rule\n  condition: ps.name = 'svchost.exe'", "rule \"Rule\" is already defined in"},
+		{"- description: Rule without name\n  condition: ps.name = 'cmd.exe'", "rule #1 has no name"},
+		{"- name: Rule\n  description: Rule without condition", "rule \"Rule\" has no condition"},
+		{"- name: Rule\n  filter: ps.name = 'cmd.exe'", "field filter not found in type rules.Rule"},
+	}
+
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "rules")
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "rules.yml"), []byte(tt.rules), 0644))
+		_, err = Load([]string{dir})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), tt.err)
+		_ = os.RemoveAll(dir)
+	}
+
+	_, err := Load([]string{"_fixtures/missing"})
+	require.Error(t, err)
+}
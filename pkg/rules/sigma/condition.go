@@ -0,0 +1,165 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sigma
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/util/wildcard"
+	"sort"
+	"strings"
+)
+
+// condParser parses the Sigma condition by combining the translated searches with
+// the logical operators. The grammar in the order of increasing precedence is:
+//
+//	expr   := term { "or" term }
+//	term   := factor { "and" factor }
+//	factor := "not" factor | "(" expr ")" | ("1" | "any" | "all") "of" (pattern | "them") | identifier
+type condParser struct {
+	toks     []string
+	pos      int
+	searches map[string]node
+}
+
+func newCondParser(cond string, searches map[string]node) *condParser {
+	r := strings.NewReplacer("(", " ( ", ")", " ) ", "|", " | ")
+	return &condParser{toks: strings.Fields(r.Replace(cond)), searches: searches}
+}
+
+func (p *condParser) parse() (node, error) {
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok == "|" {
+		return nil, fmt.Errorf("aggregation in condition")
+	} else if tok != "" {
+		return nil, fmt.Errorf("condition token %q", tok)
+	}
+	return n, nil
+}
+
+func (p *condParser) parseOr() (node, error) {
+	n, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []node{n}
+	for strings.ToLower(p.peek()) == "or" {
+		p.next()
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return newOr(nodes), nil
+}
+
+func (p *condParser) parseAnd() (node, error) {
+	n, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []node{n}
+	for strings.ToLower(p.peek()) == "and" {
+		p.next()
+		n, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return newAnd(nodes), nil
+}
+
+func (p *condParser) parseFactor() (node, error) {
+	tok := p.next()
+	switch strings.ToLower(tok) {
+	case "":
+		return nil, fmt.Errorf("end of condition")
+	case "not":
+		n, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	case "(":
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("unbalanced parentheses in condition")
+		}
+		return n, nil
+	case "1", "any", "all":
+		if strings.ToLower(p.peek()) != "of" {
+			break
+		}
+		p.next()
+		names := p.expand(p.next())
+		if len(names) == 0 {
+			return nil, fmt.Errorf("condition without searches matching %q", p.toks[p.pos-1])
+		}
+		nodes := make([]node, len(names))
+		for i, name := range names {
+			nodes[i] = p.searches[name]
+		}
+		if strings.ToLower(tok) == "all" {
+			return newAnd(nodes), nil
+		}
+		return newOr(nodes), nil
+	case "|":
+		return nil, fmt.Errorf("aggregation in condition")
+	}
+	n, ok := p.searches[tok]
+	if !ok {
+		return nil, fmt.Errorf("condition referencing undefined search %q", tok)
+	}
+	return n, nil
+}
+
+// expand returns the sorted names of the searches matching the pattern. The them
+// keyword matches all searches except those whose names start with the underscore.
+func (p *condParser) expand(pattern string) []string {
+	names := make([]string, 0)
+	for name := range p.searches {
+		if pattern == "them" && !strings.HasPrefix(name, "_") || pattern != "them" && wildcard.Match(pattern, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p *condParser) peek() string {
+	if p.pos >= len(p.toks) {
+		return ""
+	}
+	return p.toks[p.pos]
+}
+
+func (p *condParser) next() string {
+	tok := p.peek()
+	if tok != "" {
+		p.pos++
+	}
+	return tok
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sigma
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"strings"
+)
+
+// mapping translates the Sigma field to the filter field. If the value function is
+// given, it converts the Sigma value to the value of the filter field.
+type mapping struct {
+	field fields.Field
+	value func(string) (string, error)
+}
+
+// logsource determines the events the Sigma log source category refers to and the fields available in it.
+type logsource struct {
+	// expr is the filter expression that selects the events of the log source
+	expr   string
+	fields map[string]mapping
+}
+
+var processFields = map[string]mapping{
+	"Image":             {field: fields.PsExe},
+	"CommandLine":       {field: fields.PsComm},
+	"CurrentDirectory":  {field: fields.PsCwd},
+	"ProcessId":         {field: fields.PsPid},
+	"ParentProcessId":   {field: fields.PsPpid},
+	"ParentImage":       {field: fields.PsParentExe},
+	"ParentCommandLine": {field: fields.PsParentComm},
+	"OriginalFileName":  {field: fields.Field("pe.resources[OriginalFilename]")},
+	"Description":       {field: fields.Field("pe.resources[FileDescription]")},
+	"Product":           {field: fields.Field("pe.resources[ProductName]")},
+	"Company":           {field: fields.Field("pe.resources[CompanyName]")},
+}
+
+var fileFields = map[string]mapping{
+	"Image":          {field: fields.PsExe},
+	"ProcessId":      {field: fields.PsPid},
+	"TargetFilename": {field: fields.FileName},
+}
+
+var registryFields = map[string]mapping{
+	"Image":        {field: fields.PsExe},
+	"ProcessId":    {field: fields.PsPid},
+	"TargetObject": {field: fields.RegistryKeyName, value: registryKey},
+	"Details":      {field: fields.RegistryValue},
+	"EventType":    {field: fields.KevtName, value: registryEventType},
+}
+
+var networkFields = map[string]mapping{
+	"Image":               {field: fields.PsExe},
+	"ProcessId":           {field: fields.PsPid},
+	"DestinationIp":       {field: fields.NetDIP},
+	"DestinationPort":     {field: fields.NetDport},
+	"DestinationPortName": {field: fields.NetDportName},
+	"SourceIp":            {field: fields.NetSIP},
+	"SourcePort":          {field: fields.NetSport},
+	"SourcePortName":      {field: fields.NetSportName},
+	"Initiated":           {field: fields.KevtName, value: initiated},
+}
+
+var imageFields = map[string]mapping{
+	"Image":       {field: fields.PsExe},
+	"ProcessId":   {field: fields.PsPid},
+	"ImageLoaded": {field: fields.ImageName},
+}
+
+// logsources contains the supported Sigma log source categories.
+var logsources = map[string]logsource{
+	"process_creation":   {expr: "kevt.name = 'CreateProcess'", fields: processFields},
+	"file_event":         {expr: "kevt.name = 'CreateFile' and file.operation in ('create', 'supersede', 'openif', 'overwriteif')", fields: fileFields},
+	"registry_event":     {expr: "kevt.name in ('RegCreateKey', 'RegDeleteKey', 'RegSetValue', 'RegDeleteValue')", fields: registryFields},
+	"registry_add":       {expr: "kevt.name = 'RegCreateKey'", fields: registryFields},
+	"registry_set":       {expr: "kevt.name = 'RegSetValue'", fields: registryFields},
+	"registry_delete":    {expr: "kevt.name in ('RegDeleteKey', 'RegDeleteValue')", fields: registryFields},
+	"network_connection": {expr: "kevt.name in ('Connect', 'Accept')", fields: networkFields},
+	"image_load":         {expr: "kevt.name = 'LoadImage'", fields: imageFields},
+}
+
+// registryRoots maps the abbreviated root keys used in Sigma rules to the root key names.
+var registryRoots = []struct{ abbr, name string }{
+	{`HKLM\`, `HKEY_LOCAL_MACHINE\`},
+	{`HKU\`, `HKEY_USERS\`},
+	{`HKCU\`, `HKEY_CURRENT_USER\`},
+	{`HKCR\`, `HKEY_CLASSES_ROOT\`},
+}
+
+func registryKey(key string) (string, error) {
+	for _, root := range registryRoots {
+		if len(key) >= len(root.abbr) && strings.EqualFold(key[:len(root.abbr)], root.abbr) {
+			return root.name + key[len(root.abbr):], nil
+		}
+	}
+	return key, nil
+}
+
+var registryEventTypes = map[string]string{
+	"CreateKey":   "RegCreateKey",
+	"DeleteKey":   "RegDeleteKey",
+	"SetValue":    "RegSetValue",
+	"DeleteValue": "RegDeleteValue",
+}
+
+func registryEventType(typ string) (string, error) {
+	if name, ok := registryEventTypes[typ]; ok {
+		return name, nil
+	}
+	return "", fmt.Errorf("registry event type %s", typ)
+}
+
+func initiated(v string) (string, error) {
+	switch strings.ToLower(v) {
+	case "true":
+		return "Connect", nil
+	case "false":
+		return "Accept", nil
+	}
+	return "", fmt.Errorf("Initiated value %s", v)
+}
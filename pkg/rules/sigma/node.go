@@ -0,0 +1,127 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sigma
+
+import (
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"strings"
+)
+
+// node is the node of the translated detection. Negations are pushed down to
+// the predicates when the node is rendered, because filters can only negate
+// the operators of the predicates.
+type node interface{}
+
+type andNode []node
+
+type orNode []node
+
+type notNode struct {
+	n node
+}
+
+// predicate compares the field with one or more values. Values are rendered literals.
+type predicate struct {
+	field  fields.Field
+	op     string
+	values []string
+	// list indicates the values are always enclosed in parentheses
+	list bool
+}
+
+// negations contains the inverse of the comparison operators.
+var negations = map[string]string{"=": "!=", "!=": "=", "<": ">=", "<=": ">", ">": "<=", ">=": "<"}
+
+func (p *predicate) render(negate bool) string {
+	op := p.op
+	if negate {
+		if inv, ok := negations[op]; ok {
+			op = inv
+		} else {
+			op = "not " + op
+		}
+	}
+	v := p.values[0]
+	if p.list || len(p.values) > 1 {
+		v = "(" + strings.Join(p.values, ", ") + ")"
+	}
+	return string(p.field) + " " + op + " " + v
+}
+
+func newAnd(nodes []node) node {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return andNode(nodes)
+}
+
+func newOr(nodes []node) node {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return orNode(nodes)
+}
+
+// kind returns the logical operator the node is rendered with, or
+// the empty string if the node is rendered as the predicate.
+func kind(n node, negate bool) string {
+	switch n := n.(type) {
+	case andNode:
+		if negate {
+			return "or"
+		}
+		return "and"
+	case orNode:
+		if negate {
+			return "and"
+		}
+		return "or"
+	case notNode:
+		return kind(n.n, !negate)
+	}
+	return ""
+}
+
+// render renders the node as the filter expression. Negated nodes are
+// rendered according to De Morgan's laws.
+func render(n node, negate bool) string {
+	switch n := n.(type) {
+	case andNode:
+		return join(n, kind(n, negate), negate)
+	case orNode:
+		return join(n, kind(n, negate), negate)
+	case notNode:
+		return render(n.n, !negate)
+	case *predicate:
+		return n.render(negate)
+	}
+	return ""
+}
+
+func join(nodes []node, op string, negate bool) string {
+	exprs := make([]string, len(nodes))
+	for i, n := range nodes {
+		expr := render(n, negate)
+		if k := kind(n, negate); k != "" && k != op {
+			expr = "(" + expr + ")"
+		}
+		exprs[i] = expr
+	}
+	return strings.Join(exprs, " "+op+" ")
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sigma
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/util/yamlfile"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Rule represents the Sigma rule. Only the attributes relevant for the
+// translation into the filter expression are decoded.
+type Rule struct {
+	Title       string    `yaml:"title"`
+	ID          string    `yaml:"id"`
+	Description string    `yaml:"description"`
+	Level       string    `yaml:"level"`
+	Tags        []string  `yaml:"tags"`
+	Logsource   Logsource `yaml:"logsource"`
+	Detection   Detection `yaml:"detection"`
+	// Action is present in the documents of rule collections
+	Action string `yaml:"action"`
+	// File is the path of the file the rule was loaded from
+	File string `yaml:"-"`
+}
+
+// Logsource describes the events the rule applies to.
+type Logsource struct {
+	Category string `yaml:"category"`
+	Product  string `yaml:"product"`
+	Service  string `yaml:"service"`
+}
+
+// Detection contains the search identifiers and the condition that combines them.
+type Detection struct {
+	Searches  map[string]Search
+	Condition []string
+	Timeframe string
+}
+
+// UnmarshalYAML decodes the detection. The condition is either a single
+// expression or a list of expressions, and all other keys are searches.
+func (d *Detection) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var aux struct {
+		Condition interface{} `yaml:"condition"`
+		Timeframe string      `yaml:"timeframe"`
+	}
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+	switch cond := aux.Condition.(type) {
+	case string:
+		d.Condition = []string{cond}
+	case []interface{}:
+		for _, c := range cond {
+			d.Condition = append(d.Condition, fmt.Sprintf("%v", c))
+		}
+	}
+	d.Timeframe = aux.Timeframe
+
+	if err := unmarshal(&d.Searches); err != nil {
+		return err
+	}
+	delete(d.Searches, "condition")
+	delete(d.Searches, "timeframe")
+	return nil
+}
+
+// Search is the search identifier of the detection. It contains either the
+// field maps, where any of the maps has to match, or the keywords.
+type Search struct {
+	Maps     []yaml.MapSlice
+	Keywords []interface{}
+}
+
+// UnmarshalYAML decodes the search identifier from the map, the list of maps or the list of keywords.
+func (s *Search) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// the list of maps is tried first, because the sequence
+	// node is also decoded into the map slice
+	var maps []yaml.MapSlice
+	if err := unmarshal(&maps); err == nil {
+		s.Maps = maps
+		return nil
+	}
+	var m yaml.MapSlice
+	if err := unmarshal(&m); err == nil {
+		s.Maps = []yaml.MapSlice{m}
+		return nil
+	}
+	var keywords []interface{}
+	if err := unmarshal(&keywords); err == nil {
+		s.Keywords = keywords
+		return nil
+	}
+	var keyword interface{}
+	if err := unmarshal(&keyword); err != nil {
+		return err
+	}
+	s.Keywords = []interface{}{keyword}
+	return nil
+}
+
+// Parse decodes the Sigma rules from the YAML document stream.
+func Parse(b []byte) ([]*Rule, error) {
+	rules := make([]*Rule, 0)
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		var r Rule
+		err := dec.Decode(&r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &r)
+	}
+	return rules, nil
+}
+
+// Load reads the Sigma rules from the YAML files found in the given paths.
+func Load(paths []string) ([]*Rule, error) {
+	rules := make([]*Rule, 0)
+	err := yamlfile.Walk(paths, func(file string, _ os.FileInfo) error {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rs, err := Parse(b)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		for _, r := range rs {
+			r.File = file
+		}
+		rules = append(rules, rs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
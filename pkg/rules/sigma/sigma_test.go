@@ -0,0 +1,72 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sigma
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	rules, err := Load([]string{"_fixtures"})
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+
+	titles := make(map[string]*Rule)
+	for _, r := range rules {
+		titles[r.Title] = r
+	}
+	require.Contains(t, titles, "Suspicious Certutil Command")
+	require.Contains(t, titles, "Outbound RDP Connections")
+	require.Contains(t, titles, "Run Key Persistence")
+
+	r := titles["Suspicious Certutil Command"]
+	assert.Equal(t, filepath.Join("_fixtures", "proc_creation_win_susp_certutil.yml"), r.File)
+	assert.Equal(t, "high", r.Level)
+	assert.Equal(t, []string{"attack.defense_evasion", "attack.t1140"}, r.Tags)
+	assert.Equal(t, Logsource{Category: "process_creation", Product: "windows"}, r.Logsource)
+	assert.Equal(t, []string{"all of selection_* and not filter"}, r.Detection.Condition)
+	require.Len(t, r.Detection.Searches, 3)
+	assert.Len(t, r.Detection.Searches["selection_img"].Maps, 2)
+	assert.Len(t, r.Detection.Searches["selection_cli"].Maps, 1)
+	// documents of the same file are loaded in order
+	assert.Equal(t, "Outbound RDP Connections", rules[0].Title)
+	assert.Equal(t, "Run Key Persistence", rules[1].Title)
+}
+
+func TestParseKeywords(t *testing.T) {
+	rules, err := Parse([]byte(`
+title: Keywords
+detection:
+  keywords:
+    - 'mimikatz'
+    - 'sekurlsa'
+  condition:
+    - keywords
+    - 1 of them
+`))
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	d := rules[0].Detection
+	assert.Equal(t, []string{"keywords", "1 of them"}, d.Condition)
+	assert.Equal(t, []interface{}{"mimikatz", "sekurlsa"}, d.Searches["keywords"].Keywords)
+	assert.NotContains(t, d.Searches, "condition")
+}
@@ -0,0 +1,435 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sigma
+
+import (
+	"fmt"
+	"github.com/rabbitstack/fibratus/pkg/filter/fields"
+	"github.com/rabbitstack/fibratus/pkg/filter/ql"
+	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
+	"github.com/rabbitstack/fibratus/pkg/mitre"
+	"github.com/rabbitstack/fibratus/pkg/rules"
+	"gopkg.in/yaml.v2"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UnsupportedError is returned when the Sigma rule relies on the features that have no equivalent in filters.
+type UnsupportedError struct {
+	Rule    string
+	Reasons []string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("rule %q has unsupported %s", e.Rule, strings.Join(e.Reasons, ", "))
+}
+
+// severities maps the Sigma rule levels to the rule severities.
+var severities = map[string]string{
+	"informational": "normal",
+	"low":           "normal",
+	"medium":        "medium",
+	"high":          "critical",
+	"critical":      "critical",
+}
+
+// Translate converts the Sigma rule to the detection rule. The rule condition is the filter
+// expression equivalent to the Sigma detection, and can also be used in the run or replay
+// commands or as the filament filter. The rule title and level are carried over to the rule
+// name and severity. If the Sigma rule uses the log sources, fields, modifiers or conditions
+// that can't be translated, the UnsupportedError enumerating all of them is returned.
+func Translate(r *Rule) (rules.Rule, error) {
+	t := &translator{}
+	name := r.Title
+	if name == "" {
+		name = r.ID
+	}
+
+	if r.Action != "" {
+		t.unsupported("rule collection")
+	}
+	if r.Logsource.Product != "" && r.Logsource.Product != "windows" {
+		t.unsupported("product %s", r.Logsource.Product)
+	}
+	ls, ok := logsources[r.Logsource.Category]
+	if !ok {
+		t.unsupported("log source category %q", r.Logsource.Category)
+	}
+	if r.Detection.Timeframe != "" {
+		t.unsupported("timeframe")
+	}
+	if len(r.Detection.Condition) == 0 {
+		t.unsupported("detection without condition")
+	}
+	if len(t.reasons) > 0 {
+		return rules.Rule{}, &UnsupportedError{Rule: name, Reasons: t.reasons}
+	}
+
+	searches := make(map[string]node, len(r.Detection.Searches))
+	names := make([]string, 0, len(r.Detection.Searches))
+	for name := range r.Detection.Searches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		searches[name] = t.translateSearch(ls, name, r.Detection.Searches[name])
+	}
+
+	conds := make([]node, 0, len(r.Detection.Condition))
+	for _, cond := range r.Detection.Condition {
+		n, err := newCondParser(cond, searches).parse()
+		if err != nil {
+			t.unsupported("%v", err)
+			continue
+		}
+		conds = append(conds, n)
+	}
+	if len(t.reasons) > 0 {
+		return rules.Rule{}, &UnsupportedError{Rule: name, Reasons: t.reasons}
+	}
+
+	cond := newOr(conds)
+	detection := render(cond, false)
+	if kind(cond, false) == "or" {
+		detection = "(" + detection + ")"
+	}
+	expr := ls.expr + " and " + detection
+
+	p := ql.NewParser(expr)
+	e, err := p.ParseExpr()
+	if err == nil {
+		err = p.Validate(e)
+	}
+	if err != nil {
+		return rules.Rule{}, fmt.Errorf("rule %q translates to invalid filter: %v", name, err)
+	}
+
+	tactics, techniques := attack(r.Tags)
+
+	return rules.Rule{
+		Name:        name,
+		Description: strings.TrimSpace(r.Description),
+		Condition:   expr,
+		Severity:    severities[r.Level],
+		Tags:        r.Tags,
+		Tactics:     tactics,
+		Techniques:  techniques,
+	}, nil
+}
+
+// attack extracts the MITRE ATT&CK tactics and techniques from the Sigma rule tags. Tactics are
+// tagged by their names, e.g. attack.defense_evasion, and techniques by their identifiers, e.g.
+// attack.t1140. Tags referring to groups, software or unknown techniques are ignored.
+func attack(tags []string) ([]string, []string) {
+	var tactics, techniques []string
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, "attack.") {
+			continue
+		}
+		name := strings.TrimPrefix(tag, "attack.")
+		if t, ok := mitre.LookupTactic(name); ok {
+			tactics = append(tactics, t.ID)
+			continue
+		}
+		if t, ok := mitre.LookupTacticByName(name); ok {
+			tactics = append(tactics, t.ID)
+			continue
+		}
+		if t, ok := mitre.LookupTechnique(name); ok {
+			techniques = append(techniques, t.ID)
+		}
+	}
+	return tactics, techniques
+}
+
+// translator collects the reasons the Sigma rule can't be translated.
+type translator struct {
+	reasons []string
+}
+
+func (t *translator) unsupported(format string, args ...interface{}) {
+	t.reasons = append(t.reasons, fmt.Sprintf(format, args...))
+}
+
+// translateSearch translates the search identifier. Field maps of the search are
+// joined with the or operator, while the fields of each map are joined with the
+// and operator.
+func (t *translator) translateSearch(ls logsource, name string, search Search) node {
+	if len(search.Keywords) > 0 {
+		t.unsupported("keywords in %s", name)
+		return nil
+	}
+	nreasons := len(t.reasons)
+	maps := make([]node, 0, len(search.Maps))
+	for _, m := range search.Maps {
+		preds := make([]node, 0, len(m))
+		for _, item := range m {
+			if n := t.translateField(ls, fmt.Sprintf("%v", item.Key), item.Value); n != nil {
+				preds = append(preds, n)
+			}
+		}
+		if len(preds) > 0 {
+			maps = append(maps, newAnd(preds))
+		}
+	}
+	if len(t.reasons) > nreasons {
+		return nil
+	}
+	if len(maps) == 0 {
+		t.unsupported("empty search %s", name)
+		return nil
+	}
+	return newOr(maps)
+}
+
+// translateField translates the field with modifiers and its values to predicates. Values
+// of the field are joined with the or operator, unless the all modifier is present.
+func (t *translator) translateField(ls logsource, key string, value interface{}) node {
+	mods := strings.Split(key, "|")
+	m, ok := ls.fields[mods[0]]
+	if !ok {
+		t.unsupported("field %s", mods[0])
+		return nil
+	}
+
+	kind := "eq"
+	all := false
+	for _, mod := range mods[1:] {
+		switch mod {
+		case "all":
+			all = true
+		case "contains", "startswith", "endswith", "re", "cidr", "lt", "lte", "gt", "gte":
+			if kind != "eq" {
+				t.unsupported("modifier %s combined with %s", mod, kind)
+				return nil
+			}
+			kind = mod
+		default:
+			t.unsupported("modifier %s", mod)
+			return nil
+		}
+	}
+
+	var vals []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		vals = v
+	default:
+		vals = []interface{}{v}
+	}
+	values := make([]string, 0, len(vals))
+	for _, v := range vals {
+		switch v := v.(type) {
+		case nil:
+			t.unsupported("null value of %s", mods[0])
+			return nil
+		case yaml.MapSlice, []interface{}:
+			t.unsupported("nested value of %s", mods[0])
+			return nil
+		default:
+			s := fmt.Sprintf("%v", v)
+			if m.value != nil {
+				var err error
+				s, err = m.value(s)
+				if err != nil {
+					t.unsupported("%v", err)
+					return nil
+				}
+			}
+			values = append(values, s)
+		}
+	}
+	if len(values) == 0 {
+		t.unsupported("empty value of %s", mods[0])
+		return nil
+	}
+
+	if all {
+		preds := make([]node, 0, len(values))
+		for _, v := range values {
+			n := t.predicates(m.field, kind, []string{v})
+			if n == nil {
+				return nil
+			}
+			preds = append(preds, n)
+		}
+		return newAnd(preds)
+	}
+	return t.predicates(m.field, kind, values)
+}
+
+// predicates builds the predicates that compare the field with the values. Sigma
+// string comparisons are case-insensitive and the values may contain wildcards,
+// so the case-insensitive operators are used and the values with wildcards are
+// compared with the imatches operator.
+func (t *translator) predicates(field fields.Field, kind string, values []string) node {
+	typ := field.Type()
+	switch {
+	case kind == "lt" || kind == "lte" || kind == "gt" || kind == "gte":
+		if !isNumeric(typ) {
+			t.unsupported("modifier %s on %s", kind, field)
+			return nil
+		}
+		ops := map[string]string{"lt": "<", "lte": "<=", "gt": ">", "gte": ">="}
+		preds := make([]node, 0, len(values))
+		for _, v := range values {
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				t.unsupported("non-numeric value %s of %s", v, field)
+				return nil
+			}
+			preds = append(preds, &predicate{field: field, op: ops[kind], values: []string{v}})
+		}
+		return newOr(preds)
+
+	case kind == "re":
+		return &predicate{field: field, op: "regex", values: quoteAll(values)}
+
+	case kind == "cidr":
+		if !isIP(typ) {
+			t.unsupported("modifier cidr on %s", field)
+			return nil
+		}
+		for _, v := range values {
+			if _, _, err := net.ParseCIDR(v); err != nil {
+				t.unsupported("invalid CIDR %s", v)
+				return nil
+			}
+		}
+		return &predicate{field: field, op: "in", values: quoteAll(values), list: true}
+
+	case isNumeric(typ):
+		if kind != "eq" {
+			t.unsupported("modifier %s on %s", kind, field)
+			return nil
+		}
+		preds := make([]node, 0, len(values))
+		for _, v := range values {
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				t.unsupported("non-numeric value %s of %s", v, field)
+				return nil
+			}
+			preds = append(preds, &predicate{field: field, op: "=", values: []string{v}})
+		}
+		return newOr(preds)
+
+	case isIP(typ):
+		if kind != "eq" {
+			t.unsupported("modifier %s on %s", kind, field)
+			return nil
+		}
+		for _, v := range values {
+			if net.ParseIP(v) == nil {
+				t.unsupported("non-IP value %s of %s", v, field)
+				return nil
+			}
+		}
+		return &predicate{field: field, op: "in", values: quoteAll(values), list: true}
+	}
+
+	literals := make([]string, 0, len(values))
+	patterns := make([]string, 0)
+	for _, v := range values {
+		lit, pattern, ok := unescape(v)
+		if !ok {
+			t.unsupported("escaped wildcard in %s", v)
+			return nil
+		}
+		if pattern == "" {
+			literals = append(literals, lit)
+			continue
+		}
+		switch kind {
+		case "contains":
+			pattern = "*" + pattern + "*"
+		case "startswith":
+			pattern += "*"
+		case "endswith":
+			pattern = "*" + pattern
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	preds := make([]node, 0, 2)
+	if len(literals) > 0 {
+		p := &predicate{field: field, values: quoteAll(literals)}
+		switch kind {
+		case "eq":
+			p.op, p.list = "iin", true
+		default:
+			p.op = "i" + kind
+		}
+		preds = append(preds, p)
+	}
+	if len(patterns) > 0 {
+		preds = append(preds, &predicate{field: field, op: "imatches", values: quoteAll(patterns)})
+	}
+	return newOr(preds)
+}
+
+// unescape resolves the escape sequences of the Sigma value. If the value contains
+// wildcards, the wildcard pattern is returned. Patterns can't contain the escaped
+// wildcards, in which case the value is reported as not valid.
+func unescape(s string) (lit string, pattern string, ok bool) {
+	var (
+		b        strings.Builder
+		wildcard bool
+		escaped  bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && (s[i+1] == '*' || s[i+1] == '?' || s[i+1] == '\\'):
+			i++
+			escaped = escaped || s[i] != '\\'
+			b.WriteByte(s[i])
+		case c == '*' || c == '?':
+			wildcard = true
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if !wildcard {
+		return b.String(), "", true
+	}
+	return "", b.String(), !escaped
+}
+
+func quoteAll(values []string) []string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = ql.Quote(v)
+	}
+	return quoted
+}
+
+func isNumeric(typ kparams.Type) bool {
+	switch typ {
+	case kparams.Int8, kparams.Uint8, kparams.Int16, kparams.Uint16, kparams.Int32, kparams.Uint32,
+		kparams.Int64, kparams.Uint64, kparams.Float, kparams.Double, kparams.PID, kparams.TID, kparams.Port:
+		return true
+	}
+	return false
+}
+
+func isIP(typ kparams.Type) bool {
+	return typ == kparams.IP || typ == kparams.IPv4 || typ == kparams.IPv6
+}
@@ -0,0 +1,308 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sigma
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	var tests = []struct {
+		rule string
+		expr string
+	}{
+		{
+			`
+logsource:
+  category: process_creation
+detection:
+  selection:
+    Image|endswith: '\whoami.exe'
+  condition: selection
+`,
+			`kevt.name = 'CreateProcess' and ps.exe iendswith '\\whoami.exe'`,
+		},
+		{
+			`
+logsource:
+  category: process_creation
+  product: windows
+detection:
+  selection:
+    Image:
+      - 'C:\Windows\System32\cmd.exe'
+      - 'C:\Windows\System32\powershell.exe'
+    CommandLine|contains|all:
+      - ' -enc '
+      - 'hidden'
+  condition: selection
+`,
+			`kevt.name = 'CreateProcess' and ps.exe iin ('C:\\Windows\\System32\\cmd.exe', 'C:\\Windows\\System32\\powershell.exe') and ps.comm icontains ' -enc ' and ps.comm icontains 'hidden'`,
+		},
+		{
+			`
+logsource:
+  category: process_creation
+detection:
+  selection:
+    CommandLine|contains:
+      - 'vssadmin*delete'
+      - 'shadowcopy'
+    ParentImage|startswith: 'C:\Users\'
+  condition: selection
+`,
+			`kevt.name = 'CreateProcess' and (ps.comm icontains 'shadowcopy' or ps.comm imatches '*vssadmin*delete*') and ps.parent.exe istartswith 'C:\\Users\\'`,
+		},
+		{
+			`
+logsource:
+  category: process_creation
+detection:
+  selection1:
+    Image|endswith: '\rundll32.exe'
+  selection2:
+    OriginalFileName: 'RUNDLL32.EXE'
+  filter:
+    CommandLine|contains:
+      - 'shell32.dll'
+      - 'zipfldr.dll'
+    ParentProcessId: 4
+  condition: 1 of selection* and not filter
+`,
+			`kevt.name = 'CreateProcess' and (ps.exe iendswith '\\rundll32.exe' or pe.resources[OriginalFilename] iin ('RUNDLL32.EXE')) and (ps.comm not icontains ('shell32.dll', 'zipfldr.dll') or ps.ppid != 4)`,
+		},
+		{
+			`
+logsource:
+  category: process_creation
+detection:
+  selection:
+    CommandLine|re: '\s-[eE]nc\s'
+  _filter:
+    Image: 'C:\Program Files\\*'
+  condition: 1 of them or not (selection or _filter)
+`,
+			`kevt.name = 'CreateProcess' and (ps.comm regex '\\s-[eE]nc\\s' or (ps.comm not regex '\\s-[eE]nc\\s' and ps.exe not imatches 'C:\\Program Files\\*'))`,
+		},
+		{
+			`
+logsource:
+  category: file_event
+detection:
+  selection:
+    TargetFilename|endswith:
+      - '.ps1'
+      - '.vbs'
+  condition: selection
+`,
+			`kevt.name = 'CreateFile' and file.operation in ('create', 'supersede', 'openif', 'overwriteif') and file.name iendswith ('.ps1', '.vbs')`,
+		},
+		{
+			`
+logsource:
+  category: registry_event
+detection:
+  selection:
+    TargetObject|startswith: 'HKLM\SYSTEM\CurrentControlSet\Services\'
+    EventType: SetValue
+    Details: 'It''s\*'
+  condition: selection
+`,
+			`kevt.name in ('RegCreateKey', 'RegDeleteKey', 'RegSetValue', 'RegDeleteValue') and registry.key.name istartswith 'HKEY_LOCAL_MACHINE\\SYSTEM\\CurrentControlSet\\Services\\' and kevt.name iin ('RegSetValue') and registry.value iin ('It\'s*')`,
+		},
+		{
+			`
+logsource:
+  category: network_connection
+detection:
+  selection:
+    DestinationPort:
+      - 445
+      - '139'
+    Initiated: 'true'
+  filter:
+    DestinationIp|cidr: '10.0.0.0/8'
+  localhost:
+    DestinationIp:
+      - '127.0.0.1'
+      - '::1'
+  condition: selection and not (filter or localhost)
+`,
+			`kevt.name in ('Connect', 'Accept') and (net.dport = 445 or net.dport = 139) and kevt.name iin ('Connect') and net.dip not in ('10.0.0.0/8') and net.dip not in ('127.0.0.1', '::1')`,
+		},
+		{
+			`
+logsource:
+  category: network_connection
+detection:
+  selection:
+    SourcePort|gte: 49152
+    DestinationIp: '10.0.2.15'
+  condition: not selection
+`,
+			`kevt.name in ('Connect', 'Accept') and (net.sport < 49152 or net.dip not in ('10.0.2.15'))`,
+		},
+		{
+			`
+logsource:
+  category: image_load
+detection:
+  selection:
+    ImageLoaded|endswith: '\dbghelp.dll'
+    Image|endswith:
+      - '\winword.exe'
+      - '\excel.exe'
+  condition: selection
+`,
+			`kevt.name = 'LoadImage' and image.name iendswith '\\dbghelp.dll' and ps.exe iendswith ('\\winword.exe', '\\excel.exe')`,
+		},
+	}
+
+	for i, tt := range tests {
+		rules, err := Parse([]byte(tt.rule))
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		r, err := Translate(rules[0])
+		require.NoError(t, err, "%d. %s", i, tt.rule)
+		assert.Equal(t, tt.expr, r.Condition, "%d. %s", i, tt.rule)
+	}
+}
+
+func TestTranslateRule(t *testing.T) {
+	rules, err := Load([]string{"_fixtures/proc_creation_win_susp_certutil.yml"})
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+
+	r, err := Translate(rules[0])
+	require.NoError(t, err)
+	assert.Equal(t, "Suspicious Certutil Command", r.Name)
+	assert.Equal(t, "Detects a suspicious certutil command that decodes or downloads files", r.Description)
+	assert.Equal(t, "critical", r.Severity)
+	assert.Equal(t, []string{"attack.defense_evasion", "attack.t1140"}, r.Tags)
+	assert.Equal(t, []string{"TA0005"}, r.Tactics)
+	assert.Equal(t, []string{"T1140"}, r.Techniques)
+	assert.Equal(t, `kevt.name = 'CreateProcess' and ps.comm icontains (' -decode ', ' -urlcache ') and (ps.exe iendswith '\\certutil.exe' or pe.resources[OriginalFilename] iin ('CertUtil.exe')) and ps.parent.exe not istartswith 'C:\\Program Files\\'`, r.Condition)
+}
+
+func TestTranslateUnsupported(t *testing.T) {
+	var tests = []struct {
+		rule    string
+		reasons []string
+	}{
+		{
+			`
+logsource:
+  category: process_access
+detection:
+  selection:
+    TargetImage|endswith: '\lsass.exe'
+  condition: selection
+`,
+			[]string{`log source category "process_access"`},
+		},
+		{
+			`
+logsource:
+  category: process_creation
+  product: linux
+detection:
+  selection:
+    Image|endswith: '/bash'
+  condition: selection
+`,
+			[]string{"product linux"},
+		},
+		{
+			`
+logsource:
+  category: process_creation
+detection:
+  selection:
+    CommandLine|base64offset|contains: 'IEX'
+    User: 'SYSTEM'
+    IntegrityLevel: null
+  keywords:
+    - 'mimikatz'
+  condition: selection or keywords
+`,
+			[]string{"keywords in keywords", "modifier base64offset", "field User", "field IntegrityLevel"},
+		},
+		{
+			`
+logsource:
+  category: network_connection
+detection:
+  selection:
+    DestinationIp|startswith: '10.'
+    DestinationPort|contains: '44'
+    SourcePort: 'http'
+    Initiated: 'maybe'
+  condition: selection
+`,
+			[]string{"modifier startswith on net.dip", "modifier contains on net.dport", "non-numeric value http of net.sport", "Initiated value maybe"},
+		},
+		{
+			`
+logsource:
+  category: process_creation
+detection:
+  selection:
+    Image|endswith: '\svchost.exe'
+    CommandLine: '*\*'
+  condition: selection | count() by ParentImage > 10
+`,
+			[]string{`escaped wildcard in *\*`, "aggregation in condition"},
+		},
+		{
+			`
+logsource:
+  category: process_creation
+detection:
+  selection:
+    Image|endswith: '\svchost.exe'
+  condition: selection and (filter or 1 of sel_*
+`,
+			[]string{`condition referencing undefined search "filter"`},
+		},
+		{
+			`
+logsource:
+  category: process_creation
+detection:
+  selection:
+    Image|endswith: '\svchost.exe'
+  timeframe: 5m
+  condition: selection
+`,
+			[]string{"timeframe"},
+		},
+	}
+
+	for i, tt := range tests {
+		rules, err := Parse([]byte(tt.rule))
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		_, err = Translate(rules[0])
+		require.Error(t, err, "%d. %s", i, tt.rule)
+		require.IsType(t, &UnsupportedError{}, err)
+		assert.Equal(t, tt.reasons, err.(*UnsupportedError).Reasons, "%d. %s", i, tt.rule)
+	}
+}
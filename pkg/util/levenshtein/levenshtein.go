@@ -0,0 +1,72 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package levenshtein
+
+import "unicode/utf8"
+
+// Distance computes the Levenshtein edit distance between two strings, i.e. the minimum
+// number of single rune insertions, deletions or substitutions that transform one string
+// into the other.
+func Distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	if len(s) == 0 {
+		return len(t)
+	}
+	if len(t) == 0 {
+		return len(s)
+	}
+	// only the previous row of the distance matrix is kept
+	row := make([]int, len(t)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			curr := row[j]
+			row[j] = min(row[j]+1, row[j-1]+1, prev+cost)
+			prev = curr
+		}
+	}
+	return row[len(t)]
+}
+
+// Match determines whether the edit distance between two strings doesn't exceed the maximum distance.
+func Match(a, b string, maxDistance int) bool {
+	// the difference in length is the lower bound of the distance
+	if d := utf8.RuneCountInString(a) - utf8.RuneCountInString(b); d > maxDistance || -d > maxDistance {
+		return false
+	}
+	return Distance(a, b) <= maxDistance
+}
+
+func min(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package levenshtein
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	var tests = []struct {
+		a, b string
+		d    int
+	}{
+		{"", "", 0},
+		{"svchost.exe", "svchost.exe", 0},
+		{"svchost.exe", "svch0st.exe", 1},
+		{"svchost.exe", "svchosts.exe", 1},
+		{"svchost.exe", "scvhost.exe", 2},
+		{"lsass.exe", "lsas.exe", 1},
+		{"", "cmd.exe", 7},
+		{"kitten", "sitting", 3},
+		{"ｓｖchost.exe", "svchost.exe", 2},
+		{"svch😀st.exe", "svchost.exe", 1},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.d, Distance(tt.a, tt.b), tt.a+" "+tt.b)
+		assert.Equal(t, tt.d, Distance(tt.b, tt.a), tt.b+" "+tt.a)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	assert.True(t, Match("svchost.exe", "svch0st.exe", 2))
+	assert.True(t, Match("svchost.exe", "scvhost.exe", 2))
+	assert.True(t, Match("ｓｖchost.exe", "svchost.exe", 2))
+	assert.True(t, Match("svch😀st.exe", "svchost.exe", 1))
+	assert.False(t, Match("ｓｖchost.exe", "svchost.exe", 1))
+	assert.False(t, Match("svchost.exe", "svc.exe", 2))
+	assert.False(t, Match("cmd.exe", "powershell.exe", 2))
+}
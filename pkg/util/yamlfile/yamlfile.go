@@ -0,0 +1,87 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamlfile
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsYAML determines if the file is recognized as the YAML file by the .yml or .yaml extension.
+func IsYAML(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	return ext == ".yml" || ext == ".yaml"
+}
+
+// Walk calls the function for each YAML file found in the paths. Each path is either
+// the YAML file or the directory that is recursively scanned for YAML files.
+func Walk(paths []string, fn func(file string, info os.FileInfo) error) error {
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !IsYAML(file) {
+				return nil
+			}
+			return fn(file, info)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads the lists of named definitions, such as rules or exceptions, from the YAML files
+// found in the paths. Each definition must have the name that is unique across all files. The
+// kind of definitions is given for error messages. The decode function unmarshals the contents
+// of each file and validates the decoded definitions.
+func Load(paths []string, kind string, decode func(file string, b []byte) error) error {
+	names := make(map[string]string)
+	return Walk(paths, func(file string, _ os.FileInfo) error {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var defs []struct {
+			Name string `yaml:"name"`
+		}
+		if err := yaml.Unmarshal(b, &defs); err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		for i, def := range defs {
+			if def.Name == "" {
+				return fmt.Errorf("%s: %s #%d has no name", file, kind, i+1)
+			}
+			if f, ok := names[def.Name]; ok {
+				return fmt.Errorf("%s: %s %q is already defined in %s", file, kind, def.Name, f)
+			}
+			names[def.Name] = file
+		}
+		if err := decode(file, b); err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2019-2020 by Nedim Sabic Sabic
+ * https://www.fibratus.io
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package yamlfile
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yamlfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "network"), os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "process.yml"), []byte("- name: Process"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "network", "smb.YAML"), []byte("- name: SMB"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# Rules"), 0644))
+
+	var files []string
+	require.NoError(t, Load([]string{dir}, "rule", func(file string, b []byte) error {
+		files = append(files, filepath.Base(file))
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{"process.yml", "smb.YAML"}, files)
+
+	err = Load([]string{dir}, "rule", func(file string, b []byte) error { return errors.New("rule \"Process\" has no condition") })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has no condition")
+}
+
+func TestLoadErrors(t *testing.T) {
+	var tests = []struct {
+		files []string
+		err   string
+	}{
+		{[]string{"- name: Process\n- name: Process"}, "rule \"Process\" is already defined in"},
+		{[]string{"- name: Process", "- name: Process"}, "rule \"Process\" is already defined in"},
+		{[]string{"- name: Process\n- condition: ps.name = 'cmd.exe'"}, "rule #2 has no name"},
+		{[]string{"name: Process"}, "cannot unmarshal"},
+	}
+
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "yamlfile")
+		require.NoError(t, err)
+		for i, f := range tt.files {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, string(rune('a'+i))+".yml"), []byte(f), 0644))
+		}
+		err = Load([]string{dir}, "rule", func(file string, b []byte) error { return nil })
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), tt.err)
+		_ = os.RemoveAll(dir)
+	}
+
+	require.Error(t, Load([]string{"_fixtures/missing"}, "rule", func(file string, b []byte) error { return nil }))
+}
@@ -26,6 +26,7 @@ import (
 	"fmt"
 	"github.com/hillu/go-yara/v4"
 	"github.com/rabbitstack/fibratus/pkg/alertsender"
+	"github.com/rabbitstack/fibratus/pkg/mitre"
 	"github.com/rabbitstack/fibratus/pkg/ps"
 	pstypes "github.com/rabbitstack/fibratus/pkg/ps/types"
 	"github.com/rabbitstack/fibratus/pkg/util/multierror"
@@ -34,6 +35,7 @@ import (
 	"html/template"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -359,7 +361,7 @@ func (s scanner) send(ctx AlertContext) error {
 		text,
 		tagsFromMatches(ctx.Matches),
 		alertsender.Normal,
-	)
+	).WithAttack(attackFromMatches(ctx.Matches))
 
 	log.Infof("emitting yara alert via %q sender: %s", s.config.AlertVia, alert)
 
@@ -389,6 +391,41 @@ func tagsFromMatches(matches []yara.MatchRule) []string {
 	return tags
 }
 
+// attackFromMatches collects MITRE ATT&CK tactics and techniques from the tactic and technique
+// metas of matching rules. Metas contain the comma-separated list of identifiers. Identifiers
+// that are not present in the catalog are ignored.
+func attackFromMatches(matches []yara.MatchRule) ([]string, []string) {
+	var tactics, techniques []string
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		for _, meta := range match.Metas {
+			v, ok := meta.Value.(string)
+			if !ok {
+				continue
+			}
+			for _, id := range strings.Split(v, ",") {
+				id = strings.ToUpper(strings.TrimSpace(id))
+				if seen[id] {
+					continue
+				}
+				switch meta.Identifier {
+				case "tactic", "mitre_tactic":
+					if _, ok := mitre.LookupTactic(id); ok {
+						tactics = append(tactics, id)
+						seen[id] = true
+					}
+				case "technique", "mitre_technique":
+					if _, ok := mitre.LookupTechnique(id); ok {
+						techniques = append(techniques, id)
+						seen[id] = true
+					}
+				}
+			}
+		}
+	}
+	return tactics, techniques
+}
+
 func (s scanner) Close() {
 	if s.c != nil {
 		s.c.Destroy()
@@ -21,6 +21,7 @@
 package yara
 
 import (
+	"github.com/hillu/go-yara/v4"
 	"github.com/rabbitstack/fibratus/pkg/alertsender"
 	htypes "github.com/rabbitstack/fibratus/pkg/handle/types"
 	"github.com/rabbitstack/fibratus/pkg/kevent/kparams"
@@ -183,3 +184,20 @@ func TestScan(t *testing.T) {
 	assert.Contains(t, yaraAlert.Tags, "dll")
 
 }
+
+func TestAttackFromMatches(t *testing.T) {
+	matches := []yara.MatchRule{
+		{Rule: "mimikatz", Metas: []yara.Meta{
+			{Identifier: "author", Value: "rabbitstack"},
+			{Identifier: "tactic", Value: "TA0006"},
+			{Identifier: "technique", Value: "T1003.001, t1003"},
+		}},
+		{Rule: "lsass_dump", Metas: []yara.Meta{
+			{Identifier: "mitre_technique", Value: "T1003.001,T9999"},
+			{Identifier: "mitre_tactic", Value: 6},
+		}},
+	}
+	tactics, techniques := attackFromMatches(matches)
+	assert.Equal(t, []string{"TA0006"}, tactics)
+	assert.Equal(t, []string{"T1003.001", "T1003"}, techniques)
+}